@@ -0,0 +1,64 @@
+package mysqldump
+
+import (
+	"io"
+	"sync/atomic"
+
+	binary "github.com/MouseHatGames/go-mysqldump/internal/marshal"
+)
+
+// Bandwidth counts the bytes moved by a dump, to attribute its network costs to the schema dumped.
+type Bandwidth struct {
+	Schema string
+	// Size of the values read from the server, before row transformers. Protocol overhead isn't counted
+	ReadBytes int64
+	// Bytes written to the output, sent again to every destination it is copied to
+	WrittenBytes int64
+}
+
+// CostModel prices the bytes moved by a dump, per GB of 10^9 bytes.
+type CostModel struct {
+	// Egress from the database server
+	ReadPerGB float64
+	// Transfer to a destination
+	WritePerGB float64
+}
+
+// Cost returns the price of a dump whose output was written to the given number of destinations.
+func (m CostModel) Cost(b Bandwidth, destinations int) float64 {
+	return float64(b.ReadBytes)/1e9*m.ReadPerGB + float64(b.WrittenBytes)*float64(destinations)/1e9*m.WritePerGB
+}
+
+// Bandwidth returns the bytes moved by the current or last dump. It can be called while dumping.
+func (d *Dumper) Bandwidth() Bandwidth {
+	return Bandwidth{
+		Schema:       d.dumpSchema,
+		ReadBytes:    atomic.LoadInt64(&d.readBytes),
+		WrittenBytes: atomic.LoadInt64(&d.writtenBytes),
+	}
+}
+
+// countRead counts the values of a row read from the server, pacing them with the throttle. Prefetched
+// chunks are read concurrently.
+func (d *Dumper) countRead(row binary.RowData) {
+	var n int64
+	for _, v := range row {
+		if v != nil {
+			n += int64(len(*v))
+		}
+	}
+	atomic.AddInt64(&d.readBytes, n)
+	d.throttleRead(n)
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n *int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	atomic.AddInt64(c.n, int64(n))
+	return n, err
+}
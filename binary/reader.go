@@ -0,0 +1,177 @@
+// Package binary reads the dumps written by mysqldump.Dumper, for tools consuming them without restoring them.
+package binary
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/MouseHatGames/go-mysqldump/internal/marshal"
+)
+
+// FileHeader is the header written at the start of every dump.
+type FileHeader = marshal.FileHeader
+
+// TableHeader precedes the rows of every table in a dump.
+type TableHeader = marshal.TableHeader
+
+// SchemaObject is a view, stored routine, trigger or event recorded after the tables of a dump.
+type SchemaObject = marshal.SchemaObject
+
+// FileFooter is written at the end of every dump.
+type FileFooter = marshal.FileFooter
+
+// BinlogPosition is the binary log position a dump's data was read at.
+type BinlogPosition = marshal.BinlogPosition
+
+// RowData holds the values of a single row, nil meaning NULL.
+type RowData = marshal.RowData
+
+// NoData marks a table whose rows were left out of a dump on purpose.
+type NoData = marshal.NoData
+
+// Reader iterates over the tables of a dump and their rows:
+//
+//	r := binary.NewReader(f)
+//	h, err := r.ReadFileHeader()
+//	for r.NextTable() {
+//		t := r.Table()
+//		for r.NextRow() {
+//			row := r.Row()
+//		}
+//	}
+//	if err := r.Err(); err != nil {
+//	}
+//
+// The tables of a dump written by partition or shard come in one section per partition or shard,
+// each with its own header. Tables of interleaved dumps are returned one after the other like any others.
+type Reader struct {
+	r      *marshal.Reader
+	header *FileHeader
+
+	table *TableHeader
+	row   RowData
+	// Set once the rows of table are all read
+	rowsDone bool
+	objects  []*SchemaObject
+	footer   *FileFooter
+	done     bool
+	err      error
+}
+
+// NewReader returns a Reader reading a dump from in.
+func NewReader(in io.Reader) *Reader {
+	return &Reader{r: marshal.NewReader(in)}
+}
+
+// ReadFileHeader reads the header of the dump, which must come before anything else.
+func (r *Reader) ReadFileHeader() (*FileHeader, error) {
+	h, err := r.r.ReadFileHeader()
+	if err != nil {
+		return nil, fmt.Errorf("read file header: %w", err)
+	}
+	r.header = h
+	return h, nil
+}
+
+// Binlog returns the binary log coordinates and GTID set the data of the dump was read at, to start
+// replicating from once the dump is restored. It is nil before ReadFileHeader and for dumps that don't
+// record them, see mysqldump.WithBinlogPosition.
+func (r *Reader) Binlog() *BinlogPosition {
+	if r.header == nil {
+		return nil
+	}
+	return r.header.Binlog
+}
+
+// NextTable moves to the next table, skipping the rows of the current one that weren't read. It returns
+// false once there are no more tables or on an error, see Err.
+func (r *Reader) NextTable() bool {
+	if r.done {
+		return false
+	}
+	for r.NextRow() {
+	}
+	if r.err != nil {
+		return false
+	}
+	r.table, r.row, r.rowsDone = nil, nil, false
+
+	t, err := r.r.ReadTableHeader()
+	if err != nil {
+		if !errors.Is(err, io.EOF) {
+			return r.fail(fmt.Errorf("read table header: %w", err))
+		}
+
+		r.done = true
+		for {
+			o, err := r.r.ReadObject()
+			if errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return r.fail(err)
+			}
+			r.objects = append(r.objects, o)
+		}
+		if r.footer, err = r.r.ReadFileFooter(); err != nil {
+			r.err = fmt.Errorf("read file footer: %w", err)
+		}
+		return false
+	}
+
+	r.table = t
+	return true
+}
+
+// Table returns the header of the current table.
+func (r *Reader) Table() *TableHeader {
+	return r.table
+}
+
+// NextRow moves to the next row of the current table. It returns false once the table has no more
+// rows or on an error, see Err.
+func (r *Reader) NextRow() bool {
+	if r.table == nil || r.rowsDone {
+		return false
+	}
+
+	row, err := r.r.ReadRow(len(r.table.Columns))
+	if err != nil {
+		r.row, r.rowsDone = nil, true
+		if !errors.Is(err, io.EOF) {
+			return r.fail(fmt.Errorf("read row of %s: %w", r.table.Name, err))
+		}
+		return false
+	}
+
+	r.row = row
+	return true
+}
+
+// Row returns the current row, with a value for every column of the table.
+func (r *Reader) Row() RowData {
+	return r.row
+}
+
+// Objects returns the views, stored routines, triggers and events of the dump once NextTable returned
+// false, in the order they are created in.
+func (r *Reader) Objects() []*SchemaObject {
+	return r.objects
+}
+
+// Footer returns the footer of the dump once NextTable returned false, nil for dumps written before
+// footers existed. Partial dumps are marked as such.
+func (r *Reader) Footer() *FileFooter {
+	return r.footer
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (r *Reader) Err() error {
+	return r.err
+}
+
+func (r *Reader) fail(err error) bool {
+	r.err = err
+	r.done = true
+	return false
+}
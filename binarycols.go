@@ -0,0 +1,71 @@
+package mysqldump
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// isBinaryType reports whether values of a column type, as named by sql.ColumnType.DatabaseTypeName,
+// are raw bytes rather than text in the connection's character set.
+func isBinaryType(typ string) bool {
+	switch strings.ToUpper(typ) {
+	case "BINARY", "VARBINARY", "TINYBLOB", "BLOB", "MEDIUMBLOB", "LONGBLOB", "BIT", "GEOMETRY", "BYTEA":
+		return true
+	}
+	return false
+}
+
+// binaryColumns returns the columns of a result holding raw bytes, see TableHeader.BinaryColumns.
+func binaryColumns(rows *sql.Rows) ([]string, error) {
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	var cols []string
+	for _, t := range types {
+		if isBinaryType(t.DatabaseTypeName()) {
+			cols = append(cols, t.Name())
+		}
+	}
+	return cols, nil
+}
+
+// tableBinaryColumns returns the columns of a table holding raw bytes, read from the types of an
+// empty result.
+func (d *Dumper) tableBinaryColumns(name string) ([]string, error) {
+	rows, err := d.query(stmtColumnTypes(name, d.isPQ()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return binaryColumns(rows)
+}
+
+// binaryFlags returns whether each of columns holds raw bytes.
+func binaryFlags(binaryCols []string, columns []string) []bool {
+	if len(binaryCols) == 0 {
+		return nil
+	}
+
+	flags := make([]bool, len(columns))
+	for i, c := range columns {
+		flags[i] = indexFold(binaryCols, c) >= 0
+	}
+	return flags
+}
+
+// writeHex writes a value of a binary column as a hex literal, so that the bytes reach the column
+// as they are whatever the character set of the connection, returning the number of bytes written.
+func writeHex(w io.Writer, v string) int {
+	if v == "" {
+		fmt.Fprint(w, "''")
+		return 2
+	}
+	fmt.Fprint(w, "0x")
+	fmt.Fprint(w, hex.EncodeToString([]byte(v)))
+	return 2 + 2*len(v)
+}
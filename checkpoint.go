@@ -0,0 +1,147 @@
+package mysqldump
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Checkpoint records how far a dump got, saved when it is interrupted and after every chunk of rows
+// reaches the output, see WithCheckpointFile and Dumper.ResumeDump.
+type Checkpoint struct {
+	Database string
+	// Tables that were dumped completely
+	Done []string
+	// Table that was being dumped, if any, and the position its next chunk starts at
+	Table string
+	// Index of the partition, shard or column group of the table being dumped, see WithPartitions,
+	// WithShards and WithColumnGroups
+	Unit      int `json:",omitempty"`
+	Partition string
+	Shard     int
+	Filter    int
+	Offset    int
+	// Key of the last row read of the table, which its next chunk starts after
+	After []interface{} `json:",omitempty"`
+	// Column group of the table being dumped, see WithColumnGroups
+	ColumnGroup int `json:",omitempty"`
+}
+
+// save writes the checkpoint to a temporary file renamed over path, so that a dump dying while it
+// is saved leaves the previous checkpoint intact.
+func (c *Checkpoint) save(path string) error {
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp-")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// ReadCheckpoint reads a checkpoint saved by an interrupted dump.
+func ReadCheckpoint(path string) (*Checkpoint, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Checkpoint
+	if err = json.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// ResumeDump reads the checkpoint of a dump that was interrupted or died, see WithCheckpointFile. The
+// next call to Dump or DumpAllTables, given the same tables and options, then writes a new dump holding
+// only what that one didn't get to: tables it dumped completely are skipped, and the rows of the table
+// it was in continue after the last chunk that reached its output. Restoring that dump and then the
+// new one restores the database. Unlike Resume, the new dump can be compressed, encrypted or in any
+// format. Tables are read one at a time.
+func (d *Dumper) ResumeDump(checkpointPath string) error {
+	if d.journalFile != "" {
+		return errors.New("journaled dumps are resumed with Resume")
+	}
+	c, err := ReadCheckpoint(checkpointPath)
+	if err != nil {
+		return fmt.Errorf("read checkpoint: %w", err)
+	}
+
+	r := &journalResume{done: make(map[string]bool), checkpoint: c}
+	for _, t := range c.Done {
+		r.done[t] = true
+	}
+	if c.Table != "" {
+		r.at = &journalEntry{
+			Table:       c.Table,
+			Unit:        c.Unit,
+			Partition:   c.Partition,
+			Shard:       c.Shard,
+			ColumnGroup: c.ColumnGroup,
+			Filter:      c.Filter,
+			Offset:      c.Offset,
+			After:       c.After,
+		}
+	}
+	d.resume = r
+	return nil
+}
+
+// saveCheckpoint saves the checkpoint of a dump once what was written of it so far reached the
+// output: once the write queue wrote it, if any, and the compression and encryption flushed it.
+func (d *Dumper) saveCheckpoint() error {
+	if d.checkpointFile == "" {
+		return nil
+	}
+
+	c := d.checkpoint
+	c.Done = append([]string(nil), c.Done...)
+	save := func() error {
+		if err := d.flushOutput(); err != nil {
+			return fmt.Errorf("flush output: %w", err)
+		}
+		if err := c.save(d.checkpointFile); err != nil {
+			return fmt.Errorf("write checkpoint: %w", err)
+		}
+		return nil
+	}
+	if d.queue != nil {
+		return d.queue.then(save)
+	}
+	return save()
+}
+
+// checkpointChunk records a chunk of the unit of a table that reached the output, with the position
+// its next chunk starts at.
+func (d *Dumper) checkpointChunk(filter int, offset int, after []interface{}) error {
+	d.checkpoint.Filter, d.checkpoint.Offset, d.checkpoint.After = filter, offset, after
+	return d.saveCheckpoint()
+}
+
+// removeCheckpoint removes the checkpoint of a dump that is done.
+func (d *Dumper) removeCheckpoint() error {
+	if d.checkpointFile == "" {
+		return nil
+	}
+	if err := os.Remove(d.checkpointFile); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove checkpoint: %w", err)
+	}
+	return nil
+}
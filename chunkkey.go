@@ -0,0 +1,192 @@
+package mysqldump
+
+import (
+	"database/sql"
+	"sort"
+	"strings"
+)
+
+// Strategies the rows of a table are read in chunks with, see TableHeader.Chunking.
+const (
+	// Chunks are ordered by the primary key
+	ChunkPrimaryKey = "primary_key"
+	// Chunks are ordered by a unique index whose columns can't be NULL
+	ChunkUniqueKey = "unique_key"
+	// The table has no such key, its rows are read in a single query instead, consistent on its own
+	ChunkFullScan = "full_scan"
+)
+
+// chunkKey is the strategy a table is read with and the columns its chunks are ordered by.
+type chunkKey struct {
+	strategy string
+	columns  []string
+}
+
+// indexColumn is a column of an index of a table, in index order.
+type indexColumn struct {
+	index string
+	// Empty for functional key parts
+	column   string
+	primary  bool
+	unique   bool
+	nullable bool
+}
+
+// WithOrderByPrimaryKey makes the dumper read the rows of every table in the order of its primary key, or
+// of the unique index it is chunked by without one, also when not reading in chunks, so dumps of the
+// same data hold the same rows in the same order and can be compared byte for byte. Tables without
+// such a key are ordered by all their columns.
+func WithOrderByPrimaryKey() Option {
+	return func(d *Dumper) {
+		d.orderRows = true
+	}
+}
+
+// getChunkKey returns the key the chunks of a table are ordered by, each chunk starting after the key of
+// the last row of the previous one. Without a total order on the rows, chunks may hold a row twice and
+// leave another one out, so tables without a primary key or a unique index on NOT NULL columns are read
+// in a single query. Nothing is chosen when not reading in chunks.
+func (d *Dumper) getChunkKey(name string, schema string) (chunkKey, error) {
+	if d.chunkSize <= 0 {
+		return chunkKey{}, nil
+	}
+	return d.tableKey(name, schema)
+}
+
+// tableKey returns the key the rows of a table are ordered by, see getChunkKey.
+func (d *Dumper) tableKey(name string, schema string) (chunkKey, error) {
+	if k, ok := d.chunkKeys[name]; ok {
+		return k, nil
+	}
+
+	// OFFSET scans through a Vitess gateway are scattered over every shard, stream the whole table instead
+	k := chunkKey{strategy: ChunkFullScan}
+	if !d.isVitess() {
+		var cols []indexColumn
+		var err error
+		switch {
+		case d.isPQ():
+			cols, err = d.pqIndexColumns(name)
+		case d.legacyMetadata():
+			cols, err = d.showIndexColumns(name, schema)
+		default:
+			cols, err = d.indexColumns(name, schema)
+		}
+		if err != nil {
+			return chunkKey{}, err
+		}
+		k = pickChunkKey(cols)
+	}
+
+	if d.chunkKeys == nil {
+		d.chunkKeys = map[string]chunkKey{}
+	}
+	d.chunkKeys[name] = k
+	return k, nil
+}
+
+// pickChunkKey returns the primary key if there is one, or else the unique index on the fewest NOT NULL
+// columns, the first one by name on ties.
+func pickChunkKey(cols []indexColumn) chunkKey {
+	var names []string
+	indexes := map[string][]indexColumn{}
+	for _, c := range cols {
+		if indexes[c.index] == nil {
+			names = append(names, c.index)
+		}
+		indexes[c.index] = append(indexes[c.index], c)
+	}
+	sort.Strings(names)
+
+	best := chunkKey{strategy: ChunkFullScan}
+	for _, n := range names {
+		idx := indexes[n]
+		key := make([]string, 0, len(idx))
+		usable := true
+		for _, c := range idx {
+			if !c.unique || c.nullable || c.column == "" {
+				usable = false
+				break
+			}
+			key = append(key, c.column)
+		}
+		if !usable {
+			continue
+		}
+
+		if idx[0].primary {
+			return chunkKey{strategy: ChunkPrimaryKey, columns: key}
+		}
+		if best.columns == nil || len(key) < len(best.columns) {
+			best = chunkKey{strategy: ChunkUniqueKey, columns: key}
+		}
+	}
+	return best
+}
+
+// indexColumns lists the columns of the indexes of a table from INFORMATION_SCHEMA.STATISTICS.
+func (d *Dumper) indexColumns(name string, schema string) ([]indexColumn, error) {
+	rows, err := d.db.QueryContext(d.context(), stmtIndexColumns, name, schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cols []indexColumn
+	for rows.Next() {
+		var index, nullable string
+		var column sql.NullString
+		var nonUnique int
+		if err = rows.Scan(&index, &column, &nonUnique, &nullable); err != nil {
+			return nil, err
+		}
+		cols = append(cols, indexColumn{
+			index:    index,
+			column:   column.String,
+			primary:  index == "PRIMARY",
+			unique:   nonUnique == 0,
+			nullable: strings.EqualFold(nullable, "YES"),
+		})
+	}
+	return cols, rows.Err()
+}
+
+// pqIndexColumns lists the columns of the unique indexes of a table, leaving out partial indexes,
+// which don't hold every row.
+func (d *Dumper) pqIndexColumns(name string) ([]indexColumn, error) {
+	rows, err := d.db.QueryContext(d.context(), stmtIndexColumnsPQ, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cols []indexColumn
+	for rows.Next() {
+		c := indexColumn{unique: true}
+		var notNull bool
+		if err = rows.Scan(&c.index, &c.column, &c.primary, &notNull); err != nil {
+			return nil, err
+		}
+		c.nullable = !notNull
+		cols = append(cols, c)
+	}
+	return cols, rows.Err()
+}
+
+// quoteColumns returns a list of quoted column names, to order by.
+func quoteColumns(cols []string, pq bool) string {
+	q := make([]string, len(cols))
+	for i, c := range cols {
+		q[i] = quoteIdent(c, pq)
+	}
+	return strings.Join(q, ", ")
+}
+
+// quoteIdent quotes the name of a table or column for the server, doubling the quotes in it, so names
+// that are reserved words or hold dashes or quotes can't break the statement.
+func quoteIdent(name string, pq bool) string {
+	if pq {
+		return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+	}
+	return quoteName(name)
+}
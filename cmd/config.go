@@ -0,0 +1,659 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/MouseHatGames/go-mysqldump"
+	"github.com/conneqtech/std_pkg/cli"
+	"github.com/conneqtech/std_pkg/db/mysql"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// FileConfig describes a complete dump job, read from a YAML file:
+//
+//	source:
+//	  type: mysql
+//	  host: db:3306
+//	  username: backup
+//	  password: secret
+//	  database: app
+//	  chunk_size: 100000
+//	destinations:
+//	  - file: /backups/app.dump
+//	filters:
+//	  event_log: [" WHERE id >= 517837446"]
+//	  rate_limit_request_log: []
+//	exclude: ["sessions", "cache_*"]
+//	masking:
+//	  users:
+//	    email: fake_email
+//	    phone: "null"
+//	    name: constant:John Doe
+//	    token: hash
+//	    address: regex:/[0-9]+/0/
+//	rules:
+//	  payments: ["amount >= 0", "email matches ^[^@]+@[^@]+$"]
+//	row_counts:
+//	  users: non_empty
+//	  orders: 1000..
+//	guards:
+//	  max_value_size: 16MB
+//	  disallowed: ["\x00"]
+//	  on_violation: fail
+//	hooks:
+//	  before_dump: ["FLUSH LOGS"]
+//	  after_dump: ["UPDATE backups SET done = 1 WHERE id = 1"]
+//	  on_error: warn
+//	cost:
+//	  read_per_gb: 0.01
+//	  write_per_gb: 0.09
+//	schedule: "0 2 * * *"
+//	throttle: ["10MB", "02:00-05:00=unlimited"]
+//	table_limits:
+//	  "*": 2h
+//	  orders: 2h+500GB
+//	on_table_limit: skip
+type FileConfig struct {
+	Source       SourceConfig         `yaml:"source"`
+	Destinations []DestinationConfig  `yaml:"destinations"`
+	Filters      map[string][]string  `yaml:"filters"`
+	Masking      map[string]MaskRules `yaml:"masking"`
+	Queries      map[string]string    `yaml:"queries"`
+	Engines      map[string]string    `yaml:"engines"`
+	Transforms   []string             `yaml:"ddl_transforms"`
+	Hooks        HooksConfig          `yaml:"hooks"`
+	Include      []string             `yaml:"include"`
+	Exclude      []string             `yaml:"exclude"`
+	Order        []string             `yaml:"order"`
+	Priorities   map[string]int       `yaml:"priorities"`
+	Session      map[string]string    `yaml:"session"`
+	Rules        map[string][]string  `yaml:"rules"`
+	RowCounts    map[string]string    `yaml:"row_counts"`
+	Guards       GuardsConfig         `yaml:"guards"`
+	Cost         CostConfig           `yaml:"cost"`
+	Schedule     string               `yaml:"schedule"`
+	// Rate values are read at with time windows of their own, like dump --throttle
+	Throttle []string `yaml:"throttle"`
+	// Durations and sizes stopping tables, like dump --table_limits, and skip or fail
+	TableLimits  map[string]string `yaml:"table_limits"`
+	OnTableLimit string            `yaml:"on_table_limit"`
+	// Read every table back once it is written, like dump --read_back
+	ReadBack bool `yaml:"read_back"`
+}
+
+type SourceConfig struct {
+	Type      string `yaml:"type"`
+	Host      string `yaml:"host"`
+	Username  string `yaml:"username"`
+	Password  string `yaml:"password"`
+	Database  string `yaml:"database"`
+	ChunkSize int    `yaml:"chunk_size"`
+	// Read the next chunk of a table while the current one is written
+	Prefetch bool `yaml:"prefetch"`
+	// Read the rows of every table in primary key order, also without chunk_size
+	OrderByPK bool `yaml:"order_by_primary_key"`
+	// With chunk_size, read this many tables at once, interleaving their chunks in the dump
+	ParallelTables int `yaml:"parallel_tables"`
+	// Read this many tables at once, also without chunk_size
+	Concurrency int `yaml:"concurrency"`
+	// Count the rows and chunks of every table before reading any
+	Prime bool `yaml:"prime"`
+	// Dump partitioned tables partition by partition
+	Partitions bool `yaml:"partitions"`
+	// Split the rows of every table into this many sections by primary key hash
+	Shards int `yaml:"shards"`
+	// Dump tables with a primary key and more columns than this in groups of about this many columns
+	ColumnGroups int `yaml:"column_groups"`
+	// Compress the dump: none or gzip, at a level or 0 for the default of the codec
+	Compression      string `yaml:"compression"`
+	CompressionLevel int    `yaml:"compression_level"`
+	// Rows of system-versioned tables to dump: current, all or a timestamp
+	SystemTime string `yaml:"system_time"`
+	// Save the persistent InnoDB statistics of every table
+	OptimizerStats bool `yaml:"optimizer_stats"`
+	// How restores should create tables: drop, if_not_exists or error
+	CreatePolicy string `yaml:"create_policy"`
+	// Dump the stored functions and procedures, the triggers of the tables dumped and the events
+	Routines bool `yaml:"routines"`
+	Triggers bool `yaml:"triggers"`
+	Events   bool `yaml:"events"`
+	// Record the global variables and status of the server in the dump header
+	ServerSnapshot bool `yaml:"server_snapshot"`
+	// Times a chunk query failing with a transient error is tried again, 3 if unset, and the wait before
+	// the first retry, doubled before every next one
+	Retries    int    `yaml:"retries"`
+	RetryDelay string `yaml:"retry_delay"`
+}
+
+// HooksConfig holds the SQL statements run around the dump and its tables, see mysqldump.Hooks.
+type HooksConfig struct {
+	BeforeDump  []string                    `yaml:"before_dump"`
+	AfterDump   []string                    `yaml:"after_dump"`
+	BeforeTable []string                    `yaml:"before_table"`
+	AfterTable  []string                    `yaml:"after_table"`
+	Tables      map[string]TableHooksConfig `yaml:"tables"`
+	// fail or warn
+	OnError string `yaml:"on_error"`
+}
+
+// CostConfig prices the bytes moved by a dump in the report, per GB.
+type CostConfig struct {
+	// Egress from the source server
+	ReadPerGB float64 `yaml:"read_per_gb"`
+	// Transfer to each destination
+	WritePerGB float64 `yaml:"write_per_gb"`
+}
+
+// model returns the cost model of the config, nil if there is none.
+func (c CostConfig) model() *mysqldump.CostModel {
+	if c.ReadPerGB == 0 && c.WritePerGB == 0 {
+		return nil
+	}
+	return &mysqldump.CostModel{ReadPerGB: c.ReadPerGB, WritePerGB: c.WritePerGB}
+}
+
+type GuardsConfig struct {
+	// Sizes such as 16MB
+	MaxValueSize string   `yaml:"max_value_size"`
+	MaxRowSize   string   `yaml:"max_row_size"`
+	Disallowed   []string `yaml:"disallowed"`
+	// warn or fail
+	OnViolation string `yaml:"on_violation"`
+}
+
+// guards returns the value guards of the config, nil if there are none.
+func (g *GuardsConfig) guards() (*mysqldump.ValueGuards, error) {
+	if g.MaxValueSize == "" && g.MaxRowSize == "" && len(g.Disallowed) == 0 {
+		return nil, nil
+	}
+
+	vg := &mysqldump.ValueGuards{Disallowed: g.Disallowed}
+	var err error
+	if vg.OnViolation, err = mysqldump.ParseGuardPolicy(g.OnViolation); err != nil {
+		return nil, fmt.Errorf("on_violation: %w", err)
+	}
+	if g.MaxValueSize != "" {
+		n, err := parseSize(g.MaxValueSize)
+		if err != nil {
+			return nil, fmt.Errorf("max_value_size: %w", err)
+		}
+		vg.MaxValueSize = int(n)
+	}
+	if g.MaxRowSize != "" {
+		n, err := parseSize(g.MaxRowSize)
+		if err != nil {
+			return nil, fmt.Errorf("max_row_size: %w", err)
+		}
+		vg.MaxRowSize = int(n)
+	}
+	return vg, nil
+}
+
+type TableHooksConfig struct {
+	Before []string `yaml:"before"`
+	After  []string `yaml:"after"`
+}
+
+func (h *HooksConfig) hooks() mysqldump.Hooks {
+	// Checked by validate
+	policy, _ := mysqldump.ParseHookPolicy(h.OnError)
+	hooks := mysqldump.Hooks{
+		BeforeDump:  h.BeforeDump,
+		AfterDump:   h.AfterDump,
+		BeforeTable: h.BeforeTable,
+		AfterTable:  h.AfterTable,
+		OnError:     policy,
+	}
+	if len(h.Tables) > 0 {
+		hooks.Tables = make(map[string]mysqldump.TableHooks, len(h.Tables))
+		for t, th := range h.Tables {
+			hooks.Tables[t] = mysqldump.TableHooks{Before: th.Before, After: th.After}
+		}
+	}
+	return hooks
+}
+
+type DestinationConfig struct {
+	// Local file, or s3://bucket/key or gs://bucket/object URL uploaded to as the dump is written
+	File string `yaml:"file"`
+}
+
+// MaskRules maps column names to the rule used to mask them, see mysqldump.ParseMaskRule.
+type MaskRules map[string]string
+
+func loadFileConfig(path string) (*FileConfig, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fc FileConfig
+	if err = yaml.UnmarshalStrict(b, &fc); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if fc.Source.Type == "" {
+		fc.Source.Type = "mysql"
+	}
+
+	return &fc, nil
+}
+
+func (s *SourceConfig) opts() *mysql.Opts {
+	return &mysql.Opts{
+		Host:     s.Host,
+		Username: s.Username,
+		Password: s.Password,
+		Database: s.Database,
+	}
+}
+
+func (fc *FileConfig) connect() (*sql.DB, string, error) {
+	return openSource(fc.Source.Type, fc.Source.opts(), fc.Source.opts())
+}
+
+// dumperOptions returns the options applying the config's filters, masking, queries, engine policies,
+// DDL transforms, hooks, table patterns and order, session variables, row rules, value guards, throttle and table limits.
+func (fc *FileConfig) dumperOptions() []mysqldump.Option {
+	opts := defaultFilters(fc.Source.Database)
+	if fc.Filters != nil {
+		opts = []mysqldump.Option{mysqldump.WithTableFilters(fc.Filters)}
+	}
+	if len(fc.Masking) > 0 {
+		opts = append(opts, mysqldump.WithMasking(maskRules(fc.Masking)))
+	}
+	if len(fc.Queries) > 0 {
+		opts = append(opts, mysqldump.WithQueries(fc.Queries))
+	}
+	if len(fc.Engines) > 0 {
+		policies := make(map[string]mysqldump.EnginePolicy, len(fc.Engines))
+		for e, p := range fc.Engines {
+			// Checked by validate
+			policies[e], _ = mysqldump.ParseEnginePolicy(p)
+		}
+		opts = append(opts, mysqldump.WithEnginePolicies(policies))
+	}
+	if fc.Source.Partitions {
+		opts = append(opts, mysqldump.WithPartitionUnits())
+	}
+	if fc.Source.OptimizerStats {
+		opts = append(opts, mysqldump.WithOptimizerStats())
+	}
+	if fc.Source.Prefetch {
+		opts = append(opts, mysqldump.WithChunkPrefetch())
+	}
+	if fc.Source.OrderByPK {
+		opts = append(opts, mysqldump.WithOrderByPrimaryKey())
+	}
+	if fc.Source.ParallelTables > 1 {
+		opts = append(opts, mysqldump.WithParallelTables(fc.Source.ParallelTables))
+	}
+	if fc.Source.Concurrency > 1 {
+		opts = append(opts, mysqldump.WithConcurrency(fc.Source.Concurrency))
+	}
+	if fc.Source.Prime {
+		opts = append(opts, mysqldump.WithPriming())
+	}
+	if p, err := mysqldump.ParseCreatePolicy(fc.Source.CreatePolicy); err == nil {
+		opts = append(opts, mysqldump.WithCreatePolicy(p))
+	}
+	if fc.Source.Shards > 1 {
+		opts = append(opts, mysqldump.WithShards(fc.Source.Shards))
+	}
+	if fc.Source.ColumnGroups > 0 {
+		opts = append(opts, mysqldump.WithColumnGroups(fc.Source.ColumnGroups))
+	}
+	if fc.Source.ServerSnapshot {
+		opts = append(opts, mysqldump.WithServerSnapshot())
+	}
+	if fc.Source.Routines {
+		opts = append(opts, mysqldump.WithRoutines())
+	}
+	if fc.Source.Triggers {
+		opts = append(opts, mysqldump.WithTriggers())
+	}
+	if fc.Source.Events {
+		opts = append(opts, mysqldump.WithEvents())
+	}
+	if c, err := parseCompression(fc.Source.Compression); err == nil && c != mysqldump.CompressionNone {
+		opts = append(opts, mysqldump.WithCompression(c, fc.Source.CompressionLevel))
+	}
+	if st, err := mysqldump.ParseSystemTime(fc.Source.SystemTime); err == nil {
+		opts = append(opts, mysqldump.WithSystemTime(st))
+	}
+	if ts, err := mysqldump.ParseDDLTransforms(fc.Transforms); err == nil && len(ts) > 0 {
+		opts = append(opts, mysqldump.WithDDLTransforms(ts...))
+	}
+	opts = append(opts, mysqldump.WithHooks(fc.Hooks.hooks()))
+	if len(fc.Include) > 0 {
+		opts = append(opts, mysqldump.WithIncludeTables(fc.Include...))
+	}
+	if len(fc.Exclude) > 0 {
+		opts = append(opts, mysqldump.WithExcludeTables(fc.Exclude...))
+	}
+	if len(fc.Order) > 0 {
+		opts = append(opts, mysqldump.WithTableOrder(fc.Order...))
+	}
+	if len(fc.Priorities) > 0 {
+		opts = append(opts, mysqldump.WithTablePriorities(fc.Priorities))
+	}
+	if len(fc.Session) > 0 {
+		opts = append(opts, mysqldump.WithSessionVariables(fc.Session))
+	}
+	// Checked by validate
+	if g, err := fc.Guards.guards(); err == nil && g != nil {
+		opts = append(opts, mysqldump.WithValueGuards(*g))
+	}
+	if len(fc.Rules) > 0 {
+		rules := make(map[string][]mysqldump.RowRule, len(fc.Rules))
+		for table, exprs := range fc.Rules {
+			for _, e := range exprs {
+				// Checked by validate
+				if r, err := mysqldump.ParseRowRule(e); err == nil {
+					rules[table] = append(rules[table], r)
+				}
+			}
+		}
+		opts = append(opts, mysqldump.WithRowRules(rules))
+	}
+	if expected := fc.rowCounts(); len(expected) > 0 {
+		opts = append(opts, mysqldump.WithRowCounts(expected))
+	}
+	if len(fc.Throttle) > 0 {
+		// Checked by validate
+		if s, err := parseThrottle(fc.Throttle); err == nil {
+			opts = append(opts, mysqldump.WithThrottle(s))
+		}
+	}
+	if limits, err := fc.tableLimits(); err == nil && len(limits) > 0 {
+		opts = append(opts, mysqldump.WithTableLimits(limits))
+	}
+	if fc.ReadBack {
+		opts = append(opts, mysqldump.WithReadBack())
+	}
+	if fc.Source.Retries > 0 || fc.Source.RetryDelay != "" {
+		retry := mysqldump.DefaultRetryPolicy
+		if fc.Source.Retries > 0 {
+			retry.Attempts = fc.Source.Retries
+		}
+		// Checked by validate
+		if d, err := time.ParseDuration(fc.Source.RetryDelay); err == nil {
+			retry.Delay = d
+		}
+		opts = append(opts, mysqldump.WithRetryPolicy(retry))
+	}
+	return opts
+}
+
+// tableLimits returns the limits of the tables.
+func (fc *FileConfig) tableLimits() (map[string]mysqldump.TableLimit, error) {
+	action, err := mysqldump.ParseLimitAction(fc.OnTableLimit)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]string, 0, len(fc.TableLimits))
+	for table, s := range fc.TableLimits {
+		list = append(list, table+"="+s)
+	}
+	return parseTableLimits(list, action)
+}
+
+// rowCounts returns the expected row counts by table, leaving out the invalid ones reported by validate.
+func (fc *FileConfig) rowCounts() map[string]mysqldump.RowCountRange {
+	expected := make(map[string]mysqldump.RowCountRange, len(fc.RowCounts))
+	for table, s := range fc.RowCounts {
+		if r, err := mysqldump.ParseRowCountRange(s); err == nil {
+			expected[table] = r
+		}
+	}
+	return expected
+}
+
+// validate checks the config on its own, without connecting to the source.
+func (fc *FileConfig) validate() []error {
+	var errs []error
+
+	if fc.Source.Type != "mysql" && fc.Source.Type != "pg" {
+		errs = append(errs, fmt.Errorf("source: invalid type %q", fc.Source.Type))
+	}
+	if fc.Source.Host == "" || fc.Source.Database == "" {
+		errs = append(errs, fmt.Errorf("source: host and database are required"))
+	}
+	if _, err := parseCompression(fc.Source.Compression); err != nil {
+		errs = append(errs, fmt.Errorf("source.compression: %w", err))
+	}
+	if _, err := mysqldump.ParseSystemTime(fc.Source.SystemTime); err != nil {
+		errs = append(errs, fmt.Errorf("source.system_time: %w", err))
+	}
+	if _, err := mysqldump.ParseCreatePolicy(fc.Source.CreatePolicy); err != nil {
+		errs = append(errs, fmt.Errorf("source.create_policy: %w", err))
+	}
+	if fc.Source.RetryDelay != "" {
+		if _, err := time.ParseDuration(fc.Source.RetryDelay); err != nil {
+			errs = append(errs, fmt.Errorf("source.retry_delay: %w", err))
+		}
+	}
+	if fc.Cost.ReadPerGB < 0 || fc.Cost.WritePerGB < 0 {
+		errs = append(errs, fmt.Errorf("cost: prices can't be negative"))
+	}
+
+	if len(fc.Destinations) == 0 {
+		errs = append(errs, fmt.Errorf("destinations: at least one destination is required"))
+	}
+	for i, d := range fc.Destinations {
+		if d.File == "" {
+			errs = append(errs, fmt.Errorf("destinations[%d]: file is required", i))
+			continue
+		}
+		if isRemote(d.File) {
+			continue
+		}
+		if st, err := os.Stat(filepath.Dir(d.File)); err != nil || !st.IsDir() {
+			errs = append(errs, fmt.Errorf("destinations[%d]: directory of %s does not exist", i, d.File))
+		}
+	}
+
+	for table, filters := range fc.Filters {
+		for _, f := range filters {
+			if !strings.HasPrefix(f, " ") {
+				errs = append(errs, fmt.Errorf("filters.%s: %q must start with a space", table, f))
+			}
+		}
+	}
+
+	for table, rules := range fc.Masking {
+		for col, rule := range rules {
+			if _, err := mysqldump.ParseMaskRule(rule); err != nil {
+				errs = append(errs, fmt.Errorf("masking.%s.%s: %w", table, col, err))
+			}
+		}
+	}
+
+	for engine, p := range fc.Engines {
+		if _, err := mysqldump.ParseEnginePolicy(p); err != nil {
+			errs = append(errs, fmt.Errorf("engines.%s: %w", engine, err))
+		}
+	}
+
+	if _, err := fc.Guards.guards(); err != nil {
+		errs = append(errs, fmt.Errorf("guards.%w", err))
+	}
+	for table, exprs := range fc.Rules {
+		for _, e := range exprs {
+			if _, err := mysqldump.ParseRowRule(e); err != nil {
+				errs = append(errs, fmt.Errorf("rules.%s: %w", table, err))
+			}
+		}
+	}
+	for table, s := range fc.RowCounts {
+		if _, err := mysqldump.ParseRowCountRange(s); err != nil {
+			errs = append(errs, fmt.Errorf("row_counts.%s: %w", table, err))
+		}
+	}
+	if _, err := parseThrottle(fc.Throttle); err != nil {
+		errs = append(errs, fmt.Errorf("throttle: %w", err))
+	}
+	if _, err := fc.tableLimits(); err != nil {
+		errs = append(errs, fmt.Errorf("table_limits: %w", err))
+	}
+
+	if _, err := mysqldump.ParseDDLTransforms(fc.Transforms); err != nil {
+		errs = append(errs, fmt.Errorf("ddl_transforms: %w", err))
+	}
+	if _, err := mysqldump.ParseHookPolicy(fc.Hooks.OnError); err != nil {
+		errs = append(errs, fmt.Errorf("hooks.on_error: %w", err))
+	}
+
+	if fc.Schedule != "" {
+		if s, err := parseSchedule(fc.Schedule); err != nil {
+			errs = append(errs, fmt.Errorf("schedule: %w", err))
+		} else if s.next(time.Now()).IsZero() {
+			errs = append(errs, fmt.Errorf("schedule: %q never matches a date", fc.Schedule))
+		}
+	}
+
+	return errs
+}
+
+// validateSchema checks that the tables and columns the config refers to exist in the
+// source database, and that the filters are valid SQL.
+func (fc *FileConfig) validateSchema(d *mysqldump.Dumper, dbName string) []error {
+	tables, err := d.ListTables(dbName)
+	if err != nil {
+		return []error{fmt.Errorf("source: list tables: %w", err)}
+	}
+	exists := make(map[string]bool, len(tables))
+	for _, t := range tables {
+		exists[t] = true
+	}
+
+	var errs []error
+	for table, filters := range fc.Filters {
+		if !exists[table] {
+			errs = append(errs, fmt.Errorf("filters.%s: table does not exist", table))
+			continue
+		}
+		for _, f := range filters {
+			if err := d.CheckFilter(table, f); err != nil {
+				errs = append(errs, fmt.Errorf("filters.%s: %q: %w", table, f, err))
+			}
+		}
+	}
+
+	for table, rules := range fc.Masking {
+		if !exists[table] {
+			errs = append(errs, fmt.Errorf("masking.%s: table does not exist", table))
+			continue
+		}
+
+		cols, err := d.TableColumns(dbName, table)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("masking.%s: get columns: %w", table, err))
+			continue
+		}
+		hasCol := make(map[string]bool, len(cols))
+		for _, c := range cols {
+			hasCol[c] = true
+		}
+		for col := range rules {
+			if !hasCol[col] {
+				errs = append(errs, fmt.Errorf("masking.%s.%s: column does not exist", table, col))
+			}
+		}
+	}
+
+	for table := range fc.Hooks.Tables {
+		if !exists[table] {
+			errs = append(errs, fmt.Errorf("hooks.tables.%s: table does not exist", table))
+		}
+	}
+	for _, table := range fc.Order {
+		if !exists[table] {
+			errs = append(errs, fmt.Errorf("order: table %s does not exist", table))
+		}
+	}
+	for table := range fc.Priorities {
+		if !exists[table] {
+			errs = append(errs, fmt.Errorf("priorities.%s: table does not exist", table))
+		}
+	}
+
+	return errs
+}
+
+// maskRules returns the masking rules by column by table. The rules must have been validated.
+func maskRules(masking map[string]MaskRules) map[string]map[string]mysqldump.MaskRule {
+	rules := make(map[string]map[string]mysqldump.MaskRule, len(masking))
+	for table, cols := range masking {
+		rules[table] = make(map[string]mysqldump.MaskRule, len(cols))
+		for col, rule := range cols {
+			rules[table][col], _ = mysqldump.ParseMaskRule(rule)
+		}
+	}
+	return rules
+}
+
+type ConfigConfiguration struct {
+	Output string `command:"output,usage=Output format: text or json,default=text"`
+}
+
+var cfc *ConfigConfiguration
+
+// runConfig handles "config validate <file>".
+func runConfig() {
+	command := cli.Initialize("DB dumper config", &cfc)
+	command.OnRun(func() {
+		setupOutput(cfc.Output)
+		if len(args) != 2 || args[0] != "validate" {
+			logrus.Fatal("usage: config validate <config file>")
+		}
+
+		fc, err := loadFileConfig(args[1])
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		errs := fc.validate()
+		if len(errs) == 0 {
+			db, dbName, err := fc.connect()
+			if err == nil {
+				err = db.Ping()
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("source: %w", err))
+			} else {
+				errs = append(errs, fc.validateSchema(mysqldump.NewDumper(db, nil, fc.Source.ChunkSize), dbName)...)
+				db.Close()
+			}
+		}
+
+		res := struct {
+			File   string
+			Valid  bool
+			Errors []string
+		}{File: args[1], Valid: len(errs) == 0, Errors: []string{}}
+		for _, err := range errs {
+			res.Errors = append(res.Errors, err.Error())
+		}
+
+		printResult(cfc.Output, res, func() {
+			for _, err := range res.Errors {
+				fmt.Println(err)
+			}
+			if res.Valid {
+				fmt.Printf("%s is valid\n", res.File)
+			}
+		})
+		if !res.Valid {
+			os.Exit(1)
+		}
+	})
+
+	command.Execute()
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/MouseHatGames/go-mysqldump"
+	"github.com/conneqtech/std_pkg/cli"
+	"github.com/sirupsen/logrus"
+)
+
+type ConvertConfiguration struct {
+	From       string `command:"from,usage=Format of the input: binary,default=binary"`
+	To         string `command:"to,usage=Format of the output: sql csv or jsonl,default=sql"`
+	File       string `command:"file,usage=Dump file to convert or - for stdin,default=-"`
+	Out        string `command:"out,usage=Output file or - for stdout. For csv the directory to write one file per table to,default=-"`
+	Tables     string `command:"tables,usage=Comma separated list of tables to convert,required=false"`
+	QuerySize  int    `command:"query_size,default=1000000"`
+	SkipCreate bool   `command:"skip_create,default=false"`
+	Percona    string `command:"percona,usage=What to do with Percona column compression clauses: keep or strip,default=keep"`
+	Transforms string `command:"ddl_transforms,usage=Comma separated list of DDL transforms to apply,required=false"`
+	Create     string `command:"create,usage=How to create tables: auto drop if_not_exists or error. auto follows the dump,default=auto"`
+	Delimiter  string `command:"csv_delimiter,usage=Field delimiter of csv output such as ; or tab. Defaults to a comma,required=false"`
+	NoHeader   bool   `command:"csv_no_header,usage=Leave out the record naming the columns of csv output,default=false"`
+	Output     string `command:"output,usage=Output format: text or json,default=text"`
+}
+
+var cc *ConvertConfiguration
+
+// runConvert converts a binary dump file into a format usable by standard tools.
+func runConvert() {
+	command := cli.Initialize("DB dumper convert", &cc)
+	command.OnRun(func() {
+		setupOutput(cc.Output)
+		if cc.Output == "json" && cc.Out == "-" {
+			logrus.Fatal("--output json needs --out, stdout holds the converted dump")
+		}
+		if cc.From != "binary" {
+			logrus.Fatalf("unsupported input format: %s", cc.From)
+		}
+
+		var in io.Reader = os.Stdin
+		if cc.File != "-" {
+			f, err := openDump(cc.File)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			defer f.Close()
+			in = f
+		}
+
+		percona, err := mysqldump.ParseClausePolicy(cc.Percona)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		transforms, err := mysqldump.ParseDDLTransforms(splitList(cc.Transforms))
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		create, err := mysqldump.ParseCreatePolicy(cc.Create)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		csvOpt, err := parseCSVOptions(cc.Delimiter, cc.NoHeader)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		opt := mysqldump.ConvertOptions{
+			Tables:     splitList(cc.Tables),
+			SkipCreate: cc.SkipCreate,
+			Percona:    percona,
+			Create:     create,
+			CSV:        csvOpt,
+
+			DDLTransforms: transforms,
+		}
+
+		switch cc.To {
+		case "sql":
+			err = withOutput(cc.Out, func(w io.Writer) error {
+				// Nothing reads the statements as they are produced, so every flush is ready right away
+				flusher := make(chan bool, 1)
+				ready := make(chan bool, 1)
+				go func() {
+					for range flusher {
+						ready <- true
+					}
+				}()
+
+				return mysqldump.ConvertToSQL(in, w, flusher, ready, cc.QuerySize, opt)
+			})
+
+		case "jsonl":
+			err = withOutput(cc.Out, func(w io.Writer) error {
+				return mysqldump.ConvertToJSONL(in, w, opt)
+			})
+
+		case "csv":
+			if cc.Out == "-" {
+				logrus.Fatal("csv output needs a directory to be given with --out")
+			}
+			if err = os.MkdirAll(cc.Out, 0755); err != nil {
+				logrus.Fatal(err)
+			}
+			err = mysqldump.ConvertToCSV(in, func(table string) (io.WriteCloser, error) {
+				return os.Create(filepath.Join(cc.Out, table+".csv"))
+			}, opt)
+
+		default:
+			err = fmt.Errorf("unsupported output format: %s", cc.To)
+		}
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		res := struct {
+			File string
+			From string
+			To   string
+			Out  string
+		}{cc.File, cc.From, cc.To, cc.Out}
+		printResult(cc.Output, res, func() {
+			logrus.Infof("Converted %s to %s", cc.File, cc.Out)
+		})
+	})
+
+	command.Execute()
+}
+
+// parseCSVOptions parses a delimiter given as a single character or "tab", a comma if empty.
+func parseCSVOptions(delimiter string, noHeader bool) (mysqldump.CSVOptions, error) {
+	opt := mysqldump.CSVOptions{NoHeader: noHeader}
+	if delimiter == "" {
+		return opt, nil
+	}
+	if delimiter == "tab" {
+		delimiter = "\t"
+	}
+	r := []rune(delimiter)
+	if len(r) != 1 {
+		return opt, fmt.Errorf("invalid csv delimiter: %q", delimiter)
+	}
+	opt.Delimiter = r[0]
+	return opt, nil
+}
+
+// withOutput calls fn with the file at path, or stdout if path is "-".
+func withOutput(path string, fn func(w io.Writer) error) error {
+	if path == "-" {
+		return fn(os.Stdout)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err = fn(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/conneqtech/std_pkg/db/mysql"
+	"github.com/sirupsen/logrus"
+)
+
+// Passwords, in flags or the config file, can refer to where the secret is kept instead of holding it:
+//
+//	env:NAME                  the environment variable NAME
+//	file:/path                the contents of a file, without the trailing newline
+//	prompt                    asked for on the terminal
+//	vault:secret/data/db#key  a field of a Vault KV secret, using VAULT_ADDR and VAULT_TOKEN
+//	aws-sm:secret-id#key      an AWS Secrets Manager secret through the aws CLI, optionally a field of its JSON value
+//
+// An empty password is read from the environment variable given to resolveCredentials.
+var secretSources = map[string]func(ref string) (string, error){
+	"env:":    secretFromEnv,
+	"file:":   secretFromFile,
+	"vault:":  secretFromVault,
+	"aws-sm:": secretFromAWS,
+}
+
+const (
+	sourcePasswordEnv = "MYSQLDUMP_SOURCE_PASSWORD"
+	targetPasswordEnv = "MYSQLDUMP_TARGET_PASSWORD"
+)
+
+// resolveSecret returns the secret a reference points to. Values that aren't references are returned as is.
+func resolveSecret(v string) (string, error) {
+	if v == "prompt" {
+		return secretFromPrompt()
+	}
+	for prefix, fn := range secretSources {
+		if strings.HasPrefix(v, prefix) {
+			return fn(strings.TrimPrefix(v, prefix))
+		}
+	}
+	return v, nil
+}
+
+// resolveCredentials replaces the password in o by the secret it refers to, reading it from envName if it is empty.
+func resolveCredentials(o *mysql.Opts, envName string) error {
+	if o.Password == "" {
+		o.Password = os.Getenv(envName)
+		return nil
+	}
+
+	p, err := resolveSecret(o.Password)
+	if err != nil {
+		return fmt.Errorf("resolve password: %w", err)
+	}
+	o.Password = p
+	return nil
+}
+
+// openTarget connects to the database a dump is restored into.
+func openTarget(o *mysql.Opts) (*sql.DB, error) {
+	if err := resolveCredentials(o, targetPasswordEnv); err != nil {
+		return nil, err
+	}
+	return mysql.NewMysqlClient(o)
+}
+
+// warnArgvPasswords warns about passwords given literally on the command line, where any
+// user on the machine can read them.
+func warnArgvPasswords() {
+	for _, a := range os.Args[1:] {
+		i := strings.Index(a, "=")
+		if i < 0 || !strings.Contains(strings.ToLower(a[:i]), "password") {
+			continue
+		}
+
+		v := a[i+1:]
+		if v == "" || v == "prompt" {
+			continue
+		}
+		isRef := false
+		for prefix := range secretSources {
+			isRef = isRef || strings.HasPrefix(v, prefix)
+		}
+		if !isRef {
+			logrus.Warnf("%s is visible to other users, use env:, file:, prompt, vault: or aws-sm: instead", a[:i])
+		}
+	}
+}
+
+func secretFromEnv(name string) (string, error) {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return v, nil
+}
+
+func secretFromFile(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+func secretFromPrompt() (string, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return "", fmt.Errorf("prompt for password: %w", err)
+	}
+	defer tty.Close()
+
+	fmt.Fprint(tty, "Password: ")
+
+	// Don't echo the password while it's typed
+	stty := func(arg string) error {
+		cmd := exec.Command("stty", arg)
+		cmd.Stdin = tty
+		return cmd.Run()
+	}
+	if err = stty("-echo"); err == nil {
+		defer stty("echo")
+	}
+
+	line, err := bufio.NewReader(tty).ReadString('\n')
+	fmt.Fprintln(tty)
+	if err != nil {
+		return "", fmt.Errorf("prompt for password: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// splitSecretKey splits "name#key" into its parts, using def if no key is given.
+func splitSecretKey(ref string, def string) (string, string) {
+	if i := strings.LastIndex(ref, "#"); i >= 0 {
+		return ref[:i], ref[i+1:]
+	}
+	return ref, def
+}
+
+func secretFromVault(ref string) (string, error) {
+	path, key := splitSecretKey(ref, "password")
+
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		return "", fmt.Errorf("VAULT_ADDR is not set")
+	}
+	token := os.Getenv("VAULT_TOKEN")
+	if token == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			if b, err := ioutil.ReadFile(filepath.Join(home, ".vault-token")); err == nil {
+				token = strings.TrimSpace(string(b))
+			}
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, strings.TrimRight(addr, "/")+"/v1/"+strings.TrimLeft(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("read vault secret: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("read vault secret %s: %s", path, resp.Status)
+	}
+
+	// KV version 2 nests the fields one level deeper than version 1
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("decode vault secret: %w", err)
+	}
+	fields := body.Data
+	if nested, ok := fields["data"].(map[string]interface{}); ok {
+		fields = nested
+	}
+
+	v, ok := fields[key].(string)
+	if !ok {
+		return "", fmt.Errorf("vault secret %s has no field %s", path, key)
+	}
+	return v, nil
+}
+
+func secretFromAWS(ref string) (string, error) {
+	id, key := splitSecretKey(ref, "")
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("aws", "secretsmanager", "get-secret-value", "--secret-id", id, "--query", "SecretString", "--output", "text")
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("read aws secret %s: %w: %s", id, err, strings.TrimSpace(stderr.String()))
+	}
+
+	secret := strings.TrimRight(string(out), "\r\n")
+	if key == "" {
+		return secret, nil
+	}
+
+	var fields map[string]interface{}
+	if err = json.Unmarshal([]byte(secret), &fields); err != nil {
+		return "", fmt.Errorf("aws secret %s is not a JSON object: %w", id, err)
+	}
+	v, ok := fields[key].(string)
+	if !ok {
+		return "", fmt.Errorf("aws secret %s has no field %s", id, key)
+	}
+	return v, nil
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/MouseHatGames/go-mysqldump"
+	"github.com/conneqtech/std_pkg/cli"
+	"github.com/sirupsen/logrus"
+)
+
+type DiffConfiguration struct {
+	RowLimit int64  `command:"row_limit,usage=Compare tables with at most this many rows row by row,default=0"`
+	Schema   bool   `command:"schema,usage=Only compare the DDL of the tables column by column and index by index,default=false"`
+	Alter    bool   `command:"alter,usage=With --schema print the statements turning the schema of a into that of b,default=false"`
+	Output   string `command:"output,usage=Output format: text or json,default=text"`
+}
+
+var dfc *DiffConfiguration
+
+// runDiff compares two dump files. Like diff(1) it exits with 1 if they differ and 2 on errors.
+func runDiff() {
+	command := cli.Initialize("DB dumper diff", &dfc)
+	command.OnRun(func() {
+		setupOutput(dfc.Output)
+		if len(args) != 2 {
+			logrus.Error("usage: diff <dump a> <dump b>")
+			os.Exit(2)
+		}
+
+		a, err := openDump(args[0])
+		if err != nil {
+			logrus.Error(err)
+			os.Exit(2)
+		}
+		defer a.Close()
+
+		b, err := openDump(args[1])
+		if err != nil {
+			logrus.Error(err)
+			os.Exit(2)
+		}
+		defer b.Close()
+
+		if dfc.Schema {
+			runSchemaDiff(a, b)
+			return
+		}
+
+		diff, err := mysqldump.Diff(a, b, mysqldump.DiffOptions{RowLimit: dfc.RowLimit})
+		if err != nil {
+			logrus.Error(err)
+			os.Exit(2)
+		}
+
+		printResult(dfc.Output, diff, func() {
+			for _, t := range diff.Tables {
+				printTableDiff(t)
+			}
+		})
+		if !diff.Equal() {
+			os.Exit(1)
+		}
+	})
+
+	command.Execute()
+}
+
+// runSchemaDiff compares the schema of two dumps, exiting with 1 if it changed.
+func runSchemaDiff(a, b io.Reader) {
+	diff, err := mysqldump.DiffSchema(a, b, mysqldump.SchemaDiffOptions{Alter: dfc.Alter})
+	if err != nil {
+		logrus.Error(err)
+		os.Exit(2)
+	}
+
+	printResult(dfc.Output, diff, func() {
+		for _, t := range diff.Tables {
+			if dfc.Alter {
+				for _, q := range t.Alter {
+					fmt.Printf("%s;\n\n", q)
+				}
+				continue
+			}
+			printSchemaDiff(t)
+		}
+	})
+	if len(diff.Tables) > 0 {
+		os.Exit(1)
+	}
+}
+
+func printSchemaDiff(t *mysqldump.TableSchemaDiff) {
+	switch {
+	case t.Removed:
+		fmt.Printf("- %s: only in %s\n", t.Table, args[0])
+		return
+	case t.Added:
+		fmt.Printf("+ %s: only in %s\n", t.Table, args[1])
+		return
+	}
+
+	fmt.Printf("~ %s\n", t.Table)
+	for _, c := range t.RemovedColumns {
+		fmt.Printf("  - column %s\n", c)
+	}
+	for _, c := range t.AddedColumns {
+		fmt.Printf("  + column %s\n", c)
+	}
+	for _, c := range t.ChangedColumns {
+		fmt.Printf("  ~ column %s:\n    - %s\n    + %s\n", c.Name, c.A, c.B)
+	}
+	for _, i := range t.RemovedIndexes {
+		fmt.Printf("  - index %s\n", i)
+	}
+	for _, i := range t.AddedIndexes {
+		fmt.Printf("  + index %s\n", i)
+	}
+	for _, i := range t.ChangedIndexes {
+		fmt.Printf("  ~ index %s:\n    - %s\n    + %s\n", i.Name, i.A, i.B)
+	}
+	if t.OptionsA != t.OptionsB {
+		fmt.Printf("  ~ options:\n    - %s\n    + %s\n", t.OptionsA, t.OptionsB)
+	}
+}
+
+func printTableDiff(t *mysqldump.TableDiff) {
+	switch {
+	case !t.InB:
+		fmt.Printf("- %s: only in %s (%d rows)\n", t.Table, args[0], t.RowsA)
+		return
+	case !t.InA:
+		fmt.Printf("+ %s: only in %s (%d rows)\n", t.Table, args[1], t.RowsB)
+		return
+	case t.Equal():
+		return
+	}
+
+	fmt.Printf("~ %s\n", t.Table)
+	if t.SchemaChanged {
+		fmt.Printf("  schema changed:\n  - %s\n  + %s\n", t.CreateSQLA, t.CreateSQLB)
+	}
+	if t.RowsA != t.RowsB {
+		fmt.Printf("  rows: %d -> %d\n", t.RowsA, t.RowsB)
+	}
+	if t.ChecksumA != t.ChecksumB {
+		fmt.Printf("  checksum: %s -> %s\n", t.ChecksumA, t.ChecksumB)
+	}
+	for _, r := range t.RowsDeleted {
+		b, _ := json.Marshal(r)
+		fmt.Printf("  deleted %s\n", b)
+	}
+	for _, c := range t.RowsUpdated {
+		a, _ := json.Marshal(c.A)
+		b, _ := json.Marshal(c.B)
+		fmt.Printf("  updated %s\n       -> %s\n", a, b)
+	}
+	for _, r := range t.RowsInserted {
+		b, _ := json.Marshal(r)
+		fmt.Printf("  inserted %s\n", b)
+	}
+	for _, r := range t.RowsOnlyInA {
+		b, _ := json.Marshal(r)
+		fmt.Printf("  - %s\n", b)
+	}
+	for _, r := range t.RowsOnlyInB {
+		b, _ := json.Marshal(r)
+		fmt.Printf("  + %s\n", b)
+	}
+}
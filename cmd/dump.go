@@ -0,0 +1,597 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/MouseHatGames/go-mysqldump"
+	"github.com/conneqtech/std_pkg/cli"
+	"github.com/conneqtech/std_pkg/db/mysql"
+	"github.com/sirupsen/logrus"
+)
+
+// exitInterrupted is the exit code of a dump stopped by a signal, leaving a partial but valid file.
+const exitInterrupted = 130
+
+type DumpConfiguration struct {
+	Type         string     `command:"type,usage=Use mysql of pg,default=mysql"`
+	SourcePG     mysql.Opts `command:"source_pg,required=false"`
+	SourceMysql  mysql.Opts `command:"source_mysql,required=false"`
+	ChunkSize    int        `command:"chunk_size,default=0"`
+	Prefetch     bool       `command:"prefetch,usage=Read the next chunk of a table while the current one is written,default=false"`
+	OrderByPK    bool       `command:"order_by_primary_key,usage=Read the rows of every table in primary key order also without --chunk_size so dumps are reproducible,default=false"`
+	Parallel     int        `command:"parallel_tables,usage=With --chunk_size read this many tables at once interleaving their chunks in the dump,default=0"`
+	Concurrency  int        `command:"concurrency,usage=Read this many tables at once also without --chunk_size writing their rows in chunks of 1000,default=0"`
+	SingleTx     bool       `command:"single_transaction,usage=Read every table within one transaction from a single consistent snapshot,default=false"`
+	Binlog       bool       `command:"binlog_position,usage=Record the binlog coordinates and GTID set of a consistent snapshot to seed a replica from,default=false"`
+	Prime        bool       `command:"prime,usage=Count the rows and chunks of every table before reading any,default=false"`
+	File         string     `command:"file,usage=File to write the dump to or - for stdout. s3://bucket/key and gs://bucket/object URLs upload it as it is written,default=-"`
+	Format       string     `command:"format,usage=Output format: binary sql csv or jsonl. For csv --file is the directory to write one file per table to,default=binary"`
+	Key          string     `command:"encryption_key,usage=Encrypt the dump with this key: 32 hex encoded bytes or a reference such as env:NAME or file:/path,required=false"`
+	Journal      string     `command:"journal,usage=File to journal every chunk written to so --resume can continue the dump after a crash,required=false"`
+	Resume       bool       `command:"resume,usage=Continue the dump journaled with --journal truncating --file back to its last intact chunk,default=false"`
+	Checkpoint   string     `command:"checkpoint,usage=File to save the checkpoint to after every chunk and when interrupted. Defaults to the dump file with a .checkpoint suffix for binary dumps,required=false"`
+	ResumeFrom   string     `command:"resume_from,usage=Checkpoint of an interrupted dump to continue in a new --file holding only what it didn't get to,required=false"`
+	TUI          bool       `command:"tui,usage=Show per-table progress bars on the terminal,default=false"`
+	Transforms   string     `command:"ddl_transforms,usage=Comma separated list of DDL transforms to apply,required=false"`
+	Partitions   bool       `command:"partitions,usage=Dump partitioned tables partition by partition,default=false"`
+	Stats        bool       `command:"optimizer_stats,usage=Save the persistent InnoDB statistics of every table,default=false"`
+	CreatePolicy string     `command:"create_policy,usage=How restores should create tables: drop if_not_exists or error,default=drop"`
+	Shards       int        `command:"shards,usage=Split the rows of every table into this many sections by primary key hash,default=0"`
+	ColumnGroups int        `command:"column_groups,usage=Dump tables with a primary key and more columns than this in groups of about this many columns,default=0"`
+	Compression  string     `command:"compression,usage=Compress the dump: none or gzip,default=none"`
+	Level        int        `command:"compression_level,usage=Compression level or 0 for the default of the codec,default=0"`
+	SystemTime   string     `command:"system_time,usage=Rows of MariaDB system-versioned tables to dump: current or all or a timestamp,default=current"`
+	Ranges       string     `command:"range,usage=Comma separated list of table.column=from..to ranges to dump only those rows of those tables. Either bound can be left out,required=false"`
+	RowCounts    string     `command:"row_counts,usage=Comma separated list of table=range expected row counts such as users=non_empty or orders=1000..5000 marking the dump as suspect otherwise,required=false"`
+	Include      string     `command:"include_tables,usage=Comma separated list of table name patterns such as orders_* to dump only the matching tables,required=false"`
+	Exclude      string     `command:"exclude_tables,usage=Comma separated list of table name patterns such as rate_limit_* to leave the matching tables out,required=false"`
+	ServerSnap   bool       `command:"server_snapshot,usage=Record the global variables and status of the server in the dump header,default=false"`
+	Throttle     string     `command:"throttle,usage=Rate to read values at such as 10MB with time windows of their own such as 02:00-05:00=unlimited,required=false"`
+	Routines     bool       `command:"routines,usage=Dump the stored functions and procedures after the tables,default=false"`
+	Triggers     bool       `command:"triggers,usage=Dump the triggers of the tables dumped,default=false"`
+	Events       bool       `command:"events,usage=Dump the events of the database,default=false"`
+	TableOrder   string     `command:"table_order,usage=Comma separated list of tables to dump first in that order,required=false"`
+	Session      string     `command:"session_variables,usage=Comma separated list of name=value session variables overriding the dump preset,required=false"`
+	QueueChunks  int        `command:"queue_chunks,usage=Keep reading while up to this many chunks wait to be written to a slow destination,default=0"`
+	MemoryBudget string     `command:"memory_budget,usage=With --queue_chunks spill queued chunks over this size such as 256MB to temporary files,required=false"`
+	OutfileDir   string     `command:"outfile_dir,usage=Have the server write chunks to files in this directory with SELECT INTO OUTFILE,required=false"`
+	OutfileLocal string     `command:"outfile_local_dir,usage=Directory of --outfile_dir as mounted here. Defaults to --outfile_dir,required=false"`
+	MaxValueSize string     `command:"max_value_size,usage=Warn about or fail on values over this size such as 16MB,required=false"`
+	OnGuard      string     `command:"on_guard,usage=What to do with values over --max_value_size: warn or fail,default=warn"`
+	Delimiter    string     `command:"csv_delimiter,usage=Field delimiter of csv output such as ; or tab. Defaults to a comma,required=false"`
+	NoHeader     bool       `command:"csv_no_header,usage=Leave out the record naming the columns of csv output,default=false"`
+	MaxFileSize  string     `command:"max_file_size,usage=Split the dump into parts of this size such as 4GB with a manifest listing them,required=false"`
+	TableFiles   string     `command:"table_files,usage=Directory to write every table to as a dump of its own named after it. --file keeps the header and schema objects,required=false"`
+	Retries      int        `command:"retries,usage=Times a chunk query failing with a transient error such as a dropped connection or lock wait timeout is tried again,default=3"`
+	RetryDelay   string     `command:"retry_delay,usage=Wait before the first retry of a chunk query doubled before every next one up to a minute,default=1s"`
+	ReadBack     bool       `command:"read_back,usage=Read every table back once it is written and fail the dump if its records or row count are off,default=false"`
+	DedupStore   string     `command:"dedup_store,usage=Directory of chunks shared by dumps: only chunks it lacks are stored with their index in --file.dedup,required=false"`
+	TableLimits  string     `command:"table_limits,usage=Comma separated list of table=limit limits such as *=2h or orders=2h+500GB stopping tables that take longer or dump more,required=false"`
+	OnLimit      string     `command:"on_table_limit,usage=What to do with tables over --table_limits: skip the rest of their rows or fail,default=skip"`
+	Timeout      string     `command:"timeout,usage=Abort the dump if it takes longer than this such as 2h,required=false"`
+	Output       string     `command:"output,usage=Output format: text or json,default=text"`
+}
+
+var dc *DumpConfiguration
+
+// runDump writes a binary dump of the source database to a file.
+func runDump() {
+	command := cli.Initialize("DB dumper dump", &dc)
+	command.OnRun(func() {
+		setupOutput(dc.Output)
+		if dc.Output == "json" && dc.File == "-" {
+			logrus.Fatal("--output json needs --file, stdout holds the dump")
+		}
+
+		db, dbName, err := openSource(dc.Type, &dc.SourcePG, &dc.SourceMysql)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		defer db.Close()
+
+		var w io.Writer = os.Stdout
+		var f io.Closer
+		var parts *mysqldump.PartWriter
+		var dedup *mysqldump.DedupWriter
+		var sink mysqldump.Sink
+		format, err := mysqldump.ParseFormat(dc.Format)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		if dc.MaxFileSize != "" && dc.File == "-" {
+			logrus.Fatal("--max_file_size needs --file")
+		}
+		if dc.Journal != "" && (dc.File == "-" || dc.MaxFileSize != "" || format != mysqldump.FormatBinary) {
+			logrus.Fatal("--journal needs --file in the binary format, without --max_file_size")
+		}
+		if dc.DedupStore != "" && (dc.File == "-" || dc.MaxFileSize != "" || dc.Journal != "" || format == mysqldump.FormatCSV) {
+			logrus.Fatal("--dedup_store needs --file, without --max_file_size, --journal or --format csv")
+		}
+		if dc.TableFiles != "" && (format != mysqldump.FormatBinary || dc.Journal != "") {
+			logrus.Fatal("--table_files needs the binary format, without --journal")
+		}
+		if isRemote(dc.File) && (dc.MaxFileSize != "" || dc.Journal != "" || dc.DedupStore != "" || format == mysqldump.FormatCSV) {
+			logrus.Fatal("--file can't be uploaded with --max_file_size, --journal, --dedup_store or --format csv")
+		}
+		if dc.Resume && dc.Journal == "" {
+			logrus.Fatal("--resume needs --journal")
+		}
+		if dc.ResumeFrom != "" && dc.Journal != "" {
+			logrus.Fatal("--resume_from can't be used with --journal, use --resume")
+		}
+		if format == mysqldump.FormatCSV {
+			if dc.File == "-" || dc.MaxFileSize != "" {
+				logrus.Fatal("--format csv needs a directory to be given with --file, without --max_file_size")
+			}
+			if err = os.MkdirAll(dc.File, 0755); err != nil {
+				logrus.Fatal(err)
+			}
+			// Rows go to the files of the tables, nothing else is kept
+			w = ioutil.Discard
+		} else if dc.MaxFileSize != "" {
+			size, err := parseSize(dc.MaxFileSize)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			parts = mysqldump.NewPartWriter(dc.File, size)
+			f, w = parts, parts
+		} else if isRemote(dc.File) {
+			if sink, err = openSink(context.Background(), dc.File); err != nil {
+				logrus.Fatal(err)
+			}
+			w = sink
+		} else if dc.DedupStore != "" {
+			dedup = mysqldump.NewDedupWriter(mysqldump.NewDirStore(dc.DedupStore), dc.File+".dedup")
+			w = dedup
+		} else if dc.File != "-" {
+			flag := os.O_RDWR | os.O_CREATE | os.O_TRUNC
+			if dc.Resume {
+				// The dump is truncated back to its last intact chunk instead
+				flag = os.O_RDWR | os.O_CREATE
+			}
+			file, err := os.OpenFile(dc.File, flag, 0666)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			defer file.Close()
+			f, w = file, file
+		}
+		// Converted output can't be checkpointed
+		if dc.File != "-" && !isRemote(dc.File) && format == mysqldump.FormatBinary && dc.TableFiles == "" {
+			if dc.Checkpoint == "" {
+				dc.Checkpoint = dc.File + ".checkpoint"
+			}
+		}
+
+		transforms, err := mysqldump.ParseDDLTransforms(splitList(dc.Transforms))
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		opts := append(defaultFilters(dbName), mysqldump.WithDDLTransforms(transforms...))
+		if dc.Partitions {
+			opts = append(opts, mysqldump.WithPartitionUnits())
+		}
+		if dc.Stats {
+			opts = append(opts, mysqldump.WithOptimizerStats())
+		}
+		if dc.SingleTx {
+			opts = append(opts, mysqldump.WithSingleTransaction())
+		}
+		if dc.Binlog {
+			opts = append(opts, mysqldump.WithBinlogPosition())
+		}
+		if dc.Prefetch {
+			opts = append(opts, mysqldump.WithChunkPrefetch())
+		}
+		if dc.OrderByPK {
+			opts = append(opts, mysqldump.WithOrderByPrimaryKey())
+		}
+		if dc.ReadBack {
+			opts = append(opts, mysqldump.WithReadBack())
+		}
+		retryDelay, err := time.ParseDuration(dc.RetryDelay)
+		if err != nil {
+			logrus.Fatalf("invalid retry_delay: %s", err)
+		}
+		opts = append(opts, mysqldump.WithReconnect(dc.Retries, retryDelay))
+		if dc.Parallel > 1 {
+			opts = append(opts, mysqldump.WithParallelTables(dc.Parallel))
+		}
+		if dc.Concurrency > 1 {
+			opts = append(opts, mysqldump.WithConcurrency(dc.Concurrency))
+		}
+		if dc.Prime {
+			opts = append(opts, mysqldump.WithPriming())
+		}
+		create, err := mysqldump.ParseCreatePolicy(dc.CreatePolicy)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		opts = append(opts, mysqldump.WithCreatePolicy(create))
+		if format == mysqldump.FormatCSV {
+			csvOpt, err := parseCSVOptions(dc.Delimiter, dc.NoHeader)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			opts = append(opts, mysqldump.WithCSVTables(func(table string) (io.WriteCloser, error) {
+				return os.Create(filepath.Join(dc.File, table+".csv"))
+			}, csvOpt))
+		} else {
+			opts = append(opts, mysqldump.WithFormat(format))
+		}
+		if dc.TableFiles != "" {
+			opts = append(opts, mysqldump.WithTableDir(dc.TableFiles))
+		}
+		st, err := mysqldump.ParseSystemTime(dc.SystemTime)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		opts = append(opts, mysqldump.WithSystemTime(st))
+		if include := splitList(dc.Include); len(include) > 0 {
+			opts = append(opts, mysqldump.WithIncludeTables(include...))
+		}
+		if exclude := splitList(dc.Exclude); len(exclude) > 0 {
+			opts = append(opts, mysqldump.WithExcludeTables(exclude...))
+		}
+		if counts := splitList(dc.RowCounts); len(counts) > 0 {
+			expected := make(map[string]mysqldump.RowCountRange, len(counts))
+			for _, c := range counts {
+				kv := strings.SplitN(c, "=", 2)
+				if len(kv) != 2 {
+					logrus.Fatalf("invalid row count %q, expected table=range", c)
+				}
+				r, err := mysqldump.ParseRowCountRange(kv[1])
+				if err != nil {
+					logrus.Fatal(err)
+				}
+				expected[strings.TrimSpace(kv[0])] = r
+			}
+			opts = append(opts, mysqldump.WithRowCounts(expected))
+		}
+		if order := splitList(dc.TableOrder); len(order) > 0 {
+			opts = append(opts, mysqldump.WithTableOrder(order...))
+		}
+		if dc.OutfileDir != "" {
+			local := dc.OutfileLocal
+			if local == "" {
+				local = dc.OutfileDir
+			}
+			opts = append(opts, mysqldump.WithOutfile(dc.OutfileDir, local))
+		}
+		if vars := splitList(dc.Session); len(vars) > 0 {
+			session := make(map[string]string, len(vars))
+			for _, v := range vars {
+				kv := strings.SplitN(v, "=", 2)
+				if len(kv) != 2 {
+					logrus.Fatalf("invalid session variable %q, expected name=value", v)
+				}
+				session[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+			}
+			opts = append(opts, mysqldump.WithSessionVariables(session))
+		}
+		if dc.QueueChunks > 0 {
+			opts = append(opts, mysqldump.WithWriteQueue(dc.QueueChunks))
+		}
+		if dc.MemoryBudget != "" {
+			if dc.QueueChunks <= 0 {
+				logrus.Fatal("--memory_budget needs --queue_chunks")
+			}
+			budget, err := parseSize(dc.MemoryBudget)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			opts = append(opts, mysqldump.WithMemoryBudget(budget))
+		}
+		if dc.Shards > 1 {
+			opts = append(opts, mysqldump.WithShards(dc.Shards))
+		}
+		if dc.ColumnGroups > 0 {
+			opts = append(opts, mysqldump.WithColumnGroups(dc.ColumnGroups))
+		}
+		if dc.ServerSnap {
+			opts = append(opts, mysqldump.WithServerSnapshot())
+		}
+		if list := splitList(dc.Throttle); len(list) > 0 {
+			s, err := parseThrottle(list)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			opts = append(opts, mysqldump.WithThrottle(s))
+		}
+		if list := splitList(dc.TableLimits); len(list) > 0 {
+			action, err := mysqldump.ParseLimitAction(dc.OnLimit)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			limits, err := parseTableLimits(list, action)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			opts = append(opts, mysqldump.WithTableLimits(limits))
+		}
+		if dc.Routines {
+			opts = append(opts, mysqldump.WithRoutines())
+		}
+		if dc.Triggers {
+			opts = append(opts, mysqldump.WithTriggers())
+		}
+		if dc.Events {
+			opts = append(opts, mysqldump.WithEvents())
+		}
+		if c, err := parseCompression(dc.Compression); err != nil {
+			logrus.Fatal(err)
+		} else if c != mysqldump.CompressionNone {
+			opts = append(opts, mysqldump.WithCompression(c, dc.Level))
+		}
+		if dc.MaxValueSize != "" {
+			guards, err := (&GuardsConfig{MaxValueSize: dc.MaxValueSize, OnViolation: dc.OnGuard}).guards()
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			opts = append(opts, mysqldump.WithValueGuards(*guards))
+		}
+		if dc.Checkpoint != "" {
+			opts = append(opts, mysqldump.WithCheckpointFile(dc.Checkpoint))
+		}
+		if dc.Journal != "" {
+			opts = append(opts, mysqldump.WithJournal(dc.Journal))
+		}
+		if dc.Key != "" {
+			key, err := encryptionKey(dc.Key)
+			if err != nil {
+				logrus.Fatalf("encryption key: %s", err)
+			}
+			opts = append(opts, mysqldump.WithEncryption(key))
+		}
+		res := &dumpResult{Files: []string{dc.File}, Database: dbName}
+		var progress *progressDisplay
+		var update func(mysqldump.ProgressEvent)
+		if dc.TUI {
+			// Log lines would tear through the progress bars
+			logrus.SetLevel(logrus.WarnLevel)
+			progress = newProgressDisplay(os.Stderr)
+			update = progress.Update
+		}
+		opts = append(opts, mysqldump.WithProgress(res.track(update)))
+		dumper := mysqldump.NewDumper(db, w, dc.ChunkSize, opts...)
+
+		interruptOnSignal(dumper)
+		if dc.Resume {
+			if err := dumper.Resume(); errors.Is(err, os.ErrNotExist) {
+				logrus.Warnf("Nothing to resume, %s doesn't exist, starting over", dc.Journal)
+				if err = w.(*os.File).Truncate(0); err != nil {
+					logrus.Fatal(err)
+				}
+			} else if err != nil {
+				logrus.Fatal(err)
+			}
+		}
+		if dc.ResumeFrom != "" {
+			if dc.ResumeFrom == dc.Checkpoint {
+				logrus.Fatal("--resume_from has to be another file than the checkpoint of the new dump")
+			}
+			if err := dumper.ResumeDump(dc.ResumeFrom); err != nil {
+				logrus.Fatal(err)
+			}
+		}
+		ranged, err := addRanges(dumper, splitList(dc.Ranges))
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		ctx := context.Background()
+		if dc.Timeout != "" {
+			timeout, err := time.ParseDuration(dc.Timeout)
+			if err != nil {
+				logrus.Fatalf("invalid timeout: %s", err)
+			}
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		start := time.Now()
+		var wg sync.WaitGroup
+		if len(ranged) > 0 {
+			err = dumper.DumpContext(ctx, dbName, &wg, ranged...)
+		} else {
+			err = dumper.DumpAllTablesContext(ctx, dbName, &wg)
+		}
+		res.Duration = time.Since(start)
+		res.account(dumper, nil)
+		res.Warnings = dumper.Warnings()
+		res.Violations = dumper.RuleViolations()
+		res.RowCounts, res.Suspect = dumper.RowCountViolations(), dumper.Suspect()
+		res.TableLimits = dumper.TableLimitBreaches()
+		if progress != nil {
+			progress.Close()
+		}
+		if parts != nil {
+			if cerr := parts.Close(); cerr != nil && err == nil {
+				err = cerr
+			}
+			res.Files = append(parts.Parts(), dc.File+".manifest")
+		}
+		if dedup != nil {
+			if cerr := dedup.Close(); cerr != nil && err == nil {
+				err = cerr
+			}
+			stats := dedup.Stats()
+			res.Files, res.Dedup = []string{dc.File + ".dedup"}, &stats
+		}
+		if sink != nil {
+			// Interrupted dumps are kept, ending with a partial footer
+			if err != nil && !errors.Is(err, mysqldump.ErrInterrupted) {
+				if aerr := sink.Abort(); aerr != nil {
+					logrus.Warn(aerr)
+				}
+			} else if cerr := sink.Close(); cerr != nil {
+				logrus.Fatal(cerr)
+			}
+		}
+		if errors.Is(err, mysqldump.ErrInterrupted) {
+			if f != nil {
+				f.Close()
+			}
+			res.Interrupted = true
+			res.Checkpoint = dc.Checkpoint
+			printDumpResult(dc.Output, res)
+			os.Exit(exitInterrupted)
+		}
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		printDumpResult(dc.Output, res)
+	})
+
+	command.Execute()
+}
+
+// dumpResult summarizes a dump once it is done.
+type dumpResult struct {
+	Files       []string
+	Database    string
+	Tables      int
+	Rows        int64
+	Bytes       int64
+	Duration    time.Duration
+	Interrupted bool
+	Checkpoint  string
+	// Rules broken by the dumped rows
+	Violations []mysqldump.RuleViolation
+	// Tables dumped with a number of rows out of their expected range, which make the dump suspect
+	RowCounts []mysqldump.RowCountViolation
+	Suspect   bool
+	// Tables that went over their limit
+	TableLimits []mysqldump.TableLimitBreach `json:",omitempty"`
+	// Issues that didn't stop the dump
+	Warnings []mysqldump.Warning
+	// Bytes of values read from the server, and of output written to each destination
+	ReadBytes    int64
+	WrittenBytes int64
+	// Price of the dump by the configured cost model, 0 without one
+	Cost float64
+	// Chunks of a dump written to --dedup_store, and those that weren't there yet
+	Dedup *mysqldump.DedupStats `json:",omitempty"`
+}
+
+// account sets the bytes moved by the dump in the result, priced by cost if set.
+func (r *dumpResult) account(d *mysqldump.Dumper, cost *mysqldump.CostModel) {
+	bw := d.Bandwidth()
+	r.ReadBytes, r.WrittenBytes = bw.ReadBytes, bw.WrittenBytes
+	if cost != nil {
+		r.Cost = cost.Cost(bw, len(r.Files))
+	}
+}
+
+// track returns a progress callback keeping the result up to date, which forwards events to next if set.
+func (r *dumpResult) track(next func(mysqldump.ProgressEvent)) func(mysqldump.ProgressEvent) {
+	return func(e mysqldump.ProgressEvent) {
+		if e.TableDone {
+			r.Tables++
+		}
+		r.Rows = e.TotalRows
+		r.Bytes = e.TotalBytes
+
+		if next != nil {
+			next(e)
+		}
+	}
+}
+
+func printDumpResult(format string, res *dumpResult) {
+	printResult(format, res, func() {
+		if res.Interrupted {
+			logrus.Infof("Dump interrupted, checkpoint saved to %s", res.Checkpoint)
+		}
+		logrus.Infof("Dumped %d tables of %s, %d rows (%s) in %s", res.Tables, res.Database, res.Rows, formatBytes(res.Bytes), res.Duration.Round(time.Second))
+		logrus.Infof("Read %s from the server, wrote %s of output", formatBytes(res.ReadBytes), formatBytes(res.WrittenBytes))
+		if res.Cost > 0 {
+			logrus.Infof("Estimated network cost: %.4f", res.Cost)
+		}
+		if s := res.Dedup; s != nil {
+			logrus.Infof("Stored %d new chunks of %d (%s of %s), the others were in the store already", s.NewChunks, s.Chunks, formatBytes(s.NewBytes), formatBytes(s.Bytes))
+		}
+		for _, v := range res.Violations {
+			logrus.Warnf("%s: %d rows break %s", v.Table, v.Rows, v.Rule)
+		}
+		for _, b := range res.TableLimits {
+			logrus.Warnf("%s went over its %s limit after %s and %d rows, action: %s", b.Table, b.Limit, b.Elapsed.Round(time.Second), b.Rows, b.Action)
+		}
+		if res.Suspect {
+			logrus.Warnf("Dump is suspect, %d tables have an unexpected number of rows", len(res.RowCounts))
+		}
+		if len(res.Warnings) > 0 {
+			logrus.Warnf("Dumped with %d warnings", len(res.Warnings))
+		}
+	})
+}
+
+// addRanges limits the rows dumped to ranges of the form table.column=from..to, and returns the
+// tables they are of.
+func addRanges(d *mysqldump.Dumper, list []string) ([]string, error) {
+	var tables []string
+	seen := make(map[string]bool)
+	for _, s := range list {
+		eq := strings.Index(s, "=")
+		dot := strings.Index(s, ".")
+		sep := strings.Index(s, "..")
+		if eq < 0 || dot < 0 || dot > eq || sep < eq {
+			return nil, fmt.Errorf("invalid range %q, expected table.column=from..to", s)
+		}
+
+		var from, to interface{}
+		if v := s[eq+1 : sep]; v != "" {
+			from = v
+		}
+		if v := s[sep+2:]; v != "" {
+			to = v
+		}
+		table := s[:dot]
+		if err := d.DumpRange(table, s[dot+1:eq], from, to); err != nil {
+			return nil, err
+		}
+		if !seen[table] {
+			seen[table] = true
+			tables = append(tables, table)
+		}
+	}
+	return tables, nil
+}
+
+// parseCompression parses the compressions the command can write, which doesn't register a zstd
+// implementation.
+func parseCompression(s string) (mysqldump.Compression, error) {
+	c, err := mysqldump.ParseCompression(s)
+	if err == nil && c == mysqldump.CompressionZstd {
+		return c, errors.New("zstd compression isn't built into the command, use gzip")
+	}
+	return c, err
+}
+
+// interruptOnSignal stops the dumper gracefully on SIGINT or SIGTERM. A second signal exits right away.
+func interruptOnSignal(dumper *mysqldump.Dumper) {
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		sig := <-sigs
+		logrus.Infof("Received %s, stopping after the current chunk", sig)
+		dumper.Interrupt()
+
+		<-sigs
+		logrus.Info("Received second signal, exiting")
+		os.Exit(exitInterrupted)
+	}()
+}
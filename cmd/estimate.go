@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/MouseHatGames/go-mysqldump"
+	"github.com/conneqtech/std_pkg/cli"
+	"github.com/conneqtech/std_pkg/db/mysql"
+	"github.com/sirupsen/logrus"
+)
+
+type EstimateConfiguration struct {
+	Type        string     `command:"type,usage=Use mysql of pg,default=mysql"`
+	SourcePG    mysql.Opts `command:"source_pg,required=false"`
+	SourceMysql mysql.Opts `command:"source_mysql,required=false"`
+	Throughput  int64      `command:"throughput,usage=Expected dump throughput in MB/s,default=20"`
+	Output      string     `command:"output,usage=Output format: text or json,default=text"`
+}
+
+var ec *EstimateConfiguration
+
+// runEstimate predicts the size and duration of dumping the source database.
+func runEstimate() {
+	command := cli.Initialize("DB dumper estimate", &ec)
+	command.OnRun(func() {
+		setupOutput(ec.Output)
+		db, dbName, err := openSource(ec.Type, &ec.SourcePG, &ec.SourceMysql)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		defer db.Close()
+
+		est, err := mysqldump.NewDumper(db, nil, 0, defaultFilters(dbName)...).Estimate(dbName, ec.Throughput*1000*1000)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		var rows, bytes int64
+		var duration time.Duration
+		for _, e := range est {
+			rows += e.Rows
+			bytes += e.Bytes
+			duration += e.Duration
+		}
+
+		res := struct {
+			Tables   []*mysqldump.TableEstimate
+			Rows     int64
+			Bytes    int64
+			Duration time.Duration
+		}{est, rows, bytes, duration}
+
+		printResult(ec.Output, res, func() {
+			printEstimate(est, rows, bytes, duration)
+		})
+	})
+
+	command.Execute()
+}
+
+func printEstimate(est []*mysqldump.TableEstimate, rows int64, bytes int64, duration time.Duration) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "TABLE\tROWS\tSIZE\tDURATION\tFILTERS")
+	for _, e := range est {
+		filters := strings.Join(e.Filters, ";")
+		if e.Skipped {
+			filters = "data skipped"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\n", e.Table, e.Rows, formatBytes(e.Bytes), e.Duration.Round(time.Second), filters)
+	}
+	fmt.Fprintf(tw, "TOTAL\t%d\t%s\t%s\t\n", rows, formatBytes(bytes), duration.Round(time.Second))
+	tw.Flush()
+}
+
+func formatBytes(n int64) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "kMGTPE"[exp])
+}
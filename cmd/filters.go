@@ -0,0 +1,32 @@
+package main
+
+import "github.com/MouseHatGames/go-mysqldump"
+
+// filteredTables are the table filters of the databases we dump, used unless a job config sets its own.
+// Every query starts with a space.
+var filteredTables = map[string]map[string][]string{
+	"iot-api": {
+		"event_log": {
+			" WHERE event = 'geofence-in' AND id < 517837446",
+			" WHERE event = 'geofence-out' AND id < 517837446",
+			" WHERE id >= 517837446",
+		}, // skip data before 01-10-2021 except for geofences
+		"rate_limit_request_log": {}, // skip all data
+	},
+	"paztir_prod": {
+		"doctrine_migration_versions": {},
+	},
+	"paztir": {
+		"doctrine_migration_versions": {},
+		//"event_log":                   {},
+		//"app_log": {" where id = 10027821"},
+	},
+}
+
+// defaultFilters returns the options applying the built-in filters of a database.
+func defaultFilters(dbName string) []mysqldump.Option {
+	if fs, ok := filteredTables[dbName]; ok {
+		return []mysqldump.Option{mysqldump.WithTableFilters(fs)}
+	}
+	return nil
+}
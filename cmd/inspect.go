@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/MouseHatGames/go-mysqldump"
+	"github.com/conneqtech/std_pkg/cli"
+	"github.com/sirupsen/logrus"
+)
+
+type InspectConfiguration struct {
+	DDL    bool   `command:"ddl,usage=Print the CREATE statement of every table,default=true"`
+	Server bool   `command:"server,usage=Print the global variables and status of the server recorded with dump --server_snapshot,default=false"`
+	Output string `command:"output,usage=Output format: text or json,default=text"`
+}
+
+var ic *InspectConfiguration
+
+// runInspect prints what a dump file contains without restoring it.
+func runInspect() {
+	command := cli.Initialize("DB dumper inspect", &ic)
+	command.OnRun(func() {
+		setupOutput(ic.Output)
+		if len(args) != 1 {
+			logrus.Fatal("usage: inspect <dump file>")
+		}
+
+		f, err := openDump(args[0])
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		defer f.Close()
+
+		info, err := mysqldump.Inspect(f)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		printResult(ic.Output, info, func() {
+			printInspect(info, ic.DDL)
+			if ic.Server {
+				printServerSnapshot(info.Header)
+			}
+		})
+	})
+
+	command.Execute()
+}
+
+func printInspect(info *mysqldump.DumpInfo, ddl bool) {
+	fmt.Printf("Database:       %s\n", info.Header.DatabaseName)
+	fmt.Printf("Server version: %s\n", info.Header.ServerVersion)
+	fmt.Printf("Dump start:     %s\n", info.Header.DumpStart)
+	if info.Header.Managed != "" {
+		fmt.Printf("Managed by:     %s\n", info.Header.Managed)
+	}
+	if info.Header.Snapshot != "" {
+		fmt.Printf("Snapshot TSO:   %s\n", info.Header.Snapshot)
+	}
+	if info.Header.Compression != "" {
+		fmt.Printf("Compression:    %s\n", info.Header.Compression)
+	}
+	if b := info.Header.Binlog; b != nil {
+		fmt.Printf("Binlog:         %s:%d (exact: %t)\n", b.File, b.Position, b.Exact)
+		if b.GTIDSet != "" {
+			fmt.Printf("GTID set:       %s\n", b.GTIDSet)
+		}
+	}
+	if info.Footer != nil {
+		fmt.Printf("Dump end:       %s\n", info.Footer.DumpEnd)
+		fmt.Printf("Partial:        %t\n", info.Footer.Partial)
+		if len(info.Footer.Limited) > 0 {
+			fmt.Printf("Cut short:      %s\n", strings.Join(info.Footer.Limited, ", "))
+		}
+	}
+	if info.Header.Continues {
+		fmt.Printf("Continues:      an interrupted dump, restore it first\n")
+	}
+	if n := len(info.Header.ServerVariables); n > 0 {
+		fmt.Printf("Server:         %d variables, %d status counters\n", n, len(info.Header.ServerStatus))
+	}
+	fmt.Printf("Checksum:       %s\n", info.Checksum)
+	if f := info.Header.TableFilters; f != nil {
+		fmt.Printf("Filtered:       %t\n", len(f) > 0)
+		names := make([]string, 0, len(f))
+		for t := range f {
+			names = append(names, t)
+		}
+		sort.Strings(names)
+		for _, t := range names {
+			if len(f[t]) == 0 {
+				fmt.Printf("  %s: rows left out\n", t)
+			}
+			for _, q := range f[t] {
+				fmt.Printf("  %s:%s\n", t, q)
+			}
+		}
+	}
+	fmt.Println()
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "TABLE\tCOLUMNS\tROWS\tBYTES\tCHUNKING\tCHECKSUM")
+	for _, t := range info.Tables {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%d\t%s\t%s\n", t.Header.Name, len(t.Header.Columns), formatRows(t), t.Bytes, formatChunking(t.Header.Chunking, t.Header.ChunkKey), t.Checksum)
+	}
+	tw.Flush()
+
+	if len(info.Objects) > 0 {
+		fmt.Println()
+		tw = tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "OBJECT\tTYPE\tTABLE")
+		for _, o := range info.Objects {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", o.Name, o.Type, o.Table)
+		}
+		tw.Flush()
+	}
+
+	if !ddl {
+		return
+	}
+	for _, t := range info.Tables {
+		note := ""
+		if t.Header.ReconstructedDDL {
+			note = " (reconstructed from INFORMATION_SCHEMA)"
+		}
+		fmt.Printf("\n-- %s%s\n%s;\n", t.Header.Name, note, t.Header.CreateSQL)
+	}
+	for _, o := range info.Objects {
+		fmt.Printf("\n-- %s %s\n%s;\n", strings.ToLower(o.Type), o.Name, o.CreateSQL)
+	}
+}
+
+// printServerSnapshot prints the global variables and status counters recorded in a dump, sorted by name.
+func printServerSnapshot(h *mysqldump.FileHeader) {
+	for _, section := range []struct {
+		title  string
+		values map[string]string
+	}{{"Global variables", h.ServerVariables}, {"Global status", h.ServerStatus}} {
+		if len(section.values) == 0 {
+			continue
+		}
+		names := make([]string, 0, len(section.values))
+		for n := range section.values {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+
+		fmt.Printf("\n%s:\n", section.title)
+		tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		for _, n := range names {
+			fmt.Fprintf(tw, "  %s\t%s\n", n, section.values[n])
+		}
+		tw.Flush()
+	}
+}
+
+// formatRows returns the row count of a table, or why its rows were left out.
+func formatRows(t *mysqldump.TableInfo) string {
+	if n := t.NoData; n != nil {
+		if n.Detail != "" {
+			return fmt.Sprintf("skipped by %s %s", n.Reason, n.Detail)
+		}
+		return "skipped by " + n.Reason
+	}
+	return strconv.FormatInt(t.Rows, 10)
+}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"bytes"
-	"database/sql"
 	"fmt"
 	"github.com/MouseHatGames/go-mysqldump"
 	"github.com/conneqtech/std_pkg/cli"
@@ -12,6 +11,7 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"strings"
 	"sync"
 	"time"
 )
@@ -26,19 +26,65 @@ type Configuration struct {
 	ChunkSize int `command:"chunk_size,default=0"`
 
 	// target options
-	QuerySize   int `command:"query_size,default=1000000"`
-	Verbose     int `command:"verbose,default=0"`
-	WorkerCount int `command:"worker_count,default=10"` // unused for now
+	QuerySize   int    `command:"query_size,default=1000000"`
+	Verbose     int    `command:"verbose,default=0"`
+	WorkerCount int    `command:"worker_count,default=10"` // unused for now
+	Output      string `command:"output,usage=Output format: text or json,default=text"`
 }
 
 var c *Configuration
 
+// commands maps subcommand names to their entry points. Without a known subcommand the
+// tool copies the source database straight into the target.
+var commands = map[string]func(){
+	"config":   runConfig,
+	"convert":  runConvert,
+	"diff":     runDiff,
+	"dump":     runDump,
+	"estimate": runEstimate,
+	"inspect":  runInspect,
+	"profile":  runProfile,
+	"rekey":    runRekey,
+	"restore":  runRestore,
+	"run":      runRun,
+	"schema":   runSchema,
+	"users":    runUsers,
+	"verify":   runVerify,
+}
+
+// args holds the positional arguments given after the subcommand, before any flags.
+var args []string
+
 func main() {
+	warnArgvPasswords()
+
+	if len(os.Args) > 1 {
+		if run, ok := commands[os.Args[1]]; ok {
+			os.Args = append(os.Args[:1], os.Args[2:]...)
+			for len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+				args = append(args, os.Args[1])
+				os.Args = append(os.Args[:1], os.Args[2:]...)
+			}
+			run()
+			return
+		}
+	}
+
+	runCopy()
+}
+
+func runCopy() {
 	command := cli.Initialize("DB dumper", &c)
 	command.OnRun(func() {
+		setupOutput(c.Output)
 		start := time.Now()
 		defer func() {
-			logrus.Info(time.Now().Sub(start).String())
+			res := struct {
+				Duration time.Duration
+			}{time.Now().Sub(start)}
+			printResult(c.Output, res, func() {
+				logrus.Info(res.Duration.String())
+			})
 		}()
 
 		var wg sync.WaitGroup
@@ -49,22 +95,11 @@ func main() {
 
 		wg.Add(1)
 		go func() {
-			var db *sql.DB
-			var err error
-			var dbName string
-			if c.Type == "mysql" {
-				db, err = mysql.NewMysqlClient(&c.SourceMysql)
-				dbName = c.SourceMysql.Database
-			} else if c.Type == "pg" {
-				db, err = sql.Open("postgres", fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=disable", c.SourcePG.Username, c.SourcePG.Password, c.SourcePG.Host, c.SourcePG.Database))
-				dbName = c.SourcePG.Database
-			} else {
-				err = fmt.Errorf("invalid type given: %s", c.Type)
-			}
+			db, dbName, err := openSource(c.Type, &c.SourcePG, &c.SourceMysql)
 			if err != nil {
 				logrus.Fatal(err)
 			}
-			dumper := mysqldump.NewDumper(db, pw, c.ChunkSize)
+			dumper := mysqldump.NewDumper(db, pw, c.ChunkSize, defaultFilters(dbName)...)
 			err = dumper.DumpAllTables(dbName, &writerGroup)
 			if err != nil {
 				logrus.Fatal(err)
@@ -73,7 +108,7 @@ func main() {
 			wg.Done()
 		}()
 
-		db, err := mysql.NewMysqlClient(&c.TargetMysql)
+		db, err := openTarget(&c.TargetMysql)
 
 		queryWorker := make(chan string, 100)
 		wg.Add(1)
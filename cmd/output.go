@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/sirupsen/logrus"
+)
+
+// setupOutput validates the --output flag. In json mode logs are written as JSON too, so
+// that nothing on either stream needs to be scraped.
+func setupOutput(format string) {
+	switch format {
+	case "text":
+	case "json":
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	default:
+		logrus.Fatalf("invalid output format: %s", format)
+	}
+}
+
+// printResult writes v to stdout as JSON if format is json, otherwise it calls text to print it for humans.
+func printResult(format string, v interface{}, text func()) {
+	if format != "json" {
+		text()
+		return
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+// errorString returns err's message, or an empty string if it is nil.
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MouseHatGames/go-mysqldump"
+)
+
+// openDump opens a dump file, all the parts of a split dump if path is its manifest, or the chunks
+// of a deduplicated dump if path is their index. Encrypted dumps are decrypted, see decryptDump.
+func openDump(path string) (io.ReadCloser, error) {
+	var f io.ReadCloser
+	var err error
+	if strings.HasSuffix(path, ".manifest") {
+		f, err = mysqldump.OpenParts(path)
+	} else if strings.HasSuffix(path, ".dedup") {
+		f, err = mysqldump.OpenDedup(path, nil)
+	} else {
+		f, err = os.Open(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := decryptDump(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{r, f}, nil
+}
+
+var sizeUnits = []struct {
+	suffix string
+	n      int64
+}{
+	{"TB", 1 << 40},
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// parseSize parses a size such as 4GB or 500MB. A plain number is a count of bytes.
+func parseSize(s string) (int64, error) {
+	v := strings.ToUpper(strings.TrimSpace(s))
+	mul := int64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(v, u.suffix) {
+			v, mul = strings.TrimSpace(strings.TrimSuffix(v, u.suffix)), u.n
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size: %s", s)
+	}
+	return n * mul, nil
+}
+
+// parseThrottle parses a throttle schedule given as a default rate and time windows with their own,
+// such as 10MB,02:00-05:00=unlimited. Rates are sizes read per second, or unlimited.
+func parseThrottle(list []string) (mysqldump.ThrottleSchedule, error) {
+	var s mysqldump.ThrottleSchedule
+	for _, item := range list {
+		window, rate := "", item
+		if i := strings.Index(item, "="); i >= 0 {
+			window, rate = item[:i], item[i+1:]
+		}
+
+		var bps int64
+		if !strings.EqualFold(strings.TrimSpace(rate), "unlimited") {
+			var err error
+			if bps, err = parseSize(rate); err != nil {
+				return s, fmt.Errorf("invalid throttle %q: %w", item, err)
+			}
+		}
+		if window == "" {
+			s.BytesPerSecond = bps
+			continue
+		}
+		start, end, err := mysqldump.ParseTimeWindow(window)
+		if err != nil {
+			return s, err
+		}
+		s.Windows = append(s.Windows, mysqldump.ThrottleWindow{Start: start, End: end, BytesPerSecond: bps})
+	}
+	return s, nil
+}
+
+// parseTableLimits parses table limits given as table=limit, such as *=2h or orders=2h+500GB, where a
+// limit is a duration, a size, or both joined by a plus sign.
+func parseTableLimits(list []string, action mysqldump.LimitAction) (map[string]mysqldump.TableLimit, error) {
+	limits := make(map[string]mysqldump.TableLimit, len(list))
+	for _, item := range list {
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid table limit %q, expected table=limit", item)
+		}
+		l, err := parseTableLimit(kv[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid table limit %q: %w", item, err)
+		}
+		l.Action = action
+		limits[strings.TrimSpace(kv[0])] = l
+	}
+	return limits, nil
+}
+
+// parseTableLimit parses a duration such as 2h, a size such as 500GB, or both joined by a plus sign.
+func parseTableLimit(s string) (mysqldump.TableLimit, error) {
+	var l mysqldump.TableLimit
+	for _, part := range strings.Split(s, "+") {
+		part = strings.TrimSpace(part)
+		if d, err := time.ParseDuration(part); err == nil && d > 0 {
+			l.MaxDuration = d
+		} else if n, err := parseSize(part); err == nil {
+			l.MaxBytes = n
+		} else {
+			return l, fmt.Errorf("%q is neither a duration nor a size", part)
+		}
+	}
+	return l, nil
+}
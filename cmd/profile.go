@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/MouseHatGames/go-mysqldump"
+	"github.com/conneqtech/std_pkg/cli"
+	"github.com/conneqtech/std_pkg/db/mysql"
+	"github.com/sirupsen/logrus"
+)
+
+type ProfileConfiguration struct {
+	Type        string     `command:"type,usage=Use mysql of pg,default=mysql"`
+	SourcePG    mysql.Opts `command:"source_pg,required=false"`
+	SourceMysql mysql.Opts `command:"source_mysql,required=false"`
+	ChunkSize   int        `command:"chunk_size,usage=Chunk size to count chunks and their boundaries with,default=0"`
+	Output      string     `command:"output,usage=Output format: text or json,default=text"`
+}
+
+var pc *ProfileConfiguration
+
+// runProfile counts the rows and key ranges of every table in the source database, without reading them.
+func runProfile() {
+	command := cli.Initialize("DB dumper profile", &pc)
+	command.OnRun(func() {
+		setupOutput(pc.Output)
+		db, dbName, err := openSource(pc.Type, &pc.SourcePG, &pc.SourceMysql)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		defer db.Close()
+
+		prof, err := mysqldump.NewDumper(db, nil, pc.ChunkSize, defaultFilters(dbName)...).Profile(dbName)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		res := struct {
+			Tables []*mysqldump.TableProfile
+		}{prof}
+		printResult(pc.Output, res, func() {
+			printProfile(prof)
+		})
+	})
+
+	command.Execute()
+}
+
+func printProfile(prof []*mysqldump.TableProfile) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "TABLE\tCHUNKING\tKEY\tROWS\tCHUNKS\tMIN\tMAX\tFILTER")
+	for _, p := range prof {
+		if p.Skipped {
+			fmt.Fprintf(tw, "%s\t\t\t\t\t\t\tdata skipped\n", p.Table)
+			continue
+		}
+		for _, r := range p.Ranges {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%s\t%s\t%s\n", p.Table, formatChunking(p.Chunking, p.ChunkKey), p.Key, r.Rows, r.Chunks, formatKey(r.Min), formatKey(r.Max), r.Filter)
+		}
+	}
+	tw.Flush()
+}
+
+func formatKey(k *string) string {
+	if k == nil {
+		return "NULL"
+	}
+	return *k
+}
+
+// formatChunking returns a chunking strategy along with the columns of its key, if any.
+func formatChunking(strategy string, key []string) string {
+	if strategy == "" {
+		return "-"
+	}
+	if len(key) == 0 {
+		return strategy
+	}
+	return strategy + " (" + strings.Join(key, ", ") + ")"
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MouseHatGames/go-mysqldump"
+)
+
+const progressBarWidth = 30
+
+// progressDisplay draws per-table progress bars to a terminal from the dumper's progress events.
+type progressDisplay struct {
+	w io.Writer
+
+	mu     sync.Mutex
+	start  time.Time
+	last   mysqldump.ProgressEvent
+	tables []*tableProgress
+	lines  int
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+type tableProgress struct {
+	event mysqldump.ProgressEvent
+	start time.Time
+	end   time.Time
+}
+
+func newProgressDisplay(w io.Writer) *progressDisplay {
+	p := &progressDisplay{
+		w:     w,
+		start: time.Now(),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+
+	go func() {
+		defer close(p.done)
+
+		t := time.NewTicker(200 * time.Millisecond)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-t.C:
+				p.render()
+			case <-p.stop:
+				p.render()
+				return
+			}
+		}
+	}()
+
+	return p
+}
+
+// Update is passed to mysqldump.WithProgress.
+func (p *progressDisplay) Update(e mysqldump.ProgressEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.last = e
+	if len(p.tables) == 0 || p.tables[len(p.tables)-1].event.Table != e.Table {
+		p.tables = append(p.tables, &tableProgress{start: time.Now()})
+	}
+
+	t := p.tables[len(p.tables)-1]
+	t.event = e
+	if e.TableDone {
+		t.end = time.Now()
+	}
+}
+
+// Close draws the final state and stops redrawing.
+func (p *progressDisplay) Close() {
+	close(p.stop)
+	<-p.done
+}
+
+func (p *progressDisplay) render() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var b strings.Builder
+
+	// Move back to the start of the previous frame
+	if p.lines > 0 {
+		fmt.Fprintf(&b, "\033[%dF", p.lines)
+	}
+
+	elapsed := time.Since(p.start)
+	rows := fmt.Sprintf("%d rows", p.last.TotalRows)
+	if n := p.last.EstimatedTotalRows; n > 0 {
+		rows = fmt.Sprintf("%d of about %d rows", p.last.TotalRows, n)
+	}
+	lines := []string{fmt.Sprintf("Table %d/%d, %s, %s written in %s (%s/s)",
+		p.last.TableIndex, p.last.TableCount, rows, formatBytes(p.last.TotalBytes),
+		elapsed.Round(time.Second), formatBytes(rate(p.last.TotalBytes, elapsed)))}
+	if p.last.DestinationSlow {
+		lines[0] += ", waiting for the destination"
+	}
+
+	for _, t := range p.tables {
+		lines = append(lines, t.line())
+	}
+
+	for _, l := range lines {
+		b.WriteString("\033[2K")
+		b.WriteString(l)
+		b.WriteByte('\n')
+	}
+	p.lines = len(lines)
+
+	io.WriteString(p.w, b.String())
+}
+
+func (t *tableProgress) line() string {
+	e := t.event
+
+	end := t.end
+	if end.IsZero() {
+		end = time.Now()
+	}
+	elapsed := end.Sub(t.start)
+
+	frac := 0.0
+	switch {
+	case e.TableDone:
+		frac = 1
+	case e.EstimatedRows > 0:
+		frac = float64(e.Rows) / float64(e.EstimatedRows)
+		if frac > 0.99 {
+			frac = 0.99
+		}
+	}
+
+	filled := int(frac * progressBarWidth)
+	bar := strings.Repeat("#", filled) + strings.Repeat("-", progressBarWidth-filled)
+
+	status := "done"
+	if !e.TableDone {
+		status = "ETA ?"
+		if perSec := rate(e.Rows, elapsed); perSec > 0 && e.EstimatedRows > e.Rows {
+			status = "ETA " + (time.Duration(e.EstimatedRows-e.Rows) * time.Second / time.Duration(perSec)).Round(time.Second).String()
+		}
+	}
+
+	chunk := fmt.Sprint(e.Chunk)
+	if e.EstimatedChunks > 0 {
+		chunk += fmt.Sprintf("/%d", e.EstimatedChunks)
+	}
+	return fmt.Sprintf("  %-30s [%s] %3.0f%%  %d/%d rows  chunk %s  %s/s  %s",
+		e.Table, bar, frac*100, e.Rows, e.EstimatedRows, chunk, formatBytes(rate(e.Bytes, elapsed)), status)
+}
+
+// rate returns n per second over d.
+func rate(n int64, d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+	return int64(float64(n) / d.Seconds())
+}
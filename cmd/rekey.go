@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/MouseHatGames/go-mysqldump"
+	"github.com/conneqtech/std_pkg/cli"
+	"github.com/sirupsen/logrus"
+)
+
+// encryptionKeyEnv holds the key encrypted dumps are read with, or a reference to it.
+const encryptionKeyEnv = "MYSQLDUMP_ENCRYPTION_KEY"
+
+type RekeyConfiguration struct {
+	OldKey  string `command:"old_key,usage=Key the dump is encrypted with: hex or a reference such as env:NAME. Defaults to MYSQLDUMP_ENCRYPTION_KEY,required=false"`
+	NewKey  string `command:"new_key,usage=Key to encrypt the dump with instead: hex or a reference such as file:/path"`
+	Out     string `command:"out,usage=File to write the rekeyed dump to or - for stdout. Without it the key is rewrapped in place,required=false"`
+	DataKey bool   `command:"new_data_key,usage=Re-encrypt the whole dump under a new data key instead of only rewrapping it. Needs --out,default=false"`
+}
+
+var kc *RekeyConfiguration
+
+// runRekey rotates the key of an encrypted dump.
+func runRekey() {
+	command := cli.Initialize("DB dumper rekey", &kc)
+	command.OnRun(func() {
+		if len(args) != 1 {
+			logrus.Fatal("usage: rekey <dump file>")
+		}
+		if kc.DataKey && kc.Out == "" {
+			logrus.Fatal("--new_data_key needs --out")
+		}
+
+		oldKey, err := encryptionKey(kc.OldKey)
+		if err != nil {
+			logrus.Fatalf("old key: %s", err)
+		}
+		newKey, err := encryptionKey(kc.NewKey)
+		if err != nil {
+			logrus.Fatalf("new key: %s", err)
+		}
+
+		if kc.Out == "" {
+			if err = mysqldump.RewrapFile(args[0], oldKey, newKey); err != nil {
+				logrus.Fatal(err)
+			}
+			logrus.Infof("Rewrapped the key of %s", args[0])
+			return
+		}
+
+		in, err := os.Open(args[0])
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		defer in.Close()
+		err = withOutput(kc.Out, func(w io.Writer) error {
+			if kc.DataKey {
+				return mysqldump.RotateKey(in, w, oldKey, newKey)
+			}
+			return mysqldump.RewrapKey(in, w, oldKey, newKey)
+		})
+		if err != nil {
+			logrus.Fatal(err)
+		}
+	})
+
+	command.Execute()
+}
+
+// encryptionKey returns the hex encoded key a reference points to, see resolveSecret. An empty
+// reference reads MYSQLDUMP_ENCRYPTION_KEY.
+func encryptionKey(ref string) ([]byte, error) {
+	if ref == "" {
+		ref = os.Getenv(encryptionKeyEnv)
+	}
+	if ref == "" {
+		return nil, fmt.Errorf("no key given, set %s", encryptionKeyEnv)
+	}
+
+	v, err := resolveSecret(ref)
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(v))
+	if err != nil {
+		return nil, errors.New("keys are hex encoded")
+	}
+	return key, nil
+}
+
+// decryptDump decrypts a dump if it is encrypted, with the key of MYSQLDUMP_ENCRYPTION_KEY.
+func decryptDump(in io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(in)
+	if magic, _ := br.Peek(len(mysqldump.EncryptedMagic)); string(magic) != mysqldump.EncryptedMagic {
+		return br, nil
+	}
+
+	key, err := encryptionKey("")
+	if err != nil {
+		return nil, fmt.Errorf("dump is encrypted: %w", err)
+	}
+	return mysqldump.NewDecryptReader(br, key)
+}
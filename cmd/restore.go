@@ -0,0 +1,199 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/MouseHatGames/go-mysqldump"
+	"github.com/conneqtech/std_pkg/cli"
+	"github.com/conneqtech/std_pkg/db/mysql"
+	"github.com/sirupsen/logrus"
+)
+
+type RestoreConfiguration struct {
+	File        string     `command:"file,usage=Dump file to restore or - for stdin,default=-"`
+	TargetMysql mysql.Opts `command:"target_mysql,required=false"`
+	Tables      string     `command:"tables,usage=Comma separated list of tables to restore,required=false"`
+	Conflict    string     `command:"conflict,usage=What to do with existing rows: replace ignore or error,default=replace"`
+	Parallelism int        `command:"parallelism,default=4"`
+	QuerySize   int        `command:"query_size,default=1000000"`
+	SkipCreate  bool       `command:"skip_create,default=false"`
+	DryRun      bool       `command:"dry_run,usage=Print the statements instead of running them,default=false"`
+	Percona     string     `command:"percona,usage=What to do with Percona column compression clauses: auto keep or strip,default=auto"`
+	Transforms  string     `command:"ddl_transforms,usage=Comma separated list of DDL transforms to apply,required=false"`
+	Shard       int        `command:"shard,usage=Only restore this shard of sharded tables starting at 1,default=0"`
+	Stats       bool       `command:"stats,usage=Restore the dumped optimizer statistics or analyze the tables without any,default=false"`
+	Create      string     `command:"create,usage=How to create tables: auto drop if_not_exists or error. auto follows the dump,default=auto"`
+	Renames     string     `command:"rename_columns,usage=Comma separated list of table.old=new column renames to restore older dumps into. An empty new name leaves the column out,required=false"`
+	Policies    string     `command:"column_policies,usage=Comma separated list of table.column=policy with policies empty_null invalid_date_null or default:value,required=false"`
+	SchemaCheck string     `command:"schema_check,usage=What to do with target tables whose columns differ from the dump: off strict or map,default=off"`
+	Output      string     `command:"output,usage=Output format: text or json,default=text"`
+}
+
+var rc *RestoreConfiguration
+
+// runRestore loads a binary dump file into the target database.
+func runRestore() {
+	command := cli.Initialize("DB dumper restore", &rc)
+	command.OnRun(func() {
+		setupOutput(rc.Output)
+		if rc.Output == "json" && rc.DryRun {
+			logrus.Fatal("--output json can't be combined with --dry_run, stdout holds the statements")
+		}
+		start := time.Now()
+
+		conflict, err := mysqldump.ParseConflictPolicy(rc.Conflict)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		percona, err := mysqldump.ParseClausePolicy(rc.Percona)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		transforms, err := mysqldump.ParseDDLTransforms(splitList(rc.Transforms))
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		create, err := mysqldump.ParseCreatePolicy(rc.Create)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		schemaCheck, err := mysqldump.ParseSchemaCheck(rc.SchemaCheck)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		renames, err := parseColumnMap(splitList(rc.Renames))
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		policies, err := parseColumnPolicies(splitList(rc.Policies))
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		var in io.Reader = os.Stdin
+		if rc.File != "-" {
+			f, err := openDump(rc.File)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			defer f.Close()
+			in = f
+		}
+
+		opt := mysqldump.LoaderOptions{
+			Tables:      splitList(rc.Tables),
+			SkipCreate:  rc.SkipCreate,
+			Conflict:    conflict,
+			Parallelism: rc.Parallelism,
+			QuerySize:   rc.QuerySize,
+			Percona:     percona,
+			Shard:       rc.Shard,
+			Stats:       rc.Stats,
+			Create:      create,
+			SchemaCheck: schemaCheck,
+			ColumnMap:   renames,
+
+			ColumnPolicies: policies,
+
+			DDLTransforms: transforms,
+		}
+
+		var db *sql.DB
+		if rc.DryRun {
+			opt.DryRun = os.Stdout
+		} else {
+			db, err = openTarget(&rc.TargetMysql)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			defer db.Close()
+		}
+
+		loader := mysqldump.NewLoader(db, opt)
+		if err = loader.Load(in); err != nil {
+			logrus.Fatal(err)
+		}
+
+		res := struct {
+			mysqldump.LoadReport
+			Duration time.Duration
+		}{loader.Report(), time.Since(start)}
+		printResult(rc.Output, res, func() {
+			logrus.Infof("Restored %d tables, %d rows in %s", len(res.Tables), res.Rows, res.Duration.Round(time.Second))
+		})
+	})
+
+	command.Execute()
+}
+
+// splitList splits a comma separated flag value, returning nil if it is empty.
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// parseColumnPolicies parses column policies of the form table.column=policy, merging the policies
+// given for the same column.
+func parseColumnPolicies(list []string) (map[string]map[string]mysqldump.ColumnPolicy, error) {
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	m := make(map[string]map[string]mysqldump.ColumnPolicy)
+	for _, s := range list {
+		eq := strings.Index(s, "=")
+		dot := strings.Index(s, ".")
+		if eq < 0 || dot < 0 || dot > eq {
+			return nil, fmt.Errorf("invalid column policy %q, expected table.column=policy", s)
+		}
+		p, err := mysqldump.ParseColumnPolicy(s[eq+1:])
+		if err != nil {
+			return nil, err
+		}
+
+		table, column := s[:dot], s[dot+1:eq]
+		if m[table] == nil {
+			m[table] = make(map[string]mysqldump.ColumnPolicy)
+		}
+		cp := m[table][column]
+		cp.EmptyAsNull = cp.EmptyAsNull || p.EmptyAsNull
+		cp.InvalidDateAsNull = cp.InvalidDateAsNull || p.InvalidDateAsNull
+		if p.Default != nil {
+			cp.Default = p.Default
+		}
+		m[table][column] = cp
+	}
+	return m, nil
+}
+
+// parseColumnMap parses column renames of the form table.old=new.
+func parseColumnMap(renames []string) (map[string]map[string]string, error) {
+	if len(renames) == 0 {
+		return nil, nil
+	}
+
+	m := make(map[string]map[string]string)
+	for _, r := range renames {
+		eq := strings.Index(r, "=")
+		dot := strings.Index(r, ".")
+		if eq < 0 || dot < 0 || dot > eq {
+			return nil, fmt.Errorf("invalid column rename %q, expected table.old=new", r)
+		}
+		table, old := r[:dot], r[dot+1:eq]
+		if m[table] == nil {
+			m[table] = make(map[string]string)
+		}
+		m[table][old] = r[eq+1:]
+	}
+	return m, nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/MouseHatGames/go-mysqldump"
+	"github.com/conneqtech/std_pkg/cli"
+	"github.com/sirupsen/logrus"
+)
+
+type RunConfiguration struct {
+	Daemon bool   `command:"daemon,usage=Keep running and dump on the config's schedule,default=false"`
+	Output string `command:"output,usage=Output format: text or json,default=text"`
+}
+
+var runc *RunConfiguration
+
+// runRun performs the dump described by a config file, once or on its schedule.
+func runRun() {
+	command := cli.Initialize("DB dumper run", &runc)
+	command.OnRun(func() {
+		setupOutput(runc.Output)
+		if len(args) != 1 {
+			logrus.Fatal("usage: run <config file>")
+		}
+
+		fc, err := loadFileConfig(args[0])
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		if errs := fc.validate(); len(errs) > 0 {
+			for _, err := range errs {
+				logrus.Error(err)
+			}
+			os.Exit(1)
+		}
+
+		sigs := handleRunSignals()
+		if !runc.Daemon {
+			res, err := runFileConfig(fc, sigs)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			printDumpResult(runc.Output, res)
+			return
+		}
+
+		if fc.Schedule == "" {
+			logrus.Fatal("--daemon needs a schedule in the config")
+		}
+		sched, _ := parseSchedule(fc.Schedule)
+		for {
+			next := sched.next(time.Now())
+			if next.IsZero() {
+				logrus.Fatalf("schedule %q matches no date in the next years", fc.Schedule)
+			}
+			logrus.Infof("Next dump at %s", next)
+			time.Sleep(time.Until(next))
+
+			res, err := runFileConfig(fc, sigs)
+			if err != nil {
+				logrus.Error(err)
+				continue
+			}
+			printDumpResult(runc.Output, res)
+		}
+	})
+
+	command.Execute()
+}
+
+// runSignals handles SIGINT and SIGTERM for all runs of a config: the first signal stops the
+// current dump gracefully, a second one exits right away, and without a dump running they exit.
+type runSignals struct {
+	mu          sync.Mutex
+	dumper      *mysqldump.Dumper
+	interrupted bool
+}
+
+// handleRunSignals starts handling signals, once for all runs.
+func handleRunSignals() *runSignals {
+	s := &runSignals{}
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		for sig := range sigs {
+			s.mu.Lock()
+			switch {
+			case s.dumper == nil:
+				logrus.Infof("Received %s, exiting", sig)
+				os.Exit(0)
+			case s.interrupted:
+				logrus.Info("Received second signal, exiting")
+				os.Exit(exitInterrupted)
+			default:
+				logrus.Infof("Received %s, stopping after the current chunk", sig)
+				s.interrupted = true
+				s.dumper.Interrupt()
+			}
+			s.mu.Unlock()
+		}
+	}()
+	return s
+}
+
+// running sets the dumper of the current run, nil once it is done.
+func (s *runSignals) running(d *mysqldump.Dumper) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dumper, s.interrupted = d, false
+}
+
+// runFileConfig dumps the configured source into all of the configured destinations.
+func runFileConfig(fc *FileConfig, sigs *runSignals) (*dumpResult, error) {
+	db, dbName, err := fc.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	res := &dumpResult{Database: dbName}
+	var ws []io.Writer
+	var sinks []mysqldump.Sink
+	for _, d := range fc.Destinations {
+		s, err := openSink(context.Background(), d.File)
+		if err != nil {
+			closeSinks(fc, sinks, true)
+			return nil, err
+		}
+		sinks = append(sinks, s)
+		ws = append(ws, s)
+		res.Files = append(res.Files, d.File)
+		// The checkpoint is kept next to the first local destination
+		if res.Checkpoint == "" && !isRemote(d.File) {
+			res.Checkpoint = d.File + ".checkpoint"
+		}
+	}
+
+	opts := append(fc.dumperOptions(),
+		mysqldump.WithCheckpointFile(res.Checkpoint),
+		mysqldump.WithProgress(res.track(nil)))
+	dumper := mysqldump.NewDumper(db, io.MultiWriter(ws...), fc.Source.ChunkSize, opts...)
+	sigs.running(dumper)
+	defer sigs.running(nil)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	err = dumper.DumpAllTables(dbName, &wg)
+	res.Duration = time.Since(start)
+	res.account(dumper, fc.Cost.model())
+	// Interrupted dumps are kept, ending with a partial footer
+	if cerr := closeSinks(fc, sinks, err != nil && !errors.Is(err, mysqldump.ErrInterrupted)); cerr != nil && err == nil {
+		err = cerr
+	}
+	if errors.Is(err, mysqldump.ErrInterrupted) {
+		res.Interrupted = true
+		printDumpResult(runc.Output, res)
+		os.Exit(exitInterrupted)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res.Checkpoint = ""
+	res.Violations = dumper.RuleViolations()
+	res.RowCounts, res.Suspect = dumper.RowCountViolations(), dumper.Suspect()
+	res.TableLimits = dumper.TableLimitBreaches()
+	res.Warnings = dumper.Warnings()
+	return res, nil
+}
+
+// closeSinks completes the outputs of the destinations of a dump, or aborts the uploads among them if
+// the dump failed. Local files of failed dumps are kept.
+func closeSinks(fc *FileConfig, sinks []mysqldump.Sink, failed bool) error {
+	var err error
+	for i, s := range sinks {
+		if failed && isRemote(fc.Destinations[i].File) {
+			if aerr := s.Abort(); aerr != nil {
+				logrus.Warn(aerr)
+			}
+		} else if cerr := s.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// schedule is a parsed cron expression with the usual five fields: minute, hour, day of
+// month, month and day of week. Fields accept *, numbers, ranges, lists and steps, months and
+// days of week also their English names like JAN or MON, and Sunday is 0 or 7. As in cron, a
+// time matches when either of the day fields does if both are restricted, that is, neither
+// starts with *.
+type schedule struct {
+	fields [5]map[int]bool
+	// Set when the day of month or week starts with *, the day then has to match both
+	anyDay, anyWeekday bool
+}
+
+var scheduleLimits = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+// Names of the months and days of week, by field
+var scheduleNames = [5][]string{
+	3: {"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"},
+	4: {"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"},
+}
+
+func parseSchedule(expr string) (*schedule, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("%q: expected 5 fields, got %d", expr, len(parts))
+	}
+
+	var s schedule
+	for i, p := range parts {
+		f, err := parseScheduleField(p, scheduleLimits[i][0], scheduleLimits[i][1], scheduleNames[i])
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", expr, err)
+		}
+		s.fields[i] = f
+	}
+	// Sunday is 0 or 7
+	if s.fields[4][7] {
+		s.fields[4][0] = true
+	}
+	s.anyDay, s.anyWeekday = strings.HasPrefix(parts[2], "*"), strings.HasPrefix(parts[4], "*")
+	return &s, nil
+}
+
+// scheduleValue parses a number of a field, or one of its names, whose first is min.
+func scheduleValue(s string, min int, names []string) (int, error) {
+	for i, n := range names {
+		if strings.EqualFold(s, n) {
+			return min + i, nil
+		}
+	}
+	return strconv.Atoi(s)
+}
+
+func parseScheduleField(field string, min, max int, names []string) (map[int]bool, error) {
+	vals := make(map[int]bool)
+
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step in %q", part)
+			}
+			part = part[:i]
+		}
+
+		lo, hi := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if lo, err = scheduleValue(bounds[0], min, names); err != nil {
+				return nil, fmt.Errorf("invalid value %q", part)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = scheduleValue(bounds[1], min, names); err != nil {
+					return nil, fmt.Errorf("invalid range %q", part)
+				}
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			vals[v] = true
+		}
+	}
+
+	return vals, nil
+}
+
+// next returns the first time after t matching the schedule.
+func (s *schedule) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	// Every matching time repeats within a few years, give up after that
+	for end := t.AddDate(5, 0, 0); t.Before(end); t = t.Add(time.Minute) {
+		if s.fields[0][t.Minute()] && s.fields[1][t.Hour()] && s.fields[3][int(t.Month())] && s.day(t) {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// day reports whether the day of t matches the schedule.
+func (s *schedule) day(t time.Time) bool {
+	day, weekday := s.fields[2][t.Day()], s.fields[4][int(t.Weekday())]
+	if s.anyDay || s.anyWeekday {
+		return day && weekday
+	}
+	return day || weekday
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	for _, expr := range []string{
+		"", "* * * *", "* * * * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *",
+		"* * * * 8", "5-1 * * * *", "*/0 * * * *", "a * * * *", "* * * FOO *",
+	} {
+		if _, err := parseSchedule(expr); err == nil {
+			t.Errorf("parseSchedule(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	// A Wednesday
+	from := time.Date(2025, time.January, 1, 12, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2025, time.January, 1, 12, 31, 0, 0, time.UTC)},
+		{"0 2 * * *", time.Date(2025, time.January, 2, 2, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2025, time.January, 1, 12, 45, 0, 0, time.UTC)},
+		{"0,40 12-13 * * *", time.Date(2025, time.January, 1, 12, 40, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * MAR *", time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * MON", time.Date(2025, time.January, 6, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * fri-sat", time.Date(2025, time.January, 3, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2025, time.January, 5, 0, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: either matches
+		{"0 0 1 * MON", time.Date(2025, time.January, 6, 0, 0, 0, 0, time.UTC)},
+		{"0 0 2 * FRI", time.Date(2025, time.January, 2, 0, 0, 0, 0, time.UTC)},
+		// Day of week starting with *: both have to match
+		{"0 0 13 * */2", time.Date(2025, time.February, 13, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 FEB *", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		s, err := parseSchedule(tt.expr)
+		if err != nil {
+			t.Errorf("parseSchedule(%q): %s", tt.expr, err)
+			continue
+		}
+		if got := s.next(from); !got.Equal(tt.want) {
+			t.Errorf("%q: next(%s) = %s, want %s", tt.expr, from, got, tt.want)
+		}
+	}
+}
+
+func TestScheduleNever(t *testing.T) {
+	fc := &FileConfig{Schedule: "0 0 30 2 *"}
+	found := false
+	for _, err := range fc.validate() {
+		found = found || err.Error() == `schedule: "0 0 30 2 *" never matches a date`
+	}
+	if !found {
+		t.Errorf("validated a schedule without dates")
+	}
+}
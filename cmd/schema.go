@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"io"
+
+	"github.com/MouseHatGames/go-mysqldump"
+	"github.com/conneqtech/std_pkg/cli"
+	"github.com/conneqtech/std_pkg/db/mysql"
+	"github.com/sirupsen/logrus"
+)
+
+type SchemaConfiguration struct {
+	SourceMysql mysql.Opts `command:"source_mysql"`
+	File        string     `command:"file,usage=File to write the statements to or - for stdout,default=-"`
+	Transforms  string     `command:"ddl_transforms,usage=Comma separated list of DDL transforms to apply,required=false"`
+	Output      string     `command:"output,usage=Output format: text or json,default=text"`
+}
+
+var sc *SchemaConfiguration
+
+// runSchema writes the DDL of the source database, without any rows.
+func runSchema() {
+	command := cli.Initialize("DB dumper schema", &sc)
+	command.OnRun(func() {
+		setupOutput(sc.Output)
+		if sc.Output == "json" && sc.File == "-" {
+			logrus.Fatal("--output json needs --file, stdout holds the statements")
+		}
+
+		transforms, err := mysqldump.ParseDDLTransforms(splitList(sc.Transforms))
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		db, dbName, err := openSource("mysql", nil, &sc.SourceMysql)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		defer db.Close()
+
+		err = withOutput(sc.File, func(w io.Writer) error {
+			dumper := mysqldump.NewDumper(db, nil, 0, mysqldump.WithDDLTransforms(transforms...))
+			return dumper.DumpSchema(context.Background(), dbName, w)
+		})
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		res := struct {
+			File     string
+			Database string
+		}{sc.File, dbName}
+		printResult(sc.Output, res, func() {
+			if sc.File != "-" {
+				logrus.Infof("Schema of %s written to %s", dbName, sc.File)
+			}
+		})
+	})
+
+	command.Execute()
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MouseHatGames/go-mysqldump"
+)
+
+// isRemote reports whether a dump is uploaded to object storage, given as s3://bucket/key or gs://bucket/object.
+func isRemote(path string) bool {
+	return strings.HasPrefix(path, "s3://") || strings.HasPrefix(path, "gs://")
+}
+
+// openSink opens the output of a dump: an upload for s3:// and gs:// URLs, a local file otherwise.
+// S3 credentials are read from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN, the
+// region from AWS_REGION and the endpoint of S3 compatible storage from AWS_ENDPOINT_URL. GCS uploads
+// are authorized with GOOGLE_OAUTH_ACCESS_TOKEN, or the service account of the instance it runs on.
+func openSink(ctx context.Context, path string) (mysqldump.Sink, error) {
+	if !isRemote(path) {
+		return mysqldump.NewFileSink(path)
+	}
+
+	scheme := path[:2]
+	rest := path[len("s3://"):]
+	i := strings.Index(rest, "/")
+	if i <= 0 || i == len(rest)-1 {
+		return nil, fmt.Errorf("invalid object URL %q, expected %s://bucket/key", path, scheme)
+	}
+	bucket, key := rest[:i], rest[i+1:]
+
+	if scheme == "s3" {
+		region := os.Getenv("AWS_REGION")
+		if region == "" {
+			region = os.Getenv("AWS_DEFAULT_REGION")
+		}
+		return mysqldump.NewS3Sink(ctx, mysqldump.S3Config{
+			Bucket:          bucket,
+			Key:             key,
+			Region:          region,
+			Endpoint:        os.Getenv("AWS_ENDPOINT_URL"),
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		})
+	}
+
+	token := gceToken()
+	if t := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); t != "" {
+		token = func(context.Context) (string, error) { return t, nil }
+	}
+	return mysqldump.NewGCSSink(ctx, mysqldump.GCSConfig{Bucket: bucket, Object: key, Token: token})
+}
+
+// gceToken returns a source of the access tokens of the service account of the Compute Engine instance,
+// or GKE workload, the dump runs on, cached until shortly before they expire.
+func gceToken() func(context.Context) (string, error) {
+	var mu sync.Mutex
+	var token string
+	var expires time.Time
+
+	return func(ctx context.Context) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if token != "" && time.Until(expires) > time.Minute {
+			return token, nil
+		}
+
+		req, err := http.NewRequest(http.MethodGet, "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token", nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Metadata-Flavor", "Google")
+		res, err := http.DefaultClient.Do(req.WithContext(ctx))
+		if err != nil {
+			return "", fmt.Errorf("no GOOGLE_OAUTH_ACCESS_TOKEN and no metadata server: %w", err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("metadata server: %s", res.Status)
+		}
+
+		var t struct {
+			AccessToken string `json:"access_token"`
+			ExpiresIn   int    `json:"expires_in"`
+		}
+		if err = json.NewDecoder(res.Body).Decode(&t); err != nil {
+			return "", fmt.Errorf("metadata server: %w", err)
+		}
+		if t.AccessToken == "" {
+			return "", errors.New("metadata server: no access token")
+		}
+		token, expires = t.AccessToken, time.Now().Add(time.Duration(t.ExpiresIn)*time.Second)
+		return token, nil
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/conneqtech/std_pkg/db/mysql"
+)
+
+// openSource connects to the database a dump is taken from and returns it along with its name.
+func openSource(typ string, pg *mysql.Opts, my *mysql.Opts) (*sql.DB, string, error) {
+	switch typ {
+	case "mysql":
+		if err := resolveCredentials(my, sourcePasswordEnv); err != nil {
+			return nil, "", err
+		}
+		db, err := mysql.NewMysqlClient(my)
+		return db, my.Database, err
+	case "pg":
+		if err := resolveCredentials(pg, sourcePasswordEnv); err != nil {
+			return nil, "", err
+		}
+		db, err := sql.Open("postgres", fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=disable", pg.Username, pg.Password, pg.Host, pg.Database))
+		return db, pg.Database, err
+	}
+
+	return nil, "", fmt.Errorf("invalid type given: %s", typ)
+}
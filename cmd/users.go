@@ -0,0 +1,63 @@
+package main
+
+import (
+	"io"
+
+	"github.com/MouseHatGames/go-mysqldump"
+	"github.com/conneqtech/std_pkg/cli"
+	"github.com/conneqtech/std_pkg/db/mysql"
+	"github.com/sirupsen/logrus"
+)
+
+type UsersConfiguration struct {
+	SourceMysql   mysql.Opts `command:"source_mysql"`
+	File          string     `command:"file,usage=File to write the statements to or - for stdout,default=-"`
+	ResetPassword string     `command:"reset_password,usage=Create every user with this expired password instead of its own,required=false"`
+	Exclude       string     `command:"exclude,usage=Comma separated list of user@host accounts to leave out,required=false"`
+	Output        string     `command:"output,usage=Output format: text or json,default=text"`
+}
+
+var uc *UsersConfiguration
+
+// runUsers writes the SQL statements recreating the users, roles and grants of the source server.
+func runUsers() {
+	command := cli.Initialize("DB dumper users", &uc)
+	command.OnRun(func() {
+		setupOutput(uc.Output)
+		if uc.Output == "json" && uc.File == "-" {
+			logrus.Fatal("--output json needs --file, stdout holds the statements")
+		}
+
+		reset, err := resolveSecret(uc.ResetPassword)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		db, _, err := openSource("mysql", nil, &uc.SourceMysql)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		defer db.Close()
+
+		err = withOutput(uc.File, func(w io.Writer) error {
+			return mysqldump.NewDumper(db, nil, 0).DumpUsers(w, mysqldump.UsersOptions{
+				ResetPassword: reset,
+				Exclude:       splitList(uc.Exclude),
+			})
+		})
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		res := struct {
+			File string
+		}{uc.File}
+		printResult(uc.Output, res, func() {
+			if uc.File != "-" {
+				logrus.Infof("Users written to %s", uc.File)
+			}
+		})
+	})
+
+	command.Execute()
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/MouseHatGames/go-mysqldump"
+	"github.com/conneqtech/std_pkg/cli"
+	"github.com/conneqtech/std_pkg/db/mysql"
+	"github.com/sirupsen/logrus"
+)
+
+// Exit codes of the verify subcommand, so automation can tell failures apart.
+const (
+	exitVerifyError      = 1 // usage or I/O error
+	exitVerifyInvalid    = 2 // the dump is not well formed
+	exitVerifyConnection = 3 // the live database could not be queried
+	exitVerifyMismatch   = 4 // the dump doesn't match the live database
+)
+
+type VerifyConfiguration struct {
+	Type        string     `command:"type,usage=Use mysql of pg,default=mysql"`
+	SourcePG    mysql.Opts `command:"source_pg,required=false"`
+	SourceMysql mysql.Opts `command:"source_mysql,required=false"`
+	ChunkSize   int        `command:"chunk_size,default=0"`
+	Output      string     `command:"output,usage=Output format: text or json,default=text"`
+}
+
+var vc *VerifyConfiguration
+
+type verifyResult struct {
+	File        string
+	Valid       bool
+	Checksums   bool
+	Tables      int
+	Comparisons []verifyTable
+	Error       string
+	ExitCode    int
+}
+
+type verifyTable struct {
+	Table        string
+	Match        bool
+	DumpRows     int64
+	LiveRows     int64
+	DumpChecksum string
+	LiveChecksum string
+	Error        string
+}
+
+// runVerify validates the format of a dump file and, if a source database is configured,
+// compares its contents with the live tables.
+func runVerify() {
+	command := cli.Initialize("DB dumper verify", &vc)
+	command.OnRun(func() {
+		setupOutput(vc.Output)
+		if len(args) != 1 {
+			logrus.Error("usage: verify <dump file>")
+			os.Exit(exitVerifyError)
+		}
+		res := &verifyResult{File: args[0]}
+
+		f, err := openDump(args[0])
+		if err != nil {
+			finishVerify(res, exitVerifyError, err)
+		}
+		defer func() { f.Close() }()
+
+		info, err := mysqldump.Validate(f)
+		if err != nil {
+			if errors.Is(err, mysqldump.ErrInvalidDump) {
+				finishVerify(res, exitVerifyInvalid, err)
+			}
+			finishVerify(res, exitVerifyError, err)
+		}
+		res.Valid = true
+		res.Checksums = info.Footer != nil && info.Footer.Checksum != ""
+		res.Tables = len(info.Tables)
+
+		if vc.SourceMysql.Host == "" && vc.SourcePG.Host == "" {
+			finishVerify(res, 0, nil)
+		}
+
+		db, dbName, err := openSource(vc.Type, &vc.SourcePG, &vc.SourceMysql)
+		if err == nil {
+			err = db.Ping()
+		}
+		if err != nil {
+			finishVerify(res, exitVerifyConnection, err)
+		}
+		defer db.Close()
+
+		// Read the dump again from the start, split dumps can't seek
+		f.Close()
+		if f, err = openDump(args[0]); err != nil {
+			finishVerify(res, exitVerifyError, err)
+		}
+		if dbName != info.Header.DatabaseName {
+			logrus.Warnf("Dump was taken from %s, comparing it with that database instead of %s", info.Header.DatabaseName, dbName)
+		}
+
+		report, err := mysqldump.Verify(context.Background(), db, f, append(defaultFilters(info.Header.DatabaseName), mysqldump.WithChunkSize(vc.ChunkSize))...)
+		if err != nil {
+			finishVerify(res, exitVerifyConnection, err)
+		}
+
+		code := 0
+		for _, c := range report.Tables {
+			res.Comparisons = append(res.Comparisons, verifyTable{
+				Table:        c.Table,
+				Match:        c.Match(),
+				DumpRows:     c.DumpRows,
+				LiveRows:     c.LiveRows,
+				DumpChecksum: c.DumpChecksum,
+				LiveChecksum: c.LiveChecksum,
+				Error:        errorString(c.Err),
+			})
+			if !c.Match() {
+				code = exitVerifyMismatch
+			}
+		}
+		finishVerify(res, code, nil)
+	})
+
+	command.Execute()
+}
+
+// finishVerify prints the result and exits with code.
+func finishVerify(res *verifyResult, code int, err error) {
+	res.ExitCode = code
+	res.Error = errorString(err)
+
+	printResult(vc.Output, res, func() {
+		if err != nil {
+			logrus.Error(err)
+		}
+		if res.Valid && res.Checksums {
+			fmt.Printf("%s: format and checksums OK, %d tables\n", res.File, res.Tables)
+		} else if res.Valid {
+			fmt.Printf("%s: format OK, %d tables\n", res.File, res.Tables)
+		}
+		for _, c := range res.Comparisons {
+			switch {
+			case c.Error != "":
+				fmt.Printf("%s: FAIL: %s\n", c.Table, c.Error)
+			case !c.Match:
+				fmt.Printf("%s: FAIL: dump has %d rows (%s), live table has %d rows (%s)\n", c.Table, c.DumpRows, c.DumpChecksum, c.LiveRows, c.LiveChecksum)
+			default:
+				fmt.Printf("%s: OK, %d rows\n", c.Table, c.LiveRows)
+			}
+		}
+	})
+
+	os.Exit(code)
+}
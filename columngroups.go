@@ -0,0 +1,95 @@
+package mysqldump
+
+import (
+	"strings"
+
+	"github.com/MouseHatGames/go-mysqldump/internal/marshal"
+)
+
+// WithColumnGroups splits the rows of every table with a primary key and more than n columns into
+// groups of about n columns, each with the primary key, dumped one after the other as sections of the
+// table. Rows are then held a group at a time, and restores can leave out whole groups of columns, see
+// LoaderOptions.ColumnMap. The first group is inserted as usual, and the rows of the others are merged
+// into the inserted ones by primary key.
+func WithColumnGroups(n int) Option {
+	return func(d *Dumper) {
+		d.columnGroups = n
+	}
+}
+
+// groupUnits splits every unit of a table by column group, with the units of the first group first,
+// so that every row exists before the other groups are merged into it. Columns keep their table order.
+func (d *Dumper) groupUnits(units []tableUnit, columns []string, key []string) []tableUnit {
+	size := d.columnGroups - len(key)
+	if size < 1 {
+		size = 1
+	}
+
+	var groups [][]string
+	n := 0
+	for _, c := range columns {
+		if indexFold(key, c) < 0 {
+			if n%size == 0 {
+				groups = append(groups, nil)
+			}
+			n++
+		}
+	}
+	n = 0
+	for _, c := range columns {
+		if indexFold(key, c) >= 0 {
+			for i := range groups {
+				groups[i] = append(groups[i], c)
+			}
+			continue
+		}
+		groups[n/size] = append(groups[n/size], c)
+		n++
+	}
+
+	grouped := make([]tableUnit, 0, len(groups)*len(units))
+	for i, cols := range groups {
+		for _, u := range units {
+			u.group, u.columns = i+1, cols
+			grouped = append(grouped, u)
+		}
+	}
+	return grouped
+}
+
+// splitsColumns reports whether the rows of a table are dumped by column group.
+func (d *Dumper) splitsColumns(columns []string, key chunkKey) bool {
+	return d.columnGroups > 0 && len(columns) > d.columnGroups && key.strategy == ChunkPrimaryKey && len(key.columns) < len(columns)
+}
+
+// setUnitHeader sets the fields of the header of a table that differ between its sections.
+func (d *Dumper) setUnitHeader(header *marshal.TableHeader, columns []string, u tableUnit) {
+	header.Partition = u.partition
+	header.Shard = u.shard
+	if u.shard > 0 {
+		header.Shards = d.shards
+	}
+	header.Columns, header.ColumnGroup = columns, u.group
+	if u.columns != nil {
+		header.Columns = u.columns
+	}
+}
+
+// mergeClause returns the clause merging the rows of a section of a later column group into the
+// existing rows, empty for other sections.
+func mergeClause(t *marshal.TableHeader, columns []string) string {
+	if t.ColumnGroup <= 1 {
+		return ""
+	}
+
+	var set []string
+	for _, c := range columns {
+		if indexFold(t.ChunkKey, c) < 0 {
+			set = append(set, quoteName(c)+"=VALUES("+quoteName(c)+")")
+		}
+	}
+	if len(set) == 0 {
+		return ""
+	}
+	return " ON DUPLICATE KEY UPDATE " + strings.Join(set, ",")
+}
@@ -0,0 +1,61 @@
+package mysqldump
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	binary "github.com/MouseHatGames/go-mysqldump/internal/marshal"
+)
+
+// ErrColumnMismatch is returned by a dump reading rows whose columns aren't those listed in the header
+// of their table, as when the table is altered while it is dumped.
+var ErrColumnMismatch = errors.New("columns of the rows don't match the table header")
+
+// columnOrder checks the columns the server returned the rows of a table with against those of its header,
+// returning the position in the rows of every column of the header, or nil if they are in the same order.
+// The values of every row are written in the order of TableHeader.Columns, whatever order the server
+// returns them in. Nil queries, and queries not expecting columns, check nothing.
+func (tq *tableQuery) columnOrder(name string, columns []string) ([]int, error) {
+	if tq == nil || tq.columns == nil {
+		return nil, nil
+	}
+
+	mismatch := func() error {
+		return fmt.Errorf("%w: %s was read with columns %s, its header lists %s", ErrColumnMismatch, name,
+			strings.Join(columns, ", "), strings.Join(tq.columns, ", "))
+	}
+	if len(columns) != len(tq.columns) {
+		return nil, mismatch()
+	}
+
+	order := make([]int, len(tq.columns))
+	moved, seen := false, make([]bool, len(columns))
+	for i, c := range tq.columns {
+		j := indexOf(columns, c)
+		if j < 0 {
+			j = indexFold(columns, c)
+		}
+		if j < 0 || seen[j] {
+			return nil, mismatch()
+		}
+		order[i], seen[j] = j, true
+		moved = moved || i != j
+	}
+	if !moved {
+		return nil, nil
+	}
+	return order, nil
+}
+
+// reorderRow returns the values of a row in the order of the header, see columnOrder.
+func reorderRow(row binary.RowData, order []int) binary.RowData {
+	if order == nil {
+		return row
+	}
+	out := make(binary.RowData, len(order))
+	for i, j := range order {
+		out[i] = row[j]
+	}
+	return out
+}
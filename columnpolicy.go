@@ -0,0 +1,95 @@
+package mysqldump
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ColumnPolicy sets how the values of a column are restored, for dumps of servers with a laxer sql_mode
+// than the target's.
+type ColumnPolicy struct {
+	// Restore empty strings as NULL
+	EmptyAsNull bool
+	// Restore values of DATE, DATETIME and TIMESTAMP columns that aren't a valid date, such as 0000-00-00
+	// or 2021-02-30, as NULL
+	InvalidDateAsNull bool
+	// Value to restore a column of the target with when the dump doesn't have it, nil to leave it to
+	// the server's default
+	Default *string
+}
+
+// ParseColumnPolicy parses a policy given as "empty_null", "invalid_date_null" or "default:value".
+func ParseColumnPolicy(s string) (ColumnPolicy, error) {
+	var p ColumnPolicy
+	switch {
+	case strings.EqualFold(s, "empty_null"):
+		p.EmptyAsNull = true
+	case strings.EqualFold(s, "invalid_date_null"):
+		p.InvalidDateAsNull = true
+	case strings.HasPrefix(strings.ToLower(s), "default:"):
+		v := s[len("default:"):]
+		p.Default = &v
+	default:
+		return p, fmt.Errorf("invalid column policy: %s", s)
+	}
+	return p, nil
+}
+
+// restorePolicies are the policies of the columns the rows of a table are inserted with, and the
+// columns missing from the dump that are added with their default.
+type restorePolicies struct {
+	columns []ColumnPolicy
+	added   []string
+	values  RowData
+}
+
+// columnPolicies returns the policies of the columns a table is restored with, nil if there are none.
+func (l *Loader) columnPolicies(table string, columns []string) *restorePolicies {
+	policies := l.opt.ColumnPolicies[table]
+	if len(policies) == 0 {
+		return nil
+	}
+
+	p := &restorePolicies{columns: make([]ColumnPolicy, len(columns))}
+	for name, cp := range policies {
+		if i := indexFold(columns, name); i >= 0 {
+			p.columns[i] = cp
+		} else if cp.Default != nil {
+			p.added = append(p.added, name)
+			p.values = append(p.values, cp.Default)
+		}
+	}
+	return p
+}
+
+// apply rewrites the values of a row and adds the values of the added columns.
+func (p *restorePolicies) apply(row RowData) RowData {
+	if p == nil {
+		return row
+	}
+
+	for i, cp := range p.columns {
+		v := row[i]
+		if v == nil {
+			continue
+		}
+		if (cp.EmptyAsNull && *v == "") || (cp.InvalidDateAsNull && !validDate(*v)) {
+			row[i] = nil
+		}
+	}
+	return append(row, p.values...)
+}
+
+// Layouts of the DATE, DATETIME and TIMESTAMP values the server returns
+var dateLayouts = []string{"2006-01-02", "2006-01-02 15:04:05", "2006-01-02 15:04:05.999999999"}
+
+// validDate reports whether a value is a date a strict sql_mode accepts.
+func validDate(v string) bool {
+	for _, layout := range dateLayouts {
+		if _, err := time.Parse(layout, v); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,152 @@
+package mysqldump
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/MouseHatGames/go-mysqldump/internal/marshal"
+)
+
+// Compression is the codec a Dumper compresses its output with.
+type Compression int
+
+const (
+	// CompressionNone writes the output as it is.
+	CompressionNone Compression = iota
+	// CompressionGzip compresses the output with gzip.
+	CompressionGzip
+	// CompressionZstd compresses the output with zstd, once an implementation is registered with
+	// RegisterZstd. The library doesn't depend on one itself.
+	CompressionZstd
+)
+
+// ParseCompression parses "none", "gzip" or "zstd".
+func ParseCompression(s string) (Compression, error) {
+	switch strings.ToLower(s) {
+	case "none", "":
+		return CompressionNone, nil
+	case "gzip":
+		return CompressionGzip, nil
+	case "zstd":
+		return CompressionZstd, nil
+	}
+
+	return 0, fmt.Errorf("invalid compression: %s", s)
+}
+
+func (c Compression) String() string {
+	switch c {
+	case CompressionGzip:
+		return "gzip"
+	case CompressionZstd:
+		return "zstd"
+	}
+	return "none"
+}
+
+// Streams of zstd frames start with these bytes
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// RegisterZstd provides the zstd implementation CompressionZstd writes with, and which readers
+// decompress zstd compressed dumps with, e.g. that of github.com/klauspost/compress/zstd.
+func RegisterZstd(newWriter func(w io.Writer, level int) (io.WriteCloser, error), newReader func(r io.Reader) (io.ReadCloser, error)) {
+	marshal.RegisterCodec(marshal.Codec{Name: "zstd", Magic: zstdMagic, NewWriter: newWriter, NewReader: newReader})
+}
+
+// WithCompression compresses the output with a codec at a level, 0 for the codec's default. The codec
+// is recorded in the file header, and the Loader, Inspect, the converters and the Reader of the binary
+// package decompress dumps as they read them. Output split by a PartWriter is compressed as a whole,
+// so only the parts joined back together decompress.
+func WithCompression(c Compression, level int) Option {
+	return func(d *Dumper) {
+		d.compression = c
+		d.compressionLevel = level
+	}
+}
+
+// streamOutput compresses or encrypts what is written to the output.
+type streamOutput struct {
+	out io.Writer
+	zw  io.WriteCloser
+}
+
+type flusher interface {
+	Flush() error
+}
+
+// flushOutput has the compression and the encryption write out what they buffered.
+func (d *Dumper) flushOutput() error {
+	for _, s := range []*streamOutput{d.compressed, d.encrypted} {
+		if s == nil {
+			continue
+		}
+		f, ok := s.zw.(flusher)
+		if !ok {
+			return fmt.Errorf("%s compression can't be flushed", d.compression)
+		}
+		if err := f.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startCompression has the output compressed, counting the compressed bytes as written bytes unless
+// they are encrypted.
+func (d *Dumper) startCompression() error {
+	if d.compression == CompressionNone {
+		return nil
+	}
+	if d.format == FormatCSV || d.tableOpen != nil {
+		return errors.New("csv output and per-table files can't be compressed")
+	}
+	c, ok := marshal.LookupCodec(d.compression.String())
+	if !ok {
+		return fmt.Errorf("%s compression needs an implementation, see RegisterZstd", d.compression)
+	}
+
+	// Encrypted output is counted as it leaves the encryption
+	var out io.Writer = &countingWriter{d.w, &d.writtenBytes}
+	if d.encrypted != nil {
+		out = d.w
+	}
+	zw, err := c.NewWriter(out, d.compressionLevel)
+	if err != nil {
+		return fmt.Errorf("start %s compression: %w", d.compression, err)
+	}
+	if _, ok := zw.(flusher); !ok && d.checkpointFile != "" {
+		zw.Close()
+		return fmt.Errorf("%s compression can't be flushed for a checkpoint", d.compression)
+	}
+	d.compressed = &streamOutput{out: d.w, zw: zw}
+	d.w = zw
+	d.resetWriter()
+	return nil
+}
+
+// endCompression writes the end of the compressed stream.
+func (d *Dumper) endCompression() error {
+	c := d.compressed
+	if c == nil {
+		return nil
+	}
+
+	err := c.zw.Close()
+	d.compressed = nil
+	d.w = c.out
+	d.resetWriter()
+	if err != nil {
+		return fmt.Errorf("compress output: %w", err)
+	}
+	return nil
+}
+
+// compressionName returns the codec recorded in the file header, empty without compression.
+func (d *Dumper) compressionName() string {
+	if d.compressed == nil {
+		return ""
+	}
+	return d.compression.String()
+}
@@ -0,0 +1,128 @@
+package mysqldump
+
+import "strings"
+
+// foreignKey is a foreign key of a table referencing another one, or itself.
+type foreignKey struct {
+	table      string
+	name       string
+	referenced string
+}
+
+// getCyclicConstraints returns the foreign keys of the given tables that are part of a reference cycle,
+// by table. Those tables can't be created one after the other with their foreign keys, so they are
+// left out of the DDL and added once every table exists.
+func (d *Dumper) getCyclicConstraints(schema string, tables []string) map[string]map[string]bool {
+	if d.isPQ() {
+		return nil
+	}
+
+	fks, err := d.getForeignKeys(schema, tables)
+	if err != nil {
+		d.warn(WarningMetadata, "", "Can't read foreign keys, tables referencing each other may not restore: %s", err)
+		return nil
+	}
+	return cyclicConstraints(fks)
+}
+
+// getForeignKeys returns the foreign keys between the given tables. Servers without the metadata
+// tables have none.
+func (d *Dumper) getForeignKeys(schema string, tables []string) ([]foreignKey, error) {
+	if d.legacyMetadata() {
+		return nil, nil
+	}
+
+	rows, err := d.db.QueryContext(d.context(), `SELECT TABLE_NAME, CONSTRAINT_NAME, REFERENCED_TABLE_NAME FROM INFORMATION_SCHEMA.REFERENTIAL_CONSTRAINTS
+		WHERE CONSTRAINT_SCHEMA = ? AND UNIQUE_CONSTRAINT_SCHEMA = ?`, schema, schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dumped := make(map[string]bool, len(tables))
+	for _, t := range tables {
+		dumped[t] = true
+	}
+
+	var fks []foreignKey
+	for rows.Next() {
+		var fk foreignKey
+		if err = rows.Scan(&fk.table, &fk.name, &fk.referenced); err != nil {
+			return nil, err
+		}
+		if dumped[fk.table] && dumped[fk.referenced] {
+			fks = append(fks, fk)
+		}
+	}
+	return fks, rows.Err()
+}
+
+// cyclicConstraints returns the foreign keys whose referenced table references their table back,
+// directly or through other tables.
+func cyclicConstraints(fks []foreignKey) map[string]map[string]bool {
+	refs := make(map[string][]string)
+	for _, fk := range fks {
+		refs[fk.table] = append(refs[fk.table], fk.referenced)
+	}
+
+	var cyclic map[string]map[string]bool
+	for _, fk := range fks {
+		if !reaches(refs, fk.referenced, fk.table) {
+			continue
+		}
+		if cyclic == nil {
+			cyclic = make(map[string]map[string]bool)
+		}
+		if cyclic[fk.table] == nil {
+			cyclic[fk.table] = make(map[string]bool)
+		}
+		cyclic[fk.table][fk.name] = true
+	}
+	return cyclic
+}
+
+// reaches reports whether table to can be reached from table from by following references.
+func reaches(refs map[string][]string, from string, to string) bool {
+	seen := map[string]bool{from: true}
+	next := []string{from}
+	for len(next) > 0 {
+		t := next[len(next)-1]
+		next = next[:len(next)-1]
+		if t == to {
+			return true
+		}
+		for _, r := range refs[t] {
+			if !seen[r] {
+				seen[r] = true
+				next = append(next, r)
+			}
+		}
+	}
+	return false
+}
+
+// splitConstraints takes the named foreign keys out of a CREATE TABLE statement, returning the
+// statement without them and the ALTER TABLE statements adding them back.
+func splitConstraints(table string, ddl string, names map[string]bool) (string, []string) {
+	start, end := strings.Index(ddl, "(\n"), strings.LastIndex(ddl, "\n)")
+	if start < 0 || end < start || len(names) == 0 {
+		return ddl, nil
+	}
+
+	var kept, alter []string
+	for _, line := range strings.Split(ddl[start+2:end], "\n") {
+		def := strings.TrimSuffix(strings.TrimSpace(line), ",")
+		if strings.HasPrefix(def, "CONSTRAINT ") && strings.Contains(def, "FOREIGN KEY") {
+			if m := quotedNameRegex.FindStringSubmatch(def); m != nil && names[m[1]] {
+				alter = append(alter, "ALTER TABLE "+quoteName(table)+" ADD "+def)
+				continue
+			}
+		}
+		kept = append(kept, strings.TrimSuffix(line, ","))
+	}
+	if len(alter) == 0 {
+		return ddl, nil
+	}
+
+	return ddl[:start+2] + strings.Join(kept, ",\n") + ddl[end:], alter
+}
@@ -15,6 +15,14 @@ type ConvertOptions struct {
 	// If nil, all tables will be converted. If a table is specified here but is not present on the dump, no error will be returned
 	Tables     []string
 	SkipCreate bool
+	// What to do with Percona Server column compression clauses, ClauseAuto keeps them
+	Percona ClausePolicy
+	// Applied to the CREATE statement of every table
+	DDLTransforms []DDLTransform
+	// How tables are created, CreateAuto following the dump
+	Create CreatePolicy
+	// How ConvertToCSV writes tables
+	CSV CSVOptions
 }
 
 func ConvertToSQL(in io.Reader, w io.Writer, flusher chan<- bool, ready <-chan bool, querySize int, opts ...ConvertOptions) error {
@@ -36,26 +44,47 @@ func ConvertToSQL(in io.Reader, w io.Writer, flusher chan<- bool, ready <-chan b
 --
 -- ------------------------------------------------------
 -- Server version	%[2]s
-`, version, h.ServerVersion, "`"+h.DatabaseName+"`")
+`, version, h.ServerVersion, quoteName(h.DatabaseName))
+	if b := h.Binlog; b != nil {
+		// Commented out like mysqldump --source-data=2, to be run by hand when seeding a replica
+		fmt.Fprintf(w, "--\n-- CHANGE MASTER TO MASTER_LOG_FILE='%s', MASTER_LOG_POS=%d;\n", b.File, b.Position)
+		if b.GTIDSet != "" && strings.Contains(h.ServerVersion, "MariaDB") {
+			fmt.Fprintf(w, "-- SET GLOBAL gtid_slave_pos='%s';\n", b.GTIDSet)
+		} else if b.GTIDSet != "" {
+			fmt.Fprintf(w, "-- SET @@GLOBAL.GTID_PURGED='%s';\n", b.GTIDSet)
+		}
+		fmt.Fprint(w, "--\n")
+	}
 
 	if !opt.SkipCreate {
 		fmt.Fprintf(w, `CREATE DATABASE IF NOT EXISTS %[3]s;
-USE %[3]s;`, version, h.ServerVersion, "`"+h.DatabaseName+"`")
+USE %[3]s;`, version, h.ServerVersion, quoteName(h.DatabaseName))
 	}
 	fmt.Fprintf(w, `/*!40101 SET @OLD_CHARACTER_SET_CLIENT=@@CHARACTER_SET_CLIENT */;
 /*!40101 SET @OLD_CHARACTER_SET_RESULTS=@@CHARACTER_SET_RESULTS */;
 /*!40101 SET @OLD_COLLATION_CONNECTION=@@COLLATION_CONNECTION */;
 /*!40101 SET NAMES utf8 */;
+/*!50503 SET NAMES utf8mb4 */;
 /*!40103 SET @OLD_TIME_ZONE=@@TIME_ZONE */;
 /*!40103 SET TIME_ZONE='+00:00' */;
 /*!40014 SET @OLD_UNIQUE_CHECKS=@@UNIQUE_CHECKS, UNIQUE_CHECKS=0 */;
 /*!40014 SET @OLD_FOREIGN_KEY_CHECKS=@@FOREIGN_KEY_CHECKS, FOREIGN_KEY_CHECKS=0 */;
 /*!40101 SET @OLD_SQL_MODE=@@SQL_MODE, SQL_MODE='NO_AUTO_VALUE_ON_ZERO' */;
 /*!40111 SET @OLD_SQL_NOTES=@@SQL_NOTES, SQL_NOTES=0 */;
+/*T! SET @@allow_auto_random_explicit_insert = 1 */;
+/*M!101100 SET @@system_versioning_insert_history = 1 */;
  
 `)
+	if !opt.SkipCreate && opt.Percona != ClauseStrip {
+		for _, q := range createDictionaries(h) {
+			fmt.Fprintf(w, "%s;\n", q)
+		}
+	}
 	flusher <- false
 	<-ready
+	created := make(map[string]bool)
+	create := opt.Create.resolve(h.CreatePolicy)
+	var constraints []string
 	done := false
 	for {
 		if done {
@@ -80,18 +109,56 @@ USE %[3]s;`, version, h.ServerVersion, "`"+h.DatabaseName+"`")
 			continue
 		}
 
-		if !opt.SkipCreate {
+		if t.Type == TableTypeQuery {
+			log.Printf("skipping query result %s", t.Name)
+			r.SkipRows(len(t.Columns))
+			continue
+		}
+
+		if t.Type == TableTypeSequence {
+			if err = writeSequence(w, r, t, opt.SkipCreate, create); err != nil {
+				return fmt.Errorf("sequence %s: %w", t.Name, err)
+			}
+			flusher <- false
+			<-ready
+			continue
+		}
+
+		// Tables dumped by partition are created and truncated along with their first partition, and
+		// continued tables by the dump resumed. Restarted tables are created again over the rows written
+		// of them before
+		restarted := t.Restarted && created[t.Name]
+		if restarted && (opt.SkipCreate || create == CreateIfNotExists) {
+			return fmt.Errorf("table %s: %w", t.Name, errRestartedExisting)
+		}
+		first := !created[t.Name] && !t.Continued || restarted
+		created[t.Name] = true
+
+		if !opt.SkipCreate && first {
 			fmt.Fprintf(w, `--
 -- Table structure for table %[1]s
 --
 
-DROP TABLE IF EXISTS %[1]s;
 /*!40101 SET @saved_cs_client     = @@character_set_client */;
 /*!40101 SET character_set_client = utf8 */;
 
 `, t.Name)
+			if t.ReconstructedDDL {
+				fmt.Fprint(w, "-- Reconstructed from INFORMATION_SCHEMA, may lack check constraints, partitioning or table options\n")
+			}
 
-			w.Write([]byte(t.CreateSQL))
+			ddl := t.CreateSQL
+			if opt.Percona == ClauseStrip {
+				ddl = stripPercona(ddl)
+			}
+			ddl = applyDDL(ddl, opt.DDLTransforms)
+			policy := create
+			if restarted {
+				policy = CreateDrop
+			} else {
+				constraints = append(constraints, t.Constraints...)
+			}
+			w.Write([]byte(strings.Join(policy.createStatements("TABLE", t.Name, ddl), ";\n")))
 
 			fmt.Fprint(w, `;
 
@@ -107,21 +174,28 @@ DROP TABLE IF EXISTS %[1]s;
 		<-ready
 
 		rows, errs := r.ReadRows(len(t.Columns))
+		bin := binaryFlags(t.BinaryColumns, t.Columns)
 
+		// Later column groups are merged into the rows of the first
+		verb, end := "REPLACE INTO", semicolonNewline
+		if merge := mergeClause(t, t.Columns); merge != "" {
+			verb, end = "INSERT INTO", []byte(merge+";\n")
+		}
 		truncated := false
 		rowBytesWritten := 0
 	loop:
 		for {
 			rowBytesWritten = 0
 			if r, ok := <-rows; ok {
-				if !truncated {
+				// Existing tables keep their rows
+				if !truncated && first && create != CreateIfNotExists {
 					fmt.Fprintf(w, `
 						/*!40000 ALTER TABLE %[1]s DISABLE KEYS */;
-						TRUNCATE %[1]s;`, t.Name)
+						TRUNCATE %[1]s;`, quoteName(t.Name))
 					truncated = true
 				}
-				fmt.Fprintf(w, "REPLACE INTO %s(`%s`) VALUES ", t.Name, strings.Join(t.Columns, "`,`"))
-				rowBytesWritten += writeRow(w, r)
+				fmt.Fprintf(w, "%s %s%s(%s) VALUES ", verb, quoteName(t.Name), partitionClause(t), quoteColumns(t.Columns, false))
+				rowBytesWritten += writeRow(w, r, bin)
 			}
 
 			for {
@@ -131,10 +205,10 @@ DROP TABLE IF EXISTS %[1]s;
 						break loop
 					}
 					w.Write(commaNewline)
-					rowBytesWritten += writeRow(w, r)
+					rowBytesWritten += writeRow(w, r, bin)
 
 					if rowBytesWritten > querySize {
-						w.Write(semicolonNewline)
+						w.Write(end)
 						flusher <- true
 						<-ready
 
@@ -155,13 +229,13 @@ DROP TABLE IF EXISTS %[1]s;
 		}
 
 		if rowBytesWritten > 0 {
-			w.Write(semicolonNewline)
+			w.Write(end)
 			flusher <- true
 			<-ready
 		}
 		if truncated {
 			fmt.Fprintf(w, `
-/*!40000 ALTER TABLE %s ENABLE KEYS */;`, t.Name)
+/*!40000 ALTER TABLE %s ENABLE KEYS */;`, quoteName(t.Name))
 		}
 		fmt.Fprint(w, `-- Finished table data dump`)
 		flusher <- false
@@ -169,15 +243,71 @@ DROP TABLE IF EXISTS %[1]s;
 
 	}
 
+	if len(constraints) > 0 {
+		fmt.Fprint(w, `
+--
+-- Foreign keys of tables referencing each other
+--
+
+`)
+		for _, q := range constraints {
+			fmt.Fprintf(w, "%s;\n", q)
+		}
+		flusher <- false
+		<-ready
+	}
+
+	if !opt.SkipCreate {
+		if err = writeObjects(w, r, opt, create); err != nil {
+			return err
+		}
+		flusher <- false
+		<-ready
+	}
 	return nil
 }
 
-func writeRow(w io.Writer, r marshal.RowData) (l int) {
+// writeObjects writes the statements creating the views, routines, triggers and events following the
+// tables, see restoresObject. Routine, trigger and event bodies hold semicolons, so they are written
+// between DELIMITER commands.
+func writeObjects(w io.Writer, r *marshal.Reader, opt ConvertOptions, create CreatePolicy) error {
+	for {
+		o, err := r.ReadObject()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		all := len(opt.Tables) == 0
+		if !restoresObject(o, all, func(name string) bool { return all || indexOf(opt.Tables, name) >= 0 }) {
+			continue
+		}
+
+		fmt.Fprintf(w, "\n--\n-- %s %s\n--\n\n", strings.Title(strings.ToLower(o.Type)), o.Name)
+		stmts := create.objectStatements(o, applyDDL(o.CreateSQL, opt.DDLTransforms))
+		if o.Type == "VIEW" {
+			for _, q := range stmts {
+				fmt.Fprintf(w, "%s;\n", q)
+			}
+			continue
+		}
+		fmt.Fprint(w, "DELIMITER ;;\n")
+		for _, q := range stmts {
+			fmt.Fprintf(w, "%s ;;\n", q)
+		}
+		fmt.Fprint(w, "DELIMITER ;\n")
+	}
+}
+
+// writeRow writes the values of a row, those of the columns flagged as binary as hex literals.
+func writeRow(w io.Writer, r marshal.RowData, binary []bool) (l int) {
 	w.Write([]byte{'('})
 	l = 1
 
 	for i, v := range r {
-		if v != nil {
+		if v != nil && i < len(binary) && binary[i] {
+			l += writeHex(w, *v)
+		} else if v != nil {
 			w.Write(quote)
 			l += 2
 			l += writeEscapedString(w, *v)
@@ -240,3 +370,24 @@ func writeEscapedString(w io.Writer, str string) (l int) {
 	}
 	return
 }
+
+// writeSequence writes the statements recreating a sequence and restoring its current value.
+func writeSequence(w io.Writer, r *marshal.Reader, t *marshal.TableHeader, skipCreate bool, create CreatePolicy) error {
+	if !skipCreate {
+		fmt.Fprintf(w, `--
+-- Sequence structure for %[1]s
+--
+
+%[2]s;
+`, t.Name, strings.Join(create.createStatements("SEQUENCE", t.Name, t.CreateSQL), ";\n"))
+	}
+
+	row, err := readSequenceRow(r, t)
+	if err != nil {
+		return err
+	}
+	if q, ok := sequenceSetval(t, row); ok {
+		fmt.Fprintf(w, "%s;\n", q)
+	}
+	return nil
+}
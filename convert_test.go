@@ -0,0 +1,53 @@
+package mysqldump
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/MouseHatGames/go-mysqldump/internal/marshal"
+)
+
+func convertSQL(dump []byte, opt ConvertOptions) (string, error) {
+	flusher, ready := make(chan bool), make(chan bool)
+	go func() {
+		for range flusher {
+			ready <- true
+		}
+	}()
+	defer close(flusher)
+
+	var out bytes.Buffer
+	err := ConvertToSQL(bytes.NewReader(dump), &out, flusher, ready, 1000, opt)
+	return out.String(), err
+}
+
+func TestConvertRestarted(t *testing.T) {
+	var buf bytes.Buffer
+	w := marshal.NewWriter(&buf)
+	w.WriteFileHeader(&FileHeader{})
+	create := "CREATE TABLE `users` (`id` int)"
+	w.WriteTableHeader(&TableHeader{Name: "users", Columns: []string{"id"}, CreateSQL: create})
+	w.WriteRowData(stringRow("1"))
+	w.WriteTableHeader(&TableHeader{Name: "users", Columns: []string{"id"}, CreateSQL: create, Restarted: true})
+	w.WriteRowData(stringRow("1"))
+	w.WriteRowData(stringRow("2"))
+	w.WriteFileFooter(&FileFooter{})
+
+	// The table is created again over the row of the first section
+	out, err := convertSQL(buf.Bytes(), ConvertOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(out, "DROP TABLE IF EXISTS `users`"); n != 2 {
+		t.Errorf("dropped users %d times, want 2", n)
+	}
+
+	// Existing tables would keep the row of the first section along with its replacement
+	for _, opt := range []ConvertOptions{{SkipCreate: true}, {Create: CreateIfNotExists}} {
+		if _, err := convertSQL(buf.Bytes(), opt); !errors.Is(err, errRestartedExisting) {
+			t.Errorf("converted with %+v: %v, want errRestartedExisting", opt, err)
+		}
+	}
+}
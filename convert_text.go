@@ -0,0 +1,237 @@
+package mysqldump
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/MouseHatGames/go-mysqldump/internal/marshal"
+)
+
+// CSVOptions sets how tables are written as CSV.
+type CSVOptions struct {
+	// Field delimiter, ',' if 0
+	Delimiter rune
+	// Leave out the record naming the columns
+	NoHeader bool
+}
+
+// ConvertToCSV writes every table of a dump as RFC 4180 CSV, with the column names as the first
+// record unless ConvertOptions.CSV says otherwise. One writer is opened per table through open and
+// closed once the table is done. Tables dumped by partition get one writer per partition, named
+// "table.partition". NULL values are written as empty fields.
+func ConvertToCSV(in io.Reader, open func(table string) (io.WriteCloser, error), opts ...ConvertOptions) error {
+	var co CSVOptions
+	if len(opts) > 0 {
+		co = opts[0].CSV
+	}
+
+	return eachTable(in, opts, func(t *marshal.TableHeader, r *marshal.Reader) error {
+		name := t.Name
+		if t.Partition != "" {
+			name += "." + t.Partition
+		}
+		if t.Shard > 0 {
+			name += fmt.Sprintf(".shard%d", t.Shard)
+		}
+		if t.ColumnGroup > 0 {
+			name += fmt.Sprintf(".group%d", t.ColumnGroup)
+		}
+		f, err := open(name)
+		if err != nil {
+			return fmt.Errorf("open output: %w", err)
+		}
+
+		cw := csv.NewWriter(f)
+		if co.Delimiter != 0 {
+			cw.Comma = co.Delimiter
+		}
+		if !co.NoHeader {
+			if err = cw.Write(t.Columns); err != nil {
+				f.Close()
+				return err
+			}
+		}
+
+		record := make([]string, len(t.Columns))
+		for {
+			row, err := r.ReadRow(len(t.Columns))
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				f.Close()
+				return err
+			}
+
+			for i, v := range row {
+				record[i] = ""
+				if v != nil {
+					record[i] = *v
+				}
+			}
+			if err = cw.Write(record); err != nil {
+				f.Close()
+				return err
+			}
+		}
+
+		cw.Flush()
+		if err = cw.Error(); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	})
+}
+
+// ConvertToJSONL writes every row of a dump as a JSON object on its own line, of the form
+// {"table":"name","row":{"column":"value",...}}, with a "partition" key for tables dumped by
+// partition. Columns keep their order, NULL values are written as null and the values of binary
+// columns as base64 strings.
+func ConvertToJSONL(in io.Reader, w io.Writer, opts ...ConvertOptions) error {
+	bw := bufio.NewWriter(w)
+
+	err := eachTable(in, opts, func(t *marshal.TableHeader, r *marshal.Reader) error {
+		prefix, err := jsonlPrefix(t)
+		if err != nil {
+			return err
+		}
+		bin := binaryFlags(t.BinaryColumns, t.Columns)
+
+		for {
+			row, err := r.ReadRow(len(t.Columns))
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					return nil
+				}
+				return err
+			}
+
+			if err = writeJSONRow(bw, prefix, row, bin); err != nil {
+				return err
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// jsonlPrefix returns the encoded table name and column keys used by writeJSONRow.
+func jsonlPrefix(t *marshal.TableHeader) ([][]byte, error) {
+	name, err := json.Marshal(t.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([][]byte, len(t.Columns)+1)
+	keys[0] = append([]byte(`{"table":`), name...)
+	if t.Partition != "" {
+		p, err := json.Marshal(t.Partition)
+		if err != nil {
+			return nil, err
+		}
+		keys[0] = append(append(keys[0], `,"partition":`...), p...)
+	}
+	if t.Shard > 0 {
+		keys[0] = append(keys[0], fmt.Sprintf(`,"shard":%d`, t.Shard)...)
+	}
+	if t.ColumnGroup > 0 {
+		keys[0] = append(keys[0], fmt.Sprintf(`,"column_group":%d`, t.ColumnGroup)...)
+	}
+	keys[0] = append(keys[0], `,"row":{`...)
+	for i, c := range t.Columns {
+		k, err := json.Marshal(c)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			k = append([]byte{','}, k...)
+		}
+		keys[i+1] = append(k, ':')
+	}
+
+	return keys, nil
+}
+
+// writeJSONRow writes a row as a JSON object, the values of the columns flagged as binary base64
+// encoded since JSON strings can't hold arbitrary bytes.
+func writeJSONRow(w io.Writer, keys [][]byte, row marshal.RowData, binary []bool) error {
+	w.Write(keys[0])
+	for i, v := range row {
+		w.Write(keys[i+1])
+		if v == nil {
+			w.Write([]byte("null"))
+			continue
+		}
+
+		var b []byte
+		var err error
+		if i < len(binary) && binary[i] {
+			b, err = json.Marshal([]byte(*v))
+		} else {
+			b, err = json.Marshal(*v)
+		}
+		if err != nil {
+			return err
+		}
+		w.Write(b)
+	}
+
+	_, err := w.Write([]byte("}}\n"))
+	return err
+}
+
+// eachTable calls fn for every table in a dump that is selected by the options. fn must read
+// all of the table's rows.
+func eachTable(in io.Reader, opts []ConvertOptions, fn func(t *marshal.TableHeader, r *marshal.Reader) error) error {
+	var opt ConvertOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	r := marshal.NewReader(in)
+	if _, err := r.ReadFileHeader(); err != nil {
+		return fmt.Errorf("read file header: %w", err)
+	}
+
+	for {
+		t, err := r.ReadTableHeader()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			return fmt.Errorf("read table header: %w", err)
+		}
+
+		if !opt.includes(t.Name) {
+			if err = r.SkipRows(len(t.Columns)); err != nil && !errors.Is(err, io.EOF) {
+				return fmt.Errorf("skip table %s: %w", t.Name, err)
+			}
+			continue
+		}
+
+		if err = fn(t, r); err != nil {
+			return fmt.Errorf("convert table %s: %w", t.Name, err)
+		}
+	}
+}
+
+func (o *ConvertOptions) includes(table string) bool {
+	if len(o.Tables) == 0 {
+		return true
+	}
+	for _, t := range o.Tables {
+		if t == table {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package mysqldump
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CreatePolicy controls how restoring a dump creates its tables and sequences.
+type CreatePolicy int
+
+const (
+	// CreateAuto follows the policy recorded in the dump, CreateDrop for dumps without one.
+	CreateAuto CreatePolicy = iota
+	// CreateDrop drops existing tables and creates them again, like mysqldump --add-drop-table.
+	CreateDrop
+	// CreateIfNotExists keeps existing tables, rows included, and only creates the missing ones.
+	CreateIfNotExists
+	// CreateError fails the restore if a table already exists.
+	CreateError
+)
+
+// ParseCreatePolicy parses "auto", "drop", "if_not_exists" or "error".
+func ParseCreatePolicy(s string) (CreatePolicy, error) {
+	switch strings.ToLower(s) {
+	case "auto", "":
+		return CreateAuto, nil
+	case "drop":
+		return CreateDrop, nil
+	case "if_not_exists":
+		return CreateIfNotExists, nil
+	case "error":
+		return CreateError, nil
+	}
+
+	return 0, fmt.Errorf("invalid create policy: %s", s)
+}
+
+func (p CreatePolicy) String() string {
+	switch p {
+	case CreateDrop:
+		return "drop"
+	case CreateIfNotExists:
+		return "if_not_exists"
+	case CreateError:
+		return "error"
+	}
+	return "auto"
+}
+
+// WithCreatePolicy records in the dump how it should be restored, which the Loader and ConvertToSQL follow
+// unless told otherwise. Dumps default to CreateDrop.
+func WithCreatePolicy(p CreatePolicy) Option {
+	return func(d *Dumper) {
+		d.createPolicy = p
+	}
+}
+
+// resolve returns the policy to restore a dump with, given the one recorded in it.
+func (p CreatePolicy) resolve(recorded string) CreatePolicy {
+	if p != CreateAuto {
+		return p
+	}
+	if r, err := ParseCreatePolicy(recorded); err == nil && r != CreateAuto {
+		return r
+	}
+	return CreateDrop
+}
+
+// createStatements returns the statements creating a table or sequence, kind being TABLE or SEQUENCE.
+func (p CreatePolicy) createStatements(kind string, name string, ddl string) []string {
+	switch p {
+	case CreateIfNotExists:
+		prefix := "CREATE " + kind + " "
+		if strings.HasPrefix(ddl, prefix) {
+			ddl = prefix + "IF NOT EXISTS " + ddl[len(prefix):]
+		}
+		return []string{ddl}
+	case CreateError:
+		return []string{ddl}
+	}
+	return []string{"DROP " + kind + " IF EXISTS " + quoteName(name), ddl}
+}
@@ -0,0 +1,279 @@
+package mysqldump
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// ChunkStore is a content-addressed store of dump chunks, named by the hex SHA-256 of their bytes.
+// Chunks are never modified once put, so the chunks of a dump present from earlier dumps are kept
+// as they are instead of being stored again. Put must not keep data once it returns.
+type ChunkStore interface {
+	Has(id string) (bool, error)
+	Put(id string, data []byte) error
+	Get(id string) ([]byte, error)
+}
+
+// DirStore is a ChunkStore keeping every chunk in a file of a directory, under a subdirectory named
+// by the first two characters of its ID.
+type DirStore struct {
+	dir string
+}
+
+// NewDirStore returns a store of chunks in dir, which is created along with its subdirectories
+// as chunks are put.
+func NewDirStore(dir string) *DirStore {
+	return &DirStore{dir: dir}
+}
+
+func (s *DirStore) path(id string) string {
+	return filepath.Join(s.dir, id[:2], id)
+}
+
+func (s *DirStore) Has(id string) (bool, error) {
+	_, err := os.Stat(s.path(id))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Put writes a chunk to a temporary file renamed once complete, so that a chunk is never seen half
+// written.
+func (s *DirStore) Put(id string, data []byte) error {
+	dir := filepath.Dir(s.path(id))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), s.path(id))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+func (s *DirStore) Get(id string) ([]byte, error) {
+	return ioutil.ReadFile(s.path(id))
+}
+
+// DedupIndex lists the chunks a dump was split into by a DedupWriter, in order.
+type DedupIndex struct {
+	// Directory of the DirStore holding the chunks, relative to the index, if the chunks were put in one
+	Store  string `json:",omitempty"`
+	Chunks []DedupChunk
+}
+
+type DedupChunk struct {
+	ID   string
+	Size int64
+}
+
+// DedupStats is how much of a dump written by a DedupWriter was already in its store.
+type DedupStats struct {
+	Chunks int
+	Bytes  int64
+	// Chunks, and their bytes, that weren't in the store and were put in it
+	NewChunks int
+	NewBytes  int64
+}
+
+// Boundaries of the chunks a DedupWriter cuts: chunks end where the gear hash of the bytes written has
+// its lowest dedupMaskBits bits unset, about every 1MB, but are never smaller than dedupMinChunk or
+// larger than dedupMaxChunk.
+const (
+	dedupMinChunk = 256 << 10
+	dedupMaxChunk = 4 << 20
+	dedupMaskBits = 20
+)
+
+// dedupGear holds the pseudo-random values of the bytes rolled into the gear hash. They are fixed, so
+// that a dump run after run is cut at the same places.
+var dedupGear = func() (t [256]uint64) {
+	x := uint64(0x6d7973716c64756d)
+	for i := range t {
+		// splitmix64
+		x += 0x9e3779b97f4a7c15
+		z := x
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		t[i] = z ^ (z >> 31)
+	}
+	return
+}()
+
+// DedupWriter splits a dump into chunks named by their hash and puts those missing from a store into
+// it, writing the index listing the chunks on Close, like restic or borg do for backups. Chunks are
+// cut where the content of the dump says, not at fixed offsets, so rows inserted or changed since an
+// earlier dump only change the chunks they are in and dumps of a database that changes little take
+// little room in the store. Compressed or encrypted dumps differ throughout from one run to the next
+// and don't deduplicate.
+type DedupWriter struct {
+	store ChunkStore
+	index string
+
+	buf  []byte
+	hash uint64
+
+	chunks []DedupChunk
+	stats  DedupStats
+}
+
+// NewDedupWriter creates a writer putting the chunks of a dump into store and their index at index.
+func NewDedupWriter(store ChunkStore, index string) *DedupWriter {
+	return &DedupWriter{
+		store: store,
+		index: index,
+		buf:   make([]byte, 0, dedupMaxChunk),
+	}
+}
+
+func (w *DedupWriter) Write(b []byte) (int, error) {
+	const mask = 1<<dedupMaskBits - 1
+	for i, c := range b {
+		w.buf = append(w.buf, c)
+		w.hash = w.hash<<1 + dedupGear[c]
+		if n := len(w.buf); n >= dedupMaxChunk || (n >= dedupMinChunk && w.hash&mask == 0) {
+			if err := w.cut(); err != nil {
+				return i + 1, err
+			}
+		}
+	}
+	return len(b), nil
+}
+
+// cut ends the current chunk, putting it into the store unless it is there already.
+func (w *DedupWriter) cut() error {
+	sum := sha256.Sum256(w.buf)
+	id := hex.EncodeToString(sum[:])
+
+	ok, err := w.store.Has(id)
+	if err == nil && !ok {
+		if err = w.store.Put(id, w.buf); err == nil {
+			w.stats.NewChunks++
+			w.stats.NewBytes += int64(len(w.buf))
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("store chunk %s: %w", id, err)
+	}
+
+	w.chunks = append(w.chunks, DedupChunk{ID: id, Size: int64(len(w.buf))})
+	w.stats.Chunks++
+	w.stats.Bytes += int64(len(w.buf))
+	w.buf, w.hash = w.buf[:0], 0
+	return nil
+}
+
+// Stats returns how much of the dump written so far was already in the store.
+func (w *DedupWriter) Stats() DedupStats {
+	return w.stats
+}
+
+// Close stores the last chunk and writes the index.
+func (w *DedupWriter) Close() error {
+	if len(w.buf) > 0 {
+		if err := w.cut(); err != nil {
+			return err
+		}
+	}
+
+	idx := DedupIndex{Chunks: w.chunks}
+	if s, ok := w.store.(*DirStore); ok {
+		if rel, err := filepath.Rel(filepath.Dir(w.index), s.dir); err == nil {
+			idx.Store = rel
+		} else if idx.Store, err = filepath.Abs(s.dir); err != nil {
+			return err
+		}
+	}
+	b, err := json.MarshalIndent(&idx, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(w.index, b, 0644)
+}
+
+// ReadDedupIndex reads the index written by a DedupWriter.
+func ReadDedupIndex(path string) (*DedupIndex, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var idx DedupIndex
+	if err = json.Unmarshal(b, &idx); err != nil {
+		return nil, fmt.Errorf("decode dedup index: %w", err)
+	}
+	return &idx, nil
+}
+
+// OpenDedup opens the chunks listed in an index as a single dump, checking the hash of every chunk
+// as it is read. A nil store reads the chunks from the DirStore the index names.
+func OpenDedup(indexPath string, store ChunkStore) (io.ReadCloser, error) {
+	idx, err := ReadDedupIndex(indexPath)
+	if err != nil {
+		return nil, err
+	}
+	if store == nil {
+		if idx.Store == "" {
+			return nil, errors.New("dedup index doesn't name the store of its chunks")
+		}
+		dir := idx.Store
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(filepath.Dir(indexPath), dir)
+		}
+		store = NewDirStore(dir)
+	}
+	return &dedupReader{store: store, chunks: idx.Chunks}, nil
+}
+
+type dedupReader struct {
+	store  ChunkStore
+	chunks []DedupChunk
+	cur    []byte
+}
+
+func (r *dedupReader) Read(b []byte) (int, error) {
+	for len(r.cur) == 0 {
+		if len(r.chunks) == 0 {
+			return 0, io.EOF
+		}
+		c := r.chunks[0]
+		r.chunks = r.chunks[1:]
+
+		data, err := r.store.Get(c.ID)
+		if err != nil {
+			return 0, fmt.Errorf("read chunk %s: %w", c.ID, err)
+		}
+		if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != c.ID || int64(len(data)) != c.Size {
+			return 0, fmt.Errorf("chunk %s is corrupted", c.ID)
+		}
+		r.cur = data
+	}
+
+	n := copy(b, r.cur)
+	r.cur = r.cur[n:]
+	return n, nil
+}
+
+func (r *dedupReader) Close() error {
+	return nil
+}
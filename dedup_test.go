@@ -0,0 +1,112 @@
+package mysqldump
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// dedupDump writes data through a DedupWriter into the store in dir, returning the path of the index.
+func dedupDump(t *testing.T, dir string, name string, data []byte) (string, *DedupWriter) {
+	index := filepath.Join(dir, name+".dedup")
+	w := NewDedupWriter(NewDirStore(filepath.Join(dir, "chunks")), index)
+	// Written in pieces crossing the cut points
+	for len(data) > 0 {
+		n := 100000
+		if n > len(data) {
+			n = len(data)
+		}
+		if _, err := w.Write(data[:n]); err != nil {
+			t.Fatal(err)
+		}
+		data = data[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return index, w
+}
+
+func readDedup(t *testing.T, index string) ([]byte, error) {
+	r, err := OpenDedup(index, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+func TestDedupWriter(t *testing.T) {
+	dir := t.TempDir()
+	data := make([]byte, 12<<20)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	index, w := dedupDump(t, dir, "first", data)
+	idx, err := ReadDedupIndex(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idx.Store != "chunks" {
+		t.Errorf("index names store %q, want chunks", idx.Store)
+	}
+	for i, c := range idx.Chunks {
+		if i < len(idx.Chunks)-1 && (c.Size < dedupMinChunk || c.Size > dedupMaxChunk) {
+			t.Errorf("chunk %d is %d bytes", i, c.Size)
+		}
+	}
+	if s := w.Stats(); s.Bytes != int64(len(data)) || s.NewChunks != s.Chunks || s.Chunks != len(idx.Chunks) || s.Chunks < 3 {
+		t.Errorf("stats %+v for %d chunks", s, len(idx.Chunks))
+	}
+	if got, err := readDedup(t, index); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("read %d bytes back with %v, want the %d written", len(got), err, len(data))
+	}
+
+	// Bytes inserted only change the chunk they are in, the cut points after it stay
+	changed := append(append(append([]byte{}, data[:1<<20]...), "inserted row"...), data[1<<20:]...)
+	index, w = dedupDump(t, dir, "second", changed)
+	if s := w.Stats(); s.NewChunks > 2 || s.Chunks < 3 {
+		t.Errorf("stored %d of %d chunks again", s.NewChunks, s.Chunks)
+	}
+	if got, err := readDedup(t, index); err != nil || !bytes.Equal(got, changed) {
+		t.Errorf("read %d bytes back with %v, want the %d written", len(got), err, len(changed))
+	}
+}
+
+func TestDedupWriterMaxChunk(t *testing.T) {
+	// Repeated bytes never cut the hash, chunks end at the largest size and are stored once
+	data := make([]byte, 2*dedupMaxChunk+10)
+	_, w := dedupDump(t, t.TempDir(), "zeros", data)
+	if s := w.Stats(); s.Chunks != 3 || s.NewChunks != 2 || s.NewBytes != dedupMaxChunk+10 {
+		t.Errorf("stats %+v, want 3 chunks of which 2 new", s)
+	}
+}
+
+func TestOpenDedupCorrupted(t *testing.T) {
+	dir := t.TempDir()
+	data := make([]byte, 3<<20)
+	rand.New(rand.NewSource(2)).Read(data)
+	index, _ := dedupDump(t, dir, "dump", data)
+	idx, err := ReadDedupIndex(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := NewDirStore(filepath.Join(dir, "chunks")).path(idx.Chunks[1].ID)
+	chunk, _ := ioutil.ReadFile(path)
+	chunk[0] ^= 0xff
+	if err = ioutil.WriteFile(path, chunk, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = readDedup(t, index); err == nil || !strings.Contains(err.Error(), "is corrupted") {
+		t.Errorf("read a corrupted chunk with %v", err)
+	}
+
+	os.Remove(path)
+	if _, err = readDedup(t, index); err == nil {
+		t.Error("read a missing chunk")
+	}
+}
@@ -0,0 +1,256 @@
+package mysqldump
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type DiffOptions struct {
+	// Tables with at most this many rows in both dumps are also compared row by row. 0 disables row level diffs
+	RowLimit int64
+}
+
+// TableDiff describes how a table differs between two dumps.
+type TableDiff struct {
+	Table string
+	InA   bool
+	InB   bool
+
+	// Set if the column list or the CREATE statement differ
+	SchemaChanged bool
+	ColumnsA      []string
+	ColumnsB      []string
+	CreateSQLA    string
+	CreateSQLB    string
+
+	RowsA     int64
+	RowsB     int64
+	ChecksumA string
+	ChecksumB string
+
+	// Only set when the table was small enough to be compared row by row. Tables with a primary key and
+	// the same columns in both dumps have their rows matched by key, into inserted, updated and deleted
+	// rows. Rows of other tables are compared whole, into the rows only in a and only in b
+	Key          []string
+	RowsInserted []RowData
+	RowsUpdated  []RowChange
+	RowsDeleted  []RowData
+	RowsOnlyInA  []RowData
+	RowsOnlyInB  []RowData
+}
+
+// RowChange is a row whose key is in both dumps with different values.
+type RowChange struct {
+	A RowData
+	B RowData
+}
+
+// Equal reports whether the table is the same in both dumps.
+func (t *TableDiff) Equal() bool {
+	return t.InA && t.InB && !t.SchemaChanged && t.RowsA == t.RowsB && t.ChecksumA == t.ChecksumB
+}
+
+// DumpDiff holds the differences between two dumps.
+type DumpDiff struct {
+	// Every table present in either dump, in the order of dump a followed by tables only in b
+	Tables []*TableDiff
+}
+
+// Equal reports whether both dumps contain the same tables with the same schema and rows.
+func (d *DumpDiff) Equal() bool {
+	for _, t := range d.Tables {
+		if !t.Equal() {
+			return false
+		}
+	}
+	return true
+}
+
+// Diff compares the schema and contents of two dumps.
+func Diff(a, b io.Reader, opt DiffOptions) (*DumpDiff, error) {
+	ia, err := inspect(a, opt.RowLimit)
+	if err != nil {
+		return nil, fmt.Errorf("read dump a: %w", err)
+	}
+	ib, err := inspect(b, opt.RowLimit)
+	if err != nil {
+		return nil, fmt.Errorf("read dump b: %w", err)
+	}
+
+	diff := &DumpDiff{}
+	for _, ta := range ia.Tables {
+		td := &TableDiff{
+			Table:      ta.Header.Name,
+			InA:        true,
+			ColumnsA:   ta.Header.Columns,
+			CreateSQLA: ta.Header.CreateSQL,
+			RowsA:      ta.Rows,
+			ChecksumA:  ta.Checksum,
+		}
+
+		if tb := ib.Table(ta.Header.Name); tb != nil {
+			td.InB = true
+			td.ColumnsB = tb.Header.Columns
+			td.CreateSQLB = tb.Header.CreateSQL
+			td.RowsB = tb.Rows
+			td.ChecksumB = tb.Checksum
+			td.SchemaChanged = td.CreateSQLA != td.CreateSQLB ||
+				strings.Join(td.ColumnsA, ",") != strings.Join(td.ColumnsB, ",")
+
+			if opt.RowLimit > 0 && td.ChecksumA != td.ChecksumB &&
+				td.RowsA <= opt.RowLimit && td.RowsB <= opt.RowLimit {
+				if key := diffKey(ta.Header, tb.Header); key != nil {
+					td.Key = key.names
+					td.RowsInserted, td.RowsUpdated, td.RowsDeleted = diffKeyedRows(key.index, ta.rows, tb.rows)
+				} else {
+					td.RowsOnlyInA, td.RowsOnlyInB = diffRows(ta.rows, tb.rows)
+				}
+			}
+		}
+
+		diff.Tables = append(diff.Tables, td)
+	}
+
+	for _, tb := range ib.Tables {
+		if ia.Table(tb.Header.Name) != nil {
+			continue
+		}
+
+		diff.Tables = append(diff.Tables, &TableDiff{
+			Table:      tb.Header.Name,
+			InB:        true,
+			ColumnsB:   tb.Header.Columns,
+			CreateSQLB: tb.Header.CreateSQL,
+			RowsB:      tb.Rows,
+			ChecksumB:  tb.Checksum,
+		})
+	}
+
+	return diff, nil
+}
+
+// diffRows returns the rows that only appear in a and only appear in b, counting duplicates.
+func diffRows(a, b []RowData) (onlyA, onlyB []RowData) {
+	counts := make(map[string]int, len(a))
+	for _, r := range a {
+		counts[rowKey(r)]++
+	}
+	for _, r := range b {
+		k := rowKey(r)
+		if counts[k] > 0 {
+			counts[k]--
+		} else {
+			onlyB = append(onlyB, r)
+		}
+	}
+	for _, r := range a {
+		k := rowKey(r)
+		if counts[k] > 0 {
+			counts[k]--
+			onlyA = append(onlyA, r)
+		}
+	}
+
+	return
+}
+
+// rowKeyColumns are the columns rows are matched by, with their position in the rows.
+type rowKeyColumns struct {
+	names []string
+	index []int
+}
+
+// diffKey returns the primary key of a table if it has the same columns in both dumps, nil otherwise.
+func diffKey(a, b *TableHeader) *rowKeyColumns {
+	if strings.Join(a.Columns, ",") != strings.Join(b.Columns, ",") {
+		return nil
+	}
+	names := primaryKeyOf(a)
+	if len(names) == 0 || strings.Join(names, ",") != strings.Join(primaryKeyOf(b), ",") {
+		return nil
+	}
+
+	key := &rowKeyColumns{names: names}
+	for _, n := range names {
+		i := indexOf(a.Columns, n)
+		if i < 0 {
+			return nil
+		}
+		key.index = append(key.index, i)
+	}
+	return key
+}
+
+// primaryKeyOf returns the primary key of a table, known from its chunk key or else its DDL.
+func primaryKeyOf(t *TableHeader) []string {
+	if t.Chunking == ChunkPrimaryKey {
+		return t.ChunkKey
+	}
+	return primaryKeyColumns(t.CreateSQL)
+}
+
+// primaryKeyColumns returns the columns of the primary key of a CREATE TABLE statement.
+func primaryKeyColumns(ddl string) []string {
+	idx := findDefinition(parseCreateTable(ddl).indexes, "PRIMARY")
+	if idx == nil {
+		return nil
+	}
+
+	var cols []string
+	for _, m := range quotedNameRegex.FindAllStringSubmatch(idx.def, -1) {
+		cols = append(cols, strings.ReplaceAll(m[1], "``", "`"))
+	}
+	return cols
+}
+
+func indexOf(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// diffKeyedRows matches the rows of a and b by key, returning the rows only in b, the rows that
+// changed and the rows only in a, in the order of the dumps.
+func diffKeyedRows(key []int, a, b []RowData) (inserted []RowData, updated []RowChange, deleted []RowData) {
+	keyOf := func(r RowData) string {
+		k := make(RowData, len(key))
+		for i, c := range key {
+			k[i] = r[c]
+		}
+		return rowKey(k)
+	}
+
+	inA := make(map[string]RowData, len(a))
+	for _, r := range a {
+		inA[keyOf(r)] = r
+	}
+	inB := make(map[string]bool, len(b))
+	for _, r := range b {
+		k := keyOf(r)
+		inB[k] = true
+		ra, ok := inA[k]
+		switch {
+		case !ok:
+			inserted = append(inserted, r)
+		case rowKey(ra) != rowKey(r):
+			updated = append(updated, RowChange{A: ra, B: r})
+		}
+	}
+	for _, r := range a {
+		if !inB[keyOf(r)] {
+			deleted = append(deleted, r)
+		}
+	}
+
+	return
+}
+
+func rowKey(r RowData) string {
+	b, _ := json.Marshal(r)
+	return string(b)
+}
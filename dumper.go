@@ -1,6 +1,7 @@
 package mysqldump
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -8,7 +9,9 @@ import (
 	"github.com/sirupsen/logrus"
 	"io"
 	"reflect"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	binary "github.com/MouseHatGames/go-mysqldump/internal/marshal"
@@ -21,25 +24,8 @@ var commaNewline = []byte{',', '\n', '\t'}
 var quote = []byte{'\''}
 var semicolonNewline = []byte{';', '\n'}
 
-// filteredTables special queries for data dump. not should start with a space
-var filteredTables = map[string]map[string][]string{
-	"iot-api": {
-		"event_log": {
-			" WHERE event = 'geofence-in' AND id < 517837446",
-			" WHERE event = 'geofence-out' AND id < 517837446",
-			" WHERE id >= 517837446",
-		}, // skip data before 01-10-2021 except for geofences
-		"rate_limit_request_log": {}, // skip all data
-	},
-	"paztir_prod": {
-		"doctrine_migration_versions": {},
-	},
-	"paztir": {
-		"doctrine_migration_versions": {},
-		//"event_log":                   {},
-		//"app_log": {" where id = 10027821"},
-	},
-}
+// ErrInterrupted is returned by Dump when it was stopped through Interrupt.
+var ErrInterrupted = errors.New("dump interrupted")
 
 // Dumper represents a database.
 type Dumper struct {
@@ -47,29 +33,231 @@ type Dumper struct {
 	w         io.Writer
 	bin       *binary.Writer
 	chunkSize int
+
+	checkpointFile string
+	progress       func(ProgressEvent)
+	progressFunc   ProgressFunc
+	filters        map[string][]string
+	ranges         map[string][]string
+	transform      RowTransformer
+	// Check the masked columns of a table were all dumped once its rows are, see WithMasking
+	maskChecks []func(table string)
+	queries    map[string]string
+	// Queries added to the next dump only, see DumpQuery
+	nextQueries    map[string]string
+	engines        map[string]EnginePolicy
+	partitions     bool
+	shards         int
+	columnGroups   int
+	ddlTransforms  []DDLTransform
+	hooks          Hooks
+	tableOrder     []string
+	includeTables  []string
+	excludeTables  []string
+	sessionVars    map[string]string
+	systemTime     SystemTime
+	rules          map[string][]RowRule
+	optimizerStats bool
+	createPolicy   CreatePolicy
+	prefetch       bool
+	orderRows      bool
+	guards         *ValueGuards
+	priming        bool
+	parallelTables int
+	priorities     map[string]int
+	format         Format
+	csvOpen        func(table string) (io.WriteCloser, error)
+	csvOptions     CSVOptions
+	compression    Compression
+	// Level of compression, see WithCompression, and the output being compressed
+	compressionLevel int
+	compressed       *streamOutput
+	encryptionKey    []byte
+	encrypted        *streamOutput
+	// Opens the file of every table, see WithTableFiles
+	tableOpen func(table string) (io.WriteCloser, error)
+	// Journal of the output, see WithJournal, the unit of the table being journaled and the dump resumed
+	journalFile string
+	journal     *journalWriter
+	journalUnit int
+	resume      *journalResume
+	// Read every table within a single transaction, see WithSingleTransaction and WithBinlogPosition
+	singleTransaction bool
+	recordBinlog      bool
+	// Read tables at once also without a chunk size, see WithConcurrency
+	parallelUnchunked bool
+
+	interrupted int32
+	checkpoint  Checkpoint
+	cur         ProgressEvent
+	serverInfo  *serverInfo
+	tableRules  map[string]*tableRules
+	violations  []*RuleViolation
+	// Rules of tables read at once are checked concurrently
+	rulesMu sync.Mutex
+	// Issues met by the current dump, see Warnings, and those not reported with a progress event yet
+	warningsMu      sync.Mutex
+	warnings        []Warning
+	pendingWarnings []Warning
+	// Violations of the value guards by table and guard
+	guardViolations map[string]*RuleViolation
+	// Expected row counts by table, and the tables of the last dump out of them
+	rowCounts          map[string]RowCountRange
+	rowCountViolations []RowCountViolation
+	// Limits of the tables and those that went over them, see WithTableLimits, and when the table
+	// being dumped started
+	tableLimits   map[string]TableLimit
+	limitBreaches []TableLimitBreach
+	tableStart    time.Time
+	// Times a table altered while it is read is dumped again, see WithTableRestarts, and the table
+	// being dumped again
+	tableRestarts int
+	restarting    string
+	// What the current dump wrote, see Info
+	info      *DumpInfo
+	infoTable *TableInfo
+	// Header of the section of infoTable being written
+	infoHeader *TableHeader
+	// Records of the tables being written, see WithReadBack
+	readBack *readBack
+	// Set when tables are read at once, see WithParallelTables, with the ID of the last table header written
+	interleave bool
+	sectionID  uint32
+	// Tables profiled by WithPriming
+	profiles map[string]*TableProfile
+	// Estimated row count of every table, read when the progress is reported
+	rowEstimates map[string]int64
+	// Key every table is chunked by, see getChunkKey
+	chunkKeys map[string]chunkKey
+	// Bytes moved by the current dump, see Bandwidth
+	readBytes    int64
+	writtenBytes int64
+	dumpSchema   string
+	// Pacing of the values read, see WithThrottle
+	throttle *throttle
+	// Output converted from the binary dump, see WithFormat, and the size of the dump before conversion
+	converted    *convertedOutput
+	encodedBytes int64
+	// Foreign keys taken out of the DDL of each table, see getCyclicConstraints
+	cyclicFKs map[string]map[string]bool
+	// Connection table data is read through, see startSnapshot and startOLAP
+	conn      *sql.Conn
+	connSetup []string
+	// Database selected by use, selected again when reconnecting
+	dbName string
+	// Context of the operation running, see DumpContext
+	ctx context.Context
+
+	// How chunk queries failing with transient errors are tried again, see WithRetryPolicy
+	retry RetryPolicy
+
+	outfile      *outfileDirs
+	queueChunks  int
+	memoryBudget int64
+	queue        *writeQueue
+	// Schema objects dumped after the tables along with their views, see WithRoutines
+	routines bool
+	triggers bool
+	events   bool
+	// Record the global variables and status of the server, see WithServerSnapshot
+	serverSnapshot bool
+	// Registry the dumps are registered with under jobName, see WithRegistry
+	registry *Registry
+	jobName  string
 }
 
 // NewDumper creates a new dumper instance.
-func NewDumper(db *sql.DB, w io.Writer, chunkSize int) *Dumper {
-	return &Dumper{
+func NewDumper(db *sql.DB, w io.Writer, chunkSize int, opts ...Option) *Dumper {
+	d := &Dumper{
 		db:        db,
 		w:         w,
-		bin:       binary.NewWriter(w),
 		chunkSize: chunkSize,
+
+		retry:         DefaultRetryPolicy,
+		tableRestarts: defaultTableRestarts,
+	}
+	for _, o := range opts {
+		o(d)
+	}
+	d.resetWriter()
+
+	return d
+}
+
+// output returns the output the dump was given, below any compression or encryption.
+func (d *Dumper) output() io.Writer {
+	if d.encrypted != nil {
+		return d.encrypted.out
+	}
+	if d.compressed != nil {
+		return d.compressed.out
+	}
+	return d.w
+}
+
+// resetWriter makes the dump encoder write straight to the output.
+func (d *Dumper) resetWriter() {
+	d.bin = binary.NewWriter(d.encoderOutput(&countingWriter{d.w, d.outputCounter()}))
+	if p, ok := d.output().(*PartWriter); ok {
+		d.bin.OnRecord = p.boundary
+	}
+}
+
+// Interrupt makes a running dump stop once the chunk currently being read has been written.
+// Dump then finishes the output with a footer marking it as partial, writes the checkpoint
+// if one is configured and returns ErrInterrupted. It is safe to call from any goroutine.
+func (d *Dumper) Interrupt() {
+	atomic.StoreInt32(&d.interrupted, 1)
+}
+
+func (d *Dumper) isInterrupted() bool {
+	return atomic.LoadInt32(&d.interrupted) == 1
+}
+
+// withContext runs the queries of the Dumper under ctx until the returned function is called.
+func (d *Dumper) withContext(ctx context.Context) func() {
+	prev := d.ctx
+	d.ctx = ctx
+	return func() {
+		d.ctx = prev
+	}
+}
+
+// context returns the context queries are run under, see DumpContext.
+func (d *Dumper) context() context.Context {
+	if d.ctx == nil {
+		return context.Background()
 	}
+	return d.ctx
 }
 
 // Dump dumps one or more tables from a database into a writer.
 // If dbName is not empty, a "USE xxx" command will be sent prior to commencing the dump.
 func (d *Dumper) Dump(dbName string, wg *sync.WaitGroup, tables ...string) error {
-	var err error
+	return d.DumpContext(context.Background(), dbName, wg, tables...)
+}
 
+// DumpContext is Dump, running every query under ctx. Cancelling ctx aborts the query running and
+// fails the dump with the context's error, leaving the output without a footer.
+func (d *Dumper) DumpContext(ctx context.Context, dbName string, wg *sync.WaitGroup, tables ...string) (err error) {
 	if len(tables) == 0 {
 		return nil
 	}
+	defer d.withContext(ctx)()
+	d.resetWarnings()
+	d.rowCountViolations = nil
+	d.limitBreaches = nil
+	endJob, err := d.startJob(dbName)
+	if err != nil {
+		return err
+	}
+	defer func() { endJob(err) }()
+	// A resumed dump only continues once, and the queries of DumpQuery and ranges of DumpRange apply once
+	defer func() { d.resume, d.nextQueries, d.ranges = nil, nil, nil }()
+	defer d.readBack.close()
 
 	// Get server version
-	serverVer, err := getServerVersion(d.db)
+	serverVer, err := getServerVersion(d.context(), d.db)
 	if err != nil {
 		return err
 	}
@@ -77,26 +265,262 @@ func (d *Dumper) Dump(dbName string, wg *sync.WaitGroup, tables ...string) error
 	if err = d.use(dbName); err != nil {
 		return err
 	}
+	tables, views, err := d.splitViews(dbName, tables)
+	if err != nil {
+		return err
+	}
+	if err = d.runHooks("before dump", d.hooks.BeforeDump); err != nil {
+		return err
+	}
 
-	d.bin.WriteFileHeader(&binary.FileHeader{
+	var snapshot string
+	if d.isTiDB() {
+		if snapshot, err = d.startSnapshot(dbName); isPermissionError(err) {
+			d.warn(WarningSnapshot, "", "Can't read a consistent snapshot, tables are read as they are: %s", err)
+		} else if err != nil {
+			return err
+		}
+		defer d.endConn()
+	}
+	if d.isVitess() {
+		if err = d.startOLAP(dbName); err != nil {
+			return err
+		}
+		defer d.endConn()
+	}
+	var managed string
+	var binlog *binary.BinlogPosition
+	if s, _ := d.server(); d.recordBinlog && (d.conn != nil || d.isPQ()) {
+		d.warn(WarningOption, "", "Can't record the binlog position, only MySQL and MariaDB have one")
+	} else if s.managed != "" || d.recordBinlog {
+		managed = strings.TrimSpace(s.managed + " " + s.managedVersion)
+		if binlog, err = d.startConsistentRead(dbName); err != nil {
+			return err
+		}
+		defer d.endConn()
+	}
+	if d.singleTransaction && d.conn == nil {
+		if err = d.startSingleTransaction(dbName); err != nil {
+			return err
+		}
+		defer d.endConn()
+	}
+
+	if pinned, err := d.startSession(dbName); err != nil {
+		return fmt.Errorf("set up session: %w", err)
+	} else if pinned {
+		defer d.endConn()
+	}
+
+	var dicts map[string][]byte
+	if d.isPercona() {
+		if dicts, err = d.getCompressionDictionaries(); err != nil {
+			d.warn(WarningMetadata, "", "Can't read the compression dictionaries: %s", err)
+		}
+	}
+
+	variables, status := d.readServerSnapshot()
+
+	d.interleave, d.sectionID = d.interleaved(), 0
+	if d.parallelTables > 1 && !d.interleave {
+		if d.chunkSize <= 0 && !d.parallelUnchunked {
+			d.warn(WarningOption, "", "Reading tables one at a time, reading them at once needs a chunk size, see WithConcurrency")
+		} else if d.journalFile != "" {
+			d.warn(WarningOption, "", "Reading tables one at a time, tables read at once can't be journaled")
+		} else {
+			d.warn(WarningOption, "", "Reading tables one at a time, reading them at once can't keep a consistent snapshot")
+		}
+	}
+	atomic.StoreInt64(&d.readBytes, 0)
+	atomic.StoreInt64(&d.writtenBytes, 0)
+	d.dumpSchema = dbName
+	if err = d.startEncryption(); err != nil {
+		return err
+	}
+	defer func() {
+		if eerr := d.endEncryption(); eerr != nil && (err == nil || errors.Is(err, ErrInterrupted)) {
+			err = eerr
+		}
+	}()
+	if err = d.startCompression(); err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := d.endCompression(); cerr != nil && (err == nil || errors.Is(err, ErrInterrupted)) {
+			err = cerr
+		}
+	}()
+	if err = d.startJournal(); err != nil {
+		return err
+	}
+	defer func() {
+		if jerr := d.endJournal(err == nil); jerr != nil && (err == nil || errors.Is(err, ErrInterrupted)) {
+			err = jerr
+		}
+	}()
+	if err = d.startFormat(); err != nil {
+		return err
+	}
+	defer func() {
+		if ferr := d.endFormat(); ferr != nil && (err == nil || errors.Is(err, ErrInterrupted)) {
+			err = ferr
+		}
+	}()
+	d.startQueue()
+	defer func() {
+		// A failed write leaves the dump incomplete even if it was interrupted
+		if qerr := d.endQueue(); qerr != nil && (err == nil || errors.Is(err, ErrInterrupted)) {
+			err = qerr
+		}
+	}()
+
+	header := &binary.FileHeader{
 		ServerVersion: serverVer,
 		DatabaseName:  dbName,
 		DumpStart:     time.Now().UTC(),
-	})
+		Snapshot:      snapshot,
+		Compression:   d.compressionName(),
+		Managed:       managed,
+		Binlog:        binlog,
+
+		CompressionDictionaries: dicts,
+		ServerVariables:         variables,
+		ServerStatus:            status,
+		CreatePolicy:            d.createPolicy.resolve("").String(),
+		Interleaved:             d.interleave,
+		TableFilters:            d.appliedFilters(tables),
+	}
+	if d.resume != nil && d.resume.checkpoint == nil {
+		// The output holds the header and the rows journaled already
+		d.info = d.resume.info
+	} else {
+		header.Continues = d.resume != nil
+		if err = d.writeFileHeader(header); err != nil {
+			return fmt.Errorf("write file header: %w", err)
+		}
+		if err = d.journalHeader(); err != nil {
+			return err
+		}
+	}
+
+	tables = d.orderTables(tables)
+	d.cyclicFKs = d.getCyclicConstraints(dbName, tables)
+	d.chunkKeys = nil
+	d.prime(dbName, tables)
+	d.checkpoint = Checkpoint{Database: dbName}
+	d.cur = ProgressEvent{TableCount: len(tables) + len(d.queryNames()), EstimatedTotalRows: d.estimateRows(dbName, tables)}
+
+	sequential := tables
+	if d.interleave {
+		if err = d.readingBack(func() error { return d.writeParallelTables(dbName, tables, wg) }); err != nil {
+			return err
+		}
+		sequential = nil
+	}
 
 	// Write sql for each table
-	for _, t := range tables {
-		if err := d.writeTable(t, dbName, wg); err != nil {
+	for i, t := range sequential {
+		if d.resumedDone(t) {
+			d.checkpoint.Done = append(d.checkpoint.Done, t)
+			continue
+		}
+		if i > 0 && d.isInterrupted() {
+			return d.stop()
+		}
+
+		d.cur.TableIndex = i + 1
+		if err := d.runHooks("before table", d.hooks.tableHooks(t, false)); err != nil {
+			return err
+		}
+		if err := d.readingBack(func() error { return d.writeTableRestarting(t, dbName, wg) }); err != nil {
+			if errors.Is(err, ErrInterrupted) {
+				return d.stop()
+			}
+			return err
+		}
+		if err := d.runHooks("after table", d.hooks.tableHooks(t, true)); err != nil {
+			return err
+		}
+
+		d.checkpoint.Done = append(d.checkpoint.Done, t)
+		d.checkpoint.Table = ""
+		if err := d.journalDone(t); err != nil {
+			return err
+		}
+		if err := d.saveCheckpoint(); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	for i, name := range d.queryNames() {
+		if d.resumedDone(name) {
+			d.checkpoint.Done = append(d.checkpoint.Done, name)
+			continue
+		}
+		if d.isInterrupted() {
+			return d.stop()
+		}
+
+		d.cur.TableIndex = len(tables) + i + 1
+		if err := d.readingBack(func() error { return d.writeQuery(name, d.dumpedQuery(name)) }); err != nil {
+			return fmt.Errorf("query %s: %w", name, err)
+		}
+		d.checkpoint.Done = append(d.checkpoint.Done, name)
+		if err := d.journalDone(name); err != nil {
+			return err
+		}
+		if err := d.saveCheckpoint(); err != nil {
+			return err
+		}
+	}
+
+	if err = d.writeObjects(dbName, tables, views); err != nil {
+		return err
+	}
+	d.checkRowCounts()
+	if err = d.writeFileFooter(d.footer(false)); err != nil {
+		return err
+	}
+	if err = d.removeCheckpoint(); err != nil {
+		return err
+	}
+	return d.runHooks("after dump", d.hooks.AfterDump)
+}
+
+func (d *Dumper) footer(partial bool) *binary.FileFooter {
+	return &binary.FileFooter{
+		Partial: partial,
+		DumpEnd: time.Now().UTC(),
+		Tables:  len(d.checkpoint.Done),
+		Rows:    d.cur.TotalRows,
+		Limited: d.limitedTables(),
+	}
+}
+
+// stop ends an interrupted dump with a partial footer and saves the checkpoint.
+func (d *Dumper) stop() error {
+	logrus.Infof("Dump interrupted after %d tables", len(d.checkpoint.Done))
+
+	if err := d.writeFileFooter(d.footer(true)); err != nil {
+		return fmt.Errorf("write footer: %w", err)
+	}
+	if err := d.saveCheckpoint(); err != nil {
+		return err
+	}
+
+	return ErrInterrupted
 }
 
 // DumpAllTables dumps all tables in a database into a writer
 // If dbName is not empty, a "USE xxx" command will be sent prior to commencing the dump.
+// Tables are left out by WithIncludeTables and WithExcludeTables.
 func (d *Dumper) DumpAllTables(dbName string, wg *sync.WaitGroup) error {
+	return d.DumpAllTablesContext(context.Background(), dbName, wg)
+}
+
+// DumpAllTablesContext is DumpAllTables, running every query under ctx, see DumpContext.
+func (d *Dumper) DumpAllTablesContext(ctx context.Context, dbName string, wg *sync.WaitGroup) error {
+	defer d.withContext(ctx)()
 	if err := d.use(dbName); err != nil {
 		return err
 	}
@@ -106,8 +530,11 @@ func (d *Dumper) DumpAllTables(dbName string, wg *sync.WaitGroup) error {
 	if err != nil {
 		return fmt.Errorf("list tables: %w", err)
 	}
+	if tables, err = d.matchTables(tables); err != nil {
+		return err
+	}
 
-	return d.Dump(dbName, wg, tables...)
+	return d.DumpContext(ctx, dbName, wg, tables...)
 }
 
 func (d *Dumper) isPQ() bool {
@@ -121,9 +548,10 @@ func (d *Dumper) use(db string) error {
 
 	if db != "" {
 		// Use the database
-		if _, err := d.db.Exec("USE `" + db + "`"); err != nil {
+		if _, err := d.db.ExecContext(d.context(), stmtUseDatabase(db)); err != nil {
 			return fmt.Errorf("use database: %w", err)
 		}
+		d.dbName = db
 	}
 
 	return nil
@@ -133,11 +561,11 @@ func (d *Dumper) getTables(dbName string) ([]string, error) {
 	tables := make([]string, 0)
 
 	// Get table list
-	q := "SHOW TABLES where Tables_in_mygpstracker not like 'gs_tracker_data%';"
+	q := stmtListTables
 	if d.isPQ() {
-		q = "SELECT table_name FROM information_schema.tables WHERE table_schema='public' AND table_type='BASE TABLE' ORDER BY table_name;"
+		q = stmtListTablesPQ
 	}
-	rows, err := d.db.Query(q)
+	rows, err := d.db.QueryContext(d.context(), q)
 	if err != nil {
 		return tables, err
 	}
@@ -154,54 +582,214 @@ func (d *Dumper) getTables(dbName string) ([]string, error) {
 	return tables, rows.Err()
 }
 
-func getServerVersion(db *sql.DB) (string, error) {
+func getServerVersion(ctx context.Context, db *sql.DB) (string, error) {
 	var server_version sql.NullString
-	if err := db.QueryRow("SELECT version()").Scan(&server_version); err != nil {
+	if err := db.QueryRowContext(ctx, stmtServerVersion).Scan(&server_version); err != nil {
 		return "", err
 	}
 	return server_version.String, nil
 }
 
-func (d *Dumper) writeTable(name string, schema string, wg *sync.WaitGroup) error {
-	var err error
+// prepareTable reads the header of a table and the units its rows are read in. The header is nil
+// if the table is skipped, and the policy EngineSkipData if its rows are, by its engine or the filters.
+func (d *Dumper) prepareTable(name string, schema string) (*binary.TableHeader, []tableUnit, EnginePolicy, error) {
+	meta, err := d.getTableMeta(name, schema)
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("get table type: %w", err)
+	}
+	policy := d.enginePolicy(name, meta.engine)
+	if policy == EngineSkip {
+		return nil, nil, policy, nil
+	}
+	var noData *binary.NoData
+	if policy == EngineSkipData {
+		noData = &binary.NoData{Reason: binary.NoDataEngine, Detail: meta.engine}
+	} else if filters, ok := d.tableFilters(name); ok && len(filters) == 0 {
+		policy = EngineSkipData
+		noData = &binary.NoData{Reason: binary.NoDataFilter}
+	}
 
-	sql, err := d.getTableSQL(d.db, name)
+	sql, err := d.getTableSQL(d.db, name, meta.typ)
+	reconstructed := false
+	if isPermissionError(err) && meta.typ != TableTypeSequence {
+		// Accounts allowed to read the rows may still be denied SHOW CREATE TABLE
+		rebuilt, rerr := d.reconstructTableSQL(name, schema, meta.engine)
+		if rerr != nil {
+			return nil, nil, 0, fmt.Errorf("get table SQL: %w (reconstructing it: %s)", err, rerr)
+		}
+		d.warn(WarningReconstructedDDL, name, "Can't read the DDL of table %s, reconstructed it from INFORMATION_SCHEMA: %s", name, err)
+		sql, err, reconstructed = rebuilt, nil, true
+	}
 	if err != nil {
-		return fmt.Errorf("get table SQL: %w", err)
+		return nil, nil, 0, fmt.Errorf("get table SQL: %w", err)
+	}
+	var constraints []string
+	if meta.typ != TableTypeSequence {
+		sql = applyDDL(sql, d.ddlTransforms)
+		sql, constraints = splitConstraints(name, sql, d.cyclicFKs[name])
 	}
 
-	cols, err := d.getTableColumns(d.db, name, schema)
+	cols, err := d.getSelectColumns(name, schema, meta)
+	if err == nil && cols == nil {
+		cols, err = d.getTableColumns(d.db, name, schema)
+	}
 	if err != nil {
-		return fmt.Errorf("get table columns: %w", err)
+		return nil, nil, 0, fmt.Errorf("get table columns: %w", err)
 	}
 
-	d.bin.WriteTableHeader(&binary.TableHeader{
+	units := []tableUnit{{}}
+	var binaryCols []string
+	if policy != EngineSkipData {
+		if units, err = d.tableUnits(name, schema); err != nil {
+			return nil, nil, 0, err
+		}
+		if meta.typ != TableTypeSequence {
+			if binaryCols, err = d.tableBinaryColumns(name); err != nil {
+				return nil, nil, 0, fmt.Errorf("get column types: %w", err)
+			}
+		}
+	}
+
+	header := &binary.TableHeader{
 		Name:      name,
 		CreateSQL: sql,
 		Columns:   cols,
-	})
+		Type:      meta.typ,
+		Engine:    meta.engine,
 
+		BinaryColumns:    binaryCols,
+		Constraints:      constraints,
+		ReconstructedDDL: reconstructed,
+		NoData:           noData,
+	}
+	if meta.typ == TableTypeSystemVersioned {
+		header.SystemTime = d.systemTime.clause()
+	}
+	if policy != EngineSkipData {
+		key, err := d.getChunkKey(name, schema)
+		if err != nil {
+			return nil, nil, 0, fmt.Errorf("get chunk key: %w", err)
+		}
+		header.Chunking, header.ChunkKey = key.strategy, key.columns
+		if key.strategy == ChunkFullScan {
+			d.warn(WarningNoChunkKey, name, "Table %s has no primary key or unique NOT NULL index, reading it in a single query", name)
+		}
+		if d.splitsColumns(cols, key) {
+			units = d.groupUnits(units, cols, key.columns)
+			header.ColumnGroups = units[len(units)-1].group
+		}
+	}
+	d.readOptimizerStats(header, schema)
 	logrus.Infof("Read table information for %s", name)
-	if err = d.writeTableValues(name, schema, wg); err != nil {
-		return fmt.Errorf("write table rows: %w", err)
+	return header, units, policy, nil
+}
+
+func (d *Dumper) writeTable(name string, schema string, wg *sync.WaitGroup) error {
+	header, units, policy, err := d.prepareTable(name, schema)
+	if err != nil || header == nil {
+		return err
 	}
+	d.tableStart = time.Now()
+	// The first section of a table dumped again replaces those written of it before
+	header.Restarted = d.restarting == name
 
+	if policy == EngineSkipData {
+		if err = d.writeTableHeader(header); err != nil {
+			return fmt.Errorf("write table header: %w", err)
+		}
+		d.cur.Table = name
+		d.cur.Partition = ""
+		d.cur.Shard = 0
+		d.cur.Chunk = 0
+		d.cur.Rows = 0
+		d.cur.Bytes = 0
+		d.cur.EstimatedRows = 0
+		d.cur.EstimatedChunks = 0
+		d.cur.TableDone = true
+		d.emitProgress()
+		return nil
+	}
+	d.cur.Table = name
+	d.cur.Chunk = 0
+	d.cur.Rows = 0
+	d.cur.Bytes = 0
+	d.cur.EstimatedRows = 0
+	d.cur.EstimatedChunks = 0
+	d.cur.TableDone = false
+	if p := d.profiles[name]; p != nil {
+		d.cur.EstimatedRows, d.cur.EstimatedChunks = p.Rows, p.Chunks
+	} else if d.reportsProgress() {
+		d.cur.EstimatedRows = d.estimatedRows(name, schema)
+	}
+
+	at, err := d.resumeAt(name, units)
+	if err != nil {
+		return err
+	}
+	cols := header.Columns
+	for i, u := range units {
+		if at != nil && i < at.Unit {
+			continue
+		}
+		if i > 0 && d.isInterrupted() {
+			d.checkpoint.Unit = i
+			d.checkpoint.Partition = u.partition
+			d.checkpoint.Shard = u.shard
+			d.checkpoint.ColumnGroup = u.group
+			d.checkpoint.Filter = 0
+			d.checkpoint.Offset = 0
+			d.checkpoint.After = nil
+			return ErrInterrupted
+		}
+
+		d.setUnitHeader(header, cols, u)
+		if at != nil && i == at.Unit && d.resume.checkpoint != nil {
+			// The dump resumed holds the first chunks of the table
+			header.Continued = true
+			err = d.writeTableHeader(header)
+			header.Continued = false
+		} else if at != nil && i == at.Unit {
+			// The output holds the header and the first chunks of the unit already
+			d.infoTable, d.infoHeader = d.resume.table, d.resume.section
+		} else {
+			err = d.writeTableHeader(header)
+		}
+		if err != nil {
+			return fmt.Errorf("write table header: %w", err)
+		}
+		header.Restarted = false
+		d.journalUnit = i
+		if err = d.writeTableValues(name, u, header.Columns, schema, wg); err != nil {
+			var limit *tableLimitError
+			if errors.As(err, &limit) && limit.breach.Action == LimitSkip {
+				break
+			}
+			return fmt.Errorf("write table rows: %w", err)
+		}
+	}
+
+	d.reportRules(name)
+	d.cur.Partition = ""
+	d.cur.Shard = 0
+	d.cur.TableDone = true
+	d.emitProgress()
 	return nil
 }
 
-func (d *Dumper) getTableSQL(db *sql.DB, name string) (string, error) {
+func (d *Dumper) getTableSQL(db *sql.DB, name string, typ string) (string, error) {
 	if d.isPQ() {
 		return "-- DUMMY", nil
 	}
+
 	// Get table creation SQL
 	var table_return sql.NullString
 	var table_sql sql.NullString
-	err := db.QueryRow("SHOW CREATE TABLE "+name).Scan(&table_return, &table_sql)
+	err := db.QueryRowContext(d.context(), stmtShowCreate(typ, name)).Scan(&table_return, &table_sql)
 
 	if err != nil {
 		return "", err
 	}
-	if table_return.String != name {
+	if !d.sameTableName(table_return.String, name) {
 		return "", errors.New("returned table is not the same as requested table")
 	}
 
@@ -209,16 +797,19 @@ func (d *Dumper) getTableSQL(db *sql.DB, name string) (string, error) {
 }
 
 func (d *Dumper) getTableColumns(db *sql.DB, table string, schema string) (cols []string, err error) {
-	sq := "SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_NAME = ? AND TABLE_SCHEMA = ?"
-	args := []interface{}{table, schema}
+	if d.legacyMetadata() {
+		return showColumns(d.context(), db, table, schema)
+	}
+
+	sq, args := stmtTableColumns, []interface{}{table, schema}
 	if d.isPQ() {
-		sq = "SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_NAME = $1 AND TABLE_SCHEMA = 'public'"
-		args = []interface{}{table}
+		sq, args = stmtTableColumnsPQ, []interface{}{table}
 	}
-	rows, err := db.Query(sq, args...)
+	rows, err := db.QueryContext(d.context(), sq, args...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	var column string
 	for rows.Next() {
@@ -233,80 +824,520 @@ func (d *Dumper) getTableColumns(db *sql.DB, table string, schema string) (cols
 	return
 }
 
-func (d *Dumper) writeTableValues(name string, schema string, wg *sync.WaitGroup) error {
-	var queries = []string{""}
-	if fs, ok := filteredTables[schema]; ok {
-		if q, ok := fs[name]; ok {
-			queries = q
+// getSelectColumns returns the columns a table's rows are read with, or nil to read them with SELECT *.
+func (d *Dumper) getSelectColumns(name string, schema string, meta tableMeta) ([]string, error) {
+	// SELECT * leaves out invisible columns, which then have to be listed
+	s, _ := d.server()
+	versioned := meta.typ == TableTypeSystemVersioned
+	if !versioned && !s.supports(featureInvisibleColumns) {
+		return nil, nil
+	}
+
+	rows, err := d.db.QueryContext(d.context(), stmtSelectColumns, name, schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cols []string
+	invisible, period := false, false
+	for rows.Next() {
+		var c, extra, gen string
+		if err = rows.Scan(&c, &extra, &gen); err != nil {
+			return nil, err
+		}
+
+		// The row start and end columns are generated by the server and can only be restored along
+		// with the history, leave them out otherwise
+		extra, gen = strings.ToUpper(extra), strings.ToUpper(gen)
+		if versioned && (strings.Contains(extra, "ROW START") || strings.Contains(extra, "ROW END") || gen == "ROW START" || gen == "ROW END") {
+			period = true
+			if !d.systemTime.All {
+				continue
+			}
+		}
+		invisible = invisible || strings.Contains(extra, "INVISIBLE")
+		cols = append(cols, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	// Implicit period columns aren't listed, but can be read by their pseudo-column names
+	if versioned && !period && d.systemTime.All {
+		cols = append(cols, "ROW_START", "ROW_END")
+	}
+
+	if !versioned && !invisible {
+		return nil, nil
+	}
+	return cols, nil
+}
+
+// selectExpr returns what to SELECT from a table to read its rows.
+func (d *Dumper) selectExpr(name string, schema string) (string, error) {
+	if d.isPQ() {
+		return "*", nil
+	}
+	if s, _ := d.server(); !s.supports(featureInvisibleColumns) && !s.supports(featureSystemVersioning) {
+		return "*", nil
+	}
+
+	meta, err := d.getTableMeta(name, schema)
+	if err != nil {
+		return "", fmt.Errorf("get table type: %w", err)
+	}
+	cols, err := d.getSelectColumns(name, schema, meta)
+	if err != nil {
+		return "", fmt.Errorf("get table columns: %w", err)
+	}
+	if cols == nil {
+		return "*", nil
+	}
+	return quoteColumns(cols, false), nil
+}
+
+func (d *Dumper) writeTableValues(name string, unit tableUnit, columns []string, schema string, wg *sync.WaitGroup) error {
+	d.checkpoint.Table = name
+	d.checkpoint.Unit = d.journalUnit
+	d.checkpoint.Partition = unit.partition
+	d.checkpoint.Shard = unit.shard
+	d.checkpoint.ColumnGroup = unit.group
+	d.checkpoint.Filter = 0
+	d.checkpoint.Offset = 0
+	d.checkpoint.After = nil
+
+	d.cur.Partition = unit.partition
+	d.cur.Shard = unit.shard
+	d.emitProgress()
+
+	err := d.readTableValues(name, unit, columns, schema, wg, func(row binary.RowData) error {
+		size := int64(binary.RowSize(row))
+		d.cur.Rows++
+		d.cur.Bytes += size
+		d.cur.TotalRows++
+		d.cur.TotalBytes += size
+		if d.cur.Rows%progressRows == 0 {
+			d.emitProgress()
 		}
+
+		return d.writeRow(row)
+	})
+	return err
+}
+
+// tableQuery builds the queries reading the rows of a table unit, chunk by chunk.
+type tableQuery struct {
+	// WHERE clauses the rows are read with, one after the other
+	filters   []string
+	sel       string
+	from      string
+	chunkSize int
+	// Columns the chunks are ordered by, and whether rows read in a single query are ordered too
+	order  string
+	sorted bool
+	pq     bool
+	// Columns of the header of the unit, in the order rows are written in, see columnOrder
+	columns []string
+
+	// Key columns each chunk is read after the last row of the previous one by, nil to read chunks by
+	// offset, their position in the rows once known, and the key of the last row read
+	key   []string
+	keyAt []int
+	after []interface{}
+}
+
+func (d *Dumper) newTableQuery(name string, unit tableUnit, schema string) (*tableQuery, error) {
+	tq := &tableQuery{filters: []string{""}, from: quoteIdent(name, d.isPQ()), chunkSize: d.chunkSize, pq: d.isPQ()}
+	if q, ok := d.tableFilters(name); ok {
+		tq.filters = q
+	}
+	if unit.where != "" {
+		filtered := make([]string, len(tq.filters))
+		for i, q := range tq.filters {
+			filtered[i] = andWhere(q, unit.where)
+		}
+		tq.filters = filtered
+	}
+
+	var err error
+	if unit.columns != nil {
+		tq.sel = quoteColumns(unit.columns, tq.pq)
+	} else if tq.sel, err = d.selectExpr(name, schema); err != nil {
+		return nil, err
+	}
+
+	if unit.partition != "" {
+		tq.from += " PARTITION (" + quoteName(unit.partition) + ")"
+	}
+	st, err := d.systemTimeClause(name, schema)
+	if err != nil {
+		return nil, err
+	}
+	if st != "" {
+		tq.from += " FOR SYSTEM_TIME " + st
+	}
+
+	key, err := d.getChunkKey(name, schema)
+	if err != nil {
+		return nil, fmt.Errorf("get chunk key: %w", err)
+	}
+	if key.strategy == ChunkFullScan {
+		tq.chunkSize = 0
+	}
+	tq.order = quoteColumns(key.columns, tq.pq)
+	tq.key = key.columns
+	if d.orderRows && tq.chunkSize <= 0 {
+		// Rows read in a single query are ordered like chunks, see WithOrderByPrimaryKey
+		if key.columns == nil {
+			if key, err = d.tableKey(name, schema); err != nil {
+				return nil, fmt.Errorf("get primary key: %w", err)
+			}
+		}
+		tq.order, tq.sorted = quoteColumns(key.columns, tq.pq), true
+	}
+	return tq, nil
+}
+
+// chunk returns the query reading the chunk of rows matching filter at offset, or all of them without chunking.
+// Once a row was read, chunks start after its key instead, which neither scans the rows before the chunk
+// nor skips or repeats rows when rows before it are inserted or deleted meanwhile.
+func (tq *tableQuery) chunk(filter string, offset int) (string, []interface{}) {
+	q := "SELECT " + tq.sel + " FROM " + tq.from
+	if tq.chunkSize <= 0 {
+		order := tq.order
+		if order == "" && tq.sorted {
+			// Without a key the rows are ordered by all their columns
+			order = quoteColumns(tq.columns, tq.pq)
+		}
+		if tq.sorted && order != "" {
+			return q + filter + " ORDER BY " + order, nil
+		}
+		return q + filter, nil
+	}
+	if tq.after != nil {
+		cond, args := tq.seek()
+		args = append(args, tq.chunkSize)
+		return q + andWhere(filter, cond) + " ORDER BY " + tq.order + " LIMIT " + tq.param(len(args)), args
+	}
+	return q + filter + " ORDER BY " + tq.order + " LIMIT " + tq.param(1) + " OFFSET " + tq.param(2), []interface{}{tq.chunkSize, offset}
+}
+
+// seek returns the condition matching the rows whose key comes after the last one read, (a, b) > (x, y)
+// written out as a > x OR (a = x AND b > y) which every server reads by index.
+func (tq *tableQuery) seek() (string, []interface{}) {
+	var or []string
+	var args []interface{}
+	for i := range tq.key {
+		var and []string
+		for j := 0; j <= i; j++ {
+			op := " = "
+			if j == i {
+				op = " > "
+			}
+			args = append(args, tq.after[j])
+			and = append(and, quoteColumns(tq.key[j:j+1], tq.pq)+op+tq.param(len(args)))
+		}
+		or = append(or, "("+strings.Join(and, " AND ")+")")
+	}
+	return "(" + strings.Join(or, " OR ") + ")", args
+}
+
+// param returns the placeholder of the nth argument of a query.
+func (tq *tableQuery) param(n int) string {
+	if tq.pq {
+		return fmt.Sprintf("$%d", n)
+	}
+	return "?"
+}
+
+// seen records the key of a row as read from the server, the next chunk starting after the last one.
+// Tables whose key columns aren't all read are read by offset. Nil queries record nothing.
+func (tq *tableQuery) seen(columns []string, row binary.RowData) {
+	if tq == nil || tq.chunkSize <= 0 || tq.key == nil {
+		return
+	}
+	if tq.keyAt == nil {
+		for _, k := range tq.key {
+			i := indexFold(columns, k)
+			if i < 0 {
+				tq.key = nil
+				return
+			}
+			tq.keyAt = append(tq.keyAt, i)
+		}
+	}
+
+	after := make([]interface{}, len(tq.keyAt))
+	for i, c := range tq.keyAt {
+		if row[c] != nil {
+			after[i] = *row[c]
+		}
+	}
+	tq.after = after
+}
+
+// restart has the rows of the next filter read from the start.
+func (tq *tableQuery) restart() {
+	tq.after = nil
+}
+
+func indexFold(list []string, s string) int {
+	for i, v := range list {
+		if strings.EqualFold(v, s) {
+			return i
+		}
+	}
+	return -1
+}
+
+// readTableValues reads every row of a table that is part of the dump and passes it to fn.
+// If unit is set, only the rows of that partition and shard are read. Rows are passed in the order of
+// columns, if set, see columnOrder.
+func (d *Dumper) readTableValues(name string, unit tableUnit, columns []string, schema string, wg *sync.WaitGroup, fn func(binary.RowData) error) error {
+	tq, err := d.newTableQuery(name, unit, schema)
+	if err != nil {
+		return err
+	}
+	tq.columns = columns
+	chunkSize := tq.chunkSize
+	read := d.readChunk
+	if d.outfile != nil && !d.isPQ() {
+		read = d.readOutfileChunk
 	}
-	for _, filter := range queries {
+	prefetch := d.prefetch && chunkSize > 0 && d.outfile == nil
+
+	// Chunk read ahead while the previous one is written, waited for before returning
+	var next <-chan prefetchedChunk
+	defer func() {
+		if next != nil {
+			<-next
+		}
+	}()
+
+	start := d.resumeStart(name)
+	for fi, filter := range tq.filters {
 		offset := 0
+		tq.restart()
+		if start != nil {
+			// Rows of a resumed dump continue after the last journaled chunk
+			if fi < start.Filter {
+				continue
+			}
+			offset, tq.after = start.Offset, start.After
+			start = nil
+		}
 
 		for {
-			gotData := false
+			if d.isInterrupted() && (fi > 0 || offset > 0) {
+				d.checkpoint.Filter = fi
+				d.checkpoint.Offset = offset
+				d.checkpoint.After = tq.after
+				return ErrInterrupted
+			}
+			if fi > 0 || offset > 0 {
+				if err = d.checkTableLimit(name, d.tableStart, d.cur.Rows, d.cur.Bytes); err != nil {
+					return err
+				}
+			}
+
 			wg.Wait()
 			// Get Data
 			logrus.Infof("Reading row data for table %s, offset = %d", name, offset)
-			var rows *sql.Rows
-			var err error
-			if d.chunkSize > 0 {
-				q := "SELECT * FROM " + name + filter + " ORDER BY 1 LIMIT ? OFFSET ?"
-				if d.isPQ() {
-					q = "SELECT * FROM " + name + filter + " ORDER BY 1 LIMIT $1 OFFSET $2"
+			q, args := tq.chunk(filter, offset)
+			logrus.Debugf(q, args...)
+
+			var gotData bool
+			// Key of the last row of the chunk, before the next one is read ahead
+			var after []interface{}
+			if prefetch {
+				if next == nil {
+					next = d.prefetchChunk(name, tq, q, args)
+				}
+				c := <-next
+				next = nil
+				if c.err != nil {
+					return c.err
 				}
-				logrus.Debugf(q, d.chunkSize, offset)
-				rows, err = d.db.Query(q, d.chunkSize, offset)
+				tq.takeCursor(c)
+				after = tq.after
+
+				// Read the next chunk while this one is written
+				gotData = len(c.rows) > 0
+				if gotData {
+					nq, nargs := tq.chunk(filter, offset+chunkSize)
+					next = d.prefetchChunk(name, tq, nq, nargs)
+				}
+				for _, row := range c.rows {
+					if err = fn(row); err != nil {
+						return fmt.Errorf("write values: %w", err)
+					}
+				}
+			} else if gotData, err = read(name, tq, q, args, fn); err != nil {
+				return err
 			} else {
-				logrus.Debugf("SELECT * FROM " + name + filter)
-				rows, err = d.db.Query("SELECT * FROM " + name + filter)
+				after = tq.after
 			}
-			if err != nil {
-				return err
+
+			if err = d.flushQueue(); err != nil {
+				return fmt.Errorf("write values: %w", err)
 			}
+			d.cur.Chunk++
+			d.emitProgress()
 
-			// Get columns
-			columns, err := rows.Columns()
-			if err != nil {
-				rows.Close()
+			if !gotData || chunkSize <= 0 {
+				if err = d.journalChunk(name, unit, fi+1, 0, nil); err != nil {
+					return err
+				}
+				if err = d.checkpointChunk(fi+1, 0, nil); err != nil {
+					return err
+				}
+				break
+			}
+			if err = d.journalChunk(name, unit, fi, offset+chunkSize, after); err != nil {
 				return err
 			}
-			if len(columns) == 0 {
-				rows.Close()
-				return errors.New("no columns in table " + name + ".")
+			if err = d.checkpointChunk(fi, offset+chunkSize, after); err != nil {
+				return err
 			}
+			offset += chunkSize
+			logrus.Infof("Wrote row for table %s, next offset = %d", name, offset)
+		}
+	}
 
-			for rows.Next() {
-				gotData = true
-				if err = d.writeValues(rows, columns); err != nil {
-					rows.Close()
-					return fmt.Errorf("write values: %w", err)
-				}
-			}
+	return nil
+}
 
-			rows.Close()
+// readChunk runs the query reading a chunk of a table and passes its rows to fn, reporting whether there were any.
+func (d *Dumper) readChunk(name string, tq *tableQuery, q string, args []interface{}, fn func(binary.RowData) error) (bool, error) {
+	if d.bufferChunks(tq) {
+		return d.readBufferedChunk(name, tq, func() *sql.Conn { return d.conn }, d.reconnect, func() (*sql.Rows, error) {
+			return d.query(q, args...)
+		}, fn)
+	}
 
-			if !gotData || d.chunkSize <= 0 {
-				break
-			}
-			offset += d.chunkSize
-			logrus.Infof("Wrote row for table %s, next offset = %d", name, offset)
+	rows, err := d.queryChunk(q, args...)
+	if err != nil {
+		return false, err
+	}
+	return d.readRows(name, tq, rows, fn)
+}
+
+// bufferChunks reports whether the chunks of tq are read into memory before their rows are passed on,
+// so a chunk whose rows fail part way can be read again. The rows of a table read in a single query
+// aren't, as they may not fit.
+func (d *Dumper) bufferChunks(tq *tableQuery) bool {
+	return d.retry.Attempts > 0 && tq.chunkSize > 0
+}
+
+// readBufferedChunk reads the rows of a chunk into memory with query and then passes them to fn. The
+// chunk is read again from the start when the query or its rows fail with a transient error, the rows
+// read so far being dropped, see retryChunk.
+func (d *Dumper) readBufferedChunk(name string, tq *tableQuery, conn func() *sql.Conn, reconnect func() error, query func() (*sql.Rows, error), fn func(binary.RowData) error) (bool, error) {
+	var columns []string
+	var buf []binary.RowData
+	gotData := false
+	after := tq.after
+	err := d.retryChunk(conn, reconnect, func() error {
+		buf, tq.after = nil, after
+		rows, err := query()
+		if err != nil {
+			return err
 		}
+		gotData, err = d.scanRows(name, tq, rows, func(c []string, row binary.RowData) error {
+			columns = c
+			buf = append(buf, row)
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		return false, err
 	}
 
+	for _, row := range buf {
+		if err = d.passRow(name, columns, row, fn); err != nil {
+			return true, err
+		}
+	}
+	return gotData, nil
+}
+
+// readRows passes the rows of a chunk of tq to fn and closes them, reporting whether there were any.
+func (d *Dumper) readRows(name string, tq *tableQuery, rows *sql.Rows, fn func(binary.RowData) error) (bool, error) {
+	return d.scanRows(name, tq, rows, func(columns []string, row binary.RowData) error {
+		return d.passRow(name, columns, row, fn)
+	})
+}
+
+// passRow checks a row of a table against its rules and transforms it before passing it to fn.
+func (d *Dumper) passRow(name string, columns []string, row binary.RowData, fn func(binary.RowData) error) error {
+	d.checkRules(name, columns, row)
+	if d.transform != nil {
+		row = d.transform(name, columns, row)
+	}
+	if err := fn(row); err != nil {
+		return fmt.Errorf("write values: %w", err)
+	}
 	return nil
 }
 
-func (d *Dumper) writeValues(rows *sql.Rows, columns []string) error {
-	data := make([]*string, len(columns))
+// scanRows passes the rows of a chunk of tq to fn with their columns as they are read, recording the
+// key of the last one, and closes them. It reports whether there were any.
+func (d *Dumper) scanRows(name string, tq *tableQuery, rows *sql.Rows, fn func([]string, binary.RowData) error) (bool, error) {
+	defer rows.Close()
+
+	// Get columns
+	columns, err := rows.Columns()
+	if err != nil {
+		return false, err
+	}
+	if len(columns) == 0 {
+		return false, errors.New("no columns in table " + name + ".")
+	}
+	order, err := tq.columnOrder(name, columns)
+	if err != nil {
+		return false, err
+	}
+	if order != nil {
+		columns = tq.columns
+	}
+
+	gotData := false
+	for rows.Next() {
+		gotData = true
+		data, err := d.scanValues(rows, columns)
+		if err != nil {
+			return gotData, fmt.Errorf("scan values: %w", err)
+		}
+		data = reorderRow(data, order)
+		tq.seen(columns, data)
+		if err = fn(columns, data); err != nil {
+			return gotData, err
+		}
+	}
+	// A connection dropped in the middle of the rows ends them as if the chunk was complete
+	return gotData, rows.Err()
+}
+
+// scanValues reads the values of a row as the bytes the server sent, so binary values are kept as they are.
+func (d *Dumper) scanValues(rows *sql.Rows, columns []string) (binary.RowData, error) {
+	raw := make([]sql.RawBytes, len(columns))
 	ptrs := make([]interface{}, len(columns))
-	for i := range data {
-		ptrs[i] = &data[i]
+	for i := range raw {
+		ptrs[i] = &raw[i]
 	}
 
 	// Read data
 	if err := rows.Scan(ptrs...); err != nil {
-		return err
+		return nil, err
+	}
+	// Raw bytes are only valid until the next scan
+	data := make([]*string, len(columns))
+	for i, b := range raw {
+		if b != nil {
+			s := string(b)
+			data[i] = &s
+		}
 	}
 	if d.isPQ() {
 		// typecheck for bool
@@ -317,7 +1348,7 @@ func (d *Dumper) writeValues(rows *sql.Rows, columns []string) error {
 		}
 		// Read data
 		if err := rows.Scan(tptrs...); err != nil {
-			return err
+			return nil, err
 		}
 
 		for i, dd := range tdata {
@@ -336,5 +1367,29 @@ func (d *Dumper) writeValues(rows *sql.Rows, columns []string) error {
 		}
 	}
 
-	return d.bin.WriteRowData(data)
+	d.countRead(data)
+	return data, nil
+}
+
+// ListTables returns the tables DumpAllTables would dump.
+func (d *Dumper) ListTables(dbName string) ([]string, error) {
+	if err := d.use(dbName); err != nil {
+		return nil, err
+	}
+
+	return d.getTables(dbName)
+}
+
+// TableColumns returns the column names of a table.
+func (d *Dumper) TableColumns(dbName string, table string) ([]string, error) {
+	return d.getTableColumns(d.db, table, dbName)
+}
+
+// CheckFilter makes sure a table filter is valid SQL by running it without fetching any rows.
+func (d *Dumper) CheckFilter(table string, filter string) error {
+	rows, err := d.db.QueryContext(d.context(), stmtCheckFilter(table, filter, d.isPQ()))
+	if err != nil {
+		return err
+	}
+	return rows.Close()
 }
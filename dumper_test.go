@@ -0,0 +1,79 @@
+package mysqldump
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAndWhere(t *testing.T) {
+	tests := []struct {
+		filter, cond, want string
+	}{
+		{"", "", ""},
+		{" WHERE a = 1", "", " WHERE a = 1"},
+		{"", "b > 2", " WHERE b > 2"},
+		{" WHERE a = 1 OR a = 3", "b > 2", " WHERE (a = 1 OR a = 3) AND b > 2"},
+		{" where a = 1", "b > 2", " WHERE (a = 1) AND b > 2"},
+	}
+	for _, tt := range tests {
+		if got := andWhere(tt.filter, tt.cond); got != tt.want {
+			t.Errorf("andWhere(%q, %q) = %q, want %q", tt.filter, tt.cond, got, tt.want)
+		}
+	}
+}
+
+func TestSeek(t *testing.T) {
+	tq := &tableQuery{key: []string{"a", "b"}, after: []interface{}{"1", "x"}}
+	cond, args := tq.seek()
+	if want := "((`a` > ?) OR (`a` = ? AND `b` > ?))"; cond != want {
+		t.Errorf("seek = %q, want %q", cond, want)
+	}
+	if want := []interface{}{"1", "1", "x"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("seek arguments %q, want %q", args, want)
+	}
+
+	tq.pq = true
+	if cond, _ = tq.seek(); cond != `(("a" > $1) OR ("a" = $2 AND "b" > $3))` {
+		t.Errorf("PostgreSQL seek = %q", cond)
+	}
+}
+
+func TestChunkAfterKey(t *testing.T) {
+	columns := []string{"ID", "name"}
+	tq := &tableQuery{sel: "*", from: "`t`", chunkSize: 100, order: "`id`", key: []string{"id"}, columns: columns}
+
+	q, args := tq.chunk(" WHERE name <> ''", 0)
+	if want := "SELECT * FROM `t` WHERE name <> '' ORDER BY `id` LIMIT ? OFFSET ?"; q != want {
+		t.Errorf("first chunk %q, want %q", q, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{100, 0}) {
+		t.Errorf("first chunk arguments %v", args)
+	}
+
+	tq.seen(columns, stringRow("42", "ann"))
+	q, args = tq.chunk(" WHERE name <> ''", 100)
+	if want := "SELECT * FROM `t` WHERE (name <> '') AND ((`id` > ?)) ORDER BY `id` LIMIT ?"; q != want {
+		t.Errorf("next chunk %q, want %q", q, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"42", 100}) {
+		t.Errorf("next chunk arguments %v", args)
+	}
+
+	// The next filter starts over
+	tq.restart()
+	if q, _ = tq.chunk("", 0); q != "SELECT * FROM `t` ORDER BY `id` LIMIT ? OFFSET ?" {
+		t.Errorf("chunk after restart %q", q)
+	}
+}
+
+func TestChunkWithoutKeyColumns(t *testing.T) {
+	// The key isn't among the columns read, chunks are read by offset
+	tq := &tableQuery{sel: "`name`", from: "`t`", chunkSize: 100, order: "`id`", key: []string{"id"}}
+	tq.seen([]string{"name"}, stringRow("ann"))
+	if tq.key != nil {
+		t.Errorf("key %v, want none", tq.key)
+	}
+	if q, args := tq.chunk("", 100); q != "SELECT `name` FROM `t` ORDER BY `id` LIMIT ? OFFSET ?" || !reflect.DeepEqual(args, []interface{}{100, 100}) {
+		t.Errorf("chunk %q with %v, want by offset", q, args)
+	}
+}
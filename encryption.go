@@ -0,0 +1,339 @@
+package mysqldump
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/MouseHatGames/go-mysqldump/internal/marshal"
+)
+
+// ErrEncrypted is returned reading an encrypted dump without decrypting it, see NewDecryptReader.
+var ErrEncrypted = marshal.ErrEncrypted
+
+// EncryptedMagic starts encrypted dumps.
+const EncryptedMagic = marshal.EncryptedMagic
+
+// Encrypted dumps start with the magic, the version and the data key wrapped by the key the dump was
+// encrypted with. The segments of the dump follow, each a flag marking the last one, the length of the
+// sealed segment and the AES-256-GCM sealed plaintext, with the flag as additional data so the dump
+// can't be cut short unnoticed.
+const (
+	encryptionVersion = 1
+	encryptionKeySize = 32
+	segmentSize       = 64 << 10
+	wrappedKeySize    = 12 + encryptionKeySize + 16
+	envelopeSize      = len(marshal.EncryptedMagic) + 1 + wrappedKeySize
+)
+
+// WithEncryption encrypts the output with AES-256-GCM under a random data key, which is stored at the
+// start of the output wrapped by key, 32 bytes. Compressed output is compressed before it is encrypted.
+// Readers decrypt dumps with NewDecryptReader, and RewrapKey and RotateKey rotate the key.
+func WithEncryption(key []byte) Option {
+	return func(d *Dumper) {
+		d.encryptionKey = key
+	}
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != encryptionKeySize {
+		return nil, fmt.Errorf("encryption keys are %d bytes, not %d", encryptionKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// writeEnvelope writes the start of an encrypted dump, wrapping dataKey with key.
+func writeEnvelope(w io.Writer, key []byte, dataKey []byte) error {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return err
+	}
+
+	b := append([]byte(marshal.EncryptedMagic), encryptionVersion)
+	b = append(b, nonce...)
+	b = gcm.Seal(b, nonce, dataKey, []byte(marshal.EncryptedMagic))
+	_, err = w.Write(b)
+	return err
+}
+
+// readEnvelope reads the start of an encrypted dump, returning its data key unwrapped with key.
+func readEnvelope(r io.Reader, key []byte) ([]byte, error) {
+	b := make([]byte, envelopeSize)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, fmt.Errorf("read encryption header: %w", err)
+	}
+	if string(b[:4]) != marshal.EncryptedMagic {
+		return nil, errors.New("dump isn't encrypted")
+	}
+	if b[4] != encryptionVersion {
+		return nil, fmt.Errorf("unsupported encryption version %d", b[4])
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	wrapped := b[5:]
+	dataKey, err := gcm.Open(nil, wrapped[:gcm.NonceSize()], wrapped[gcm.NonceSize():], []byte(marshal.EncryptedMagic))
+	if err != nil {
+		return nil, errors.New("wrong encryption key")
+	}
+	return dataKey, nil
+}
+
+// encryptWriter encrypts what is written to it segment by segment.
+type encryptWriter struct {
+	w    io.Writer
+	gcm  cipher.AEAD
+	buf  []byte
+	seq  uint64
+	done bool
+}
+
+// newEncryptWriter starts an encrypted dump under a new data key wrapped by key.
+func newEncryptWriter(w io.Writer, key []byte) (*encryptWriter, error) {
+	dataKey := make([]byte, encryptionKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	if err := writeEnvelope(w, key, dataKey); err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptWriter{w: w, gcm: gcm, buf: make([]byte, 0, segmentSize)}, nil
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(e.buf)+len(p) > segmentSize {
+		k := segmentSize - len(e.buf)
+		e.buf = append(e.buf, p[:k]...)
+		p = p[k:]
+		if err := e.seal(false); err != nil {
+			return n - len(p), err
+		}
+	}
+	e.buf = append(e.buf, p...)
+	return n, nil
+}
+
+// Flush writes what is buffered as a segment of its own.
+func (e *encryptWriter) Flush() error {
+	if len(e.buf) == 0 || e.done {
+		return nil
+	}
+	return e.seal(false)
+}
+
+// Close writes the last segment. The dump can't be written to afterwards.
+func (e *encryptWriter) Close() error {
+	if e.done {
+		return nil
+	}
+	e.done = true
+	return e.seal(true)
+}
+
+// seal writes the buffered plaintext as a segment.
+func (e *encryptWriter) seal(last bool) error {
+	flag := []byte{0}
+	if last {
+		flag[0] = 1
+	}
+	nonce := make([]byte, e.gcm.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], e.seq)
+	e.seq++
+
+	b := append(flag, 0, 0, 0, 0)
+	b = e.gcm.Seal(b, nonce, e.buf, flag)
+	binary.LittleEndian.PutUint32(b[1:5], uint32(len(b)-5))
+	e.buf = e.buf[:0]
+	_, err := e.w.Write(b)
+	return err
+}
+
+// decryptReader decrypts the segments of an encrypted dump.
+type decryptReader struct {
+	r    io.Reader
+	gcm  cipher.AEAD
+	buf  []byte
+	seq  uint64
+	done bool
+}
+
+// NewDecryptReader returns a reader of the decrypted contents of an encrypted dump, to pass to the
+// Loader, Inspect, the converters or the Reader of the binary package. key is the one the dump was
+// encrypted with, see WithEncryption.
+func NewDecryptReader(r io.Reader, key []byte) (io.Reader, error) {
+	dataKey, err := readEnvelope(r, key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptReader{r: bufio.NewReader(r), gcm: gcm}, nil
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+// open reads and decrypts the next segment.
+func (d *decryptReader) open() error {
+	head := make([]byte, 5)
+	if _, err := io.ReadFull(d.r, head); errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return errors.New("encrypted dump is truncated")
+	} else if err != nil {
+		return err
+	}
+	size := binary.LittleEndian.Uint32(head[1:])
+	if size > segmentSize+uint32(d.gcm.Overhead()) {
+		return errors.New("encrypted dump is corrupt")
+	}
+
+	sealed := make([]byte, size)
+	if _, err := io.ReadFull(d.r, sealed); err != nil {
+		return errors.New("encrypted dump is truncated")
+	}
+	nonce := make([]byte, d.gcm.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], d.seq)
+	d.seq++
+
+	plain, err := d.gcm.Open(sealed[:0], nonce, sealed, head[:1])
+	if err != nil {
+		return errors.New("encrypted dump is corrupt")
+	}
+	d.buf, d.done = plain, head[0] == 1
+	return nil
+}
+
+// RewrapKey copies an encrypted dump from in to out with its data key wrapped by newKey instead of
+// oldKey, rotating the key without decrypting anything: the encrypted rows are copied as they are.
+func RewrapKey(in io.Reader, out io.Writer, oldKey []byte, newKey []byte) error {
+	dataKey, err := readEnvelope(in, oldKey)
+	if err != nil {
+		return err
+	}
+	if err = writeEnvelope(out, newKey, dataKey); err != nil {
+		return fmt.Errorf("write encryption header: %w", err)
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		return fmt.Errorf("copy dump: %w", err)
+	}
+	return nil
+}
+
+// RewrapFile rewraps the data key of an encrypted dump file with newKey in place, see RewrapKey. The
+// wrapped key has a fixed size, so only the start of the file is rewritten.
+func RewrapFile(path string, oldKey []byte, newKey []byte) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dataKey, err := readEnvelope(f, oldKey)
+	if err != nil {
+		return err
+	}
+	w := &offsetWriter{f: f}
+	if err = writeEnvelope(w, newKey, dataKey); err != nil {
+		return fmt.Errorf("write encryption header: %w", err)
+	}
+	return f.Sync()
+}
+
+// offsetWriter writes to a file from its start on, regardless of where it was read up to.
+type offsetWriter struct {
+	f   *os.File
+	off int64
+}
+
+func (w *offsetWriter) Write(p []byte) (int, error) {
+	n, err := w.f.WriteAt(p, w.off)
+	w.off += int64(n)
+	return n, err
+}
+
+// RotateKey re-encrypts an encrypted dump from in to out under a new data key wrapped by newKey,
+// decrypting and encrypting it segment by segment so the plaintext never reaches the disk.
+func RotateKey(in io.Reader, out io.Writer, oldKey []byte, newKey []byte) error {
+	dec, err := NewDecryptReader(in, oldKey)
+	if err != nil {
+		return err
+	}
+	enc, err := newEncryptWriter(out, newKey)
+	if err != nil {
+		return fmt.Errorf("start encryption: %w", err)
+	}
+	if _, err = io.Copy(enc, dec); err != nil {
+		return fmt.Errorf("re-encrypt dump: %w", err)
+	}
+	return enc.Close()
+}
+
+// startEncryption has the output encrypted, counting the encrypted bytes as written bytes.
+func (d *Dumper) startEncryption() error {
+	if d.encryptionKey == nil {
+		return nil
+	}
+	if d.format == FormatCSV || d.tableOpen != nil {
+		return errors.New("csv output and per-table files can't be encrypted")
+	}
+
+	ew, err := newEncryptWriter(&countingWriter{d.w, &d.writtenBytes}, d.encryptionKey)
+	if err != nil {
+		return fmt.Errorf("start encryption: %w", err)
+	}
+	d.encrypted = &streamOutput{out: d.w, zw: ew}
+	d.w = ew
+	d.resetWriter()
+	return nil
+}
+
+// endEncryption writes the last segment of the encrypted output.
+func (d *Dumper) endEncryption() error {
+	e := d.encrypted
+	if e == nil {
+		return nil
+	}
+
+	err := e.zw.Close()
+	d.encrypted = nil
+	d.w = e.out
+	d.resetWriter()
+	if err != nil {
+		return fmt.Errorf("encrypt output: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package mysqldump
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func testKey(t *testing.T) []byte {
+	key := make([]byte, encryptionKeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// encrypt encrypts plain under key, flushing a short segment half way.
+func encrypt(t *testing.T, key []byte, plain []byte) []byte {
+	var buf bytes.Buffer
+	ew, err := newEncryptWriter(&buf, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	half := len(plain) / 2
+	if _, err = ew.Write(plain[:half]); err != nil {
+		t.Fatal(err)
+	}
+	if err = ew.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ew.Write(plain[half:]); err != nil {
+		t.Fatal(err)
+	}
+	if err = ew.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func decrypt(enc []byte, key []byte) ([]byte, error) {
+	r, err := NewDecryptReader(bytes.NewReader(enc), key)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(r)
+}
+
+func testPlaintext(t *testing.T) []byte {
+	plain := make([]byte, 2*segmentSize+1234)
+	if _, err := rand.Read(plain); err != nil {
+		t.Fatal(err)
+	}
+	return plain
+}
+
+func TestEncryptionRoundTrip(t *testing.T) {
+	key := testKey(t)
+	for _, size := range []int{0, 1, segmentSize, 2*segmentSize + 1234} {
+		plain := testPlaintext(t)[:size]
+		got, err := decrypt(encrypt(t, key, plain), key)
+		if err != nil {
+			t.Errorf("%d bytes: %s", size, err)
+		} else if !bytes.Equal(got, plain) {
+			t.Errorf("%d bytes: decrypted %d bytes that differ", size, len(got))
+		}
+	}
+}
+
+func TestEncryptionWrongKey(t *testing.T) {
+	enc := encrypt(t, testKey(t), testPlaintext(t))
+	if _, err := decrypt(enc, testKey(t)); err == nil {
+		t.Error("decrypted with the wrong key")
+	}
+	if _, err := decrypt(enc, make([]byte, 16)); err == nil {
+		t.Error("decrypted with a short key")
+	}
+	if _, err := newEncryptWriter(ioutil.Discard, make([]byte, 16)); err == nil {
+		t.Error("encrypted with a short key")
+	}
+}
+
+func TestEncryptionTampered(t *testing.T) {
+	key := testKey(t)
+	enc := encrypt(t, key, testPlaintext(t))
+	for _, at := range []int{5, envelopeSize, envelopeSize + 5, envelopeSize + 1000, len(enc) - 1} {
+		tampered := append([]byte(nil), enc...)
+		tampered[at] ^= 1
+		if _, err := decrypt(tampered, key); err == nil {
+			t.Errorf("decrypted the dump with byte %d flipped", at)
+		}
+	}
+}
+
+func TestEncryptionTruncated(t *testing.T) {
+	key := testKey(t)
+	enc := encrypt(t, key, testPlaintext(t))
+	// Cut right after the first segment, which isn't the last one
+	first := envelopeSize + 5 + int(binary.LittleEndian.Uint32(enc[envelopeSize+1:]))
+	for _, n := range []int{envelopeSize - 1, envelopeSize, envelopeSize + 3, first, len(enc) - 1} {
+		if _, err := decrypt(enc[:n], key); err == nil {
+			t.Errorf("decrypted the dump cut to %d of %d bytes", n, len(enc))
+		}
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldKey, newKey := testKey(t), testKey(t)
+	plain := testPlaintext(t)
+	enc := encrypt(t, oldKey, plain)
+
+	rotate := map[string]func(in []byte) ([]byte, error){
+		"RewrapKey": func(in []byte) ([]byte, error) {
+			var out bytes.Buffer
+			err := RewrapKey(bytes.NewReader(in), &out, oldKey, newKey)
+			return out.Bytes(), err
+		},
+		"RotateKey": func(in []byte) ([]byte, error) {
+			var out bytes.Buffer
+			err := RotateKey(bytes.NewReader(in), &out, oldKey, newKey)
+			return out.Bytes(), err
+		},
+		"RewrapFile": func(in []byte) ([]byte, error) {
+			path := filepath.Join(t.TempDir(), "dump")
+			if err := ioutil.WriteFile(path, in, 0600); err != nil {
+				return nil, err
+			}
+			if err := RewrapFile(path, oldKey, newKey); err != nil {
+				return nil, err
+			}
+			return ioutil.ReadFile(path)
+		},
+	}
+	for name, fn := range rotate {
+		out, err := fn(enc)
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+		if got, err := decrypt(out, newKey); err != nil {
+			t.Errorf("%s: decrypt with the new key: %s", name, err)
+		} else if !bytes.Equal(got, plain) {
+			t.Errorf("%s: decrypted %d bytes that differ", name, len(got))
+		}
+		if _, err := decrypt(out, oldKey); err == nil {
+			t.Errorf("%s: still decrypts with the old key", name)
+		}
+		if _, err := fn(out); err == nil {
+			t.Errorf("%s: rotated a dump not encrypted with the old key", name)
+		}
+	}
+}
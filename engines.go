@@ -0,0 +1,95 @@
+package mysqldump
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// EnginePolicy controls how the tables of a storage engine are dumped.
+type EnginePolicy int
+
+const (
+	// EngineDump dumps the table like any other.
+	EngineDump EnginePolicy = iota
+	// EngineWarn dumps the table after warning about the quirks of its engine.
+	EngineWarn
+	// EngineSkipData dumps the DDL of the table without reading its rows.
+	EngineSkipData
+	// EngineSkip leaves the table out of the dump.
+	EngineSkip
+)
+
+// ParseEnginePolicy parses "dump", "warn", "skip_data" or "skip".
+func ParseEnginePolicy(s string) (EnginePolicy, error) {
+	switch strings.ToLower(s) {
+	case "dump", "":
+		return EngineDump, nil
+	case "warn":
+		return EngineWarn, nil
+	case "skip_data":
+		return EngineSkipData, nil
+	case "skip":
+		return EngineSkip, nil
+	}
+
+	return 0, fmt.Errorf("invalid engine policy: %s", s)
+}
+
+// Policies applied unless replaced through WithEnginePolicies. Reading a FEDERATED table pulls its
+// rows across the link to the remote server, and BLACKHOLE tables never hold any.
+var defaultEnginePolicies = map[string]EnginePolicy{
+	"FEDERATED":  EngineSkipData,
+	"FEDERATEDX": EngineSkipData,
+	"BLACKHOLE":  EngineSkipData,
+	"CSV":        EngineWarn,
+	"ARCHIVE":    EngineWarn,
+	"ARIA":       EngineWarn,
+	"ROCKSDB":    EngineWarn,
+}
+
+var engineWarnings = map[string]string{
+	"CSV":     "CSV tables have no indexes, every chunk scans the whole file and NULLs are restored as empty strings",
+	"ARCHIVE": "ARCHIVE tables have no indexes, every chunk scans the whole table",
+	"ARIA":    "Aria tables are non-transactional, their rows may change while they are dumped",
+	"ROCKSDB": "MyRocks tables are created with the default engine of servers without MyRocks",
+}
+
+// WithEnginePolicies sets the policies of storage engines by name, on top of the defaults which skip
+// the data of FEDERATED and BLACKHOLE tables and warn about CSV, ARCHIVE, Aria and MyRocks tables.
+func WithEnginePolicies(policies map[string]EnginePolicy) Option {
+	return func(d *Dumper) {
+		d.engines = make(map[string]EnginePolicy, len(defaultEnginePolicies)+len(policies))
+		for e, p := range defaultEnginePolicies {
+			d.engines[e] = p
+		}
+		for e, p := range policies {
+			d.engines[strings.ToUpper(e)] = p
+		}
+	}
+}
+
+// enginePolicy returns the policy of a table's engine, logging its warning if it has one.
+func (d *Dumper) enginePolicy(name string, engine string) EnginePolicy {
+	policies := d.engines
+	if policies == nil {
+		policies = defaultEnginePolicies
+	}
+
+	engine = strings.ToUpper(engine)
+	p := policies[engine]
+	switch p {
+	case EngineWarn:
+		msg, ok := engineWarnings[engine]
+		if !ok {
+			msg = "its rows may not be dumped correctly"
+		}
+		d.warn(WarningEngine, name, "Table %s uses the %s engine: %s", name, engine, msg)
+	case EngineSkipData:
+		logrus.Infof("Skipping data of %s table %s", engine, name)
+	case EngineSkip:
+		logrus.Infof("Skipping %s table %s", engine, name)
+	}
+	return p
+}
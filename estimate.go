@@ -0,0 +1,193 @@
+package mysqldump
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TableEstimate is the predicted size of a table's part of a dump.
+type TableEstimate struct {
+	Table string
+	// True if the table's data is skipped entirely by the filters
+	Skipped bool
+	// Filters applied to the table's data, if any
+	Filters []string
+	// Expected number of rows dumped and their encoded size
+	Rows  int64
+	Bytes int64
+	// Time it takes to write Bytes at the given throughput
+	Duration time.Duration
+}
+
+// Estimate predicts the size of dumping every table in a database from the server's table
+// statistics, taking the table filters into account. bytesPerSecond is the expected dump
+// throughput used to predict the duration.
+func (d *Dumper) Estimate(dbName string, bytesPerSecond int64) ([]*TableEstimate, error) {
+	return d.EstimateContext(context.Background(), dbName, bytesPerSecond)
+}
+
+// EstimateContext is Estimate, running every query under ctx.
+func (d *Dumper) EstimateContext(ctx context.Context, dbName string, bytesPerSecond int64) ([]*TableEstimate, error) {
+	defer d.withContext(ctx)()
+	if err := d.use(dbName); err != nil {
+		return nil, err
+	}
+
+	tables, err := d.getTables(dbName)
+	if err != nil {
+		return nil, fmt.Errorf("list tables: %w", err)
+	}
+
+	res := make([]*TableEstimate, 0, len(tables))
+	for _, t := range tables {
+		e, err := d.estimateTable(t, dbName)
+		if err != nil {
+			return nil, fmt.Errorf("estimate table %s: %w", t, err)
+		}
+		if bytesPerSecond > 0 {
+			e.Duration = time.Duration(float64(e.Bytes) / float64(bytesPerSecond) * float64(time.Second))
+		}
+
+		res = append(res, e)
+	}
+
+	return res, nil
+}
+
+func (d *Dumper) estimateTable(name string, schema string) (*TableEstimate, error) {
+	e := &TableEstimate{Table: name}
+
+	filters, filtered := d.tableFilters(name)
+	if filtered && len(filters) == 0 {
+		e.Skipped = true
+		return e, nil
+	}
+	e.Filters = filters
+
+	meta, err := d.getTableMeta(name, schema)
+	if err != nil {
+		return nil, fmt.Errorf("get table type: %w", err)
+	}
+	if p := d.enginePolicy(name, meta.engine); p == EngineSkipData || p == EngineSkip {
+		e.Skipped = true
+		return e, nil
+	}
+
+	cols, err := d.getTableColumns(d.db, name, schema)
+	if err != nil {
+		return nil, fmt.Errorf("get table columns: %w", err)
+	}
+
+	rows, avgRowLength, err := d.getTableStats(name, schema)
+	if err != nil {
+		return nil, fmt.Errorf("get table statistics: %w", err)
+	}
+
+	if filtered {
+		rows = 0
+		for _, f := range filters {
+			n, err := d.explainRows("SELECT * FROM " + quoteIdent(name, d.isPQ()) + f)
+			if err != nil {
+				return nil, fmt.Errorf("explain filter: %w", err)
+			}
+			rows += n
+		}
+	}
+
+	// Every value carries a null marker and a length prefix on top of its data
+	overhead := int64(1 + len(cols)*(1+binary.MaxVarintLen64))
+
+	e.Rows = rows
+	e.Bytes = rows * (avgRowLength + overhead)
+	return e, nil
+}
+
+// getTableStats returns the estimated row count and average row length of a table.
+func (d *Dumper) getTableStats(name string, schema string) (rows int64, avgRowLength int64, err error) {
+	if d.legacyMetadata() {
+		return d.showTableStats(name, schema)
+	}
+
+	var nrows, avg sql.NullInt64
+
+	if d.isPQ() {
+		err = d.db.QueryRowContext(d.context(), "SELECT reltuples::bigint, CASE WHEN reltuples > 0 THEN (pg_relation_size(oid) / reltuples)::bigint ELSE 0 END FROM pg_class WHERE relname = $1 AND relkind = 'r'", name).Scan(&nrows, &avg)
+	} else {
+		err = d.db.QueryRowContext(d.context(), "SELECT TABLE_ROWS, AVG_ROW_LENGTH FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_NAME = ? AND TABLE_SCHEMA = ?", name, schema).Scan(&nrows, &avg)
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return nrows.Int64, avg.Int64, nil
+}
+
+var pqExplainRows = regexp.MustCompile(`rows=(\d+)`)
+
+// explainRows returns the number of rows the server expects a query to return.
+func (d *Dumper) explainRows(q string) (int64, error) {
+	rows, err := d.db.QueryContext(d.context(), "EXPLAIN "+q)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+
+	if d.isPQ() {
+		// The first line of the plan looks like "Seq Scan on t  (cost=0.00..1.00 rows=100 width=8)"
+		if !rows.Next() {
+			return 0, rows.Err()
+		}
+		var line string
+		if err = rows.Scan(&line); err != nil {
+			return 0, err
+		}
+		m := pqExplainRows.FindStringSubmatch(line)
+		if m == nil {
+			return 0, nil
+		}
+		return strconv.ParseInt(m[1], 10, 64)
+	}
+
+	if d.isTiDB() {
+		return tidbExplainRows(cols, rows)
+	}
+
+	// MySQL returns one line per table in the query, the estimate is in the rows and filtered columns
+	var total float64
+	for rows.Next() {
+		data := make([]sql.NullString, len(cols))
+		ptrs := make([]interface{}, len(cols))
+		for i := range data {
+			ptrs[i] = &data[i]
+		}
+		if err = rows.Scan(ptrs...); err != nil {
+			return 0, err
+		}
+
+		n, filtered := 0.0, 100.0
+		for i, c := range cols {
+			switch strings.ToLower(c) {
+			case "rows":
+				n, _ = strconv.ParseFloat(data[i].String, 64)
+			case "filtered":
+				if f, err := strconv.ParseFloat(data[i].String, 64); err == nil {
+					filtered = f
+				}
+			}
+		}
+		total += n * filtered / 100
+	}
+
+	return int64(total), rows.Err()
+}
@@ -0,0 +1,163 @@
+package mysqldump
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+)
+
+// fakeDB is a database/sql driver answering every query with handle, recording the queries it ran.
+type fakeDB struct {
+	handle func(q string, args []driver.Value) (*fakeRows, error)
+
+	mu      sync.Mutex
+	queries []string
+}
+
+// fakeRows are the rows a fakeDB returns, nil values being NULL, followed by err if set.
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+	err     error
+	i       int
+}
+
+// stringRows returns rows of columns holding the given values, as the MySQL driver reads them.
+func stringRows(columns []string, rows ...[]string) *fakeRows {
+	r := &fakeRows{columns: columns}
+	for _, row := range rows {
+		vals := make([]driver.Value, len(row))
+		for i, v := range row {
+			vals[i] = []byte(v)
+		}
+		r.rows = append(r.rows, vals)
+	}
+	return r
+}
+
+var (
+	fakeMu  sync.Mutex
+	fakeDBs = make(map[string]*fakeDB)
+)
+
+func init() {
+	sql.Register("mysqldump-fake", fakeDriver{})
+}
+
+// openFakeDB opens a database answering queries with handle, closed when the test ends.
+func openFakeDB(t *testing.T, handle func(q string, args []driver.Value) (*fakeRows, error)) (*sql.DB, *fakeDB) {
+	f := &fakeDB{handle: handle}
+	fakeMu.Lock()
+	name := fmt.Sprintf("%s-%d", t.Name(), len(fakeDBs))
+	fakeDBs[name] = f
+	fakeMu.Unlock()
+
+	db, err := sql.Open("mysqldump-fake", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, f
+}
+
+// ran returns the queries run so far.
+func (f *fakeDB) ran() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.queries...)
+}
+
+func (f *fakeDB) run(q string, args []driver.Value) (*fakeRows, error) {
+	f.mu.Lock()
+	f.queries = append(f.queries, q)
+	f.mu.Unlock()
+
+	r, err := f.handle(q, args)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		r = &fakeRows{}
+	}
+	return &fakeRows{columns: r.columns, rows: r.rows, err: r.err}, nil
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fakeMu.Lock()
+	defer fakeMu.Unlock()
+	f, ok := fakeDBs[name]
+	if !ok {
+		return nil, fmt.Errorf("no fake database %s", name)
+	}
+	return &fakeConn{f}, nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(q string) (driver.Stmt, error) {
+	return &fakeStmt{c.db, q}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeStmt struct {
+	db *fakeDB
+	q  string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if _, err := s.db.run(s.q, args); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.db.run(s.q, args)
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.i >= len(r.rows) {
+		if r.err != nil {
+			return r.err
+		}
+		return io.EOF
+	}
+	copy(dest, r.rows[r.i])
+	r.i++
+	return nil
+}
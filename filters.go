@@ -0,0 +1,119 @@
+package mysqldump
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// WithTableFilters sets the table filters, without which every table is dumped whole. filters maps
+// table names to the WHERE clauses their data is read with, each starting with a space, e.g.
+// " WHERE id > 10". The rows matching every clause are dumped one after the other, and a table
+// mapped to an empty list has its data skipped entirely.
+func WithTableFilters(filters map[string][]string) Option {
+	return func(d *Dumper) {
+		d.filters = filters
+	}
+}
+
+// DumpRange limits the rows of a table, as dumped by the next dump, to those whose column is at least
+// from and below to, e.g. to dump a range of an auto-increment primary key or of a date again. An open
+// bound is nil. Values are integers, floats, strings or times, and the range applies on top of the
+// table filters. The rows of every range given for a table are dumped, and dumps after the next one
+// go without them. Restoring them with ConflictReplace and SkipCreate overwrites those rows of the
+// target, leaving the others alone.
+func (d *Dumper) DumpRange(table string, column string, from interface{}, to interface{}) error {
+	var conds []string
+	for _, b := range []struct {
+		op string
+		v  interface{}
+	}{{">=", from}, {"<", to}} {
+		if b.v == nil {
+			continue
+		}
+		lit, err := literal(b.v, d.isPQ())
+		if err != nil {
+			return fmt.Errorf("range of %s: %w", table, err)
+		}
+		conds = append(conds, quoteColumns([]string{column}, d.isPQ())+" "+b.op+" "+lit)
+	}
+	if len(conds) == 0 {
+		return fmt.Errorf("range of %s has no bounds", table)
+	}
+
+	if d.ranges == nil {
+		d.ranges = make(map[string][]string)
+	}
+	d.ranges[table] = append(d.ranges[table], strings.Join(conds, " AND "))
+	return nil
+}
+
+// rangeCondition returns the condition matching the rows of any of the ranges of a table, if any.
+func (d *Dumper) rangeCondition(name string) string {
+	ranges, ok := d.ranges[name]
+	for t, r := range d.ranges {
+		if !ok && d.sameTableName(t, name) {
+			ranges, ok = r, true
+		}
+	}
+	if len(ranges) <= 1 {
+		return strings.Join(ranges, "")
+	}
+	return "((" + strings.Join(ranges, ") OR (") + "))"
+}
+
+// literal returns the SQL literal of a range bound.
+func literal(v interface{}, pq bool) (string, error) {
+	switch v := v.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v), nil
+	case time.Time:
+		return "'" + v.Format("2006-01-02 15:04:05.999999") + "'", nil
+	case string:
+		if pq {
+			return "'" + strings.ReplaceAll(v, "'", "''") + "'", nil
+		}
+		var buf bytes.Buffer
+		writeEscapedString(&buf, v)
+		return "'" + buf.String() + "'", nil
+	}
+	return "", fmt.Errorf("unsupported value %v of type %T", v, v)
+}
+
+// appliedFilters returns the filters of the given tables, by table, to record in the dump.
+func (d *Dumper) appliedFilters(tables []string) map[string][]string {
+	applied := make(map[string][]string)
+	for _, t := range tables {
+		if q, ok := d.tableFilters(t); ok {
+			applied[t] = append([]string{}, q...)
+		}
+	}
+	return applied
+}
+
+// tableFilters returns the WHERE clauses used to read a table, and whether its data is filtered at all.
+func (d *Dumper) tableFilters(name string) ([]string, bool) {
+	q, ok := d.filters[name]
+	if !ok {
+		for t, tq := range d.filters {
+			if d.sameTableName(t, name) {
+				q, ok = tq, true
+				break
+			}
+		}
+	}
+
+	cond := d.rangeCondition(name)
+	if cond == "" {
+		return q, ok
+	}
+	if !ok {
+		q = []string{""}
+	}
+	ranged := make([]string, len(q))
+	for i, f := range q {
+		ranged[i] = andWhere(f, cond)
+	}
+	return ranged, true
+}
@@ -0,0 +1,194 @@
+package mysqldump
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// Format is the format a Dumper writes its output in.
+type Format int
+
+const (
+	// FormatBinary writes the binary dump format, read back by the Loader and the Reader of the binary package.
+	FormatBinary Format = iota
+	// FormatSQL writes mysqldump style SQL, restorable with the mysql client, as ConvertToSQL would.
+	FormatSQL
+	// FormatCSV writes one CSV stream per table, as ConvertToCSV would, see WithCSVTables.
+	FormatCSV
+	// FormatJSONL writes every row as a JSON object on its own line, keyed by column name within an
+	// envelope naming its table, as ConvertToJSONL would.
+	FormatJSONL
+)
+
+// ParseFormat parses "binary", "sql", "csv" or "jsonl".
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "binary", "":
+		return FormatBinary, nil
+	case "sql":
+		return FormatSQL, nil
+	case "csv":
+		return FormatCSV, nil
+	case "jsonl":
+		return FormatJSONL, nil
+	}
+
+	return 0, fmt.Errorf("invalid format: %s", s)
+}
+
+func (f Format) String() string {
+	switch f {
+	case FormatSQL:
+		return "SQL"
+	case FormatCSV:
+		return "CSV"
+	case FormatJSONL:
+		return "JSON Lines"
+	}
+	return "binary"
+}
+
+// WithFormat sets the format of the output. Dumps default to FormatBinary.
+// Rows of virtual tables of WithQueries and DumpQuery are only left out of SQL output.
+func WithFormat(f Format) Option {
+	return func(d *Dumper) {
+		d.format = f
+	}
+}
+
+// WithCSVTables writes the rows of every table as CSV to its own writer, opened through open and
+// closed once the table is done, instead of writing a dump to the output. Sets the format to FormatCSV.
+func WithCSVTables(open func(table string) (io.WriteCloser, error), opt CSVOptions) Option {
+	return func(d *Dumper) {
+		d.format = FormatCSV
+		d.csvOpen = open
+		d.csvOptions = opt
+	}
+}
+
+// sqlQuerySize is the size past which the INSERT statements of SQL output are split.
+const sqlQuerySize = 1000000
+
+// convertedOutput converts the binary dump written to it as it comes.
+type convertedOutput struct {
+	out  io.Writer
+	pw   *io.PipeWriter
+	done chan error
+}
+
+// startFormat has the dump written to the output converted, for formats other than FormatBinary.
+func (d *Dumper) startFormat() error {
+	if d.format == FormatBinary && d.tableOpen == nil {
+		return nil
+	}
+	if d.format == FormatCSV && d.csvOpen == nil {
+		return errors.New("csv output needs WithCSVTables")
+	}
+	if d.tableOpen != nil && d.format != FormatBinary {
+		return errors.New("per-table files are in the binary format")
+	}
+	if d.checkpointFile != "" {
+		// Converters buffer what they write, the rows a checkpoint counts might not have reached the output
+		return errors.New("a checkpoint needs a single output in the binary format")
+	}
+
+	pr, pw := io.Pipe()
+	c := &convertedOutput{out: d.w, pw: pw, done: make(chan error, 1)}
+	var out io.Writer = &countingWriter{d.w, &d.writtenBytes}
+	if d.compressed != nil || d.encrypted != nil {
+		// Compressed and encrypted output is counted as it leaves the compressor or the encryption
+		out = d.w
+	}
+	go func() {
+		// Nothing waits for the statements to be written, every flush is ready right away
+		flusher := make(chan bool)
+		ready := make(chan bool)
+		go func() {
+			for range flusher {
+				ready <- true
+			}
+		}()
+
+		var err error
+		switch {
+		case d.tableOpen != nil:
+			err = SplitTables(pr, out, d.openTable)
+		case d.format == FormatCSV:
+			err = ConvertToCSV(pr, d.openCSV, ConvertOptions{CSV: d.csvOptions})
+		case d.format == FormatJSONL:
+			err = ConvertToJSONL(pr, out)
+		default:
+			err = ConvertToSQL(pr, out, flusher, ready, sqlQuerySize, ConvertOptions{})
+		}
+		close(flusher)
+		if err == nil {
+			// The footer isn't converted
+			_, err = io.Copy(ioutil.Discard, pr)
+		}
+		// Unblock the dump if the conversion stopped early
+		pr.CloseWithError(err)
+		c.done <- err
+	}()
+
+	d.converted = c
+	d.w = pw
+	d.resetWriter()
+	return nil
+}
+
+// openCSV opens the output of a table, counting what is written to it as written bytes.
+func (d *Dumper) openCSV(table string) (io.WriteCloser, error) {
+	f, err := d.csvOpen(table)
+	if err != nil {
+		return nil, err
+	}
+	return &tableOutput{f, &countingWriter{f, &d.writtenBytes}}, nil
+}
+
+// openTable opens the file of a table, see openCSV.
+func (d *Dumper) openTable(table string) (io.WriteCloser, error) {
+	f, err := d.tableOpen(table)
+	if err != nil {
+		return nil, err
+	}
+	return &tableOutput{f, &countingWriter{f, &d.writtenBytes}}, nil
+}
+
+type tableOutput struct {
+	io.Closer
+	w io.Writer
+}
+
+func (o *tableOutput) Write(p []byte) (int, error) {
+	return o.w.Write(p)
+}
+
+// endFormat waits for the rest of the dump to be converted.
+func (d *Dumper) endFormat() error {
+	c := d.converted
+	if c == nil {
+		return nil
+	}
+
+	c.pw.Close()
+	err := <-c.done
+	d.converted = nil
+	d.w = c.out
+	d.resetWriter()
+	if err != nil {
+		return fmt.Errorf("write %s: %w", d.format, err)
+	}
+	return nil
+}
+
+// outputCounter returns what the bytes of the encoded dump are counted in. Converted, compressed and
+// encrypted output is counted as it is written instead.
+func (d *Dumper) outputCounter() *int64 {
+	if d.converted != nil || d.compressed != nil || d.encrypted != nil {
+		return &d.encodedBytes
+	}
+	return &d.writtenBytes
+}
@@ -0,0 +1,182 @@
+package mysqldump
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// GCSConfig is where a GCSSink uploads a dump to and how it is authorized.
+type GCSConfig struct {
+	Bucket string
+	Object string
+	// Returns the OAuth 2 access token the requests are authorized with, called for every request so
+	// tokens can be refreshed during long uploads
+	Token func(ctx context.Context) (string, error)
+
+	// Size of the chunks uploaded, a multiple of 256KB. Defaults to 16MB
+	ChunkSize int64
+	// http.DefaultClient if nil
+	Client *http.Client
+}
+
+const (
+	gcsChunkUnit        = 256 << 10
+	gcsDefaultChunkSize = 16 << 20
+)
+
+// GCSSink is a Sink uploading a dump to Google Cloud Storage with a resumable upload, a chunk at a time.
+// Chunks the service didn't get in full are sent again from where it got them. The object only appears
+// once Close completes the upload.
+type GCSSink struct {
+	ctx context.Context
+	c   GCSConfig
+
+	session string
+	buf     []byte
+	// Bytes the service has
+	offset int64
+	err    error
+}
+
+// NewGCSSink starts a resumable upload to GCS, sending its requests under ctx.
+func NewGCSSink(ctx context.Context, c GCSConfig) (*GCSSink, error) {
+	if c.Bucket == "" || c.Object == "" {
+		return nil, errors.New("a GCS upload needs a bucket and an object")
+	}
+	if c.Token == nil {
+		return nil, errors.New("a GCS upload needs an access token")
+	}
+	if c.ChunkSize == 0 {
+		c.ChunkSize = gcsDefaultChunkSize
+	}
+	if c.ChunkSize%gcsChunkUnit != 0 {
+		return nil, fmt.Errorf("chunks of GCS uploads are multiples of %d bytes", gcsChunkUnit)
+	}
+
+	s := &GCSSink{ctx: ctx, c: c, buf: make([]byte, 0, c.ChunkSize)}
+	start := "https://storage.googleapis.com/upload/storage/v1/b/" + url.PathEscape(c.Bucket) +
+		"/o?uploadType=resumable&name=" + url.QueryEscape(c.Object)
+	res, _, err := s.do(http.MethodPost, start, nil, "", http.StatusOK)
+	if err != nil {
+		return nil, fmt.Errorf("start GCS upload: %w", err)
+	}
+	if s.session = res.Header.Get("Location"); s.session == "" {
+		return nil, errors.New("start GCS upload: no session URI in the response")
+	}
+	return s, nil
+}
+
+func (s *GCSSink) Write(b []byte) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+
+	n := 0
+	for len(b) > 0 {
+		m := int(s.c.ChunkSize) - len(s.buf)
+		if m > len(b) {
+			m = len(b)
+		}
+		s.buf, b, n = append(s.buf, b[:m]...), b[m:], n+m
+		if int64(len(s.buf)) == s.c.ChunkSize {
+			if s.err = s.upload(false); s.err != nil {
+				return n, s.err
+			}
+		}
+	}
+	return n, nil
+}
+
+// upload sends the buffered chunk, the last one of the dump if last is set.
+func (s *GCSSink) upload(last bool) error {
+	for {
+		total := "*"
+		if last {
+			total = strconv.FormatInt(s.offset+int64(len(s.buf)), 10)
+		}
+		span := "bytes */" + total
+		if len(s.buf) > 0 {
+			span = fmt.Sprintf("bytes %d-%d/%s", s.offset, s.offset+int64(len(s.buf))-1, total)
+		}
+
+		res, _, err := s.do(http.MethodPut, s.session, s.buf, span, http.StatusOK, http.StatusCreated, http.StatusPermanentRedirect)
+		if err != nil {
+			return fmt.Errorf("upload to GCS at %d bytes: %w", s.offset, err)
+		}
+		if res.StatusCode != http.StatusPermanentRedirect {
+			if !last {
+				return errors.New("GCS upload completed before the end of the dump")
+			}
+			s.offset += int64(len(s.buf))
+			s.buf = s.buf[:0]
+			return nil
+		}
+
+		// The service has the bytes up to the end of the range, the rest is sent again
+		got := int64(0)
+		if r := res.Header.Get("Range"); strings.HasPrefix(r, "bytes=0-") {
+			if got, err = strconv.ParseInt(r[len("bytes=0-"):], 10, 64); err != nil {
+				return fmt.Errorf("upload to GCS: invalid range %q", r)
+			}
+			got++
+		}
+		if got < s.offset || got > s.offset+int64(len(s.buf)) {
+			return fmt.Errorf("upload to GCS: the service has %d bytes of %d", got, s.offset+int64(len(s.buf)))
+		}
+		s.buf = s.buf[:copy(s.buf, s.buf[got-s.offset:])]
+		s.offset = got
+		if len(s.buf) == 0 && !last {
+			return nil
+		}
+	}
+}
+
+// Close uploads the last chunk, completing the upload.
+func (s *GCSSink) Close() error {
+	if s.err != nil {
+		return s.err
+	}
+	if s.err = s.upload(true); s.err != nil {
+		return s.err
+	}
+	s.err = errors.New("GCS upload is complete")
+	return nil
+}
+
+// Abort cancels the upload.
+func (s *GCSSink) Abort() error {
+	s.err = errors.New("GCS upload was aborted")
+	// Cancelled uploads answer 499
+	if _, _, err := s.do(http.MethodDelete, s.session, nil, "", 499, http.StatusNoContent); err != nil {
+		return fmt.Errorf("abort GCS upload: %w", err)
+	}
+	return nil
+}
+
+// do sends an authorized request about the upload, with the Content-Range span if set.
+func (s *GCSSink) do(method string, uri string, body []byte, span string, expect ...int) (*http.Response, []byte, error) {
+	return doSinkRequest(s.ctx, s.c.Client, func() (*http.Request, error) {
+		token, err := s.c.Token(s.ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get access token: %w", err)
+		}
+		req, err := http.NewRequest(method, uri, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		if span != "" {
+			req.Header.Set("Content-Range", span)
+		}
+		if s.session == "" {
+			req.Header.Set("X-Upload-Content-Type", "application/octet-stream")
+		}
+		return req, nil
+	}, expect...)
+}
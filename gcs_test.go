@@ -0,0 +1,111 @@
+package mysqldump
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// gcsServer is a fake GCS resumable upload, keeping only the first short bytes of the first chunk.
+type gcsServer struct {
+	url   string
+	short int
+
+	spans []string
+	got   []byte
+	done  bool
+}
+
+func (s *gcsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer token" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	body, _ := ioutil.ReadAll(r.Body)
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/upload/storage/v1/b/bucket/o":
+		if r.URL.Query().Get("name") != "dumps/app.dump" {
+			http.Error(w, "unexpected object", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Location", s.url+"/session")
+	case r.Method == http.MethodPut && r.URL.Path == "/session":
+		span := r.Header.Get("Content-Range")
+		s.spans = append(s.spans, span)
+		var start, end int
+		var total string
+		if _, err := fmt.Sscanf(span, "bytes %d-%d/%s", &start, &end, &total); err != nil || start != len(s.got) || end-start+1 != len(body) {
+			http.Error(w, "unexpected range "+span, http.StatusBadRequest)
+			return
+		}
+		if s.short > 0 {
+			body, s.short = body[:s.short], 0
+		}
+		s.got = append(s.got, body...)
+		if total != "*" {
+			s.done = true
+			return
+		}
+		w.Header().Set("Range", "bytes=0-"+strconv.Itoa(len(s.got)-1))
+		w.WriteHeader(http.StatusPermanentRedirect)
+	default:
+		http.Error(w, "unexpected request "+r.URL.String(), http.StatusBadRequest)
+	}
+}
+
+// rewriteTransport sends the requests to GCS to a test server.
+type rewriteTransport struct {
+	to *url.URL
+}
+
+func (t rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = t.to.Scheme, t.to.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestGCSSinkResume(t *testing.T) {
+	srv := &gcsServer{short: 100000}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	srv.url = ts.URL
+	to, _ := url.Parse(ts.URL)
+
+	s, err := NewGCSSink(context.Background(), GCSConfig{
+		Bucket: "bucket", Object: "dumps/app.dump", ChunkSize: gcsChunkUnit,
+		Token:  func(ctx context.Context) (string, error) { return "token", nil },
+		Client: &http.Client{Transport: rewriteTransport{to}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dump := bytes.Repeat([]byte("0123456789"), 61440)
+	if _, err := s.Write(dump); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The rest of the first chunk is sent again from where the service stopped
+	want := []string{
+		"bytes 0-262143/*",
+		"bytes 100000-262143/*",
+		"bytes 262144-524287/*",
+		"bytes 524288-614399/614400",
+	}
+	if !reflect.DeepEqual(srv.spans, want) {
+		t.Errorf("uploaded %s, want %s", strings.Join(srv.spans, ", "), strings.Join(want, ", "))
+	}
+	if !srv.done || !bytes.Equal(srv.got, dump) {
+		t.Errorf("uploaded %d bytes that differ from the %d written", len(srv.got), len(dump))
+	}
+}
@@ -6,4 +6,5 @@ require (
 	github.com/conneqtech/std_pkg v1.14.0
 	github.com/lib/pq v1.1.1
 	github.com/sirupsen/logrus v1.8.1
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -0,0 +1,120 @@
+package mysqldump
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	binary "github.com/MouseHatGames/go-mysqldump/internal/marshal"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrGuardViolation is returned (wrapped) when a dumped value trips a guard with GuardFail.
+var ErrGuardViolation = errors.New("value guard violated")
+
+// GuardPolicy controls what happens when a dumped value trips a guard.
+type GuardPolicy int
+
+const (
+	// GuardWarn logs the first violation of each guard in a table, counts the rest and dumps the rows anyway.
+	GuardWarn GuardPolicy = iota
+	// GuardFail stops the dump with ErrGuardViolation.
+	GuardFail
+)
+
+// ParseGuardPolicy parses "warn" or "fail".
+func ParseGuardPolicy(s string) (GuardPolicy, error) {
+	switch strings.ToLower(s) {
+	case "warn", "":
+		return GuardWarn, nil
+	case "fail":
+		return GuardFail, nil
+	}
+
+	return 0, fmt.Errorf("invalid guard policy: %s", s)
+}
+
+// ValueGuards flag the dumped values unlikely to come from healthy rows, such as corrupted ones, before
+// they reach the systems reading the dump. They check the rows as written, after any row transformer.
+type ValueGuards struct {
+	// Largest value in bytes, 0 for no limit
+	MaxValueSize int
+	// Largest encoded row in bytes, 0 for no limit
+	MaxRowSize int
+	// Byte sequences no value may contain, such as "\x00"
+	Disallowed []string
+
+	OnViolation GuardPolicy
+}
+
+// WithValueGuards checks every dumped row against the guards. Violations are counted along with
+// those of WithRowRules and returned by RuleViolations.
+func WithValueGuards(g ValueGuards) Option {
+	return func(d *Dumper) {
+		d.guards = &g
+	}
+}
+
+// checkGuards checks a row about to be written to the current table.
+func (d *Dumper) checkGuards(row binary.RowData) error {
+	if d.guards == nil {
+		return nil
+	}
+
+	g, t := d.guards, d.infoHeader
+	if g.MaxRowSize > 0 {
+		if size := binary.RowSize(row); size > g.MaxRowSize {
+			if err := d.guardViolated(t.Name, fmt.Sprintf("row over %d bytes", g.MaxRowSize)); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i, v := range row {
+		if v == nil {
+			continue
+		}
+		col := fmt.Sprint(i + 1)
+		if i < len(t.Columns) {
+			col = t.Columns[i]
+		}
+
+		if g.MaxValueSize > 0 && len(*v) > g.MaxValueSize {
+			if err := d.guardViolated(t.Name, fmt.Sprintf("%s over %d bytes", col, g.MaxValueSize)); err != nil {
+				return err
+			}
+		}
+		for _, seq := range g.Disallowed {
+			if seq != "" && strings.Contains(*v, seq) {
+				if err := d.guardViolated(t.Name, fmt.Sprintf("%s contains %q", col, seq)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// guardViolated fails the dump or counts a violation, depending on the policy.
+func (d *Dumper) guardViolated(table string, guard string) error {
+	if d.guards.OnViolation == GuardFail {
+		return fmt.Errorf("%w: %s in table %s", ErrGuardViolation, guard, table)
+	}
+
+	// Parallel workers share the counts
+	d.rulesMu.Lock()
+	defer d.rulesMu.Unlock()
+	if d.guardViolations == nil {
+		d.guardViolations = make(map[string]*RuleViolation)
+	}
+	key := table + "\x00" + guard
+	v := d.guardViolations[key]
+	if v == nil {
+		logrus.Warnf("A row of %s trips a guard: %s", table, guard)
+		v = &RuleViolation{Table: table, Rule: guard}
+		d.guardViolations[key] = v
+		d.violations = append(d.violations, v)
+	}
+	v.Rows++
+	return nil
+}
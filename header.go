@@ -0,0 +1,30 @@
+package mysqldump
+
+import "github.com/MouseHatGames/go-mysqldump/internal/marshal"
+
+// FileHeader is the header written at the start of every dump.
+type FileHeader = marshal.FileHeader
+
+// BinlogPosition is the binary log position a dump's data was read at.
+type BinlogPosition = marshal.BinlogPosition
+
+// TableHeader precedes the rows of every table in a dump.
+type TableHeader = marshal.TableHeader
+
+// NoData marks a table whose rows were left out of a dump on purpose, see TableHeader.NoData.
+type NoData = marshal.NoData
+
+// Reasons for the rows of a table to be left out of a dump.
+const (
+	NoDataFilter = marshal.NoDataFilter
+	NoDataEngine = marshal.NoDataEngine
+)
+
+// SchemaObject is a view, stored routine, trigger or event recorded after the tables of a dump.
+type SchemaObject = marshal.SchemaObject
+
+// FileFooter is written at the end of every dump.
+type FileFooter = marshal.FileFooter
+
+// RowData holds the values of a single row, nil meaning NULL.
+type RowData = marshal.RowData
@@ -0,0 +1,104 @@
+package mysqldump
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// HookPolicy controls what happens when a hook statement fails.
+type HookPolicy int
+
+const (
+	// HookFail stops the dump with the error.
+	HookFail HookPolicy = iota
+	// HookWarn logs the error and carries on with the dump.
+	HookWarn
+)
+
+// ParseHookPolicy parses "fail" or "warn".
+func ParseHookPolicy(s string) (HookPolicy, error) {
+	switch strings.ToLower(s) {
+	case "fail", "":
+		return HookFail, nil
+	case "warn":
+		return HookWarn, nil
+	}
+
+	return 0, fmt.Errorf("invalid hook policy: %s", s)
+}
+
+// Hooks are SQL statements run on the dump connection around the dump and its tables, like
+// FLUSH LOGS or marking a backup as in progress. In the statements of BeforeTable and AfterTable,
+// {table} is replaced by the name of the table.
+type Hooks struct {
+	// Run before the snapshot the tables are read from is started, so statements committing
+	// implicitly are safe here
+	BeforeDump []string
+	// Run once the dump is complete
+	AfterDump []string
+	// Run before and after every table
+	BeforeTable []string
+	AfterTable  []string
+	// Run before and after a single table, after the statements of BeforeTable and before those of AfterTable
+	Tables map[string]TableHooks
+
+	OnError HookPolicy
+}
+
+type TableHooks struct {
+	Before []string
+	After  []string
+}
+
+// WithHooks sets the SQL statements run around the dump and around each table.
+func WithHooks(h Hooks) Option {
+	return func(d *Dumper) {
+		d.hooks = h
+	}
+}
+
+// tableHooks returns the statements run before or after a table.
+func (h *Hooks) tableHooks(name string, after bool) []string {
+	all, own := h.BeforeTable, h.Tables[name].Before
+	if after {
+		all, own = h.AfterTable, h.Tables[name].After
+	}
+
+	stmts := make([]string, 0, len(all)+len(own))
+	if after {
+		stmts = append(stmts, own...)
+	}
+	for _, q := range all {
+		stmts = append(stmts, strings.ReplaceAll(q, "{table}", name))
+	}
+	if !after {
+		stmts = append(stmts, own...)
+	}
+	return stmts
+}
+
+// runHooks runs hook statements on the pinned connection if there is one, applying the hook policy to failures.
+func (d *Dumper) runHooks(stage string, stmts []string) error {
+	for _, q := range stmts {
+		logrus.Debugf("Running %s hook: %s", stage, q)
+
+		var err error
+		if d.conn != nil {
+			_, err = d.conn.ExecContext(d.context(), q)
+		} else {
+			_, err = d.db.ExecContext(d.context(), q)
+		}
+		if err == nil {
+			continue
+		}
+
+		if d.hooks.OnError == HookWarn {
+			d.warn(WarningHook, "", "%s hook %q failed: %s", stage, q, err)
+			continue
+		}
+		return fmt.Errorf("%s hook %q: %w", stage, q, err)
+	}
+	return nil
+}
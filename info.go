@@ -0,0 +1,60 @@
+package mysqldump
+
+// Info returns what the last dump wrote, the same Inspect would read back from its output, so it
+// can be indexed without reading the output again. Tables are added as they are dumped, while the
+// checksums and Footer are only set once the dump is done or interrupted. Nil before the first dump.
+func (d *Dumper) Info() *DumpInfo {
+	return d.info
+}
+
+func (d *Dumper) writeFileHeader(h *FileHeader) error {
+	d.info = &DumpInfo{Header: h}
+	d.infoTable, d.infoHeader = nil, nil
+	return d.bin.WriteFileHeader(h)
+}
+
+func (d *Dumper) writeTableHeader(h *TableHeader) error {
+	if d.interleave {
+		d.sectionID++
+		h.ID = d.sectionID
+	}
+
+	// The header is reused for every section of a table
+	section := *h
+	d.infoTable, d.infoHeader = d.info.addSection(&section), &section
+	if d.readBack != nil {
+		d.readBack.section(h)
+	}
+	if err := d.bin.WriteTableHeader(h); err != nil || h.NoData == nil {
+		return err
+	}
+	return d.bin.WriteNoData(h.NoData)
+}
+
+func (d *Dumper) writeRow(row RowData) error {
+	if err := d.checkGuards(row); err != nil {
+		return err
+	}
+
+	d.infoTable.add(row, d.infoHeader)
+	if d.readBack != nil {
+		d.readBack.row(d.infoHeader.Name)
+	}
+	return d.bin.WriteRowData(row)
+}
+
+func (d *Dumper) writeObject(o *SchemaObject) error {
+	d.info.Objects = append(d.info.Objects, o)
+	return d.bin.WriteObject(o)
+}
+
+func (d *Dumper) writeFileFooter(f *FileFooter) error {
+	d.info.Footer = f
+	d.info.sumTables()
+	f.Checksums = make(map[string]string, len(d.info.Tables))
+	for _, t := range d.info.Tables {
+		f.Checksums[t.Header.Name] = t.Checksum
+	}
+	f.Checksum = d.info.Checksum
+	return d.bin.WriteFileFooter(f)
+}
@@ -0,0 +1,183 @@
+package mysqldump
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/MouseHatGames/go-mysqldump/internal/marshal"
+)
+
+// TableInfo summarizes a single table in a dump.
+type TableInfo struct {
+	Header *TableHeader
+	// Partitions the table was dumped by, in dump order
+	Partitions []string
+	// Number of shards the table's rows were split into, 0 if it wasn't sharded
+	Shards int
+	Rows   int64
+	// Size of the encoded rows in bytes
+	Bytes int64
+	// Hex encoded, order independent checksum of the table's rows, see marshal.Checksum
+	Checksum string
+	// Why the table's rows were left out of the dump, nil if they weren't
+	NoData *NoData
+
+	// The table's rows, only kept when inspecting for a row level diff
+	rows []RowData
+	sum  *marshal.Checksum
+}
+
+// DumpInfo summarizes the contents of a dump.
+type DumpInfo struct {
+	Header *FileHeader
+	// Nil for dumps written before footers were introduced
+	Footer *FileFooter
+	Tables []*TableInfo
+	// Views, stored routines, triggers and events following the tables
+	Objects []*SchemaObject
+	// Hex encoded SHA-256 of all table checksums, in dump order
+	Checksum string
+}
+
+// Table returns the table with the given name, or nil if the dump doesn't contain it.
+func (i *DumpInfo) Table(name string) *TableInfo {
+	for _, t := range i.Tables {
+		if t.Header.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// Inspect reads a whole dump and returns what it contains, without restoring it.
+func Inspect(in io.Reader) (*DumpInfo, error) {
+	return inspect(in, 0)
+}
+
+// inspect reads a dump, keeping the rows of every table that has no more than keepRows rows.
+func inspect(in io.Reader, keepRows int64) (*DumpInfo, error) {
+	r := marshal.NewReader(in)
+
+	h, err := r.ReadFileHeader()
+	if err != nil {
+		return nil, fmt.Errorf("read file header: %w", err)
+	}
+
+	info := &DumpInfo{Header: h}
+	for {
+		t, err := r.ReadTableHeader()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			return nil, fmt.Errorf("read table header: %w", err)
+		}
+
+		ti := info.addSection(t)
+		if err = inspectTable(r, t, ti, keepRows); err != nil {
+			return nil, fmt.Errorf("read table %s: %w", t.Name, err)
+		}
+	}
+
+	for {
+		o, err := r.ReadObject()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		info.Objects = append(info.Objects, o)
+	}
+	if info.Footer, err = r.ReadFileFooter(); err != nil {
+		return nil, fmt.Errorf("read file footer: %w", err)
+	}
+
+	info.sumTables()
+	return info, nil
+}
+
+// addSection returns the table the rows following a table header belong to, adding it if it's new.
+func (i *DumpInfo) addSection(t *TableHeader) *TableInfo {
+	// A table dumped again replaces the sections written of it before
+	if t.Restarted {
+		kept := i.Tables[:0]
+		for _, ti := range i.Tables {
+			if ti.Header.Name != t.Name {
+				kept = append(kept, ti)
+			}
+		}
+		i.Tables = kept
+	}
+
+	// The sections of a table dumped by partition or shard add up to a single table
+	ti := i.Table(t.Name)
+	if (t.Partition == "" && t.Shard <= 1 && t.ColumnGroup <= 1) || ti == nil {
+		ti = &TableInfo{Header: t, Shards: t.Shards, NoData: t.NoData, sum: marshal.NewChecksum()}
+		i.Tables = append(i.Tables, ti)
+	}
+	if t.Partition != "" && t.Shard <= 1 && t.ColumnGroup <= 1 {
+		ti.Partitions = append(ti.Partitions, t.Partition)
+	}
+	return ti
+}
+
+// add counts a row of a section of the table. The rows of later column groups are those of the first
+// one again, they only add to the size and checksum.
+func (ti *TableInfo) add(row RowData, section *TableHeader) {
+	if section.ColumnGroup <= 1 {
+		ti.Rows++
+	}
+	ti.Bytes += int64(marshal.RowSize(row))
+	ti.sum.Add(row)
+}
+
+// sumTables sets the checksum of every table and that of the whole dump.
+func (i *DumpInfo) sumTables() {
+	sums := make([]string, len(i.Tables))
+	for n, ti := range i.Tables {
+		ti.Checksum = ti.sum.String()
+		sums[n] = ti.Checksum
+	}
+	i.Checksum = dumpChecksum(sums)
+}
+
+// dumpChecksum returns the checksum of a dump holding tables with the given checksums, in dump order.
+func dumpChecksum(tables []string) string {
+	sum := sha256.New()
+	for _, t := range tables {
+		sum.Write([]byte(t))
+	}
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+// inspectTable reads the rows of a table section into ti.
+func inspectTable(r *marshal.Reader, t *TableHeader, ti *TableInfo, keepRows int64) error {
+	for {
+		row, err := r.ReadRow(len(t.Columns))
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+
+		ti.add(row, t)
+		if t.ColumnGroup > 1 {
+			// Rows of a later column group only hold some of the columns
+			ti.rows = nil
+			continue
+		}
+		if ti.Rows <= keepRows {
+			ti.rows = append(ti.rows, row)
+		} else {
+			ti.rows = nil
+		}
+	}
+
+	ti.Checksum = ti.sum.String()
+	return nil
+}
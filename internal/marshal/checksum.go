@@ -0,0 +1,58 @@
+package marshal
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"math/big"
+)
+
+var checksumModulus = new(big.Int).Lsh(big.NewInt(1), 256)
+
+// Checksum is an order independent checksum over a set of rows: the sum of the SHA-256 of
+// every row modulo 2^256. Rows are hashed using a canonical encoding, so the result doesn't
+// depend on how they were framed on disk.
+type Checksum struct {
+	sum big.Int
+	h   hash.Hash
+	buf []byte
+	row big.Int
+}
+
+func NewChecksum() *Checksum {
+	return &Checksum{
+		h:   sha256.New(),
+		buf: make([]byte, binary.MaxVarintLen64),
+	}
+}
+
+func (c *Checksum) Add(r RowData) {
+	c.h.Reset()
+
+	for _, v := range r {
+		if v == nil {
+			c.h.Write([]byte{0})
+			continue
+		}
+
+		c.h.Write([]byte{1})
+		n := binary.PutUvarint(c.buf, uint64(len(*v)))
+		c.h.Write(c.buf[:n])
+		c.h.Write([]byte(*v))
+	}
+
+	c.row.SetBytes(c.h.Sum(nil))
+	c.sum.Add(&c.sum, &c.row)
+	c.sum.Mod(&c.sum, checksumModulus)
+}
+
+// Sum returns the 32 byte checksum.
+func (c *Checksum) Sum() []byte {
+	b := make([]byte, 32)
+	return c.sum.FillBytes(b)
+}
+
+func (c *Checksum) String() string {
+	return hex.EncodeToString(c.Sum())
+}
@@ -0,0 +1,63 @@
+package marshal
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"sync"
+)
+
+// Codec compresses whole dumps. Readers recognize compressed dumps by the magic bytes the codec's
+// streams start with and decompress them before reading the file header.
+type Codec struct {
+	Name      string
+	Magic     []byte
+	NewWriter func(w io.Writer, level int) (io.WriteCloser, error)
+	NewReader func(r io.Reader) (io.ReadCloser, error)
+}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[string]Codec{
+		"gzip": {
+			Name:  "gzip",
+			Magic: []byte{0x1f, 0x8b},
+			NewWriter: func(w io.Writer, level int) (io.WriteCloser, error) {
+				if level == 0 {
+					level = gzip.DefaultCompression
+				}
+				return gzip.NewWriterLevel(w, level)
+			},
+			NewReader: func(r io.Reader) (io.ReadCloser, error) {
+				return gzip.NewReader(r)
+			},
+		},
+	}
+)
+
+// RegisterCodec adds a codec, replacing the one of the same name.
+func RegisterCodec(c Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[c.Name] = c
+}
+
+// LookupCodec returns the codec of the given name.
+func LookupCodec(name string) (Codec, bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	c, ok := codecs[name]
+	return c, ok
+}
+
+// detectCodec returns the codec whose streams start like prefix.
+func detectCodec(prefix []byte) (Codec, bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	for _, c := range codecs {
+		if len(c.Magic) > 0 && bytes.HasPrefix(prefix, c.Magic) {
+			return c, true
+		}
+	}
+	return Codec{}, false
+}
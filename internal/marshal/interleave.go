@@ -0,0 +1,221 @@
+package marshal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// spillSize is the size of the rows of a table read ahead past which they are moved to a temporary file.
+const spillSize = 32 << 20
+
+// pendingTable is a table of an interleaved dump. The Reader returns tables one after the other in the
+// order of their headers, so the chunks of the tables after the current one are read ahead and kept
+// until it's their turn.
+type pendingTable struct {
+	id     uint32
+	header *TableHeader
+	// Set once the table end marker was read
+	done bool
+
+	// Rows read ahead, encoded as in a dump, in buf until they outgrow spillSize and move to file
+	buf  bytes.Buffer
+	file *os.File
+	err  error
+}
+
+func (p *pendingTable) Write(b []byte) (int, error) {
+	if p.err != nil {
+		return 0, p.err
+	}
+
+	if p.file == nil {
+		if p.buf.Len()+len(b) <= spillSize {
+			return p.buf.Write(b)
+		}
+		if p.file, p.err = ioutil.TempFile("", "mysqldump-table-"); p.err != nil {
+			return 0, fmt.Errorf("spill rows of %s: %w", p.header.Name, p.err)
+		}
+		if _, p.err = p.file.Write(p.buf.Bytes()); p.err != nil {
+			return 0, fmt.Errorf("spill rows of %s: %w", p.header.Name, p.err)
+		}
+		p.buf = bytes.Buffer{}
+	}
+
+	n, err := p.file.Write(b)
+	if err != nil {
+		p.err = fmt.Errorf("spill rows of %s: %w", p.header.Name, err)
+	}
+	return n, p.err
+}
+
+// rows returns a reader over the rows read ahead, nil if there are none.
+func (p *pendingTable) rows() (*Reader, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	if p.file == nil {
+		if p.buf.Len() == 0 {
+			return nil, nil
+		}
+		return NewReader(bytes.NewReader(p.buf.Bytes())), nil
+	}
+
+	if _, err := p.file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return NewReader(p.file), nil
+}
+
+func (p *pendingTable) close() {
+	p.buf = bytes.Buffer{}
+	if p.file != nil {
+		p.file.Close()
+		os.Remove(p.file.Name())
+		p.file = nil
+	}
+}
+
+// nextInterleavedTable skips what is left of the current table and returns the next one.
+func (r *Reader) nextInterleavedTable() (*TableHeader, error) {
+	if r.cur != nil {
+		if err := r.SkipRows(len(r.cur.header.Columns)); err != nil {
+			return nil, err
+		}
+		r.cur.close()
+		delete(r.tables, r.cur.id)
+		r.cur = nil
+	}
+
+	for len(r.queue) == 0 {
+		if r.ended {
+			r.closeTables()
+			return nil, io.EOF
+		}
+		if err := r.step(); err != nil {
+			return nil, err
+		}
+	}
+
+	r.cur, r.queue = r.queue[0], r.queue[1:]
+	ahead, err := r.cur.rows()
+	if err != nil {
+		return nil, err
+	}
+	r.ahead = ahead
+	return r.cur.header, nil
+}
+
+// readInterleavedRow returns the next row of the current table, reading the input until there is one.
+func (r *Reader) readInterleavedRow(ncol int) (RowData, error) {
+	if r.cur == nil {
+		return nil, io.EOF
+	}
+
+	for {
+		if r.ahead != nil {
+			row, err := r.ahead.ReadRow(ncol)
+			if !errors.Is(err, io.EOF) {
+				return row, err
+			}
+			r.ahead = nil
+		}
+		if r.streaming {
+			row, err := r.readStreamRow(ncol)
+			if !errors.Is(err, io.EOF) {
+				return row, err
+			}
+			r.streaming = false
+		}
+
+		// The tables of a partial dump end with the footer
+		if r.cur.done || r.ended {
+			return nil, io.EOF
+		}
+		if err := r.step(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// step reads the next record of an interleaved dump, reading chunks of the tables other than the current one ahead.
+func (r *Reader) step() error {
+	m, err := r.br.ReadByte()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			r.ended = true
+			return nil
+		}
+		return fmt.Errorf("read marker: %w", err)
+	}
+
+	switch m {
+	case MarkerFooter, MarkerObject:
+		// Schema objects and the footer can be read with ReadObject and ReadFileFooter
+		r.br.UnreadByte()
+		r.ended = true
+		return nil
+
+	case MarkerTable:
+		var h *TableHeader
+		if err = r.decodePrefixed(&h); err != nil {
+			return err
+		}
+		if err = r.readNoData(h); err != nil {
+			return err
+		}
+		p := &pendingTable{id: h.ID, header: h}
+		r.tables[h.ID] = p
+		r.queue = append(r.queue, p)
+		return nil
+
+	case MarkerChunk, MarkerTableEnd:
+		var id uint32
+		if err = binary.Read(r.br, binary.LittleEndian, &id); err != nil {
+			return fmt.Errorf("read table ID: %w", err)
+		}
+		p := r.tables[id]
+		if p == nil {
+			return fmt.Errorf("rows of unknown table %d", id)
+		}
+
+		switch {
+		case m == MarkerTableEnd:
+			p.done = true
+		case p == r.cur:
+			r.streaming = true
+		default:
+			return r.readAhead(p)
+		}
+		return nil
+	}
+
+	r.br.UnreadByte()
+	return ErrInvalidMarker
+}
+
+// readAhead keeps the rows of a chunk of a table other than the current one.
+func (r *Reader) readAhead(p *pendingTable) error {
+	w := NewWriter(p)
+	for {
+		row, err := r.readStreamRow(len(p.header.Columns))
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return p.err
+			}
+			return err
+		}
+		w.WriteRowData(row)
+	}
+}
+
+// closeTables removes the files of the tables that were never returned.
+func (r *Reader) closeTables() {
+	for _, p := range r.tables {
+		p.close()
+	}
+}
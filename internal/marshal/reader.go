@@ -11,11 +11,29 @@ import (
 
 var ErrInvalidMarker = errors.New("invalid marker")
 
+// ErrEncrypted is returned reading the file header of an encrypted dump, which has to be decrypted first.
+var ErrEncrypted = errors.New("dump is encrypted")
+
+// EncryptedMagic starts encrypted dumps.
+const EncryptedMagic = "DENC"
+
 type Reader struct {
 	r  io.Reader
 	br *bufio.Reader
 
 	isSkipping bool
+
+	// Tables of an interleaved dump by ID, nil for other dumps. See interleave.go
+	tables map[uint32]*pendingTable
+	// Tables whose header was read, in order, and the one being returned
+	queue []*pendingTable
+	cur   *pendingTable
+	// Rows of cur read ahead, returned before those following in the input
+	ahead *Reader
+	// Set while the rows of cur follow in the input
+	streaming bool
+	// Set once the footer or the end of the input is reached
+	ended bool
 }
 
 func NewReader(r io.Reader) *Reader {
@@ -45,18 +63,38 @@ func (r *Reader) decodePrefixed(v interface{}) error {
 }
 
 func (r *Reader) ReadFileHeader() (h *FileHeader, err error) {
-	magic := make([]byte, 4)
+	// Compressed dumps are decompressed as they are read
+	if prefix, _ := r.br.Peek(4); len(prefix) > 0 {
+		if string(prefix) == EncryptedMagic {
+			return nil, ErrEncrypted
+		}
+		if c, ok := detectCodec(prefix); ok {
+			dec, err := c.NewReader(r.br)
+			if err != nil {
+				return nil, fmt.Errorf("decompress %s: %w", c.Name, err)
+			}
+			r.r, r.br = dec, bufio.NewReader(dec)
+		}
+	}
 
-	r.r.Read(magic)
+	magic := make([]byte, 4)
+	io.ReadFull(r.br, magic)
 	if string(magic) != "DUMP" {
 		return nil, errors.New("invalid magic file string")
 	}
 
 	err = r.decodePrefixed(&h)
+	if err == nil && h != nil && h.Interleaved {
+		r.tables = make(map[uint32]*pendingTable)
+	}
 	return
 }
 
 func (r *Reader) ReadTableHeader() (h *TableHeader, err error) {
+	if r.tables != nil {
+		return r.nextInterleavedTable()
+	}
+
 	m, err := r.br.ReadByte()
 	if err != nil {
 		if errors.Is(err, io.EOF) {
@@ -65,12 +103,73 @@ func (r *Reader) ReadTableHeader() (h *TableHeader, err error) {
 
 		return nil, fmt.Errorf("read marker: %w", err)
 	}
+	if m == MarkerFooter || m == MarkerObject {
+		// Schema objects and the footer end the dump, they can be read with ReadObject and ReadFileFooter
+		r.br.UnreadByte()
+		return nil, io.EOF
+	}
 	if m != MarkerTable {
 		r.br.UnreadByte()
 		return nil, ErrInvalidMarker
 	}
 
-	err = r.decodePrefixed(&h)
+	if err = r.decodePrefixed(&h); err != nil {
+		return nil, err
+	}
+	return h, r.readNoData(h)
+}
+
+// readNoData reads the NoData record following a table header into it, if there is one.
+func (r *Reader) readNoData(h *TableHeader) error {
+	// The end of the input is left for the next read to report
+	if m, err := r.br.Peek(1); err != nil || m[0] != MarkerNoData {
+		return nil
+	}
+	r.br.Discard(1)
+	return r.decodePrefixed(&h.NoData)
+}
+
+// ReadObject reads the next schema object once ReadTableHeader has returned io.EOF, returning io.EOF
+// once there are no more.
+func (r *Reader) ReadObject() (*SchemaObject, error) {
+	m, err := r.br.Peek(1)
+	if err != nil || m[0] != MarkerObject {
+		return nil, io.EOF
+	}
+	r.br.Discard(1)
+
+	var o *SchemaObject
+	if err = r.decodePrefixed(&o); err != nil {
+		return nil, fmt.Errorf("read schema object: %w", err)
+	}
+	return o, nil
+}
+
+// ReadFileFooter reads the footer once ReadTableHeader has returned io.EOF, skipping the schema objects
+// not read with ReadObject. Dumps written before footers existed have none, in which case nil is returned.
+func (r *Reader) ReadFileFooter() (f *FileFooter, err error) {
+	for {
+		if _, err = r.ReadObject(); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	m, err := r.br.ReadByte()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("read marker: %w", err)
+	}
+	if m != MarkerFooter {
+		r.br.UnreadByte()
+		return nil, ErrInvalidMarker
+	}
+
+	err = r.decodePrefixed(&f)
 	return
 }
 
@@ -83,25 +182,13 @@ func (r *Reader) ReadRows(ncol int) (rows <-chan RowData, err <-chan error) {
 		defer close(cerr)
 
 		for {
-			d := make([]*string, ncol)
-
-			m, err := r.br.ReadByte()
+			d, err := r.ReadRow(ncol)
 			if err != nil {
 				if errors.Is(err, io.EOF) {
 					break
 				}
 
-				cerr <- fmt.Errorf("read marker: %w", err)
-				return
-			}
-			if m != MarkerRow {
-				r.br.UnreadByte()
-				break
-			}
-
-			err = r.readRow(d)
-			if err != nil {
-				cerr <- fmt.Errorf("read row: %w", err)
+				cerr <- err
 				return
 			}
 
@@ -112,6 +199,37 @@ func (r *Reader) ReadRows(ncol int) (rows <-chan RowData, err <-chan error) {
 	return crows, cerr
 }
 
+// ReadRow reads the next row of the current table. io.EOF is returned once the table has no more rows.
+func (r *Reader) ReadRow(ncol int) (RowData, error) {
+	if r.tables != nil {
+		return r.readInterleavedRow(ncol)
+	}
+	return r.readStreamRow(ncol)
+}
+
+// readStreamRow reads the row that follows in the input, if any.
+func (r *Reader) readStreamRow(ncol int) (RowData, error) {
+	m, err := r.br.ReadByte()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+
+		return nil, fmt.Errorf("read marker: %w", err)
+	}
+	if m != MarkerRow {
+		r.br.UnreadByte()
+		return nil, io.EOF
+	}
+
+	d := make([]*string, ncol)
+	if err = r.readRow(d); err != nil {
+		return nil, fmt.Errorf("read row: %w", err)
+	}
+
+	return d, nil
+}
+
 func (r *Reader) readRow(cols []*string) error {
 	for i := 0; i < len(cols); i++ {
 		// Read null marker
@@ -160,6 +278,17 @@ func (r *Reader) readRow(cols []*string) error {
 }
 
 func (r *Reader) SkipRows(ncol int) error {
+	if r.tables != nil {
+		for {
+			if _, err := r.readInterleavedRow(ncol); err != nil {
+				if errors.Is(err, io.EOF) {
+					return nil
+				}
+				return fmt.Errorf("skip row: %w", err)
+			}
+		}
+	}
+
 	cols := make([]*string, ncol)
 
 	r.isSkipping = true
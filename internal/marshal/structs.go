@@ -7,18 +7,168 @@ import (
 const (
 	MarkerTable byte = 231 + iota
 	MarkerRow
+	MarkerFooter
+	// Start and end of a chunk of the rows of a table in an interleaved dump, see FileHeader.Interleaved
+	MarkerChunk
+	MarkerTableEnd
+	// Follows the header of a table whose rows were left out on purpose, see NoData
+	MarkerNoData
+	// Precedes a view, stored routine, trigger or event, after the tables and before the footer
+	MarkerObject
 )
 
 type FileHeader struct {
 	ServerVersion string
 	DatabaseName  string
 	DumpStart     time.Time
+	// TiDB TSO the data was read at, if any
+	Snapshot string
+	// Codec the dump is compressed with, as recorded inside the compressed stream, empty if it isn't
+	Compression string
+	// Managed service the server is run by, like "Aurora 3.02.0" or "RDS"
+	Managed string
+	// Binary log position the data was read at, if known
+	Binlog *BinlogPosition
+	// Percona Server compression dictionaries used by the dumped columns, by name
+	CompressionDictionaries map[string][]byte
+	// Global variables and status counters of the server as the dump started, if they were recorded
+	ServerVariables map[string]string
+	ServerStatus    map[string]string
+	// How tables should be created on restore: drop, if_not_exists or error. Empty for drop
+	CreatePolicy string
+	// Set when the rows of several tables are written at once. Every table header then carries an ID,
+	// the rows following a chunk marker belong to the table with the ID after it, and a table end
+	// marker with its ID follows the last chunk of a table. The Reader reassembles the tables
+	Interleaved bool
+	// WHERE clauses the rows of the filtered tables were read with, by table, an empty list for tables
+	// whose rows were left out. Empty if the dump holds every row, nil for dumps from before filters
+	// were recorded
+	TableFilters map[string][]string
+	// Set when the dump holds what an interrupted one didn't get to, resumed from its checkpoint. It is
+	// restored after that one, and the table the interrupted dump was in is continued, see
+	// TableHeader.Continued
+	Continues bool `json:",omitempty"`
+}
+
+type BinlogPosition struct {
+	File     string
+	Position uint64
+	GTIDSet  string
+	// False if the position couldn't be locked to the snapshot and was read just after it
+	Exact bool
 }
 
 type TableHeader struct {
+	Name string
+	// Names of the columns, in the order of the values of every row that follows
+	Columns []string
+	// Columns of binary types like VARBINARY, BLOB or BIT, whose values are raw bytes rather than text.
+	// Converters write them losslessly, as hex literals in SQL and base64 in JSON
+	BinaryColumns []string `json:",omitempty"`
+	CreateSQL     string
+	// Set when SHOW CREATE TABLE was denied and CreateSQL was rebuilt from INFORMATION_SCHEMA. It has the
+	// columns, indexes and foreign keys of the table but may lack details like check constraints,
+	// partitioning or table options
+	ReconstructedDDL bool `json:",omitempty"`
+	// Set when the rows that follow continue those of the table in the dump this one resumes, see
+	// FileHeader.Continues. The table exists already and isn't created again
+	Continued bool `json:",omitempty"`
+	// Set when the table was altered while it was dumped and was dumped again from the start. The rows
+	// that follow replace those of the earlier sections of the table, which is created again
+	Restarted bool `json:",omitempty"`
+	// Empty for base tables
+	Type   string
+	Engine string
+	// Set when the rows that follow are those of a single partition. The table then has one
+	// section per partition, each with the same header apart from this
+	Partition string
+	// Set when the rows that follow are those of one shard, starting at 1, out of Shards,
+	// split by a hash of the primary key
+	Shard  int
+	Shards int
+	// Set when the rows that follow hold only Columns, the primary key and one group of the other
+	// columns, starting at 1, out of ColumnGroups. Rows of later groups are merged into those of the
+	// first by primary key
+	ColumnGroup  int
+	ColumnGroups int
+	// FOR SYSTEM_TIME clause the rows of a system-versioned table were read with, empty for its
+	// current rows. With ALL the rows hold the whole history, including the period columns
+	SystemTime string
+	// Persistent InnoDB statistics of the table, if they were dumped
+	Stats *TableStats
+	// MySQL 8 column histograms of the table by column, as found in INFORMATION_SCHEMA.COLUMN_STATISTICS
+	Histograms map[string]string
+	// ALTER TABLE statements adding the foreign keys left out of CreateSQL because they are part of
+	// a reference cycle, to run once every table is created
+	Constraints []string
+	// Identifies the rows of the table in an interleaved dump
+	ID uint32
+	// Strategy the rows were read in chunks with and the columns ordering them, empty without chunking
+	Chunking string
+	ChunkKey []string
+
+	// Set by the Reader if the rows of the table were left out on purpose, nil for tables dumped
+	// with their rows, even if there are none
+	NoData *NoData `json:"-"`
+}
+
+// Reasons for the rows of a table to be left out of a dump, see NoData.
+const (
+	// The table filters map the table to no clause
+	NoDataFilter = "filter"
+	// The engine policy of the table is to skip its data
+	NoDataEngine = "engine"
+)
+
+// NoData is the record following the header of a table whose rows were left out of the dump on purpose,
+// telling it apart from an empty table.
+type NoData struct {
+	Reason string
+	// The engine of the table for NoDataEngine
+	Detail string `json:",omitempty"`
+}
+
+// SchemaObject is a view, stored routine, trigger or event of the database. Objects follow the tables
+// of a dump in the order they are created in: routines, views after the views they select from,
+// triggers, then events.
+type SchemaObject struct {
+	// VIEW, FUNCTION, PROCEDURE, TRIGGER or EVENT
+	Type      string
 	Name      string
-	Columns   []string
 	CreateSQL string
+	// Table a trigger is defined on
+	Table string `json:",omitempty"`
+}
+
+// TableStats holds a table's rows of mysql.innodb_table_stats and mysql.innodb_index_stats.
+type TableStats struct {
+	Rows                 int64
+	ClusteredIndexSize   int64
+	SumOfOtherIndexSizes int64
+	Indexes              []IndexStat
+}
+
+type IndexStat struct {
+	Index       string
+	Name        string
+	Value       int64
+	SampleSize  *int64
+	Description string
+}
+
+// FileFooter is written once the dump is finished. Dumps that were interrupted are marked as partial.
+type FileFooter struct {
+	Partial bool
+	DumpEnd time.Time
+	Tables  int
+	Rows    int64
+	// Tables that went over their limit, whose rows were cut short
+	Limited []string `json:",omitempty"`
+	// Hex encoded checksum of the rows of every table the dump holds, see Checksum, and the SHA-256 of
+	// them all in dump order, checked by reading the dump back. Empty for dumps written before they
+	// were recorded
+	Checksums map[string]string `json:",omitempty"`
+	Checksum  string            `json:",omitempty"`
 }
 
 type RowData = []*string
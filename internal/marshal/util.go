@@ -29,3 +29,15 @@ func readString(r io.Reader) (s string, err error) {
 
 	return string(b), nil
 }
+
+// RowSize returns the number of bytes WriteRowData uses to encode a row.
+func RowSize(r RowData) int {
+	n := 1
+	for _, v := range r {
+		n++
+		if v != nil {
+			n += binary.MaxVarintLen64 + len(*v)
+		}
+	}
+	return n
+}
@@ -3,12 +3,14 @@ package marshal
 import (
 	"encoding/binary"
 	"encoding/json"
-	"fmt"
 	"io"
 )
 
 type Writer struct {
 	w io.Writer
+
+	// Called after every record, if set
+	OnRecord func() error
 }
 
 func NewWriter(w io.Writer) *Writer {
@@ -32,41 +34,108 @@ func (d *Writer) writePrefixed(v interface{}) error {
 	return err
 }
 
+// marker writes the marker starting a record.
+func (d *Writer) marker(m byte) error {
+	_, err := d.w.Write([]byte{m})
+	return err
+}
+
+func (d *Writer) record(err error) error {
+	if err != nil || d.OnRecord == nil {
+		return err
+	}
+	return d.OnRecord()
+}
+
 func (d *Writer) WriteFileHeader(h *FileHeader) error {
-	d.w.Write([]byte("DUMP"))
+	if _, err := d.w.Write([]byte("DUMP")); err != nil {
+		return err
+	}
 
-	return d.writePrefixed(h)
+	return d.record(d.writePrefixed(h))
 }
 
 func (d *Writer) WriteTableHeader(h *TableHeader) error {
-	d.w.Write([]byte{MarkerTable})
+	if err := d.marker(MarkerTable); err != nil {
+		return err
+	}
 
-	return d.writePrefixed(h)
+	return d.record(d.writePrefixed(h))
 }
 
 func (d *Writer) WriteRowData(r RowData) error {
-	d.w.Write([]byte{MarkerRow})
+	if err := d.marker(MarkerRow); err != nil {
+		return err
+	}
 
 	buf := make([]byte, binary.MaxVarintLen64)
 
 	for _, v := range r {
 		// Write null marker: 0 if null, 1 if not
 		if v == nil {
-			d.w.Write([]byte{0})
+			if _, err := d.w.Write([]byte{0}); err != nil {
+				return err
+			}
 			continue
-		} else {
-			d.w.Write([]byte{1})
+		} else if _, err := d.w.Write([]byte{1}); err != nil {
+			return err
 		}
 
-		// Encode the value length as a varint
-		n := binary.PutUvarint(buf, uint64(len(*v)))
-		fmt.Sprint(n)
-		//d.w.Write(buf[:n])
-		d.w.Write(buf)
+		// Encode the value length as a varint, padded to its maximum length
+		binary.PutUvarint(buf, uint64(len(*v)))
+		if _, err := d.w.Write(buf); err != nil {
+			return err
+		}
 
 		// Write the string value as bytes
-		d.w.Write([]byte(*v))
+		if _, err := d.w.Write([]byte(*v)); err != nil {
+			return err
+		}
+	}
+
+	return d.record(nil)
+}
+
+// WriteChunk starts a chunk of rows of the table with the given ID, in an interleaved dump.
+func (d *Writer) WriteChunk(id uint32) error {
+	if err := d.marker(MarkerChunk); err != nil {
+		return err
+	}
+
+	return d.record(binary.Write(d.w, binary.LittleEndian, id))
+}
+
+// WriteTableEnd follows the last chunk of the table with the given ID, in an interleaved dump.
+func (d *Writer) WriteTableEnd(id uint32) error {
+	if err := d.marker(MarkerTableEnd); err != nil {
+		return err
+	}
+
+	return d.record(binary.Write(d.w, binary.LittleEndian, id))
+}
+
+// WriteNoData follows the header of a table whose rows are left out.
+func (d *Writer) WriteNoData(n *NoData) error {
+	if err := d.marker(MarkerNoData); err != nil {
+		return err
+	}
+
+	return d.record(d.writePrefixed(n))
+}
+
+// WriteObject writes a schema object, once every table was written.
+func (d *Writer) WriteObject(o *SchemaObject) error {
+	if err := d.marker(MarkerObject); err != nil {
+		return err
+	}
+
+	return d.record(d.writePrefixed(o))
+}
+
+func (d *Writer) WriteFileFooter(f *FileFooter) error {
+	if err := d.marker(MarkerFooter); err != nil {
+		return err
 	}
 
-	return nil
+	return d.record(d.writePrefixed(f))
 }
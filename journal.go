@@ -0,0 +1,390 @@
+package mysqldump
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/MouseHatGames/go-mysqldump/internal/marshal"
+	"github.com/sirupsen/logrus"
+)
+
+// WithJournal appends an entry to the journal at path every time a chunk of rows was written to the
+// output and synced to disk, with the position its table continues at, the size of the output and a
+// checksum of what was written since the previous entry. After a crash Resume truncates the output
+// back to the last chunk that made it to disk intact and has the dump continue right after it, so no
+// row is dumped twice or missed. The output has to be a file opened for reading and writing, with the
+// dump in the binary format, neither compressed nor encrypted, and tables are read one at a time. The journal is
+// removed once the dump is done.
+func WithJournal(path string) Option {
+	return func(d *Dumper) {
+		d.journalFile = path
+	}
+}
+
+// journalEntry records how far a dump got once a chunk was written. Entries without a table follow
+// the file header.
+type journalEntry struct {
+	Table string `json:",omitempty"`
+	// Index of the unit of the table, and the position in it its next chunk starts at
+	Unit        int           `json:",omitempty"`
+	Partition   string        `json:",omitempty"`
+	Shard       int           `json:",omitempty"`
+	ColumnGroup int           `json:",omitempty"`
+	Filter      int           `json:",omitempty"`
+	Offset      int           `json:",omitempty"`
+	After       []interface{} `json:",omitempty"`
+	// Set once the table, or query, was dumped completely
+	Done bool `json:",omitempty"`
+	// Rows dumped so far
+	Rows int64
+	// Size of the output, and the CRC-32 of the bytes written since the previous entry
+	End      int64
+	Checksum string
+}
+
+// resumableOutput is an output a journaled dump can be resumed into, like an *os.File.
+type resumableOutput interface {
+	io.ReadWriteSeeker
+	Truncate(size int64) error
+}
+
+// journalWriter writes the output, appending the entries of the journal once the output holds the
+// bytes they cover. Entries are marked as the dump flushes them, possibly before a write queue wrote
+// the bytes.
+type journalWriter struct {
+	w    io.Writer
+	file *os.File
+
+	mu      sync.Mutex
+	n       int64
+	base    int64
+	crc     hash.Hash32
+	pending []journalEntry
+	err     error
+}
+
+func (j *journalWriter) Write(p []byte) (int, error) {
+	n, err := j.w.Write(p)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	b := p[:n]
+	for len(j.pending) > 0 && j.n+int64(len(b)) >= j.pending[0].End {
+		k := j.pending[0].End - j.n
+		j.crc.Write(b[:k])
+		j.n, b = j.n+k, b[k:]
+		j.commit()
+	}
+	j.crc.Write(b)
+	j.n += int64(len(b))
+	return n, err
+}
+
+// mark adds an entry, appended once the output holds the bytes up to its end.
+func (j *journalWriter) mark(e journalEntry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.pending = append(j.pending, e)
+	for len(j.pending) > 0 && j.pending[0].End <= j.n {
+		j.commit()
+	}
+	return j.err
+}
+
+// commit syncs the output and appends the first pending entry.
+func (j *journalWriter) commit() {
+	e := j.pending[0]
+	j.pending = j.pending[1:]
+	e.Checksum = fmt.Sprintf("%08x", j.crc.Sum32())
+	j.crc.Reset()
+	if j.err != nil {
+		return
+	}
+
+	if s, ok := j.w.(interface{ Sync() error }); ok {
+		if err := s.Sync(); err != nil {
+			j.err = fmt.Errorf("sync output: %w", err)
+			return
+		}
+	}
+	b, err := json.Marshal(e)
+	if err == nil {
+		_, err = j.file.Write(append(b, '\n'))
+	}
+	if err == nil {
+		err = j.file.Sync()
+	}
+	if err != nil {
+		j.err = fmt.Errorf("write journal: %w", err)
+	}
+}
+
+// journalResume is where a journaled dump continues, see Resume.
+type journalResume struct {
+	entries []journalEntry
+	// Tables and queries dumped completely
+	done map[string]bool
+	// Chunk the dump continues after, nil once the dump got past it
+	at *journalEntry
+	// What the output holds, and the table and the section of it the dump continues in
+	info    *DumpInfo
+	table   *TableInfo
+	section *TableHeader
+	// Checkpoint a new dump is written from instead, see ResumeDump
+	checkpoint *Checkpoint
+}
+
+// Resume reads the journal of a dump that didn't finish, see WithJournal, and truncates the output
+// back to the end of the last chunk that was written intact. The next call to Dump or DumpAllTables,
+// given the same tables and options, then continues that dump instead of starting a new one. The
+// dump starts over if no chunk made it to the output.
+func (d *Dumper) Resume() error {
+	if d.journalFile == "" {
+		return errors.New("resuming a dump needs WithJournal")
+	}
+	out, ok := d.w.(resumableOutput)
+	if !ok {
+		return errors.New("resuming a dump needs a file opened for reading and writing as the output")
+	}
+	entries, err := readJournal(d.journalFile)
+	if err != nil {
+		return fmt.Errorf("read journal: %w", err)
+	}
+
+	// Chunks are good up to the first one the output doesn't hold as it was written
+	if _, err = out.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	good, pos := 0, int64(0)
+	for _, e := range entries {
+		crc := crc32.NewIEEE()
+		if n, _ := io.CopyN(crc, out, e.End-pos); n != e.End-pos || fmt.Sprintf("%08x", crc.Sum32()) != e.Checksum {
+			break
+		}
+		good, pos = good+1, e.End
+	}
+	if err = out.Truncate(pos); err != nil {
+		return fmt.Errorf("truncate output: %w", err)
+	}
+	if good == 0 {
+		logrus.Infof("No chunk of the journaled dump was written, starting over")
+		d.resume = nil
+		_, err = out.Seek(0, io.SeekStart)
+		return err
+	}
+
+	r := &journalResume{entries: entries[:good], done: make(map[string]bool)}
+	for _, e := range r.entries {
+		if e.Done {
+			r.done[e.Table] = true
+		}
+	}
+	if last := r.entries[good-1]; last.Table != "" && !last.Done {
+		r.at = &last
+	}
+	if _, err = out.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if r.info, r.table, r.section, err = inspectPartial(io.LimitReader(out, pos)); err != nil {
+		return fmt.Errorf("read output: %w", err)
+	}
+	if _, err = out.Seek(pos, io.SeekStart); err != nil {
+		return err
+	}
+
+	logrus.Infof("Resuming dump after %d tables and %d bytes", len(r.done), pos)
+	d.resume = r
+	return nil
+}
+
+// readJournal reads the entries of a journal, leaving out the last one if it wasn't written completely.
+func readJournal(path string) ([]journalEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []journalEntry
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 64<<20)
+	for s.Scan() {
+		var e journalEntry
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			break
+		}
+		entries = append(entries, e)
+	}
+	return entries, s.Err()
+}
+
+// inspectPartial reads a dump cut off after a record, returning what it holds along with the table
+// and the header of its last section.
+func inspectPartial(in io.Reader) (*DumpInfo, *TableInfo, *TableHeader, error) {
+	r := marshal.NewReader(in)
+	h, err := r.ReadFileHeader()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("read file header: %w", err)
+	}
+
+	info := &DumpInfo{Header: h}
+	var ti *TableInfo
+	var section *TableHeader
+	for {
+		t, err := r.ReadTableHeader()
+		if errors.Is(err, io.EOF) {
+			return info, ti, section, nil
+		}
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("read table header: %w", err)
+		}
+
+		ti, section = info.addSection(t), t
+		if err = inspectTable(r, t, ti, 0); err != nil {
+			return nil, nil, nil, fmt.Errorf("read table %s: %w", t.Name, err)
+		}
+	}
+}
+
+// startJournal has the output journaled, continuing the journal of a resumed dump.
+func (d *Dumper) startJournal() error {
+	if d.journalFile == "" {
+		if d.resume != nil && d.resume.checkpoint == nil {
+			d.resume = nil
+		}
+		return nil
+	}
+	if d.format != FormatBinary || d.tableOpen != nil || d.compressed != nil || d.encrypted != nil {
+		return errors.New("a journal needs unencrypted and uncompressed output in the binary format")
+	}
+	if _, ok := d.w.(resumableOutput); !ok {
+		return errors.New("a journal needs a file opened for reading and writing as the output")
+	}
+
+	f, err := os.Create(d.journalFile)
+	if err != nil {
+		return fmt.Errorf("create journal: %w", err)
+	}
+	j := &journalWriter{w: d.w, file: f, crc: crc32.NewIEEE()}
+	if d.resume != nil {
+		// Entries of chunks that didn't make it to the output are left out
+		for _, e := range d.resume.entries {
+			b, _ := json.Marshal(e)
+			if _, err = f.Write(append(b, '\n')); err != nil {
+				f.Close()
+				return fmt.Errorf("write journal: %w", err)
+			}
+		}
+		last := d.resume.entries[len(d.resume.entries)-1]
+		j.n, j.base = last.End, last.End
+		d.cur.TotalRows = last.Rows
+	}
+	d.journal = j
+	d.w = j
+	d.resetWriter()
+	return nil
+}
+
+// endJournal stops journaling the output, removing the journal of a dump that is done.
+func (d *Dumper) endJournal(done bool) error {
+	j := d.journal
+	if j == nil {
+		return nil
+	}
+
+	d.journal, d.resume = nil, nil
+	d.w = j.w
+	d.resetWriter()
+	err := j.file.Close()
+	if j.err != nil {
+		return j.err
+	}
+	if err == nil && done {
+		err = os.Remove(j.file.Name())
+	}
+	if err != nil {
+		return fmt.Errorf("close journal: %w", err)
+	}
+	return nil
+}
+
+// journalPosition returns the size of the output once everything written so far reaches it.
+func (d *Dumper) journalPosition() int64 {
+	return d.journal.base + atomic.LoadInt64(&d.writtenBytes)
+}
+
+// journalHeader journals the file header.
+func (d *Dumper) journalHeader() error {
+	if d.journal == nil {
+		return nil
+	}
+	return d.journal.mark(journalEntry{End: d.journalPosition()})
+}
+
+// journalChunk journals a chunk of the unit of a table, with the position its next chunk starts at.
+func (d *Dumper) journalChunk(name string, unit tableUnit, filter int, offset int, after []interface{}) error {
+	if d.journal == nil {
+		return nil
+	}
+	return d.journal.mark(journalEntry{
+		Table:       name,
+		Unit:        d.journalUnit,
+		Partition:   unit.partition,
+		Shard:       unit.shard,
+		ColumnGroup: unit.group,
+		Filter:      filter,
+		Offset:      offset,
+		After:       after,
+		Rows:        d.cur.TotalRows,
+		End:         d.journalPosition(),
+	})
+}
+
+// journalDone journals a table or query that was dumped completely.
+func (d *Dumper) journalDone(name string) error {
+	if d.journal == nil {
+		return nil
+	}
+	return d.journal.mark(journalEntry{Table: name, Done: true, Rows: d.cur.TotalRows, End: d.journalPosition()})
+}
+
+// resumedDone reports whether a resumed dump holds a table or query completely already.
+func (d *Dumper) resumedDone(name string) bool {
+	return d.resume != nil && d.resume.done[name]
+}
+
+// resumeAt returns the chunk of a table a resumed dump continues after, nil if it doesn't continue
+// in the table.
+func (d *Dumper) resumeAt(name string, units []tableUnit) (*journalEntry, error) {
+	if d.resume == nil || d.resume.at == nil || d.resume.at.Table != name {
+		return nil, nil
+	}
+
+	at := d.resume.at
+	if at.Unit >= len(units) {
+		return nil, fmt.Errorf("journal doesn't match table %s", name)
+	}
+	if u := units[at.Unit]; u.partition != at.Partition || u.shard != at.Shard || u.group != at.ColumnGroup {
+		return nil, fmt.Errorf("journal doesn't match table %s", name)
+	}
+	return at, nil
+}
+
+// resumeStart returns the chunk the rows of a table continue after, once, nil if they start over.
+func (d *Dumper) resumeStart(name string) *journalEntry {
+	if d.resume == nil || d.resume.at == nil || d.resume.at.Table != name {
+		return nil
+	}
+
+	at := d.resume.at
+	d.resume.at = nil
+	return at
+}
@@ -0,0 +1,166 @@
+package mysqldump
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/MouseHatGames/go-mysqldump/internal/marshal"
+)
+
+func crcOf(b []byte) string {
+	return fmt.Sprintf("%08x", crc32.ChecksumIEEE(b))
+}
+
+func TestJournalWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dump.journal")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	j := &journalWriter{w: &out, file: f, crc: crc32.NewIEEE()}
+
+	// Entries wait for the output to hold the bytes they cover
+	if err = j.mark(journalEntry{Table: "t", Offset: 2, End: 5}); err != nil {
+		t.Fatal(err)
+	}
+	j.Write([]byte("abc"))
+	if entries, _ := readJournal(path); len(entries) != 0 {
+		t.Errorf("journaled %+v before the output held the chunk", entries)
+	}
+	j.Write([]byte("defg"))
+	if err = j.mark(journalEntry{Table: "t", Done: true, End: 7}); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	entries, err := readJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []journalEntry{
+		{Table: "t", Offset: 2, End: 5, Checksum: crcOf([]byte("abcde"))},
+		{Table: "t", Done: true, End: 7, Checksum: crcOf([]byte("fg"))},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("journaled %+v, want %+v", entries, want)
+	}
+}
+
+// journaledDump writes a dump of a table of four rows in chunks of two, along with its journal, the
+// journal ending with an entry cut short. It returns the paths of both and where the chunks end.
+func journaledDump(t *testing.T) (string, string, []int64) {
+	var buf bytes.Buffer
+	w := marshal.NewWriter(&buf)
+	w.WriteFileHeader(&FileHeader{})
+	ends := []int64{int64(buf.Len())}
+	w.WriteTableHeader(&TableHeader{Name: "t", Columns: []string{"id", "name"}})
+	for i, r := range []RowData{stringRow("1", "ann"), stringRow("2", "bob"), stringRow("3", "cid"), stringRow("4", "dan")} {
+		w.WriteRowData(r)
+		if i%2 == 1 {
+			ends = append(ends, int64(buf.Len()))
+		}
+	}
+
+	dir := t.TempDir()
+	out, journal := filepath.Join(dir, "app.dump"), filepath.Join(dir, "app.dump.journal")
+	if err := ioutil.WriteFile(out, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var lines []byte
+	b := buf.Bytes()
+	for i, e := range []journalEntry{
+		{End: ends[0], Checksum: crcOf(b[:ends[0]])},
+		{Table: "t", Offset: 2, After: []interface{}{"2", "bob"}, Rows: 2, End: ends[1], Checksum: crcOf(b[ends[0]:ends[1]])},
+		{Table: "t", Offset: 4, After: []interface{}{"4", "dan"}, Rows: 4, End: ends[2], Checksum: crcOf(b[ends[1]:ends[2]])},
+	} {
+		line, _ := json.Marshal(e)
+		if i == 2 {
+			// Torn by the crash
+			line = line[:len(line)/2]
+		}
+		lines = append(lines, append(line, '\n')...)
+	}
+	if err := ioutil.WriteFile(journal, lines, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return out, journal, ends
+}
+
+func resumeJournal(t *testing.T, out, journal string) *Dumper {
+	f, err := os.OpenFile(out, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	d := NewDumper(nil, f, 2, WithJournal(journal))
+	if err = d.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func outputSize(t *testing.T, path string) int64 {
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fi.Size()
+}
+
+func TestJournalResume(t *testing.T) {
+	out, journal, ends := journaledDump(t)
+	d := resumeJournal(t, out, journal)
+
+	// The last chunk was written but its entry wasn't, it's dumped again
+	if size := outputSize(t, out); size != ends[1] {
+		t.Errorf("truncated the output to %d bytes, want %d", size, ends[1])
+	}
+	at := d.resume.at
+	if at == nil {
+		t.Fatal("resumed at the start of the dump")
+	}
+	if at.Table != "t" || at.Offset != 2 || at.Rows != 2 {
+		t.Errorf("resumed after %+v, want the first chunk of t", at)
+	}
+	// Keys are written to the journal as they were read
+	if want := []interface{}{"2", "bob"}; !reflect.DeepEqual(at.After, want) {
+		t.Errorf("resumed after key %#v, want %#v", at.After, want)
+	}
+	if d.resume.table == nil || d.resume.table.Rows != 2 || d.resume.section.Name != "t" {
+		t.Errorf("resumed in table %+v, want t with 2 rows", d.resume.table)
+	}
+}
+
+func TestJournalResumeCorrupted(t *testing.T) {
+	out, journal, ends := journaledDump(t)
+	b, _ := ioutil.ReadFile(out)
+	b[ends[1]-1] ^= 0xff
+	ioutil.WriteFile(out, b, 0644)
+
+	// The chunk doesn't match its checksum anymore, the dump continues after the file header
+	d := resumeJournal(t, out, journal)
+	if size := outputSize(t, out); size != ends[0] {
+		t.Errorf("truncated the output to %d bytes, want %d", size, ends[0])
+	}
+	if d.resume.at != nil || len(d.resume.done) != 0 {
+		t.Errorf("resumed after %+v, want the start of the tables", d.resume.at)
+	}
+
+	// Nothing intact, the dump starts over
+	b[0] ^= 0xff
+	ioutil.WriteFile(out, b, 0644)
+	if d = resumeJournal(t, out, journal); d.resume != nil {
+		t.Errorf("resumed a dump with no intact chunk")
+	}
+	if size := outputSize(t, out); size != 0 {
+		t.Errorf("truncated the output to %d bytes, want 0", size)
+	}
+}
@@ -0,0 +1,123 @@
+package mysqldump
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// legacyMetadata reports whether table metadata is read with SHOW statements instead of INFORMATION_SCHEMA.
+// Before MySQL 5.6 every INFORMATION_SCHEMA query opens all the tables of the schema, which takes minutes
+// on large ones, and some of the columns used elsewhere don't exist yet.
+func (d *Dumper) legacyMetadata() bool {
+	if d.isPQ() {
+		return false
+	}
+	s, err := d.server()
+	return err == nil && s.flavor == flavorMySQL && !s.atLeast(5, 6, 0)
+}
+
+// showColumns lists the columns of a table with SHOW COLUMNS, in table order.
+func showColumns(ctx context.Context, db *sql.DB, table string, schema string) ([]string, error) {
+	rows, err := db.QueryContext(ctx, stmtShowColumns(table, schema))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var res []string
+	data := make([]sql.NullString, len(cols))
+	ptrs := make([]interface{}, len(cols))
+	for i := range data {
+		ptrs[i] = &data[i]
+	}
+	for rows.Next() {
+		if err = rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		// The first column is Field
+		res = append(res, data[0].String)
+	}
+	return res, rows.Err()
+}
+
+// showTableStatus returns the SHOW TABLE STATUS line of a table, keyed by column name.
+func (d *Dumper) showTableStatus(table string, schema string) (map[string]string, error) {
+	status, err := d.queryRowMap(stmtShowTableStatus(schema), likeName(table))
+	if err != nil {
+		return nil, err
+	}
+	if len(status) == 0 || status["Name"] == "" {
+		return nil, fmt.Errorf("table %s not found", table)
+	}
+	return status, nil
+}
+
+func (d *Dumper) showTableMeta(table string, schema string) (tableMeta, error) {
+	status, err := d.showTableStatus(table, schema)
+	if err != nil {
+		return tableMeta{}, err
+	}
+
+	m := tableMeta{engine: status["Engine"]}
+	if status["Engine"] == "" && strings.EqualFold(status["Comment"], "VIEW") {
+		m.typ = "VIEW"
+	}
+	return m, nil
+}
+
+func (d *Dumper) showTableStats(table string, schema string) (rows int64, avgRowLength int64, err error) {
+	status, err := d.showTableStatus(table, schema)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	rows, _ = strconv.ParseInt(status["Rows"], 10, 64)
+	avgRowLength, _ = strconv.ParseInt(status["Avg_row_length"], 10, 64)
+	return rows, avgRowLength, nil
+}
+
+// showIndexColumns lists the columns of the indexes of a table with SHOW INDEX, in index order.
+func (d *Dumper) showIndexColumns(table string, schema string) ([]indexColumn, error) {
+	rows, err := d.db.QueryContext(d.context(), stmtShowIndex(table, schema))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var res []indexColumn
+	data := make([]sql.NullString, len(names))
+	ptrs := make([]interface{}, len(names))
+	for i := range data {
+		ptrs[i] = &data[i]
+	}
+	for rows.Next() {
+		if err = rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		line := make(map[string]string, len(names))
+		for i, n := range names {
+			line[n] = data[i].String
+		}
+		res = append(res, indexColumn{
+			index:    line["Key_name"],
+			column:   line["Column_name"],
+			primary:  line["Key_name"] == "PRIMARY",
+			unique:   line["Non_unique"] == "0",
+			nullable: strings.EqualFold(line["Null"], "YES"),
+		})
+	}
+	return res, rows.Err()
+}
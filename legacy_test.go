@@ -0,0 +1,39 @@
+package mysqldump
+
+import (
+	"database/sql/driver"
+	"io/ioutil"
+	"testing"
+)
+
+func TestShowTableStatus(t *testing.T) {
+	var gotArgs []driver.Value
+	db, f := openFakeDB(t, func(q string, args []driver.Value) (*fakeRows, error) {
+		gotArgs = args
+		return stringRows([]string{"Name", "Engine", "Rows", "Avg_row_length", "Comment"},
+			[]string{"order_items", "InnoDB", "42", "100", ""}), nil
+	})
+	d := NewDumper(db, ioutil.Discard, 0)
+
+	m, err := d.showTableMeta("order_items", "shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.engine != "InnoDB" {
+		t.Errorf("engine = %q, want InnoDB", m.engine)
+	}
+	if q := f.ran()[0]; q != "SHOW TABLE STATUS FROM `shop` LIKE ?" {
+		t.Errorf("ran %q", q)
+	}
+	if len(gotArgs) != 1 || gotArgs[0] != `order\_items` {
+		t.Errorf("LIKE %q, want the name with _ escaped", gotArgs)
+	}
+
+	rows, avg, err := d.showTableStats("order_items", "shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows != 42 || avg != 100 {
+		t.Errorf("stats = %d rows of %d bytes, want 42 of 100", rows, avg)
+	}
+}
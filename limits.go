@@ -0,0 +1,134 @@
+package mysqldump
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// LimitAction is what happens to a table that goes over its TableLimit.
+type LimitAction int
+
+const (
+	// LimitSkip stops reading the table, keeping the rows dumped so far, and goes on with the next one.
+	LimitSkip LimitAction = iota
+	// LimitFail fails the dump with ErrTableLimit.
+	LimitFail
+)
+
+// ParseLimitAction parses "skip" or "fail".
+func ParseLimitAction(s string) (LimitAction, error) {
+	switch strings.ToLower(s) {
+	case "skip", "":
+		return LimitSkip, nil
+	case "fail":
+		return LimitFail, nil
+	}
+	return 0, fmt.Errorf("invalid limit action: %s", s)
+}
+
+func (a LimitAction) String() string {
+	if a == LimitFail {
+		return "fail"
+	}
+	return "skip"
+}
+
+// TableLimit is how long a table may take to dump and how many bytes of rows it may dump.
+type TableLimit struct {
+	// No limit if 0
+	MaxDuration time.Duration
+	MaxBytes    int64
+	Action      LimitAction
+}
+
+// ErrTableLimit is returned by a dump failed by a table that went over a TableLimit with LimitFail.
+var ErrTableLimit = errors.New("table went over its limit")
+
+// WithTableLimits sets the limits of tables by name, with "*" for the tables without their own, so
+// that one pathological table can't take the whole backup window. Limits are checked between chunks,
+// so a table read in a single query is only stopped once it was read. Tables stopped by LimitSkip
+// keep the rows dumped so far, and are warned about, returned by TableLimitBreaches and listed in the
+// footer of the dump (FileFooter.Limited).
+func WithTableLimits(limits map[string]TableLimit) Option {
+	return func(d *Dumper) {
+		d.tableLimits = limits
+	}
+}
+
+// TableLimitBreach is a table that went over its limit.
+type TableLimitBreach struct {
+	Table string
+	// "duration" or "size"
+	Limit   string
+	Action  LimitAction
+	Elapsed time.Duration
+	Rows    int64
+	Bytes   int64
+}
+
+// TableLimitBreaches returns the tables of the last dump that went over their limit, in dump order,
+// see WithTableLimits.
+func (d *Dumper) TableLimitBreaches() []TableLimitBreach {
+	return append([]TableLimitBreach(nil), d.limitBreaches...)
+}
+
+// tableLimitError stops reading a table that went over its limit.
+type tableLimitError struct {
+	breach TableLimitBreach
+}
+
+func (e *tableLimitError) Error() string {
+	b := e.breach
+	return fmt.Sprintf("table %s went over its %s limit after %s and %d bytes", b.Table, b.Limit, b.Elapsed.Round(time.Second), b.Bytes)
+}
+
+func (e *tableLimitError) Unwrap() error {
+	return ErrTableLimit
+}
+
+// checkTableLimit returns a *tableLimitError if a table went over its limit after dumping rows of bytes
+// since it started, recording the breach.
+func (d *Dumper) checkTableLimit(name string, started time.Time, rows int64, bytes int64) error {
+	l, ok := d.tableLimits[name]
+	if !ok {
+		if l, ok = d.tableLimits["*"]; !ok {
+			return nil
+		}
+	}
+
+	b := TableLimitBreach{Table: name, Action: l.Action, Elapsed: time.Since(started), Rows: rows, Bytes: bytes}
+	switch {
+	case l.MaxDuration > 0 && b.Elapsed > l.MaxDuration:
+		b.Limit = "duration"
+	case l.MaxBytes > 0 && bytes > l.MaxBytes:
+		b.Limit = "size"
+	default:
+		return nil
+	}
+
+	d.limitBreaches = append(d.limitBreaches, b)
+	err := &tableLimitError{breach: b}
+	if l.Action == LimitSkip {
+		d.warn(WarningTableLimit, name, "Skipping the rest of %s: %s", name, err)
+	}
+	return err
+}
+
+// limitedTables returns the tables of the dump stopped by LimitSkip.
+func (d *Dumper) limitedTables() []string {
+	var tables []string
+	for _, b := range d.limitBreaches {
+		if b.Action == LimitSkip {
+			tables = append(tables, b.Table)
+		}
+	}
+	return tables
+}
+
+// skipLimited reports whether a table read in parallel was stopped by LimitSkip.
+func (t *parallelTable) skipLimited() bool {
+	return atomic.LoadInt32(&t.limited) == 1
+}
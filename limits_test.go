@@ -0,0 +1,82 @@
+package mysqldump
+
+import (
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// limitedRead reads the table t of ids 1 to 10 in chunks of 2, the table having started long ago.
+func limitedRead(t *testing.T, limits map[string]TableLimit) (*Dumper, []interface{}, error) {
+	db, _ := openFakeDB(t, keysetDB(t, 10))
+	d := NewDumper(db, ioutil.Discard, 2, WithTableLimits(limits))
+	d.chunkKeys = map[string]chunkKey{"t": {strategy: ChunkPrimaryKey, columns: []string{"id"}}}
+	d.tableStart = time.Now().Add(-time.Hour)
+
+	var got []interface{}
+	var wg sync.WaitGroup
+	err := d.readTableValues("t", tableUnit{}, []string{"id"}, "app", &wg, func(row RowData) error {
+		got = append(got, rowValues(row)...)
+		return nil
+	})
+	return d, got, err
+}
+
+func TestTableLimitSkip(t *testing.T) {
+	d, got, err := limitedRead(t, map[string]TableLimit{"*": {MaxDuration: time.Minute}})
+	var limit *tableLimitError
+	if !errors.As(err, &limit) || !errors.Is(err, ErrTableLimit) {
+		t.Fatalf("read with %v, want a table limit error", err)
+	}
+	// Limits are checked between chunks
+	if want := []interface{}{"1", "2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("wrote %q, want %q", got, want)
+	}
+
+	b := d.TableLimitBreaches()
+	if len(b) != 1 || b[0].Table != "t" || b[0].Limit != "duration" || b[0].Action != LimitSkip || b[0].Elapsed < time.Hour {
+		t.Errorf("breaches %+v, want the duration of t", b)
+	}
+	if tables := d.limitedTables(); !reflect.DeepEqual(tables, []string{"t"}) {
+		t.Errorf("limited %q, want t", tables)
+	}
+	w := d.Warnings()
+	if len(w) != 1 || w[0].Kind != WarningTableLimit {
+		t.Errorf("warned %+v, want the skipped table", w)
+	}
+}
+
+func TestTableLimitFail(t *testing.T) {
+	d, _, err := limitedRead(t, map[string]TableLimit{"t": {MaxDuration: time.Minute, Action: LimitFail}})
+	if !errors.Is(err, ErrTableLimit) {
+		t.Fatalf("read with %v, want ErrTableLimit", err)
+	}
+	if len(d.limitedTables()) != 0 || len(d.Warnings()) != 0 {
+		t.Errorf("failed table listed as skipped")
+	}
+}
+
+func TestTableLimitOverride(t *testing.T) {
+	// The limits of a table replace those of "*"
+	d, got, err := limitedRead(t, map[string]TableLimit{"*": {MaxDuration: time.Minute}, "t": {}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 10 || len(d.TableLimitBreaches()) != 0 {
+		t.Errorf("wrote %d rows with breaches %+v, want all 10", len(got), d.TableLimitBreaches())
+	}
+
+	d = NewDumper(nil, ioutil.Discard, 0, WithTableLimits(map[string]TableLimit{"t": {MaxBytes: 100}}))
+	if err = d.checkTableLimit("t", time.Now(), 5, 100); err != nil {
+		t.Errorf("checked 100 bytes against 100 with %v", err)
+	}
+	if err = d.checkTableLimit("t", time.Now(), 6, 101); err == nil || d.limitBreaches[0].Limit != "size" {
+		t.Errorf("checked 101 bytes against 100 with %v, breaches %+v", err, d.limitBreaches)
+	}
+	if err = d.checkTableLimit("u", time.Now(), 6, 1<<30); err != nil {
+		t.Errorf("checked a table without limits with %v", err)
+	}
+}
@@ -0,0 +1,664 @@
+package mysqldump
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/MouseHatGames/go-mysqldump/internal/marshal"
+	"github.com/sirupsen/logrus"
+)
+
+// ConflictPolicy controls what happens when a restored row collides with an existing one.
+type ConflictPolicy int
+
+const (
+	// ConflictReplace overwrites existing rows (REPLACE INTO).
+	ConflictReplace ConflictPolicy = iota
+	// ConflictIgnore keeps existing rows and drops the restored ones (INSERT IGNORE).
+	ConflictIgnore
+	// ConflictError aborts the restore on the first duplicate key (INSERT).
+	ConflictError
+)
+
+// ParseConflictPolicy parses "replace", "ignore" or "error".
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	switch strings.ToLower(s) {
+	case "replace", "":
+		return ConflictReplace, nil
+	case "ignore":
+		return ConflictIgnore, nil
+	case "error":
+		return ConflictError, nil
+	}
+
+	return 0, fmt.Errorf("invalid conflict policy: %s", s)
+}
+
+func (p ConflictPolicy) verb() string {
+	switch p {
+	case ConflictIgnore:
+		return "INSERT IGNORE INTO"
+	case ConflictError:
+		return "INSERT INTO"
+	}
+	return "REPLACE INTO"
+}
+
+type LoaderOptions struct {
+	// If nil, all tables will be restored
+	Tables     []string
+	SkipCreate bool
+	Conflict   ConflictPolicy
+	// Number of connections inserting rows concurrently, defaults to 1
+	Parallelism int
+	// Maximum size in bytes of a single INSERT statement, defaults to 1000000
+	QuerySize int
+	// If set, statements are written here instead of being executed
+	DryRun io.Writer
+	// What to do with Percona Server column compression clauses
+	Percona ClausePolicy
+	// Applied to the CREATE statement of every table
+	DDLTransforms []DDLTransform
+	// If set, only the rows of this shard of sharded tables are restored, starting at 1, along
+	// with the tables that weren't sharded. See WithShards
+	Shard int
+	// How tables are created, CreateAuto following the dump
+	Create CreatePolicy
+	// Restore the optimizer statistics saved with WithOptimizerStats once the rows are loaded,
+	// running ANALYZE TABLE on the tables without any or if they can't be written
+	Stats bool
+	// How tables whose columns on the target differ from the dumped ones are restored, checked before
+	// inserting their rows
+	SchemaCheck SchemaCheck
+	// New names of dumped columns by table and old name, to restore rows dumped before a column was
+	// renamed. Columns renamed to "" are left out. Tables created from the dump keep the dumped names,
+	// so renames are meant for existing tables, see SkipCreate and CreateIfNotExists
+	ColumnMap map[string]map[string]string
+	// How the values of columns are restored, by table and column name as inserted
+	ColumnPolicies map[string]map[string]ColumnPolicy
+}
+
+// LoadReport summarizes what a Loader restored.
+type LoadReport struct {
+	Database string
+	Tables   []string
+	Skipped  []string
+	// Views, routines, triggers and events created
+	Objects []string
+	Rows    int64
+}
+
+// Loader restores a binary dump into a MySQL database.
+type Loader struct {
+	db  *sql.DB
+	opt LoaderOptions
+
+	tables map[string]bool
+	report LoadReport
+	// Tables of the current load already created, for the ones dumped by partition
+	created map[string]bool
+	// Server restored into, nil if unknown
+	target *serverInfo
+	// Create policy of the current load
+	create CreatePolicy
+	// Optimizer statistics and column histograms of the tables loaded, if dumped
+	stats      map[string]*marshal.TableStats
+	histograms map[string]map[string]string
+	// Foreign keys of the tables loaded to add once they are all created
+	constraints map[string][]string
+	// Position of the dumped columns inserted into each table checked, see SchemaCheck
+	columns map[string][]int
+}
+
+// NewLoader creates a new loader instance. db may be nil when doing a dry run.
+func NewLoader(db *sql.DB, opt LoaderOptions) *Loader {
+	if opt.Parallelism <= 0 {
+		opt.Parallelism = 1
+	}
+	if opt.QuerySize <= 0 {
+		opt.QuerySize = 1000000
+	}
+
+	l := &Loader{
+		db:  db,
+		opt: opt,
+	}
+	if len(opt.Tables) > 0 {
+		l.tables = make(map[string]bool, len(opt.Tables))
+		for _, t := range opt.Tables {
+			l.tables[t] = true
+		}
+	}
+
+	return l
+}
+
+// Load reads a dump from in and replays it into the database.
+func (l *Loader) Load(in io.Reader) error {
+	r := marshal.NewReader(in)
+
+	h, err := r.ReadFileHeader()
+	if err != nil {
+		return fmt.Errorf("read file header: %w", err)
+	}
+	logrus.Infof("Restoring dump of %s taken at %s", h.DatabaseName, h.DumpStart)
+	l.report = LoadReport{Database: h.DatabaseName}
+	l.created = make(map[string]bool)
+	l.create = l.opt.Create.resolve(h.CreatePolicy)
+	l.stats = make(map[string]*marshal.TableStats)
+	l.histograms = make(map[string]map[string]string)
+	l.constraints = make(map[string][]string)
+	l.columns = make(map[string][]int)
+
+	e, err := l.newExecutor()
+	if err != nil {
+		return err
+	}
+
+	if !l.opt.SkipCreate && !l.stripPercona() {
+		for _, q := range createDictionaries(h) {
+			if err = e.execNow(q); err != nil {
+				e.close()
+				return fmt.Errorf("create compression dictionary: %w", err)
+			}
+		}
+	}
+
+	for {
+		t, err := r.ReadTableHeader()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			e.close()
+			return fmt.Errorf("read table header: %w", err)
+		}
+
+		if t.Type == TableTypeQuery {
+			logrus.Infof("Skipping query result %s", t.Name)
+			if err = r.SkipRows(len(t.Columns)); err != nil && !errors.Is(err, io.EOF) {
+				e.close()
+				return fmt.Errorf("skip query %s: %w", t.Name, err)
+			}
+			continue
+		}
+
+		if !l.includes(t.Name) {
+			logrus.Infof("Skipping table %s", t.Name)
+			l.report.Skipped = append(l.report.Skipped, t.Name)
+			if err = r.SkipRows(len(t.Columns)); err != nil && !errors.Is(err, io.EOF) {
+				e.close()
+				return fmt.Errorf("skip table %s: %w", t.Name, err)
+			}
+			continue
+		}
+
+		if err = l.loadTable(r, t, e); err != nil {
+			e.close()
+			return fmt.Errorf("load table %s: %w", t.Name, err)
+		}
+		if !l.created[t.Name] {
+			l.report.Tables = append(l.report.Tables, t.Name)
+			l.created[t.Name] = true
+		}
+		if t.Stats != nil {
+			l.stats[t.Name] = t.Stats
+		}
+		if t.Histograms != nil {
+			l.histograms[t.Name] = t.Histograms
+		}
+		if len(t.Constraints) > 0 {
+			l.constraints[t.Name] = t.Constraints
+		}
+	}
+
+	if !l.opt.SkipCreate {
+		if err = l.addConstraints(e); err != nil {
+			e.close()
+			return err
+		}
+		if err = l.loadObjects(r, e); err != nil {
+			e.close()
+			return err
+		}
+	}
+
+	if l.opt.Stats {
+		if err = l.restoreStats(e); err != nil {
+			e.close()
+			return err
+		}
+	}
+	return e.close()
+}
+
+// addConstraints adds the foreign keys left out of the DDL of the tables loaded.
+func (l *Loader) addConstraints(e *executor) error {
+	if err := e.wait(); err != nil {
+		return err
+	}
+
+	for _, name := range l.report.Tables {
+		for _, q := range l.constraints[name] {
+			err := e.execNow(q)
+			// Tables that already existed have their foreign keys
+			if err != nil && l.create == CreateIfNotExists {
+				logrus.Warnf("Can't add foreign key to %s: %s", name, err)
+			} else if err != nil {
+				return fmt.Errorf("add foreign key to %s: %w", name, err)
+			}
+		}
+		if len(l.constraints[name]) > 0 {
+			logrus.Infof("Added %d foreign keys to %s", len(l.constraints[name]), name)
+		}
+	}
+	return nil
+}
+
+// loadObjects creates the views, routines, triggers and events following the tables, those of the
+// tables restored if not restoring every table, see restoresObject.
+func (l *Loader) loadObjects(r *marshal.Reader, e *executor) error {
+	for {
+		o, err := r.ReadObject()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if !restoresObject(o, l.tables == nil, l.includes) {
+			continue
+		}
+
+		ddl := applyDDL(o.CreateSQL, l.opt.DDLTransforms)
+		err = execAll(e, l.create.objectStatements(o, ddl))
+		// Objects that already exist are kept
+		if err != nil && l.create == CreateIfNotExists {
+			logrus.Warnf("Can't create %s %s: %s", strings.ToLower(o.Type), o.Name, err)
+		} else if err != nil {
+			return fmt.Errorf("create %s %s: %w", strings.ToLower(o.Type), o.Name, err)
+		}
+		l.report.Objects = append(l.report.Objects, o.Name)
+	}
+}
+
+// includes reports whether a table is restored, comparing its name like the target server does.
+func (l *Loader) includes(name string) bool {
+	if l.tables == nil || l.tables[name] {
+		return true
+	}
+	if l.target == nil || l.target.lowerCaseTableNames == 0 {
+		return false
+	}
+	for t := range l.tables {
+		if strings.EqualFold(t, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Report returns what the last call to Load restored.
+func (l *Loader) Report() LoadReport {
+	return l.report
+}
+
+// Restore replays a whole dump into db with a new Loader, creating its tables and inserting their rows
+// in batches, and returns what it restored.
+func Restore(db *sql.DB, in io.Reader, opt LoaderOptions) (LoadReport, error) {
+	l := NewLoader(db, opt)
+	err := l.Load(in)
+	return l.Report(), err
+}
+
+func (l *Loader) loadTable(r *marshal.Reader, t *marshal.TableHeader, e *executor) error {
+	if t.Type == TableTypeSequence {
+		return l.loadSequence(r, t, e)
+	}
+
+	// A continued table was created by the dump resumed, a restarted one is created again over the rows
+	// loaded of it before. Existing tables kept can't tell those rows from their own
+	restarted := t.Restarted && l.created[t.Name]
+	if restarted && (l.opt.SkipCreate || l.create == CreateIfNotExists) {
+		return errRestartedExisting
+	}
+	if restarted {
+		for section := range l.columns {
+			if section == t.Name || strings.HasPrefix(section, t.Name+"/") {
+				delete(l.columns, section)
+			}
+		}
+	}
+	if !l.opt.SkipCreate && (!l.created[t.Name] && !t.Continued || restarted) {
+		// Make sure pending inserts don't race with the table being recreated
+		if err := e.wait(); err != nil {
+			return err
+		}
+		ddl := t.CreateSQL
+		if l.stripPercona() {
+			ddl = stripPercona(ddl)
+		}
+		ddl = applyDDL(ddl, l.opt.DDLTransforms)
+		create := l.create
+		if restarted {
+			create = CreateDrop
+		}
+		if err := execAll(e, create.createStatements("TABLE", t.Name, ddl)); err != nil {
+			return fmt.Errorf("create table: %w", err)
+		}
+	}
+
+	// The period columns of a table's history can only be inserted with system_versioning_insert_history
+	if t.SystemTime == "ALL" && l.target != nil && !l.target.supports(featureInsertHistory) {
+		return fmt.Errorf("table %s holds the history of a system-versioned table, which needs MariaDB 10.11 or later to restore", t.Name)
+	}
+
+	if shardSkipped(t, l.opt.Shard) {
+		if err := r.SkipRows(len(t.Columns)); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		return nil
+	}
+
+	names := l.columnNames(t)
+	// The column groups of a table each have their own columns
+	section := t.Name
+	if t.ColumnGroup > 1 {
+		section = fmt.Sprintf("%s/%d", t.Name, t.ColumnGroup)
+	}
+	keep, checked := l.columns[section]
+	if !checked {
+		var err error
+		if keep, err = l.insertColumns(t, names); err != nil {
+			return fmt.Errorf("check schema: %w", err)
+		}
+		l.columns[section] = keep
+	}
+	columns := names
+	bin := binaryFlags(t.BinaryColumns, t.Columns)
+	if keep != nil {
+		columns = make([]string, len(keep))
+		var kept []bool
+		if bin != nil {
+			kept = make([]bool, len(keep))
+		}
+		for i, c := range keep {
+			columns[i] = names[c]
+			if bin != nil {
+				kept[i] = bin[c]
+			}
+		}
+		bin = kept
+	}
+	policies := l.columnPolicies(t.Name, columns)
+	if policies != nil {
+		columns = append(columns, policies.added...)
+	}
+
+	verb := l.opt.Conflict.verb()
+	merge := mergeClause(t, columns)
+	if t.ColumnGroup > 1 {
+		// Every column of the group was left out
+		if merge == "" {
+			if err := r.SkipRows(len(t.Columns)); err != nil && !errors.Is(err, io.EOF) {
+				return err
+			}
+			return nil
+		}
+		// The rows of the first group have to be inserted first
+		if err := e.wait(); err != nil {
+			return err
+		}
+		verb = "INSERT INTO"
+	}
+	prefix := fmt.Sprintf("%s %s%s (%s) VALUES ", verb, quoteName(t.Name), partitionClause(t), quoteColumns(columns, false))
+
+	var buf bytes.Buffer
+	nrows := 0
+	for {
+		row, err := r.ReadRow(len(t.Columns))
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+
+		if buf.Len() == 0 {
+			buf.WriteString(prefix)
+		} else {
+			buf.Write(comma)
+		}
+		if keep != nil {
+			kept := make(RowData, len(keep))
+			for i, c := range keep {
+				kept[i] = row[c]
+			}
+			row = kept
+		}
+		writeRow(&buf, policies.apply(row), bin)
+		nrows++
+
+		if buf.Len() > l.opt.QuerySize {
+			if err = e.exec(buf.String() + merge); err != nil {
+				return err
+			}
+			buf.Reset()
+		}
+	}
+
+	if buf.Len() > 0 {
+		if err := e.exec(buf.String() + merge); err != nil {
+			return err
+		}
+	}
+
+	if t.ColumnGroup > 1 {
+		logrus.Infof("Merged column group %d/%d of %d rows into %s", t.ColumnGroup, t.ColumnGroups, nrows, t.Name)
+		return nil
+	}
+	l.report.Rows += int64(nrows)
+	if t.Partition != "" {
+		logrus.Infof("Restored %d rows into %s partition %s", nrows, t.Name, t.Partition)
+	} else if t.Shard > 0 {
+		logrus.Infof("Restored %d rows of shard %d/%d into %s", nrows, t.Shard, t.Shards, t.Name)
+	} else {
+		logrus.Infof("Restored %d rows into %s", nrows, t.Name)
+	}
+	return nil
+}
+
+func (l *Loader) loadSequence(r *marshal.Reader, t *marshal.TableHeader, e *executor) error {
+	if err := e.wait(); err != nil {
+		return err
+	}
+	if !l.opt.SkipCreate {
+		if err := execAll(e, l.create.createStatements("SEQUENCE", t.Name, t.CreateSQL)); err != nil {
+			return fmt.Errorf("create sequence: %w", err)
+		}
+	}
+
+	row, err := readSequenceRow(r, t)
+	if err != nil {
+		return err
+	}
+	if q, ok := sequenceSetval(t, row); ok {
+		if err = e.execNow(q); err != nil {
+			return fmt.Errorf("set sequence value: %w", err)
+		}
+	}
+
+	logrus.Infof("Restored sequence %s", t.Name)
+	return nil
+}
+
+// executor runs statements on a fixed set of connections, each prepared with the same session settings.
+type executor struct {
+	dryRun io.Writer
+
+	conns []*sql.Conn
+	queue chan string
+	wg    sync.WaitGroup
+	pend  sync.WaitGroup
+
+	mu  sync.Mutex
+	err error
+}
+
+var loaderSession = []string{
+	"SET NAMES utf8",
+	"SET TIME_ZONE='+00:00'",
+	"SET UNIQUE_CHECKS=0",
+	"SET FOREIGN_KEY_CHECKS=0",
+	"SET SQL_MODE='NO_AUTO_VALUE_ON_ZERO'",
+	"SET SQL_NOTES=0",
+}
+
+// Dumped AUTO_RANDOM values can only be inserted into TiDB if explicitly allowed
+var tidbLoaderSession = []string{
+	"SET @@allow_auto_random_explicit_insert = 1",
+}
+
+// The history of system-versioned tables can only be inserted into MariaDB if explicitly allowed
+var mariadbLoaderSession = []string{
+	"SET @@system_versioning_insert_history = 1",
+}
+
+// MyRocks holds the row locks of a statement until it commits, failing inserts of more rows than
+// rocksdb_max_row_locks unless they are committed in batches
+var myrocksLoaderSession = []string{
+	"SET SESSION rocksdb_commit_in_the_middle = 1",
+}
+
+func (l *Loader) newExecutor() (*executor, error) {
+	e := &executor{dryRun: l.opt.DryRun}
+	if e.dryRun != nil {
+		return e, nil
+	}
+
+	session := append([]string(nil), loaderSession...)
+	if target, err := detectServer(context.Background(), l.db); err == nil {
+		l.target = &target
+		if target.supports(featureUTF8MB4) {
+			session[0] = "SET NAMES utf8mb4"
+		}
+		if target.flavor == flavorTiDB {
+			session = append(session, tidbLoaderSession...)
+		}
+		if target.supports(featureInsertHistory) {
+			session = append(session, mariadbLoaderSession...)
+		}
+		if target.myrocks {
+			session = append(session, myrocksLoaderSession...)
+		}
+	}
+
+	for i := 0; i < l.opt.Parallelism; i++ {
+		conn, err := l.db.Conn(context.Background())
+		if err != nil {
+			e.close()
+			return nil, fmt.Errorf("open connection: %w", err)
+		}
+		e.conns = append(e.conns, conn)
+
+		for _, q := range session {
+			if _, err = conn.ExecContext(context.Background(), q); err != nil {
+				if isPermissionError(err) {
+					if i == 0 {
+						logrus.Warnf("Skipping %s: %s", q, err)
+					}
+					continue
+				}
+				e.close()
+				return nil, fmt.Errorf("set up session: %w", err)
+			}
+		}
+	}
+
+	e.queue = make(chan string, len(e.conns))
+	for _, conn := range e.conns {
+		e.wg.Add(1)
+		go e.work(conn)
+	}
+
+	return e, nil
+}
+
+func (e *executor) work(conn *sql.Conn) {
+	defer e.wg.Done()
+
+	for q := range e.queue {
+		if e.failed() == nil {
+			if _, err := conn.ExecContext(context.Background(), q); err != nil {
+				e.fail(err)
+			}
+		}
+		e.pend.Done()
+	}
+}
+
+func (e *executor) fail(err error) {
+	e.mu.Lock()
+	if e.err == nil {
+		e.err = err
+	}
+	e.mu.Unlock()
+}
+
+func (e *executor) failed() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.err
+}
+
+// exec queues a statement to be run by any of the connections.
+func (e *executor) exec(q string) error {
+	if e.dryRun != nil {
+		_, err := fmt.Fprintf(e.dryRun, "%s;\n", q)
+		return err
+	}
+	if err := e.failed(); err != nil {
+		return err
+	}
+
+	e.pend.Add(1)
+	e.queue <- q
+	return nil
+}
+
+// execNow runs a statement synchronously on the first connection.
+func (e *executor) execNow(q string) error {
+	if e.dryRun != nil {
+		_, err := fmt.Fprintf(e.dryRun, "%s;\n", q)
+		return err
+	}
+
+	_, err := e.conns[0].ExecContext(context.Background(), q)
+	return err
+}
+
+// wait blocks until all queued statements have been run.
+func (e *executor) wait() error {
+	e.pend.Wait()
+	return e.failed()
+}
+
+func (e *executor) close() error {
+	if e.queue != nil {
+		close(e.queue)
+		e.wg.Wait()
+		e.queue = nil
+	}
+	for _, conn := range e.conns {
+		conn.Close()
+	}
+	e.conns = nil
+
+	return e.failed()
+}
@@ -0,0 +1,109 @@
+package mysqldump
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/MouseHatGames/go-mysqldump/internal/marshal"
+)
+
+// detectManaged tells Aurora and RDS apart from a self-hosted MySQL server.
+func detectManaged(ctx context.Context, db *sql.DB) (string, string) {
+	var v sql.NullString
+	if err := db.QueryRowContext(ctx, stmtAuroraVersion).Scan(&v); err == nil {
+		return "Aurora", v.String
+	}
+	if err := db.QueryRowContext(ctx, stmtBaseDir).Scan(&v); err == nil && strings.HasPrefix(v.String, "/rdsdbbin/") {
+		return "RDS", ""
+	}
+	return "", ""
+}
+
+// startConsistentRead pins a connection reading every table from a single consistent snapshot and
+// returns the binary log position of the snapshot, if it can be read. Managed servers don't grant the
+// RELOAD privilege FLUSH TABLES WITH READ LOCK needs, in which case the position is read right after
+// the snapshot is started and may be slightly ahead of it. Used for managed servers and by
+// WithBinlogPosition.
+func (d *Dumper) startConsistentRead(dbName string) (*marshal.BinlogPosition, error) {
+	if err := d.startConn(dbName, "SET SESSION TRANSACTION ISOLATION LEVEL REPEATABLE READ"); err != nil {
+		return nil, fmt.Errorf("set up consistent read: %w", err)
+	}
+
+	exec := func(q string) error {
+		_, err := d.conn.ExecContext(d.context(), q)
+		return err
+	}
+
+	locked := true
+	if err := exec("FLUSH TABLES WITH READ LOCK"); isPermissionError(err) {
+		d.warn(WarningSnapshot, "", "Can't lock tables, the binlog position may be slightly ahead of the dumped data: %s", err)
+		locked = false
+	} else if err != nil {
+		d.endConn()
+		return nil, fmt.Errorf("lock tables: %w", err)
+	}
+
+	if err := exec("START TRANSACTION WITH CONSISTENT SNAPSHOT"); err != nil {
+		d.endConn()
+		return nil, fmt.Errorf("start consistent snapshot: %w", err)
+	}
+	d.connSetup = append(d.connSetup, "START TRANSACTION WITH CONSISTENT SNAPSHOT")
+
+	pos, err := d.binlogPosition()
+	if err != nil {
+		d.warn(WarningSnapshot, "", "Can't read the binlog position: %s", err)
+	} else if pos != nil {
+		pos.Exact = locked
+	} else if d.recordBinlog {
+		d.warn(WarningSnapshot, "", "Binary logging is disabled, no binlog position is recorded")
+	}
+
+	if locked {
+		if err = exec("UNLOCK TABLES"); err != nil {
+			d.endConn()
+			return nil, fmt.Errorf("unlock tables: %w", err)
+		}
+	}
+
+	return pos, nil
+}
+
+// binlogPosition returns the current binary log position, or nil if binary logging is disabled.
+// The GTID set is the one executed up to the position, in the format of the server's flavor.
+func (d *Dumper) binlogPosition() (*marshal.BinlogPosition, error) {
+	s, _ := d.server()
+	q := stmtMasterStatus
+	if s.supports(featureBinaryLogStatus) {
+		q = stmtBinaryLogStatus
+	}
+	status, err := d.queryRowMap(q)
+	if err != nil {
+		return nil, err
+	}
+	if status["File"] == "" {
+		return nil, nil
+	}
+
+	pos, err := strconv.ParseUint(status["Position"], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid binlog position %q", status["Position"])
+	}
+	b := &marshal.BinlogPosition{
+		File:     status["File"],
+		Position: pos,
+	}
+	if s.supports(featureGTID) {
+		b.GTIDSet = strings.ReplaceAll(status["Executed_Gtid_Set"], "\n", "")
+	} else if s.flavor == flavorMariaDB {
+		// MariaDB GTIDs aren't listed by SHOW MASTER STATUS, they are derived from the position
+		var gtid sql.NullString
+		if err = d.db.QueryRowContext(d.context(), stmtBinlogGTIDPos, b.File, b.Position).Scan(&gtid); err != nil {
+			return nil, fmt.Errorf("read gtid position: %w", err)
+		}
+		b.GTIDSet = gtid.String
+	}
+	return b, nil
+}
@@ -0,0 +1,152 @@
+package mysqldump
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/MouseHatGames/go-mysqldump/internal/marshal"
+)
+
+// Table types recorded in TableHeader.Type. Base tables leave it empty.
+const (
+	// MariaDB sequences, restored with CREATE SEQUENCE and SETVAL instead of rows
+	TableTypeSequence = "SEQUENCE"
+	// MariaDB system-versioned tables. Only the current rows are dumped unless WithSystemTime says otherwise
+	TableTypeSystemVersioned = "SYSTEM VERSIONED"
+)
+
+// SystemTime selects the rows of system-versioned tables that are dumped. The zero value dumps the current rows.
+type SystemTime struct {
+	// Dump the whole history, restored with the period columns on MariaDB 10.11 and later
+	All bool
+	// Dump the rows as they were at this time, restored as current rows
+	AsOf time.Time
+}
+
+// ParseSystemTime parses "current", "all" or a timestamp such as 2024-01-31 12:00:00.
+func ParseSystemTime(s string) (SystemTime, error) {
+	switch strings.ToLower(s) {
+	case "current", "":
+		return SystemTime{}, nil
+	case "all":
+		return SystemTime{All: true}, nil
+	}
+
+	for _, layout := range []string{"2006-01-02 15:04:05.999999", time.RFC3339Nano, "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return SystemTime{AsOf: t}, nil
+		}
+	}
+	return SystemTime{}, fmt.Errorf("invalid system time: %s", s)
+}
+
+// clause returns the FOR SYSTEM_TIME clause reading the rows, empty for the current ones.
+func (st SystemTime) clause() string {
+	switch {
+	case st.All:
+		return "ALL"
+	case !st.AsOf.IsZero():
+		return "AS OF TIMESTAMP '" + st.AsOf.UTC().Format("2006-01-02 15:04:05.999999") + "'"
+	}
+	return ""
+}
+
+// WithSystemTime sets which rows of system-versioned tables are dumped.
+func WithSystemTime(st SystemTime) Option {
+	return func(d *Dumper) {
+		d.systemTime = st
+	}
+}
+
+// systemTimeClause returns the FOR SYSTEM_TIME clause a table is read with, empty if it isn't system-versioned.
+func (d *Dumper) systemTimeClause(name string, schema string) (string, error) {
+	st := d.systemTime.clause()
+	if st == "" || d.isPQ() {
+		return "", nil
+	}
+	if s, _ := d.server(); !s.supports(featureSystemVersioning) {
+		return "", nil
+	}
+
+	meta, err := d.getTableMeta(name, schema)
+	if err != nil {
+		return "", fmt.Errorf("get table type: %w", err)
+	}
+	if meta.typ != TableTypeSystemVersioned {
+		return "", nil
+	}
+	return st, nil
+}
+
+type tableMeta struct {
+	typ    string
+	engine string
+}
+
+func (d *Dumper) getTableMeta(name string, schema string) (tableMeta, error) {
+	if d.isPQ() {
+		return tableMeta{}, nil
+	}
+	if d.legacyMetadata() {
+		return d.showTableMeta(name, schema)
+	}
+
+	var typ, engine sql.NullString
+	err := d.db.QueryRowContext(d.context(), "SELECT TABLE_TYPE, ENGINE FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_NAME = ? AND TABLE_SCHEMA = ?", name, schema).Scan(&typ, &engine)
+	if err != nil {
+		return tableMeta{}, err
+	}
+
+	m := tableMeta{engine: engine.String}
+	if typ.String != "BASE TABLE" {
+		m.typ = typ.String
+	}
+	return m, nil
+}
+
+// sequenceSetval returns the statement restoring the state of a sequence from its dumped row.
+func sequenceSetval(t *TableHeader, row RowData) (string, bool) {
+	if row == nil {
+		return "", false
+	}
+
+	next, round := -1, -1
+	for i, c := range t.Columns {
+		switch c {
+		case "next_not_cached_value":
+			next = i
+		case "cycle_count":
+			round = i
+		}
+	}
+	if next < 0 || row[next] == nil {
+		return "", false
+	}
+
+	q := fmt.Sprintf("SELECT SETVAL(%s, %s, 0", quoteName(t.Name), *row[next])
+	if round >= 0 && row[round] != nil {
+		q += ", " + *row[round]
+	}
+	return q + ")", true
+}
+
+// readSequenceRow reads the single row holding a sequence's state, which is nil if it wasn't dumped.
+func readSequenceRow(r *marshal.Reader, t *marshal.TableHeader) (RowData, error) {
+	var first RowData
+	for {
+		row, err := r.ReadRow(len(t.Columns))
+		if errors.Is(err, io.EOF) {
+			return first, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if first == nil {
+			first = row
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package mysqldump
+
+import (
+	"database/sql/driver"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// mariadbServer answers the queries detecting a MariaDB 10.6 server, with MyRocks if rocksdb is set,
+// and the queries of tables with tables.
+func mariadbServer(rocksdb string, tables func(q string, args []driver.Value) (*fakeRows, error)) func(q string, args []driver.Value) (*fakeRows, error) {
+	return func(q string, args []driver.Value) (*fakeRows, error) {
+		switch q {
+		case stmtServerVersion:
+			return stringRows([]string{"version()"}, []string{"5.5.5-10.6.12-MariaDB-log"}), nil
+		case stmtLowerCaseTableNames:
+			return stringRows([]string{"@@lower_case_table_names"}, []string{"0"}), nil
+		case stmtMyRocks:
+			if rocksdb == "" {
+				return nil, nil
+			}
+			return stringRows([]string{"SUPPORT"}, []string{rocksdb}), nil
+		}
+		if tables != nil {
+			return tables(q, args)
+		}
+		return nil, nil
+	}
+}
+
+func TestParseServerVersionMariaDB(t *testing.T) {
+	tests := []struct {
+		version  string
+		want     [3]int
+		features map[feature]bool
+	}{
+		{"5.5.5-10.6.12-MariaDB-log", [3]int{10, 6, 12}, map[feature]bool{featureSequences: true, featureSystemVersioning: true, featureInsertHistory: false, featureGTID: false}},
+		{"10.11.2-MariaDB-1:10.11.2+maria~ubu2204", [3]int{10, 11, 2}, map[feature]bool{featureInsertHistory: true}},
+		{"10.2.44-MariaDB", [3]int{10, 2, 44}, map[feature]bool{featureSequences: false, featureSystemVersioning: false, featureWindowFunctions: true}},
+	}
+	for _, tt := range tests {
+		s := parseServerVersion(tt.version)
+		if s.flavor != flavorMariaDB {
+			t.Errorf("%s: flavor %s, want MariaDB", tt.version, s.flavor)
+		}
+		if got := [3]int{s.major, s.minor, s.patch}; got != tt.want {
+			t.Errorf("%s: version %v, want %v", tt.version, got, tt.want)
+		}
+		for f, want := range tt.features {
+			if got := s.supports(f); got != want {
+				t.Errorf("%s: supports(%d) = %v, want %v", tt.version, f, got, want)
+			}
+		}
+	}
+}
+
+func TestDetectMyRocks(t *testing.T) {
+	for support, want := range map[string]bool{"YES": true, "DEFAULT": true, "NO": false, "": false} {
+		db, _ := openFakeDB(t, mariadbServer(support, nil))
+		d := NewDumper(db, ioutil.Discard, 0)
+		s, err := d.server()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.myrocks != want {
+			t.Errorf("SUPPORT %q: myrocks = %v, want %v", support, s.myrocks, want)
+		}
+	}
+}
+
+func TestLoaderMyRocksSession(t *testing.T) {
+	const set = "SET SESSION rocksdb_commit_in_the_middle = 1"
+	for support, want := range map[string]bool{"YES": true, "": false} {
+		db, f := openFakeDB(t, mariadbServer(support, nil))
+		e, err := NewLoader(db, LoaderOptions{}).newExecutor()
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.close()
+
+		ran := false
+		for _, q := range f.ran() {
+			ran = ran || q == set
+		}
+		if ran != want {
+			t.Errorf("SUPPORT %q: ran %s = %v, want %v", support, set, ran, want)
+		}
+	}
+}
+
+func TestEnginePolicies(t *testing.T) {
+	d := NewDumper(nil, ioutil.Discard, 0, WithEnginePolicies(map[string]EnginePolicy{"archive": EngineSkip}))
+	tests := map[string]EnginePolicy{
+		"InnoDB":    EngineDump,
+		"Aria":      EngineWarn,
+		"ROCKSDB":   EngineWarn,
+		"FEDERATED": EngineSkipData,
+		"ARCHIVE":   EngineSkip,
+	}
+	for engine, want := range tests {
+		if got := d.enginePolicy("t_"+engine, engine); got != want {
+			t.Errorf("%s: policy %d, want %d", engine, got, want)
+		}
+	}
+
+	var warned []string
+	for _, w := range d.Warnings() {
+		if w.Kind == WarningEngine {
+			warned = append(warned, w.Table)
+		}
+	}
+	if len(warned) != 2 {
+		t.Errorf("warned about %v, want the Aria and MyRocks tables", warned)
+	}
+}
+
+func TestSequence(t *testing.T) {
+	db, f := openFakeDB(t, mariadbServer("", func(q string, args []driver.Value) (*fakeRows, error) {
+		switch {
+		case strings.HasPrefix(q, "SELECT TABLE_TYPE, ENGINE FROM INFORMATION_SCHEMA.TABLES"):
+			return stringRows([]string{"TABLE_TYPE", "ENGINE"}, []string{"SEQUENCE", "Aria"}), nil
+		case strings.HasPrefix(q, "SHOW CREATE"):
+			return stringRows([]string{"Table", "Create Table"}, []string{"order_ids", "CREATE SEQUENCE `order_ids` start with 1"}), nil
+		}
+		return nil, nil
+	}))
+	d := NewDumper(db, ioutil.Discard, 0)
+
+	meta, err := d.getTableMeta("order_ids", "shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.typ != TableTypeSequence {
+		t.Errorf("type %q, want %q", meta.typ, TableTypeSequence)
+	}
+	if _, err = d.getTableSQL(db, "order_ids", meta.typ); err != nil {
+		t.Fatal(err)
+	}
+	if ran := f.ran(); ran[len(ran)-1] != "SHOW CREATE SEQUENCE `order_ids`" {
+		t.Errorf("ran %q, want SHOW CREATE SEQUENCE", ran[len(ran)-1])
+	}
+
+	next, cycles := "1001", "2"
+	h := &TableHeader{Name: "order_ids", Columns: []string{"next_not_cached_value", "minimum_value", "cycle_count"}}
+	if q, _ := sequenceSetval(h, RowData{&next, nil, &cycles}); q != "SELECT SETVAL(`order_ids`, 1001, 0, 2)" {
+		t.Errorf("restored with %q", q)
+	}
+	if _, ok := sequenceSetval(h, nil); ok {
+		t.Error("restored a sequence whose row wasn't dumped")
+	}
+}
+
+func TestSystemVersionedColumns(t *testing.T) {
+	tests := []struct {
+		st      SystemTime
+		columns [][]string
+		want    []string
+	}{
+		// Explicit period columns
+		{SystemTime{}, [][]string{{"id", "", ""}, {"row_start", "STORED GENERATED, INVISIBLE", "ROW START"}, {"row_end", "STORED GENERATED, INVISIBLE", "ROW END"}}, []string{"id"}},
+		{SystemTime{All: true}, [][]string{{"id", "", ""}, {"row_start", "STORED GENERATED, INVISIBLE", "ROW START"}, {"row_end", "STORED GENERATED, INVISIBLE", "ROW END"}}, []string{"id", "row_start", "row_end"}},
+		// Implicit ones, read as pseudo-columns
+		{SystemTime{}, [][]string{{"id", "", ""}, {"name", "", ""}}, []string{"id", "name"}},
+		{SystemTime{All: true}, [][]string{{"id", "", ""}, {"name", "", ""}}, []string{"id", "name", "ROW_START", "ROW_END"}},
+	}
+	for _, tt := range tests {
+		db, _ := openFakeDB(t, mariadbServer("", func(q string, args []driver.Value) (*fakeRows, error) {
+			if q == stmtSelectColumns {
+				return stringRows([]string{"COLUMN_NAME", "EXTRA", "GENERATION_EXPRESSION"}, tt.columns...), nil
+			}
+			return nil, nil
+		}))
+		d := NewDumper(db, ioutil.Discard, 0, WithSystemTime(tt.st))
+
+		got, err := d.getSelectColumns("t", "shop", tableMeta{typ: TableTypeSystemVersioned})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("system time %+v selected %v, want %v", tt.st, got, tt.want)
+		}
+	}
+}
+
+func TestParseSystemTime(t *testing.T) {
+	tests := map[string]string{
+		"current":                     "",
+		"ALL":                         "ALL",
+		"2024-01-31 12:00:00":         "AS OF TIMESTAMP '2024-01-31 12:00:00'",
+		"2024-01-31T12:00:00.5+01:00": "AS OF TIMESTAMP '2024-01-31 11:00:00.5'",
+	}
+	for s, want := range tests {
+		st, err := ParseSystemTime(s)
+		if err != nil {
+			t.Errorf("%s: %s", s, err)
+		} else if got := st.clause(); got != want {
+			t.Errorf("%s: clause %q, want %q", s, got, want)
+		}
+	}
+	if _, err := ParseSystemTime("yesterday"); err == nil {
+		t.Error("parsed yesterday")
+	}
+}
@@ -0,0 +1,164 @@
+package mysqldump
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// MaskType is how a MaskRule replaces the values of a column.
+type MaskType int
+
+const (
+	// MaskNull replaces values with NULL.
+	MaskNull MaskType = iota
+	// MaskHash replaces values with their hex SHA-256, so equal values stay equal.
+	MaskHash
+	// MaskConstant replaces values with MaskRule.Value.
+	MaskConstant
+	// MaskFakeEmail replaces values with an address at example.com made from their hash, so that unique
+	// columns stay unique and values joined on still match.
+	MaskFakeEmail
+	// MaskRegex replaces the matches of MaskRule.Pattern with MaskRule.Value, which can refer to the
+	// groups of the match as $1.
+	MaskRegex
+)
+
+// MaskRule is how the values of a column are masked, see WithMasking. NULL values stay NULL, but with
+// MaskNull.
+type MaskRule struct {
+	Type    MaskType
+	Value   string
+	Pattern *regexp.Regexp
+}
+
+// ParseMaskRule parses "null", "hash", "fake_email", "constant:<value>" or "regex:/<pattern>/<replacement>/",
+// where the character following "regex:" may be any delimiter not found in the pattern.
+func ParseMaskRule(s string) (MaskRule, error) {
+	switch {
+	case s == "null":
+		return MaskRule{Type: MaskNull}, nil
+	case s == "hash":
+		return MaskRule{Type: MaskHash}, nil
+	case s == "fake_email":
+		return MaskRule{Type: MaskFakeEmail}, nil
+	case strings.HasPrefix(s, "constant:"):
+		return MaskRule{Type: MaskConstant, Value: strings.TrimPrefix(s, "constant:")}, nil
+	case strings.HasPrefix(s, "regex:") && len(s) > len("regex:"):
+		rest := s[len("regex:"):]
+		parts := strings.Split(rest[1:], rest[:1])
+		if len(parts) != 3 || parts[2] != "" {
+			return MaskRule{}, fmt.Errorf("invalid masking rule %q, expected regex:/pattern/replacement/", s)
+		}
+		re, err := regexp.Compile(parts[0])
+		if err != nil {
+			return MaskRule{}, fmt.Errorf("masking rule %q: %w", s, err)
+		}
+		return MaskRule{Type: MaskRegex, Pattern: re, Value: parts[1]}, nil
+	}
+	return MaskRule{}, fmt.Errorf("unknown masking rule %q", s)
+}
+
+// apply returns the masked value of v.
+func (r MaskRule) apply(v *string) *string {
+	if r.Type == MaskNull || v == nil {
+		return nil
+	}
+
+	var m string
+	switch r.Type {
+	case MaskHash:
+		sum := sha256.Sum256([]byte(*v))
+		m = hex.EncodeToString(sum[:])
+	case MaskFakeEmail:
+		sum := sha256.Sum256([]byte(*v))
+		m = "user" + hex.EncodeToString(sum[:8]) + "@example.com"
+	case MaskConstant:
+		m = r.Value
+	case MaskRegex:
+		if r.Pattern == nil {
+			return v
+		}
+		m = r.Pattern.ReplaceAllString(*v, r.Value)
+	}
+	return &m
+}
+
+// WithMasking masks the values of columns as they are dumped, with rules by column by table, so dumps
+// of production can be loaded into development. Masking runs along with the row transformers, in the
+// order the options were given, after the row rules checked the values. Masked columns missing from
+// the rows of a table are warned about, as their values would otherwise be dumped unmasked under
+// another name.
+func WithMasking(rules map[string]map[string]MaskRule) Option {
+	return func(d *Dumper) {
+		WithRowTransformer(d.maskRows(rules))(d)
+	}
+}
+
+// maskRows returns a row transformer applying the masking rules. Tables may be dumped in parallel.
+// The rules are looked up by the columns of the rows, which differ between the column groups of a
+// table, and columns missing from all of them are warned about once the table is done.
+func (d *Dumper) maskRows(rules map[string]map[string]MaskRule) RowTransformer {
+	var mu sync.Mutex
+	cache := make(map[string][]*MaskRule)
+	found := make(map[string]map[string]bool)
+
+	mask := func(table string, columns []string) []*MaskRule {
+		mu.Lock()
+		defer mu.Unlock()
+		set := table + "\x00" + strings.Join(columns, "\x00")
+		if m, ok := cache[set]; ok {
+			return m
+		}
+
+		if found[table] == nil {
+			found[table] = make(map[string]bool)
+		}
+		m := make([]*MaskRule, len(columns))
+		for name, r := range rules[table] {
+			r := r
+			if i := indexFold(columns, name); i >= 0 {
+				m[i] = &r
+				found[table][name] = true
+			}
+		}
+		cache[set] = m
+		return m
+	}
+
+	d.maskChecks = append(d.maskChecks, func(table string) {
+		mu.Lock()
+		defer mu.Unlock()
+		seen, ok := found[table]
+		if !ok {
+			return
+		}
+		for name := range rules[table] {
+			if !seen[name] {
+				d.warn(WarningRule, table, "Masked column %s.%s isn't in the dumped rows", table, name)
+			}
+		}
+		// A table dumped again may have other columns
+		delete(found, table)
+		for set := range cache {
+			if strings.HasPrefix(set, table+"\x00") {
+				delete(cache, set)
+			}
+		}
+	})
+
+	return func(table string, columns []string, row []*string) []*string {
+		if _, ok := rules[table]; !ok {
+			return row
+		}
+		for i, r := range mask(table, columns) {
+			if r != nil {
+				row[i] = r.apply(row[i])
+			}
+		}
+		return row
+	}
+}
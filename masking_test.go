@@ -0,0 +1,88 @@
+package mysqldump
+
+import (
+	"io/ioutil"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestMaskRule(t *testing.T) {
+	tests := []struct {
+		rule  string
+		value *string
+		want  string
+	}{
+		{"null", strp("ann"), "NULL"},
+		{"hash", nil, "NULL"},
+		{"hash", strp("ann"), "^[0-9a-f]{64}$"},
+		{"constant:x", strp("ann"), "^x$"},
+		{"fake_email", strp("ann@corp.com"), "^user[0-9a-f]{16}@example\\.com$"},
+		{"regex:/^(.)[^@]*/$1***/", strp("ann@corp.com"), "^a\\*\\*\\*@corp\\.com$"},
+		{"regex:#a|n#-#", strp("ann"), "^---$"},
+	}
+	for _, tt := range tests {
+		r, err := ParseMaskRule(tt.rule)
+		if err != nil {
+			t.Errorf("%s: %s", tt.rule, err)
+			continue
+		}
+		got := r.apply(tt.value)
+		if got == nil || tt.want == "NULL" {
+			if got != nil || tt.want != "NULL" {
+				t.Errorf("%s masked %v as %v, want %s", tt.rule, tt.value, got, tt.want)
+			}
+			continue
+		}
+		if !regexp.MustCompile(tt.want).MatchString(*got) {
+			t.Errorf("%s masked %q as %q, want %s", tt.rule, *tt.value, *got, tt.want)
+		}
+	}
+
+	// Equal values stay equal
+	h, _ := ParseMaskRule("fake_email")
+	if *h.apply(strp("a@b")) != *h.apply(strp("a@b")) || *h.apply(strp("a@b")) == *h.apply(strp("c@d")) {
+		t.Error("fake emails don't map equal values to equal addresses")
+	}
+
+	for _, s := range []string{"", "shuffle", "regex:/a/b", "regex:/(/x/"} {
+		if _, err := ParseMaskRule(s); err == nil {
+			t.Errorf("parsed %q", s)
+		}
+	}
+}
+
+func TestMaskingColumnGroups(t *testing.T) {
+	phone, err := ParseMaskRule("constant:000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	email, err := ParseMaskRule("null")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := NewDumper(nil, ioutil.Discard, 0, WithMasking(map[string]map[string]MaskRule{
+		"users": {"phone": phone, "email": email, "fax": email},
+	}))
+
+	// Groups of the same width, each with one of the masked columns
+	first := d.transform("users", []string{"id", "name", "email"}, stringRow("1", "ann", "ann@corp.com"))
+	second := d.transform("users", []string{"id", "phone", "addr"}, stringRow("1", "555", "main st"))
+	if want := []interface{}{"1", "ann", nil}; !reflect.DeepEqual(rowValues(first), want) {
+		t.Errorf("first group masked as %q, want %q", rowValues(first), want)
+	}
+	if want := []interface{}{"1", "000", "main st"}; !reflect.DeepEqual(rowValues(second), want) {
+		t.Errorf("second group masked as %q, want %q", rowValues(second), want)
+	}
+
+	d.reportRules("users")
+	var warned []string
+	for _, w := range d.Warnings() {
+		if w.Kind == WarningRule {
+			warned = append(warned, w.Message)
+		}
+	}
+	if want := []string{"Masked column users.fax isn't in the dumped rows"}; !reflect.DeepEqual(warned, want) {
+		t.Errorf("warned %q, want %q", warned, want)
+	}
+}
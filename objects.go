@@ -0,0 +1,141 @@
+package mysqldump
+
+import (
+	"fmt"
+
+	"github.com/MouseHatGames/go-mysqldump/internal/marshal"
+	"github.com/sirupsen/logrus"
+)
+
+// WithRoutines dumps the stored functions and procedures of the database after its tables, see
+// SchemaObject.
+func WithRoutines() Option {
+	return func(d *Dumper) {
+		d.routines = true
+	}
+}
+
+// WithTriggers dumps the triggers of the tables dumped after the tables, see SchemaObject.
+func WithTriggers() Option {
+	return func(d *Dumper) {
+		d.triggers = true
+	}
+}
+
+// WithEvents dumps the events of the database after its tables, see SchemaObject.
+func WithEvents() Option {
+	return func(d *Dumper) {
+		d.events = true
+	}
+}
+
+// splitViews takes the views out of the tables to dump, their definitions being dumped after the
+// tables instead of their rows.
+func (d *Dumper) splitViews(schema string, tables []string) ([]string, []schemaObject, error) {
+	if d.isPQ() {
+		return tables, nil, nil
+	}
+
+	rows, err := d.db.QueryContext(d.context(), stmtListViews, schema)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list views: %w", err)
+	}
+	defer rows.Close()
+
+	isView := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			return nil, nil, fmt.Errorf("list views: %w", err)
+		}
+		isView[name] = true
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("list views: %w", err)
+	}
+	if len(isView) == 0 {
+		return tables, nil, nil
+	}
+
+	var views []schemaObject
+	base := make([]string, 0, len(tables))
+	for _, t := range tables {
+		if isView[t] {
+			views = append(views, schemaObject{name: t, typ: "VIEW"})
+		} else {
+			base = append(base, t)
+		}
+	}
+	return base, views, nil
+}
+
+// writeObjects writes the routines, views, triggers and events dumped along with the tables, in the
+// order they are created in.
+func (d *Dumper) writeObjects(schema string, tables []string, views []schemaObject) error {
+	var objects []schemaObject
+	if d.routines {
+		routines, err := d.schemaRoutines(schema)
+		if err != nil {
+			return fmt.Errorf("list routines: %w", err)
+		}
+		objects = append(objects, routines...)
+	}
+	views, err := d.schemaViews(schema, views)
+	if err != nil {
+		return err
+	}
+	objects = append(objects, views...)
+	if d.triggers {
+		triggers, err := d.schemaTriggers(schema)
+		if err != nil {
+			return fmt.Errorf("list triggers: %w", err)
+		}
+		dumped := make(map[string]bool, len(tables))
+		for _, t := range tables {
+			dumped[t] = true
+		}
+		for _, t := range triggers {
+			if dumped[t.table] {
+				objects = append(objects, t)
+			}
+		}
+	}
+	if d.events {
+		events, err := d.schemaEvents(schema)
+		if err != nil {
+			return fmt.Errorf("list events: %w", err)
+		}
+		objects = append(objects, events...)
+	}
+
+	for _, o := range objects {
+		if err := d.writeObject(&marshal.SchemaObject{Type: o.typ, Name: o.name, CreateSQL: o.ddl, Table: o.table}); err != nil {
+			return err
+		}
+	}
+	if len(objects) > 0 {
+		logrus.Infof("Dumped %d views, routines, triggers and events", len(objects))
+	}
+	return nil
+}
+
+// restoresObject reports whether restoring a dump restores a schema object: views and the triggers
+// of the tables restored, and routines and events only along with every table.
+func restoresObject(o *marshal.SchemaObject, all bool, includes func(name string) bool) bool {
+	switch o.Type {
+	case "VIEW":
+		return includes(o.Name)
+	case "TRIGGER":
+		return includes(o.Table)
+	}
+	return all
+}
+
+// objectStatements returns the statements creating a schema object. Objects are created as they are
+// under CreateIfNotExists and CreateError, failing if they exist.
+func (p CreatePolicy) objectStatements(o *marshal.SchemaObject, ddl string) []string {
+	if p != CreateDrop {
+		return []string{ddl}
+	}
+	return []string{"DROP " + o.Type + " IF EXISTS " + quoteName(o.Name), ddl}
+}
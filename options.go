@@ -0,0 +1,22 @@
+package mysqldump
+
+// Option configures optional behaviour of a Dumper.
+type Option func(*Dumper)
+
+// WithCheckpointFile makes the dumper save a Checkpoint to path when it is interrupted, and after every
+// chunk of rows and every table that reached the output, so that a dump that died can be resumed with
+// ResumeDump. A chunk is checkpointed once it was written out of the write queue and the compression
+// and encryption flushed it, and dumps converted to other formats or per-table files fail to start
+// since their converters buffer what they write. The checkpoint is removed once the dump is done.
+func WithCheckpointFile(path string) Option {
+	return func(d *Dumper) {
+		d.checkpointFile = path
+	}
+}
+
+// WithChunkSize overrides the number of rows read per query, 0 reading every table in one go.
+func WithChunkSize(n int) Option {
+	return func(d *Dumper) {
+		d.chunkSize = n
+	}
+}
@@ -0,0 +1,56 @@
+package mysqldump
+
+import "sort"
+
+// WithTableOrder dumps the given tables first, in that order, followed by the other tables. Tables
+// that matter most should come first, so a dump interrupted before it is done still holds them.
+func WithTableOrder(tables ...string) Option {
+	return func(d *Dumper) {
+		d.tableOrder = tables
+	}
+}
+
+// WithTablePriorities dumps tables with a higher priority first. Tables default to priority 0, and
+// tables of the same priority keep their order. Priorities apply after the order of WithTableOrder.
+func WithTablePriorities(priorities map[string]int) Option {
+	return func(d *Dumper) {
+		d.priorities = priorities
+	}
+}
+
+// orderTables sorts tables by the explicit order first, then by priority.
+func (d *Dumper) orderTables(tables []string) []string {
+	if len(d.tableOrder) == 0 && len(d.priorities) == 0 {
+		return tables
+	}
+
+	position := func(name string) int {
+		for i, t := range d.tableOrder {
+			if d.sameTableName(t, name) {
+				return i
+			}
+		}
+		return len(d.tableOrder)
+	}
+	priority := func(name string) int {
+		if p, ok := d.priorities[name]; ok {
+			return p
+		}
+		for t, p := range d.priorities {
+			if d.sameTableName(t, name) {
+				return p
+			}
+		}
+		return 0
+	}
+
+	ordered := append([]string(nil), tables...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		pi, pj := position(ordered[i]), position(ordered[j])
+		if pi != pj {
+			return pi < pj
+		}
+		return priority(ordered[i]) > priority(ordered[j])
+	})
+	return ordered
+}
@@ -0,0 +1,186 @@
+package mysqldump
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	binary "github.com/MouseHatGames/go-mysqldump/internal/marshal"
+	"github.com/sirupsen/logrus"
+)
+
+// WithOutfile makes the server write every chunk to a file with SELECT ... INTO OUTFILE, which the
+// dumper then reads and removes, instead of sending the rows over the connection. serverDir is the
+// directory as seen by the server, which must be allowed by secure_file_priv, and localDir the same
+// directory as seen by the dumper, e.g. through a shared mount. The user needs the FILE privilege.
+// Not supported on PostgreSQL.
+func WithOutfile(serverDir string, localDir string) Option {
+	return func(d *Dumper) {
+		d.outfile = &outfileDirs{server: serverDir, local: localDir, columns: make(map[outfileQuery][]string)}
+	}
+}
+
+type outfileDirs struct {
+	server string
+	local  string
+	// Number of files written so far, to name the next one
+	n int64
+	// Columns returned by the query reading each table, by the columns it selects since every column
+	// group of a table selects others
+	columns map[outfileQuery][]string
+}
+
+type outfileQuery struct {
+	table string
+	sel   string
+}
+
+// Options of the files written by the server. These are the defaults, spelled out since readOutfile relies on them.
+const outfileFormat = `CHARACTER SET binary FIELDS TERMINATED BY '\t' ESCAPED BY '\\' LINES TERMINATED BY '\n'`
+
+// readOutfileChunk has the server write a chunk of a table to a file and passes its rows to fn, reporting whether there were any.
+func (d *Dumper) readOutfileChunk(name string, tq *tableQuery, q string, args []interface{}, fn func(binary.RowData) error) (bool, error) {
+	columns, err := d.outfileColumns(name, tq.sel, q, args)
+	if err != nil {
+		return false, err
+	}
+	order, err := tq.columnOrder(name, columns)
+	if err != nil {
+		return false, err
+	}
+	if order != nil {
+		columns = tq.columns
+	}
+
+	file := fmt.Sprintf("mysqldump-%d-%d.tsv", os.Getpid(), atomic.AddInt64(&d.outfile.n, 1))
+	serverPath := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(path.Join(d.outfile.server, file))
+
+	rows, err := d.queryChunk(q+" INTO OUTFILE '"+serverPath+"' "+outfileFormat, args...)
+	if err != nil {
+		return false, fmt.Errorf("select into outfile: %w", err)
+	}
+	rows.Close()
+
+	localPath := filepath.Join(d.outfile.local, file)
+	f, err := os.Open(localPath)
+	if err != nil {
+		return false, fmt.Errorf("open outfile: %w", err)
+	}
+	defer func() {
+		f.Close()
+		if err := os.Remove(localPath); err != nil {
+			logrus.Warnf("Can't remove %s: %s", localPath, err)
+		}
+	}()
+
+	gotData := false
+	err = readOutfile(bufio.NewReader(f), len(columns), func(data binary.RowData) error {
+		gotData = true
+		d.countRead(data)
+		data = reorderRow(data, order)
+		tq.seen(columns, data)
+		d.checkRules(name, columns, data)
+		if d.transform != nil {
+			data = d.transform(name, columns, data)
+		}
+		if err := fn(data); err != nil {
+			return fmt.Errorf("write values: %w", err)
+		}
+		return nil
+	})
+	return gotData, err
+}
+
+// outfileColumns returns the columns the query reading a table returns, which the files written by the server don't hold.
+func (d *Dumper) outfileColumns(name string, sel string, q string, args []interface{}) ([]string, error) {
+	key := outfileQuery{name, sel}
+	if cols, ok := d.outfile.columns[key]; ok {
+		return cols, nil
+	}
+
+	rows, err := d.queryChunk("SELECT * FROM ("+q+") AS t LIMIT 0", args...)
+	if err != nil {
+		return nil, err
+	}
+	cols, err := rows.Columns()
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+	if len(cols) == 0 {
+		return nil, errors.New("no columns in table " + name + ".")
+	}
+
+	d.outfile.columns[key] = cols
+	return cols, nil
+}
+
+// readOutfile parses the rows of a file written by SELECT ... INTO OUTFILE with outfileFormat.
+func readOutfile(r *bufio.Reader, ncol int, fn func(binary.RowData) error) error {
+	var (
+		row     binary.RowData
+		field   []byte
+		null    bool
+		escaped bool
+	)
+
+	endField := func() {
+		if null && len(field) == 0 {
+			row = append(row, nil)
+		} else {
+			v := string(field)
+			row = append(row, &v)
+		}
+		field, null = field[:0], false
+	}
+
+	for {
+		b, err := r.ReadByte()
+		if errors.Is(err, io.EOF) {
+			if len(row) > 0 || len(field) > 0 {
+				return errors.New("outfile ends in the middle of a row")
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read outfile: %w", err)
+		}
+
+		switch {
+		case escaped:
+			escaped = false
+			switch b {
+			case '0':
+				field = append(field, 0)
+			case 'N':
+				null = true
+			case 'Z':
+				field = append(field, 0x1A)
+			default:
+				// The escape character itself and the terminators
+				field = append(field, b)
+			}
+		case b == '\\':
+			escaped = true
+		case b == '\t':
+			endField()
+		case b == '\n':
+			endField()
+			if len(row) != ncol {
+				return fmt.Errorf("outfile row has %d fields, expected %d", len(row), ncol)
+			}
+			if err = fn(row); err != nil {
+				return err
+			}
+			row = nil
+		default:
+			field = append(field, b)
+		}
+	}
+}
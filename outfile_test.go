@@ -0,0 +1,98 @@
+package mysqldump
+
+import (
+	"bufio"
+	"database/sql/driver"
+	"io/ioutil"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	binary "github.com/MouseHatGames/go-mysqldump/internal/marshal"
+)
+
+func TestReadOutfile(t *testing.T) {
+	tests := []struct {
+		in   string
+		ncol int
+		want [][]interface{}
+		err  bool
+	}{
+		{in: "", ncol: 2},
+		{in: "1\ta\n2\tb\n", ncol: 2, want: [][]interface{}{{"1", "a"}, {"2", "b"}}},
+		{in: "1\t\\N\n", ncol: 2, want: [][]interface{}{{"1", nil}}},
+		{in: "1\t\n", ncol: 2, want: [][]interface{}{{"1", ""}}},
+		{in: "a\\\tb\\\nc\\\\d\\0\\Z\n", ncol: 1, want: [][]interface{}{{"a\tb\nc\\d\x00\x1a"}}},
+		// NULL only when the field is \N alone
+		{in: "\\NN\n", ncol: 1, want: [][]interface{}{{"N"}}},
+		{in: "1\t2\n", ncol: 3, err: true},
+		{in: "1\t2", ncol: 2, err: true},
+	}
+	for _, tt := range tests {
+		var got [][]interface{}
+		err := readOutfile(bufio.NewReader(strings.NewReader(tt.in)), tt.ncol, func(row binary.RowData) error {
+			got = append(got, rowValues(row))
+			return nil
+		})
+		if tt.err {
+			if err == nil {
+				t.Errorf("readOutfile(%q) succeeded, want an error", tt.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("readOutfile(%q): %s", tt.in, err)
+		} else if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("readOutfile(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func rowValues(row binary.RowData) []interface{} {
+	vals := make([]interface{}, len(row))
+	for i, v := range row {
+		if v != nil {
+			vals[i] = *v
+		}
+	}
+	return vals
+}
+
+var (
+	outfileSelect = regexp.MustCompile("^SELECT (.*?) FROM `t`")
+	outfilePath   = regexp.MustCompile("INTO OUTFILE '([^']*)'")
+)
+
+func TestOutfileColumnGroups(t *testing.T) {
+	dir := t.TempDir()
+	// The server writes the selected columns, whose values are their names
+	db, _ := openFakeDB(t, func(q string, args []driver.Value) (*fakeRows, error) {
+		inner := strings.TrimPrefix(q, "SELECT * FROM (")
+		var cols []string
+		for _, c := range strings.Split(outfileSelect.FindStringSubmatch(inner)[1], ", ") {
+			cols = append(cols, strings.Trim(c, "`"))
+		}
+		if m := outfilePath.FindStringSubmatch(q); m != nil {
+			return nil, ioutil.WriteFile(m[1], []byte(strings.Join(cols, "\t")+"\n"), 0644)
+		}
+		return &fakeRows{columns: cols}, nil
+	})
+	d := NewDumper(db, ioutil.Discard, 0, WithOutfile(dir, dir))
+
+	for _, group := range [][]string{{"id", "a"}, {"id", "b"}} {
+		tq := &tableQuery{filters: []string{""}, sel: quoteColumns(group, false), from: "`t`", columns: group}
+		q, args := tq.chunk("", 0)
+		var got [][]interface{}
+		_, err := d.readOutfileChunk("t", tq, q, args, func(row binary.RowData) error {
+			got = append(got, rowValues(row))
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("group %v: %s", group, err)
+		}
+		if want := [][]interface{}{{group[0], group[1]}}; !reflect.DeepEqual(got, want) {
+			t.Errorf("group %v read %q, want %q", group, got, want)
+		}
+	}
+}
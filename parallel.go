@@ -0,0 +1,480 @@
+package mysqldump
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	binary "github.com/MouseHatGames/go-mysqldump/internal/marshal"
+	"github.com/sirupsen/logrus"
+)
+
+// WithParallelTables reads up to n tables at once, each over its own connection, while their chunks
+// are written to the single output as they come, tagged with the table they belong to. Partitions and
+// shards of a table, see WithPartitionUnits and WithShards, are read at once too, each taking one of the n
+// connections. Readers of the dump reassemble the tables, keeping the rows of each one in order.
+// Only applies when reading in chunks, without WithOutfile, and when tables aren't read through a
+// single connection for a consistent snapshot. Row transformers are then called from several
+// goroutines at once. An interrupted dump stops once the tables being read are done.
+func WithParallelTables(n int) Option {
+	return func(d *Dumper) {
+		d.parallelTables = n
+	}
+}
+
+// WithConcurrency reads up to n tables at once like WithParallelTables, also when tables aren't read in
+// chunks. The rows of a table read in a single query are then written in chunks of concurrencyRows
+// rows as they come, so the tables being read all make progress in the output.
+func WithConcurrency(n int) Option {
+	return func(d *Dumper) {
+		d.parallelTables = n
+		d.parallelUnchunked = true
+	}
+}
+
+// concurrencyRows is the number of rows of the chunks tables read at once are written in, without a chunk size.
+const concurrencyRows = 1000
+
+// interleaved reports whether tables are read at once. Servers whose snapshot is read through a single
+// connection, see startSnapshot, startOLAP and startConsistentRead, and dumps within a single
+// transaction read them one at a time.
+func (d *Dumper) interleaved() bool {
+	if d.parallelTables <= 1 || (d.chunkSize <= 0 && !d.parallelUnchunked) || d.outfile != nil || d.singleTransaction || d.recordBinlog || d.journalFile != "" || d.resume != nil || d.isTiDB() || d.isVitess() {
+		return false
+	}
+	s, _ := d.server()
+	return s.managed == ""
+}
+
+// workerConn opens a connection set up like the pinned one, see startSession, nil if there is none.
+func (d *Dumper) workerConn() (*sql.Conn, error) {
+	if d.conn == nil {
+		return nil, nil
+	}
+
+	conn, err := d.db.Conn(d.context())
+	if err != nil {
+		return nil, fmt.Errorf("open connection: %w", err)
+	}
+	for _, q := range d.connSetup {
+		if _, err = conn.ExecContext(d.context(), q); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// errQuit stops reading a table once the dump stopped writing its chunks.
+var errQuit = errors.New("dump stopped")
+
+// parallelTable is a table being read by a worker of WithParallelTables.
+type parallelTable struct {
+	name  string
+	index int
+	// Headers of the units of the table, as written
+	units    []tableUnit
+	queries  []*tableQuery
+	headers  []*binary.TableHeader
+	sections []*TableInfo
+	// Units not read completely yet, and those of the first column group among them. The units of the
+	// other groups are read once those of the first are, so every row exists before they merge into it
+	left      int
+	firstLeft int
+
+	chunks          int
+	rows            int64
+	bytes           int64
+	estimatedRows   int64
+	estimatedChunks int
+	// When the table started, and set once it went over its limit, see WithTableLimits
+	started time.Time
+	limited int32
+}
+
+// parallelUnit is a unit of a table read by a worker.
+type parallelUnit struct {
+	table *parallelTable
+	unit  int
+	// Connection the unit is read through, nil for the pool
+	conn *sql.Conn
+}
+
+// firstUnits returns the units of a table read first, all of them unless they are split by column group.
+func (t *parallelTable) firstUnits() []*parallelUnit {
+	var first []*parallelUnit
+	for i, u := range t.units {
+		if u.group <= 1 {
+			first = append(first, &parallelUnit{table: t, unit: i})
+		}
+	}
+	t.firstLeft = len(first)
+	return first
+}
+
+// unitDone records that a unit was read completely, returning the units of the table that can be read now.
+func (t *parallelTable) unitDone(unit int) []*parallelUnit {
+	t.left--
+	if t.units[unit].group > 1 {
+		return nil
+	}
+	if t.firstLeft--; t.firstLeft > 0 {
+		return nil
+	}
+	var later []*parallelUnit
+	for i, u := range t.units {
+		if u.group > 1 {
+			later = append(later, &parallelUnit{table: t, unit: i})
+		}
+	}
+	return later
+}
+
+// parallelChunk is a chunk of a unit of a table read by a worker. done marks the end of the unit.
+type parallelChunk struct {
+	table *parallelTable
+	unit  int
+	rows  []binary.RowData
+	done  bool
+	err   error
+}
+
+// writeParallelTables dumps the tables WithParallelTables at a time, in order, writing their chunks as they are read.
+func (d *Dumper) writeParallelTables(schema string, tables []string, wg *sync.WaitGroup) error {
+	chunks := make(chan parallelChunk)
+	quit := make(chan struct{})
+	var workers sync.WaitGroup
+	defer func() {
+		close(quit)
+		workers.Wait()
+	}()
+
+	// Units of the tables started that wait for a worker
+	var pending []*parallelUnit
+	next, active := 0, 0
+	for {
+		for active < d.parallelTables {
+			if len(pending) == 0 {
+				// Tables being read are read completely even if the dump was interrupted
+				if next >= len(tables) || (next > 0 && d.isInterrupted()) {
+					break
+				}
+				d.cur.TableIndex = next + 1
+				t, err := d.startParallelTable(tables[next], schema)
+				if err != nil {
+					return err
+				}
+				next++
+				if t != nil {
+					pending = t.firstUnits()
+				}
+				continue
+			}
+
+			u := pending[0]
+			pending = pending[1:]
+			conn, err := d.workerConn()
+			if err != nil {
+				return err
+			}
+			u.conn = conn
+			active++
+			workers.Add(1)
+			go func() {
+				defer workers.Done()
+				d.readParallelUnit(u, wg, chunks, quit)
+			}()
+		}
+		if active == 0 {
+			break
+		}
+
+		c := <-chunks
+		if c.err != nil {
+			return fmt.Errorf("write table rows: %w", c.err)
+		}
+		if err := d.writeParallelChunk(c); err != nil {
+			return fmt.Errorf("write table rows: %w", err)
+		}
+		if !c.done && !c.table.skipLimited() {
+			if err := d.checkTableLimit(c.table.name, c.table.started, c.table.rows, c.table.bytes); err != nil {
+				var limit *tableLimitError
+				if !errors.As(err, &limit) || limit.breach.Action != LimitSkip {
+					return fmt.Errorf("write table rows: %w", err)
+				}
+				atomic.StoreInt32(&c.table.limited, 1)
+			}
+		}
+		if c.done {
+			active--
+			pending = append(pending, c.table.unitDone(c.unit)...)
+			if c.table.left == 0 {
+				if err := d.endParallelTable(c.table); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if next < len(tables) {
+		return d.stop()
+	}
+	return nil
+}
+
+// startParallelTable runs the hooks before a table and writes the headers of its units, returning nil
+// if the table has no rows to read.
+func (d *Dumper) startParallelTable(name string, schema string) (*parallelTable, error) {
+	if err := d.runHooks("before table", d.hooks.tableHooks(name, false)); err != nil {
+		return nil, err
+	}
+	header, units, policy, err := d.prepareTable(name, schema)
+	if err != nil {
+		return nil, err
+	}
+	t := &parallelTable{name: name, index: d.cur.TableIndex, started: time.Now()}
+	if header == nil {
+		return nil, d.endParallelTable(t)
+	}
+	if policy == EngineSkipData {
+		if err = d.writeTableHeader(header); err == nil {
+			err = d.bin.WriteTableEnd(header.ID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		d.emitParallelProgress(t, 0, true)
+		return nil, d.endParallelTable(t)
+	}
+
+	if p := d.profiles[name]; p != nil {
+		t.estimatedRows, t.estimatedChunks = p.Rows, p.Chunks
+	} else if d.reportsProgress() {
+		t.estimatedRows = d.estimatedRows(name, schema)
+	}
+
+	cols := header.Columns
+	for _, u := range units {
+		tq, err := d.newTableQuery(name, u, schema)
+		if err != nil {
+			return nil, err
+		}
+		t.queries = append(t.queries, tq)
+
+		d.setUnitHeader(header, cols, u)
+		tq.columns = header.Columns
+		if err = d.writeTableHeader(header); err != nil {
+			return nil, err
+		}
+		h := *header
+		t.units = append(t.units, u)
+		t.headers = append(t.headers, &h)
+		t.sections = append(t.sections, d.infoTable)
+	}
+	t.left = len(units)
+
+	d.emitParallelProgress(t, 0, false)
+	return t, nil
+}
+
+// readParallelUnit reads the chunks of a unit of a table, until quit is closed.
+func (d *Dumper) readParallelUnit(u *parallelUnit, wg *sync.WaitGroup, chunks chan<- parallelChunk, quit <-chan struct{}) {
+	// The connection is replaced if it was dropped
+	defer func() {
+		if u.conn != nil {
+			u.conn.Close()
+		}
+	}()
+
+	send := func(c parallelChunk) bool {
+		select {
+		case chunks <- c:
+			return c.err == nil
+		case <-quit:
+			return false
+		}
+	}
+
+	batch := d.chunkSize
+	if batch <= 0 {
+		batch = concurrencyRows
+	}
+	t, i := u.table, u.unit
+	tq := t.queries[i]
+	for _, filter := range tq.filters {
+		if t.skipLimited() {
+			break
+		}
+		tq.restart()
+		for offset := 0; ; offset += tq.chunkSize {
+			if offset > 0 && t.skipLimited() {
+				break
+			}
+			wg.Wait()
+			logrus.Infof("Reading row data for table %s, offset = %d", t.name, offset)
+			q, args := tq.chunk(filter, offset)
+
+			c := parallelChunk{table: t, unit: i}
+			gotData := false
+			if d.bufferChunks(tq) {
+				conn, reconnect := d.workerRetry(u)
+				gotData, c.err = d.readBufferedChunk(t.name, tq, conn, reconnect, func() (*sql.Rows, error) {
+					return d.queryWorker(u, q, args)
+				}, func(row binary.RowData) error {
+					c.rows = append(c.rows, row)
+					return nil
+				})
+			} else if rows, err := d.queryWorkerChunk(u, q, args); err != nil {
+				c.err = err
+			} else {
+				gotData, c.err = d.readRows(t.name, tq, rows, func(row binary.RowData) error {
+					c.rows = append(c.rows, row)
+					// Tables read in a single query are still written a chunk at a time
+					if len(c.rows) >= batch {
+						if !send(c) {
+							return errQuit
+						}
+						c = parallelChunk{table: t, unit: i}
+					}
+					return nil
+				})
+			}
+			if errors.Is(c.err, errQuit) {
+				return
+			}
+			if len(c.rows) > 0 || c.err != nil {
+				if !send(c) {
+					return
+				}
+			}
+			if !gotData || tq.chunkSize <= 0 {
+				break
+			}
+		}
+	}
+	send(parallelChunk{table: t, unit: i, done: true})
+}
+
+// queryWorkerChunk runs the query reading a chunk of a unit on the connection of its worker, trying
+// it again after transient errors like queryChunk.
+func (d *Dumper) queryWorkerChunk(u *parallelUnit, q string, args []interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	conn, reconnect := d.workerRetry(u)
+	err := d.retryChunk(conn, reconnect, func() error {
+		var err error
+		rows, err = d.queryWorker(u, q, args)
+		return err
+	})
+	return rows, err
+}
+
+// workerRetry returns the connection of the worker of a unit and the function replacing it once
+// dropped, as the tables read at once aren't read from a snapshot, see retryChunk.
+func (d *Dumper) workerRetry(u *parallelUnit) (func() *sql.Conn, func() error) {
+	reconnect := func() error {
+		if u.conn == nil {
+			return nil
+		}
+		conn, err := d.workerConn()
+		if err != nil {
+			return err
+		}
+		u.conn.Close()
+		u.conn = conn
+		return nil
+	}
+	return func() *sql.Conn { return u.conn }, reconnect
+}
+
+// queryWorker runs a query on the connection of the worker of a unit, or the pool if it has none.
+func (d *Dumper) queryWorker(u *parallelUnit, q string, args []interface{}) (*sql.Rows, error) {
+	if u.conn != nil {
+		return u.conn.QueryContext(d.context(), q, args...)
+	}
+	return d.db.QueryContext(d.context(), q, args...)
+}
+
+// writeParallelChunk writes a chunk of a table, or the end of one of its units.
+func (d *Dumper) writeParallelChunk(c parallelChunk) error {
+	t, h := c.table, c.table.headers[c.unit]
+	if c.done {
+		return d.bin.WriteTableEnd(h.ID)
+	}
+	// Chunks read ahead of a table that went over its limit are dropped
+	if t.skipLimited() {
+		return nil
+	}
+
+	if err := d.bin.WriteChunk(h.ID); err != nil {
+		return err
+	}
+	d.infoTable, d.infoHeader = t.sections[c.unit], h
+	for _, row := range c.rows {
+		size := int64(binary.RowSize(row))
+		t.rows++
+		t.bytes += size
+		d.cur.TotalRows++
+		d.cur.TotalBytes += size
+		if err := d.writeRow(row); err != nil {
+			return err
+		}
+	}
+	if err := d.flushQueue(); err != nil {
+		return err
+	}
+
+	t.chunks++
+	d.emitParallelProgress(t, c.unit, false)
+	return nil
+}
+
+// endParallelTable reports the rules the rows of a table broke and runs the hooks after it.
+func (d *Dumper) endParallelTable(t *parallelTable) error {
+	d.reportRules(t.name)
+	if len(t.units) > 0 {
+		d.emitParallelProgress(t, len(t.units)-1, true)
+	}
+	if err := d.runHooks("after table", d.hooks.tableHooks(t.name, true)); err != nil {
+		return err
+	}
+
+	d.checkpoint.Done = append(d.checkpoint.Done, t.name)
+	return d.saveCheckpoint()
+}
+
+func (d *Dumper) emitParallelProgress(t *parallelTable, unit int, done bool) {
+	d.cur.Table = t.name
+	d.cur.TableIndex = t.index
+	d.cur.Partition = ""
+	d.cur.Shard = 0
+	if !done && unit < len(t.units) {
+		d.cur.Partition = t.units[unit].partition
+		d.cur.Shard = t.units[unit].shard
+	}
+	d.cur.Chunk = t.chunks
+	d.cur.Rows = t.rows
+	d.cur.Bytes = t.bytes
+	d.cur.EstimatedRows = t.estimatedRows
+	d.cur.EstimatedChunks = t.estimatedChunks
+	d.cur.TableDone = done
+	d.emitProgress()
+}
+
+// startRows starts the rows of a section written in one go, in an interleaved dump.
+func (d *Dumper) startRows(h *binary.TableHeader) error {
+	if !d.interleave {
+		return nil
+	}
+	return d.bin.WriteChunk(h.ID)
+}
+
+// endRows ends the rows of a section, in an interleaved dump.
+func (d *Dumper) endRows(h *binary.TableHeader) error {
+	if !d.interleave {
+		return nil
+	}
+	return d.bin.WriteTableEnd(h.ID)
+}
@@ -0,0 +1,48 @@
+package mysqldump
+
+import (
+	"reflect"
+	"testing"
+)
+
+func unitIndexes(units []*parallelUnit) []int {
+	var is []int
+	for _, u := range units {
+		is = append(is, u.unit)
+	}
+	return is
+}
+
+func TestParallelUnitsPartitions(t *testing.T) {
+	pt := &parallelTable{units: []tableUnit{{partition: "p0"}, {partition: "p1"}, {partition: "p2"}}, left: 3}
+	if got := unitIndexes(pt.firstUnits()); !reflect.DeepEqual(got, []int{0, 1, 2}) {
+		t.Fatalf("first units %v, want every partition", got)
+	}
+	for i := range pt.units {
+		if later := pt.unitDone(i); len(later) > 0 {
+			t.Errorf("unit %d done: got later units %v", i, unitIndexes(later))
+		}
+	}
+	if pt.left != 0 {
+		t.Errorf("%d units left", pt.left)
+	}
+}
+
+func TestParallelUnitsColumnGroups(t *testing.T) {
+	// Two shards of two column groups, those of the first group first
+	pt := &parallelTable{units: []tableUnit{{shard: 1, group: 1}, {shard: 2, group: 1}, {shard: 1, group: 2}, {shard: 2, group: 2}}, left: 4}
+	if got := unitIndexes(pt.firstUnits()); !reflect.DeepEqual(got, []int{0, 1}) {
+		t.Fatalf("first units %v, want those of the first group", got)
+	}
+	if later := pt.unitDone(1); len(later) > 0 {
+		t.Fatalf("later units %v before the first group was read", unitIndexes(later))
+	}
+	if got := unitIndexes(pt.unitDone(0)); !reflect.DeepEqual(got, []int{2, 3}) {
+		t.Fatalf("later units %v, want those of the second group", got)
+	}
+	pt.unitDone(3)
+	pt.unitDone(2)
+	if pt.left != 0 {
+		t.Errorf("%d units left", pt.left)
+	}
+}
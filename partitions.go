@@ -0,0 +1,72 @@
+package mysqldump
+
+import (
+	"fmt"
+
+	"github.com/MouseHatGames/go-mysqldump/internal/marshal"
+)
+
+// WithPartitionUnits dumps the partitions of partitioned tables one by one, each in its own
+// section of the dump named after the partition. Restores then insert the rows of each
+// section into the same partition. With WithParallelTables the partitions of a table are read at once.
+func WithPartitionUnits() Option {
+	return func(d *Dumper) {
+		d.partitions = true
+	}
+}
+
+// tableUnits returns the sections a table is dumped in, by partition and shard.
+func (d *Dumper) tableUnits(name string, schema string) ([]tableUnit, error) {
+	parts, err := d.tablePartitions(name, schema)
+	if err != nil {
+		return nil, fmt.Errorf("get table partitions: %w", err)
+	}
+
+	units := make([]tableUnit, len(parts))
+	for i, p := range parts {
+		units[i].partition = p
+	}
+	return d.shardUnits(name, schema, units)
+}
+
+// tablePartitions returns the partitions a table is dumped by, or a single empty name to dump it whole.
+func (d *Dumper) tablePartitions(name string, schema string) ([]string, error) {
+	whole := []string{""}
+	if !d.partitions || d.isPQ() {
+		return whole, nil
+	}
+	if s, _ := d.server(); !s.supports(featurePartitionSelection) {
+		return whole, nil
+	}
+
+	rows, err := d.db.QueryContext(d.context(), stmtPartitions, name, schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var parts []string
+	for rows.Next() {
+		var p string
+		if err = rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		parts = append(parts, p)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(parts) == 0 {
+		return whole, nil
+	}
+	return parts, nil
+}
+
+// partitionClause returns the PARTITION clause inserting rows into the partition a section was dumped from.
+func partitionClause(t *marshal.TableHeader) string {
+	if t.Partition == "" {
+		return ""
+	}
+	return " PARTITION (" + quoteName(t.Partition) + ")"
+}
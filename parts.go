@@ -0,0 +1,225 @@
+package mysqldump
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Manifest lists the parts a dump was split into by a PartWriter, in order.
+type Manifest struct {
+	Parts []ManifestPart
+}
+
+type ManifestPart struct {
+	// Name of the file, relative to the manifest
+	File   string
+	Size   int64
+	SHA256 string
+}
+
+// PartWriter writes a dump to numbered files of about maxSize bytes each: base.part0001,
+// base.part0002, ... Files are only switched between records, so a part can go over maxSize by
+// the size of a single record, and every part but the first continues the previous one. Close
+// writes the manifest listing the parts to base.manifest.
+type PartWriter struct {
+	base    string
+	maxSize int64
+
+	f    *os.File
+	h    hash.Hash
+	size int64
+	full bool
+
+	manifest Manifest
+}
+
+// NewPartWriter creates a writer splitting a dump into parts of about maxSize bytes.
+func NewPartWriter(base string, maxSize int64) *PartWriter {
+	return &PartWriter{
+		base:    base,
+		maxSize: maxSize,
+	}
+}
+
+func (p *PartWriter) Write(b []byte) (int, error) {
+	if p.f == nil || p.full {
+		if err := p.next(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := p.f.Write(b)
+	p.h.Write(b[:n])
+	p.size += int64(n)
+	return n, err
+}
+
+// boundary is called by the dumper after every record. Once the current part is full the next
+// write starts a new one, so no empty part is left after the last record.
+func (p *PartWriter) boundary() error {
+	p.full = p.f != nil && p.size >= p.maxSize
+	return nil
+}
+
+func (p *PartWriter) next() error {
+	if p.f != nil {
+		if err := p.closePart(); err != nil {
+			return err
+		}
+	}
+
+	name := fmt.Sprintf("%s.part%04d", p.base, len(p.manifest.Parts)+1)
+	f, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("create part: %w", err)
+	}
+
+	p.f = f
+	p.h = sha256.New()
+	p.size = 0
+	p.full = false
+	p.manifest.Parts = append(p.manifest.Parts, ManifestPart{File: filepath.Base(name)})
+	return nil
+}
+
+func (p *PartWriter) closePart() error {
+	part := &p.manifest.Parts[len(p.manifest.Parts)-1]
+	part.Size = p.size
+	part.SHA256 = hex.EncodeToString(p.h.Sum(nil))
+
+	err := p.f.Close()
+	p.f = nil
+	return err
+}
+
+// Close closes the last part and writes the manifest.
+func (p *PartWriter) Close() error {
+	if p.f != nil {
+		if err := p.closePart(); err != nil {
+			return err
+		}
+	}
+
+	b, err := json.MarshalIndent(&p.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(p.base+".manifest", b, 0644)
+}
+
+// Parts returns the names of the files written so far.
+func (p *PartWriter) Parts() []string {
+	dir := filepath.Dir(p.base)
+	names := make([]string, len(p.manifest.Parts))
+	for i, part := range p.manifest.Parts {
+		names[i] = filepath.Join(dir, part.File)
+	}
+	return names
+}
+
+// ReadManifest reads the manifest written by a PartWriter.
+func ReadManifest(path string) (*Manifest, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if err = json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("decode manifest: %w", err)
+	}
+	return &m, nil
+}
+
+// ErrPartMismatch is returned reading a part of a dump whose size or SHA-256 isn't that listed in the manifest.
+var ErrPartMismatch = errors.New("part doesn't match the manifest")
+
+// OpenParts opens the parts listed in a manifest as a single dump. Parts are opened one at a time as
+// they are read, and the reads fail with ErrPartMismatch once a part turns out not to be the size or
+// to have the SHA-256 the manifest lists.
+func OpenParts(manifestPath string) (io.ReadCloser, error) {
+	m, err := ReadManifest(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	return &partsReader{dir: filepath.Dir(manifestPath), parts: m.Parts}, nil
+}
+
+// partsReader reads the parts of a dump one after the other, checking each against the manifest.
+type partsReader struct {
+	dir   string
+	parts []ManifestPart
+	next  int
+
+	f    *os.File
+	part ManifestPart
+	h    hash.Hash
+	size int64
+	err  error
+}
+
+func (r *partsReader) Read(b []byte) (int, error) {
+	for r.err == nil {
+		if r.f == nil {
+			if r.next == len(r.parts) {
+				return 0, io.EOF
+			}
+			r.part = r.parts[r.next]
+			r.next++
+			if r.f, r.err = os.Open(filepath.Join(r.dir, r.part.File)); r.err != nil {
+				break
+			}
+			r.h, r.size = sha256.New(), 0
+		}
+
+		n, err := r.f.Read(b)
+		r.h.Write(b[:n])
+		r.size += int64(n)
+		if r.size > r.part.Size {
+			r.err = fmt.Errorf("%w: %s is over %d bytes", ErrPartMismatch, r.part.File, r.part.Size)
+			return n, r.err
+		}
+		if errors.Is(err, io.EOF) {
+			r.err = r.endPart()
+		} else if err != nil {
+			r.err = err
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+	return 0, r.err
+}
+
+// endPart closes the part read completely and checks it against the manifest.
+func (r *partsReader) endPart() error {
+	err := r.f.Close()
+	r.f = nil
+	if err != nil {
+		return err
+	}
+	if r.size != r.part.Size {
+		return fmt.Errorf("%w: %s has %d bytes, not %d", ErrPartMismatch, r.part.File, r.size, r.part.Size)
+	}
+	if sum := hex.EncodeToString(r.h.Sum(nil)); sum != r.part.SHA256 {
+		return fmt.Errorf("%w: SHA-256 of %s is %s, not %s", ErrPartMismatch, r.part.File, sum, r.part.SHA256)
+	}
+	return nil
+}
+
+func (r *partsReader) Close() error {
+	if r.f == nil {
+		return nil
+	}
+	err := r.f.Close()
+	r.f = nil
+	return err
+}
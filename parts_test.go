@@ -0,0 +1,114 @@
+package mysqldump
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeParts splits records of 100 bytes into parts of about 250 bytes, returning what was written.
+func writeParts(t *testing.T, base string) []byte {
+	p := NewPartWriter(base, 250)
+	var all []byte
+	for i := 0; i < 10; i++ {
+		rec := bytes.Repeat([]byte{byte('a' + i)}, 100)
+		if _, err := p.Write(rec); err != nil {
+			t.Fatal(err)
+		}
+		p.boundary()
+		all = append(all, rec...)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return all
+}
+
+func readParts(manifest string) ([]byte, error) {
+	r, err := OpenParts(manifest)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+func TestOpenParts(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "dump")
+	want := writeParts(t, base)
+
+	m, err := ReadManifest(base + ".manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Parts) != 4 {
+		t.Errorf("%d parts, want 4", len(m.Parts))
+	}
+	got, err := readParts(base + ".manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("read %q, want %q", got, want)
+	}
+}
+
+func TestOpenPartsMismatch(t *testing.T) {
+	tests := map[string]func(part string) error{
+		"changed": func(part string) error {
+			b, err := ioutil.ReadFile(part)
+			if err != nil {
+				return err
+			}
+			b[10] ^= 1
+			return ioutil.WriteFile(part, b, 0644)
+		},
+		"truncated": func(part string) error {
+			return os.Truncate(part, 100)
+		},
+		"extended": func(part string) error {
+			f, err := os.OpenFile(part, os.O_APPEND|os.O_WRONLY, 0)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = f.Write([]byte("x"))
+			return err
+		},
+	}
+	for name, change := range tests {
+		base := filepath.Join(t.TempDir(), "dump")
+		writeParts(t, base)
+		if err := change(base + ".part0002"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := readParts(base + ".manifest"); !errors.Is(err, ErrPartMismatch) {
+			t.Errorf("%s part: read with %v, want ErrPartMismatch", name, err)
+		}
+	}
+}
+
+func TestOpenPartsLazily(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "dump")
+	writeParts(t, base)
+	if err := os.Remove(base + ".part0003"); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := OpenParts(base + ".manifest")
+	if err != nil {
+		t.Fatalf("open parts with a missing part: %s", err)
+	}
+	defer r.Close()
+	// The first two parts read before the missing one
+	got, err := ioutil.ReadAll(r)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("read with %v, want the missing part", err)
+	}
+	if len(got) != 600 {
+		t.Errorf("read %d bytes, want 600", len(got))
+	}
+}
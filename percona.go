@@ -0,0 +1,107 @@
+package mysqldump
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ClausePolicy controls what happens to DDL clauses only some servers understand when a dump is restored.
+type ClausePolicy int
+
+const (
+	// ClauseAuto strips the clauses if the target server doesn't support them. Conversions keep them.
+	ClauseAuto ClausePolicy = iota
+	// ClauseKeep restores the DDL as it was dumped.
+	ClauseKeep
+	// ClauseStrip removes the clauses from the DDL.
+	ClauseStrip
+)
+
+// ParseClausePolicy parses "auto", "keep" or "strip".
+func ParseClausePolicy(s string) (ClausePolicy, error) {
+	switch strings.ToLower(s) {
+	case "auto", "":
+		return ClauseAuto, nil
+	case "keep":
+		return ClauseKeep, nil
+	case "strip":
+		return ClauseStrip, nil
+	}
+
+	return 0, fmt.Errorf("invalid clause policy: %s", s)
+}
+
+// Percona writes column compression either bare or in a version comment vanilla MySQL 5.6.33+ executes
+var perconaColumnFormat = regexp.MustCompile("(?i)\\s*(?:/\\*!\\d+\\s*)?COLUMN_FORMAT\\s+COMPRESSED(?:\\s+WITH\\s+COMPRESSION_DICTIONARY\\s+(?:`[^`]*`|\\w+))?(?:\\s*\\*/)?")
+
+// stripPercona removes the Percona Server column compression clauses from a CREATE TABLE statement.
+func stripPercona(ddl string) string {
+	return perconaColumnFormat.ReplaceAllString(ddl, "")
+}
+
+func (d *Dumper) isPercona() bool {
+	s, err := d.server()
+	return err == nil && s.percona
+}
+
+// getCompressionDictionaries returns the Percona compression dictionaries of the server by name.
+func (d *Dumper) getCompressionDictionaries() (map[string][]byte, error) {
+	// Percona Server 5.7 calls the table XTRADB_ZIP_DICT
+	q := "SELECT NAME, DATA FROM INFORMATION_SCHEMA.COMPRESSION_DICTIONARY"
+	if s, _ := d.server(); !s.atLeast(8, 0, 0) {
+		q = "SELECT name, zip_dict FROM INFORMATION_SCHEMA.XTRADB_ZIP_DICT"
+	}
+
+	rows, err := d.db.QueryContext(d.context(), q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dicts := make(map[string][]byte)
+	for rows.Next() {
+		var name sql.NullString
+		var data []byte
+		if err = rows.Scan(&name, &data); err != nil {
+			return nil, err
+		}
+		dicts[name.String] = data
+	}
+	return dicts, rows.Err()
+}
+
+// createDictionaries returns the statements creating a dump's compression dictionaries.
+func createDictionaries(h *FileHeader) []string {
+	names := make([]string, 0, len(h.CompressionDictionaries))
+	for name := range h.CompressionDictionaries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var qs []string
+	for _, name := range names {
+		data := h.CompressionDictionaries[name]
+		var b strings.Builder
+		fmt.Fprintf(&b, "CREATE COMPRESSION_DICTIONARY IF NOT EXISTS %s ('", quoteName(name))
+		writeEscapedString(&b, string(data))
+		b.WriteString("')")
+		qs = append(qs, b.String())
+	}
+	return qs
+}
+
+// stripPercona reports whether the Loader strips the Percona clauses from the DDL it restores.
+func (l *Loader) stripPercona() bool {
+	switch l.opt.Percona {
+	case ClauseKeep:
+		return false
+	case ClauseStrip:
+		return true
+	}
+
+	// Dry runs don't know their target, so they keep the DDL as it is
+	return l.target != nil && !l.target.percona
+}
@@ -0,0 +1,79 @@
+package mysqldump
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrConsumerStopped fails the writes of a dump into a Pipe whose consumer returned before reading it all.
+var ErrConsumerStopped = errors.New("consumer of the dump returned before reading it all")
+
+// Pipe runs dump, writing a dump, and consume, reading it, at once, connected by an io.Pipe, so a dump
+// can be uploaded or restored as it is taken without being stored anywhere:
+//
+//	err := mysqldump.Pipe(ctx, func(ctx context.Context, w io.Writer) error {
+//		var wg sync.WaitGroup
+//		return mysqldump.NewDumper(db, w, 1000).DumpAllTablesContext(ctx, "app", &wg)
+//	}, func(ctx context.Context, r io.Reader) error {
+//		_, err := io.Copy(upload, r)
+//		return err
+//	})
+//
+// Either failing stops the other: the context both run under is cancelled, the reads of consume fail
+// with the error of dump and the writes of dump with that of consume, or ErrConsumerStopped if consume
+// returned nil while the dump goes on writing. Pipe returns once both have, with the error that
+// stopped them first.
+func Pipe(ctx context.Context, dump func(ctx context.Context, w io.Writer) error, consume func(ctx context.Context, r io.Reader) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	pr, pw := io.Pipe()
+
+	var mu sync.Mutex
+	var first error
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if first == nil {
+			first = err
+			cancel()
+		}
+	}
+
+	consumed := make(chan struct{})
+	go func() {
+		defer close(consumed)
+		err := consume(ctx, pr)
+		if err != nil {
+			fail(err)
+		} else {
+			// Only fails the dump if it writes more
+			err = ErrConsumerStopped
+		}
+		// Unblock the dump if it is still writing
+		pr.CloseWithError(err)
+	}()
+
+	err := dump(ctx, pw)
+	if err != nil {
+		fail(err)
+	}
+	// The consumer reads the rest of the dump, and the end of it or its error
+	pw.CloseWithError(err)
+	<-consumed
+
+	mu.Lock()
+	defer mu.Unlock()
+	return first
+}
+
+// PipeRestore restores a dump into db as dump writes it, see Pipe, and returns what it restored.
+func PipeRestore(ctx context.Context, db *sql.DB, opt LoaderOptions, dump func(ctx context.Context, w io.Writer) error) (LoadReport, error) {
+	l := NewLoader(db, opt)
+	err := Pipe(ctx, dump, func(ctx context.Context, r io.Reader) error {
+		return l.Load(r)
+	})
+	return l.Report(), err
+}
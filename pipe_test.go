@@ -0,0 +1,59 @@
+package mysqldump
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/MouseHatGames/go-mysqldump/internal/marshal"
+)
+
+func TestPipeConsumerStopped(t *testing.T) {
+	var rows int
+	var writeErr error
+	err := Pipe(context.Background(), func(ctx context.Context, w io.Writer) error {
+		bw := marshal.NewWriter(w)
+		if writeErr = bw.WriteFileHeader(&FileHeader{}); writeErr != nil {
+			return writeErr
+		}
+		for ; rows < 100000; rows++ {
+			if writeErr = bw.WriteRowData(stringRow("1", "ann")); writeErr != nil {
+				return writeErr
+			}
+		}
+		return bw.WriteFileFooter(&FileFooter{})
+	}, func(ctx context.Context, r io.Reader) error {
+		_, err := io.ReadFull(r, make([]byte, 4))
+		return err
+	})
+
+	if !errors.Is(err, ErrConsumerStopped) {
+		t.Errorf("piped with %v, want ErrConsumerStopped", err)
+	}
+	if !errors.Is(writeErr, ErrConsumerStopped) || rows > 0 {
+		t.Errorf("wrote %d rows before failing with %v, want the first write to fail", rows, writeErr)
+	}
+}
+
+func TestPipeDumpFailed(t *testing.T) {
+	failed := errors.New("dump failed")
+	var readErr error
+	err := Pipe(context.Background(), func(ctx context.Context, w io.Writer) error {
+		if _, err := w.Write([]byte("DUMP")); err != nil {
+			return err
+		}
+		return failed
+	}, func(ctx context.Context, r io.Reader) error {
+		_, readErr = ioutil.ReadAll(r)
+		return readErr
+	})
+
+	if err != failed {
+		t.Errorf("piped with %v, want the error of the dump", err)
+	}
+	if readErr != failed {
+		t.Errorf("read with %v, want the error of the dump", readErr)
+	}
+}
@@ -0,0 +1,44 @@
+package mysqldump
+
+import (
+	binary "github.com/MouseHatGames/go-mysqldump/internal/marshal"
+)
+
+// WithChunkPrefetch makes the dumper read each chunk of a table into memory and run the query for the
+// next one while it is written, so the writer isn't left idle waiting for the server on high latency
+// connections. Up to two chunks are held in memory at once. Only applies when reading in chunks,
+// and not with WithOutfile.
+func WithChunkPrefetch() Option {
+	return func(d *Dumper) {
+		d.prefetch = true
+	}
+}
+
+// prefetchedChunk holds the rows of a chunk read ahead of time, and the cursor of the query having
+// read them, with the key of its last row.
+type prefetchedChunk struct {
+	rows   []binary.RowData
+	cursor *tableQuery
+	err    error
+}
+
+// prefetchChunk starts reading a chunk into memory. The chunk is sent on the returned channel once read.
+// It is read with a copy of tq, whose key only moves on to the chunk once it is taken, see takeCursor.
+func (d *Dumper) prefetchChunk(name string, tq *tableQuery, q string, args []interface{}) <-chan prefetchedChunk {
+	ch := make(chan prefetchedChunk, 1)
+	cursor := *tq
+	go func() {
+		c := prefetchedChunk{cursor: &cursor}
+		_, c.err = d.readChunk(name, &cursor, q, args, func(row binary.RowData) error {
+			c.rows = append(c.rows, row)
+			return nil
+		})
+		ch <- c
+	}()
+	return ch
+}
+
+// takeCursor moves tq on to the end of a prefetched chunk, as if it had been read with tq.
+func (tq *tableQuery) takeCursor(c prefetchedChunk) {
+	tq.key, tq.keyAt, tq.after = c.cursor.key, c.cursor.keyAt, c.cursor.after
+}
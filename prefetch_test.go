@@ -0,0 +1,80 @@
+package mysqldump
+
+import (
+	"database/sql/driver"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// keysetDB answers the chunk queries of a table of ids 1 to n by their arguments, the key after which
+// a chunk starts and its size, or its size and offset.
+func keysetDB(t *testing.T, n int) func(q string, args []driver.Value) (*fakeRows, error) {
+	return func(q string, args []driver.Value) (*fakeRows, error) {
+		if len(args) != 2 {
+			return nil, nil
+		}
+		after, size := int64(0), args[1].(int64)
+		if s, ok := args[0].(string); ok {
+			a, _ := strconv.Atoi(s)
+			after = int64(a)
+		} else {
+			size, after = args[0].(int64), args[1].(int64)
+		}
+		r := &fakeRows{columns: []string{"id"}}
+		for id := after + 1; id <= int64(n) && id <= after+size; id++ {
+			r.rows = append(r.rows, []driver.Value{[]byte(strconv.FormatInt(id, 10))})
+		}
+		return r, nil
+	}
+}
+
+func TestPrefetchInterrupted(t *testing.T) {
+	db, _ := openFakeDB(t, keysetDB(t, 10))
+	d := NewDumper(db, ioutil.Discard, 2, WithChunkPrefetch())
+	d.chunkKeys = map[string]chunkKey{"t": {strategy: ChunkPrimaryKey, columns: []string{"id"}}}
+
+	var got []interface{}
+	var wg sync.WaitGroup
+	err := d.readTableValues("t", tableUnit{}, []string{"id"}, "app", &wg, func(row RowData) error {
+		got = append(got, rowValues(row)...)
+		if len(got) == 4 {
+			d.Interrupt()
+			// Let the next chunk be read ahead before the interrupt is seen
+			time.Sleep(10 * time.Millisecond)
+		}
+		return nil
+	})
+	if err != ErrInterrupted {
+		t.Fatalf("read with %v, want ErrInterrupted", err)
+	}
+	if want := []interface{}{"1", "2", "3", "4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("wrote %q, want %q", got, want)
+	}
+	// The checkpoint resumes after the last row written, not the last one read ahead
+	if c := d.checkpoint; c.Offset != 4 || !reflect.DeepEqual(c.After, []interface{}{"4"}) {
+		t.Errorf("checkpoint at offset %d after %q, want offset 4 after 4", c.Offset, c.After)
+	}
+}
+
+func TestPrefetch(t *testing.T) {
+	db, _ := openFakeDB(t, keysetDB(t, 7))
+	d := NewDumper(db, ioutil.Discard, 2, WithChunkPrefetch())
+	d.chunkKeys = map[string]chunkKey{"t": {strategy: ChunkPrimaryKey, columns: []string{"id"}}}
+
+	var got []interface{}
+	var wg sync.WaitGroup
+	err := d.readTableValues("t", tableUnit{}, []string{"id"}, "app", &wg, func(row RowData) error {
+		got = append(got, rowValues(row)...)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []interface{}{"1", "2", "3", "4", "5", "6", "7"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("wrote %q, want %q", got, want)
+	}
+}
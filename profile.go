@@ -0,0 +1,225 @@
+package mysqldump
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// TableProfile is what cheap aggregate queries tell about a table's data before it is read.
+type TableProfile struct {
+	Table string
+	// True if the table's data is skipped entirely by the filters or its engine policy
+	Skipped bool
+	// Strategy the rows are read in chunks with, see ChunkPrimaryKey, and the columns ordering them.
+	// Empty without a chunk size
+	Chunking string
+	ChunkKey []string
+	// Column the key ranges are given in, the first one of ChunkKey, or the first one read without
+	Key string
+	// Rows read with each filter, a single range for unfiltered tables
+	Ranges []KeyRange
+	// Rows over every range, and the chunks holding them
+	Rows   int64
+	Chunks int
+}
+
+// KeyRange profiles the rows of a table read with a filter.
+type KeyRange struct {
+	Filter string
+	// Rows read with the filter, and the chunks holding them
+	Rows   int64
+	Chunks int
+	// Smallest and largest key, nil for no rows
+	Min *string
+	Max *string
+	// First key of every chunk, nil without chunking, for tables read in a single query or on servers
+	// without window functions. Only the first column of the key is given
+	Boundaries []string
+}
+
+// WithPriming profiles every table with Profile before any data is read, so the progress reports
+// exact row and chunk counts instead of the server's statistics. The profile is taken outside of the
+// dump's snapshot, rows written meanwhile make the counts off.
+func WithPriming() Option {
+	return func(d *Dumper) {
+		d.priming = true
+	}
+}
+
+// Profile counts the rows every table of a database would be dumped with, along with the range of
+// their keys and, with a chunk size, the boundaries of their chunks, without reading the rows.
+func (d *Dumper) Profile(dbName string) ([]*TableProfile, error) {
+	return d.ProfileContext(context.Background(), dbName)
+}
+
+// ProfileContext is Profile, running every query under ctx. The aggregate queries scan whole tables,
+// cancelling ctx aborts the one running.
+func (d *Dumper) ProfileContext(ctx context.Context, dbName string) ([]*TableProfile, error) {
+	defer d.withContext(ctx)()
+	if err := d.use(dbName); err != nil {
+		return nil, err
+	}
+
+	tables, err := d.getTables(dbName)
+	if err != nil {
+		return nil, fmt.Errorf("list tables: %w", err)
+	}
+	d.chunkKeys = nil
+
+	res := make([]*TableProfile, 0, len(tables))
+	for _, t := range tables {
+		p, err := d.profileTable(t, dbName)
+		if err != nil {
+			return nil, fmt.Errorf("profile table %s: %w", t, err)
+		}
+		res = append(res, p)
+	}
+	return res, nil
+}
+
+// prime profiles the tables about to be dumped, leaving them unprofiled on errors.
+func (d *Dumper) prime(schema string, tables []string) {
+	d.profiles = nil
+	if !d.priming {
+		return
+	}
+
+	d.profiles = make(map[string]*TableProfile, len(tables))
+	for _, t := range tables {
+		p, err := d.profileTable(t, schema)
+		if err != nil {
+			d.warn(WarningMetadata, t, "Can't profile table %s, using its statistics: %s", t, err)
+			continue
+		}
+		d.profiles[t] = p
+	}
+	logrus.Infof("Profiled %d tables", len(d.profiles))
+}
+
+func (d *Dumper) profileTable(name string, schema string) (*TableProfile, error) {
+	p := &TableProfile{Table: name}
+
+	filters, filtered := d.tableFilters(name)
+	if filtered && len(filters) == 0 {
+		p.Skipped = true
+		return p, nil
+	}
+	if !filtered {
+		filters = []string{""}
+	}
+
+	meta, err := d.getTableMeta(name, schema)
+	if err != nil {
+		return nil, fmt.Errorf("get table type: %w", err)
+	}
+	if pol := d.enginePolicy(name, meta.engine); pol == EngineSkipData || pol == EngineSkip {
+		p.Skipped = true
+		return p, nil
+	}
+
+	sel, err := d.selectExpr(name, schema)
+	if err != nil {
+		return nil, err
+	}
+	from := quoteIdent(name, d.isPQ())
+	st, err := d.systemTimeClause(name, schema)
+	if err != nil {
+		return nil, err
+	}
+	if st != "" {
+		from += " FOR SYSTEM_TIME " + st
+	}
+
+	k, err := d.getChunkKey(name, schema)
+	if err != nil {
+		return nil, fmt.Errorf("get chunk key: %w", err)
+	}
+	p.Chunking, p.ChunkKey = k.strategy, k.columns
+	if len(k.columns) > 0 {
+		p.Key = k.columns[0]
+	} else if p.Key, err = d.firstColumn(stmtNoRows(sel, from)); err != nil {
+		return nil, fmt.Errorf("get key column: %w", err)
+	}
+	key := quoteColumns([]string{p.Key}, d.isPQ())
+	order := key
+	if len(k.columns) > 0 {
+		order = quoteColumns(k.columns, d.isPQ())
+	}
+
+	chunkSize := d.chunkSize
+	if k.strategy == ChunkFullScan {
+		chunkSize = 0
+	}
+	s, _ := d.server()
+
+	for _, f := range filters {
+		// The table itself rather than the columns read, the key may include columns that aren't
+		rows := from + f
+		r := KeyRange{Filter: f}
+		var min, max sql.NullString
+		err = d.db.QueryRowContext(d.context(), stmtKeyRange(key, rows)).Scan(&r.Rows, &min, &max)
+		if err != nil {
+			return nil, fmt.Errorf("count rows: %w", err)
+		}
+		if min.Valid {
+			r.Min, r.Max = &min.String, &max.String
+		}
+
+		r.Chunks = 1
+		if chunkSize > 0 {
+			r.Chunks = int((r.Rows + int64(chunkSize) - 1) / int64(chunkSize))
+			if r.Rows > 0 && s.supports(featureWindowFunctions) {
+				if r.Boundaries, err = d.chunkBoundaries(key, order, rows, chunkSize); err != nil {
+					return nil, fmt.Errorf("get chunk boundaries: %w", err)
+				}
+			}
+		}
+
+		p.Rows += r.Rows
+		p.Chunks += r.Chunks
+		p.Ranges = append(p.Ranges, r)
+	}
+
+	return p, nil
+}
+
+// firstColumn returns the name of the first column returned by a query reading no rows.
+func (d *Dumper) firstColumn(q string) (string, error) {
+	rows, err := d.db.QueryContext(d.context(), q)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	if len(cols) == 0 {
+		return "", errors.New("no columns")
+	}
+	return cols[0], nil
+}
+
+// chunkBoundaries returns the key every chunk of the rows starts at, numbering them in chunk order in a single scan.
+func (d *Dumper) chunkBoundaries(key string, order string, rows string, chunkSize int) ([]string, error) {
+	res, err := d.db.QueryContext(d.context(), stmtChunkBoundaries(key, order, rows, d.isPQ()), chunkSize)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	var bounds []string
+	for res.Next() {
+		var b sql.NullString
+		if err = res.Scan(&b); err != nil {
+			return nil, err
+		}
+		bounds = append(bounds, b.String)
+	}
+	return bounds, res.Err()
+}
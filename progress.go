@@ -0,0 +1,110 @@
+package mysqldump
+
+// ProgressEvent describes how far a running dump got. Events are sent when a table starts,
+// after every chunk or every progressRows rows, and when a table is done.
+type ProgressEvent struct {
+	Table string
+	// Partition of Table being dumped, if it is dumped per partition
+	Partition string
+	// Shard of Table being dumped, starting at 1, if its rows are split by shard
+	Shard int
+	// Position of the table in the dump, starting at 1, and number of tables being dumped
+	TableIndex int
+	TableCount int
+	// Number of chunks of the table read so far
+	Chunk int
+	// Rows and encoded bytes of the table written so far
+	Rows  int64
+	Bytes int64
+	// Number of rows in the table according to the server's statistics or WithPriming, 0 if unknown
+	EstimatedRows int64
+	// Number of chunks holding the rows of the table, only known with WithPriming
+	EstimatedChunks int
+	// Number of rows of every table being dumped, as counted by EstimatedRows
+	EstimatedTotalRows int64
+	// Rows and bytes written for the whole dump
+	TotalRows  int64
+	TotalBytes int64
+	TableDone  bool
+	// Set while the dump waits for the destination to write the queued chunks, see WithWriteQueue
+	DestinationSlow bool
+	// Set on the events reporting a warning, one per warning, sent ahead of the next progress event.
+	// They carry the progress so far, without TableDone
+	Warning *Warning
+}
+
+const progressRows = 10000
+
+// WithProgress makes the dumper report its progress to fn. fn is called from the goroutine running the dump.
+func WithProgress(fn func(ProgressEvent)) Option {
+	return func(d *Dumper) {
+		d.progress = fn
+	}
+}
+
+// ProgressFunc is told the rows and encoded bytes of a table written so far, see WithProgressFunc.
+type ProgressFunc func(table string, rowsWritten int64, bytesWritten int64)
+
+// WithProgressFunc calls fn with the progress of the table being dumped whenever a progress event is
+// sent, see ProgressEvent, for callers that only drive a progress bar. It can be given along with
+// WithProgress.
+func WithProgressFunc(fn ProgressFunc) Option {
+	return func(d *Dumper) {
+		d.progressFunc = fn
+	}
+}
+
+// reportsProgress reports whether anything is told the progress of the dump.
+func (d *Dumper) reportsProgress() bool {
+	return d.progress != nil || d.progressFunc != nil || d.registry != nil
+}
+
+func (d *Dumper) emitProgress() {
+	if d.registry != nil {
+		d.registry.update(d.jobName, d.cur, d.Bandwidth())
+	}
+	if d.progressFunc != nil && d.cur.Table != "" {
+		d.progressFunc(d.cur.Table, d.cur.Rows, d.cur.Bytes)
+	}
+	if d.progress == nil {
+		return
+	}
+	for _, w := range d.takeWarnings() {
+		w := w
+		e := d.cur
+		e.TableDone = false
+		e.Warning = &w
+		d.progress(e)
+	}
+	d.progress(d.cur)
+}
+
+// estimateRows reads the estimated row count of every table to dump, unless nothing is told the
+// progress, and returns their total.
+func (d *Dumper) estimateRows(schema string, tables []string) int64 {
+	d.rowEstimates = nil
+	if !d.reportsProgress() {
+		return 0
+	}
+
+	d.rowEstimates = make(map[string]int64, len(tables))
+	var total int64
+	for _, t := range tables {
+		n := d.estimatedRows(t, schema)
+		d.rowEstimates[t] = n
+		total += n
+	}
+	return total
+}
+
+// estimatedRows returns the estimated row count of a table, by WithPriming or the server's statistics.
+func (d *Dumper) estimatedRows(name string, schema string) int64 {
+	if p := d.profiles[name]; p != nil {
+		return p.Rows
+	}
+	if n, ok := d.rowEstimates[name]; ok {
+		return n
+	}
+	n, _, _ := d.getTableStats(name, schema)
+	return n
+}
@@ -0,0 +1,152 @@
+package mysqldump
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	binary "github.com/MouseHatGames/go-mysqldump/internal/marshal"
+	"github.com/sirupsen/logrus"
+)
+
+// TableTypeQuery marks the result of a query given to WithQueries or DumpQuery. It has no DDL and isn't restored.
+const TableTypeQuery = "QUERY"
+
+// WithQueries dumps the result of each query as a read-only table named by its key, after the
+// tables of the database. It is meant for diagnostics snapshots of performance_schema and sys
+// views, which can't go through SHOW CREATE TABLE like regular tables.
+func WithQueries(queries map[string]string) Option {
+	return func(d *Dumper) {
+		d.queries = make(map[string]string, len(queries))
+		for name, q := range queries {
+			d.queries[name] = q
+		}
+	}
+}
+
+// DumpQuery adds the result set of a SELECT to the next dump, as a virtual table named name
+// with the columns returned by the query. It is meant for exporting joined or aggregated
+// datasets along with the tables, and like the queries of WithQueries isn't restored. Unlike
+// those, it is only part of the next dump, and dumps after it go without it.
+func (d *Dumper) DumpQuery(name string, query string) error {
+	if name == "" {
+		return errors.New("query needs a name")
+	}
+	if _, ok := d.queries[name]; ok {
+		return fmt.Errorf("query %s is already dumped", name)
+	}
+	if _, ok := d.nextQueries[name]; ok {
+		return fmt.Errorf("query %s is already dumped", name)
+	}
+	q := strings.ToUpper(strings.TrimLeft(query, " \t\r\n("))
+	if !strings.HasPrefix(q, "SELECT") && !strings.HasPrefix(q, "WITH") {
+		return fmt.Errorf("query %s is not a SELECT", name)
+	}
+
+	if d.nextQueries == nil {
+		d.nextQueries = make(map[string]string)
+	}
+	d.nextQueries[name] = query
+	return nil
+}
+
+// queryNames returns the names of the queries to dump in a stable order.
+func (d *Dumper) queryNames() []string {
+	names := make([]string, 0, len(d.queries)+len(d.nextQueries))
+	for name := range d.queries {
+		names = append(names, name)
+	}
+	for name := range d.nextQueries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// dumpedQuery returns the query dumped under name.
+func (d *Dumper) dumpedQuery(name string) string {
+	if q, ok := d.nextQueries[name]; ok {
+		return q
+	}
+	return d.queries[name]
+}
+
+// writeQuery dumps the rows returned by a query in one go, since views like those of
+// performance_schema don't return the same rows twice and can't be read in chunks.
+func (d *Dumper) writeQuery(name string, q string) error {
+	logrus.Infof("Reading rows of query %s", name)
+
+	rows, err := d.query(q)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		return errors.New("no columns returned by query " + name)
+	}
+
+	binaryCols, err := binaryColumns(rows)
+	if err != nil {
+		return err
+	}
+
+	header := &binary.TableHeader{
+		Name:          name,
+		CreateSQL:     "-- " + q,
+		Columns:       columns,
+		BinaryColumns: binaryCols,
+		Type:          TableTypeQuery,
+	}
+	if err = d.writeTableHeader(header); err != nil {
+		return fmt.Errorf("write table header: %w", err)
+	}
+	if err = d.startRows(header); err != nil {
+		return err
+	}
+
+	d.cur.Table = name
+	d.cur.Partition = ""
+	d.cur.Chunk = 0
+	d.cur.Rows = 0
+	d.cur.Bytes = 0
+	d.cur.EstimatedRows = 0
+	d.cur.EstimatedChunks = 0
+	d.cur.TableDone = false
+	d.emitProgress()
+
+	for rows.Next() {
+		data, err := d.scanValues(rows, columns)
+		if err != nil {
+			return fmt.Errorf("scan values: %w", err)
+		}
+		if d.transform != nil {
+			data = d.transform(name, columns, data)
+		}
+
+		size := int64(binary.RowSize(data))
+		d.cur.Rows++
+		d.cur.Bytes += size
+		d.cur.TotalRows++
+		d.cur.TotalBytes += size
+		if err = d.writeRow(data); err != nil {
+			return fmt.Errorf("write values: %w", err)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	if err = d.endRows(header); err != nil {
+		return err
+	}
+
+	d.cur.Chunk = 1
+	d.cur.TableDone = true
+	d.emitProgress()
+	return nil
+}
@@ -0,0 +1,265 @@
+package mysqldump
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	binary "github.com/MouseHatGames/go-mysqldump/internal/marshal"
+	"github.com/sirupsen/logrus"
+)
+
+// Size at which a chunk is queued even though the dumper is still reading it, so tables read in a
+// single chunk are queued in pieces
+const maxQueuedChunkBytes = 4 << 20
+
+// WithWriteQueue makes the dumper hand the chunks it reads to a goroutine writing them to the output,
+// so reading carries on while the destination is busy. Once n chunks are waiting the dumper blocks
+// until the destination catches up, sending a progress event with DestinationSlow set, instead of
+// buffering without bounds.
+func WithWriteQueue(n int) Option {
+	return func(d *Dumper) {
+		d.queueChunks = n
+	}
+}
+
+// WithMemoryBudget spills a queued chunk to a temporary file once it grows over n bytes, so rows with
+// huge TEXT or BLOB values don't pile up in memory while they wait for the destination. The file is
+// removed once written. It only bounds the write queue and is ignored without WithWriteQueue, with a
+// warning. Values aren't streamed: each row is still read into memory whole by the driver, however
+// large, and chunks read ahead, see WithChunkPrefetch, or by the workers of WithParallelTables are
+// held in memory regardless of the budget.
+func WithMemoryBudget(n int64) Option {
+	return func(d *Dumper) {
+		d.memoryBudget = n
+	}
+}
+
+// queuedChunk holds the bytes of a chunk, or the file it was spilled to, or a function run once the
+// chunks queued before it were written.
+type queuedChunk struct {
+	data []byte
+	file *os.File
+	then func() error
+}
+
+// writeQueue buffers what the dumper writes and queues it chunk by chunk for a goroutine writing it out.
+type writeQueue struct {
+	w   io.Writer
+	buf bytes.Buffer
+	// Chunks over budget bytes are written to spill instead of buf
+	budget   int64
+	spill    *os.File
+	spillErr error
+
+	chunks chan queuedChunk
+	done   chan struct{}
+	// Closed when a write fails, after setting err
+	failed chan struct{}
+	err    error
+	// Called when a chunk has to wait for the destination, and again with false once it is queued
+	slow func(bool)
+}
+
+func newWriteQueue(w io.Writer, n int, budget int64, slow func(bool)) *writeQueue {
+	q := &writeQueue{
+		w:      w,
+		budget: budget,
+		chunks: make(chan queuedChunk, n),
+		done:   make(chan struct{}),
+		failed: make(chan struct{}),
+		slow:   slow,
+	}
+	go q.run()
+	return q
+}
+
+func (q *writeQueue) run() {
+	defer close(q.done)
+
+	p, _ := q.w.(*PartWriter)
+	for chunk := range q.chunks {
+		// Keep draining after a failure so the dumper doesn't block before it sees the error
+		if q.err != nil {
+			removeSpill(chunk.file)
+			continue
+		}
+
+		if chunk.then != nil {
+			if err := chunk.then(); err != nil {
+				q.err = err
+				close(q.failed)
+			}
+			continue
+		}
+
+		err := chunk.writeTo(q.w)
+		// Chunks end on record boundaries
+		if err == nil && p != nil {
+			err = p.boundary()
+		}
+		if err != nil {
+			q.err = err
+			close(q.failed)
+		}
+	}
+}
+
+func (c *queuedChunk) writeTo(w io.Writer) error {
+	if c.file == nil {
+		_, err := w.Write(c.data)
+		return err
+	}
+
+	defer removeSpill(c.file)
+	if _, err := c.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("read spilled chunk: %w", err)
+	}
+	_, err := io.Copy(w, c.file)
+	return err
+}
+
+func removeSpill(f *os.File) {
+	if f != nil {
+		f.Close()
+		os.Remove(f.Name())
+	}
+}
+
+func (q *writeQueue) Write(b []byte) (int, error) {
+	if q.spill == nil && q.budget > 0 && int64(q.buf.Len()+len(b)) > q.budget {
+		f, err := ioutil.TempFile("", "mysqldump-spill-")
+		if err == nil {
+			_, err = f.Write(q.buf.Bytes())
+		}
+		if err != nil {
+			removeSpill(f)
+			q.spillErr = fmt.Errorf("spill chunk: %w", err)
+			return 0, q.spillErr
+		}
+
+		logrus.Debugf("Chunk is over %d bytes, spilling it to %s", q.budget, f.Name())
+		q.buf.Reset()
+		q.spill = f
+	}
+	if q.spill != nil {
+		n, err := q.spill.Write(b)
+		if err != nil && q.spillErr == nil {
+			q.spillErr = fmt.Errorf("spill chunk: %w", err)
+		}
+		return n, err
+	}
+	return q.buf.Write(b)
+}
+
+// record queues the buffer once it is large or was spilled, called after every record.
+func (q *writeQueue) record() error {
+	if q.spillErr != nil {
+		return q.spillErr
+	}
+	if q.spill == nil && q.buf.Len() < maxQueuedChunkBytes {
+		return nil
+	}
+	return q.flush()
+}
+
+// flush queues what was written since the last flush, blocking while the queue is full.
+func (q *writeQueue) flush() error {
+	if q.spillErr != nil {
+		return q.spillErr
+	}
+	if q.buf.Len() == 0 && q.spill == nil {
+		return nil
+	}
+	select {
+	case <-q.failed:
+		return q.err
+	default:
+	}
+
+	var chunk queuedChunk
+	if q.spill != nil {
+		chunk.file = q.spill
+		q.spill = nil
+	} else {
+		chunk.data = make([]byte, q.buf.Len())
+		copy(chunk.data, q.buf.Bytes())
+		q.buf.Reset()
+	}
+
+	select {
+	case q.chunks <- chunk:
+		return nil
+	default:
+	}
+
+	logrus.Warnf("Destination is slow, waiting for %d queued chunks to be written", cap(q.chunks))
+	q.slow(true)
+	q.chunks <- chunk
+	q.slow(false)
+	return nil
+}
+
+// then queues the buffer and fn, run once everything queued before was written.
+func (q *writeQueue) then(fn func() error) error {
+	if err := q.flush(); err != nil {
+		return err
+	}
+	select {
+	case <-q.failed:
+		return q.err
+	case q.chunks <- queuedChunk{then: fn}:
+		return nil
+	}
+}
+
+// close queues the rest of the buffer and waits for everything to be written.
+func (q *writeQueue) close() error {
+	err := q.flush()
+	removeSpill(q.spill)
+	close(q.chunks)
+	<-q.done
+	if q.err != nil {
+		return q.err
+	}
+	return err
+}
+
+// startQueue starts writing the output through a write queue, if WithWriteQueue was given.
+func (d *Dumper) startQueue() {
+	if d.queueChunks <= 0 {
+		if d.memoryBudget > 0 {
+			d.warn(WarningOption, "", "Ignoring the memory budget, it only applies to the write queue, see WithWriteQueue")
+		}
+		return
+	}
+
+	d.queue = newWriteQueue(d.w, d.queueChunks, d.memoryBudget, func(slow bool) {
+		d.cur.DestinationSlow = slow
+		d.emitProgress()
+	})
+	d.bin = binary.NewWriter(d.encoderOutput(&countingWriter{d.queue, d.outputCounter()}))
+	d.bin.OnRecord = d.queue.record
+}
+
+// endQueue waits for the queued chunks to be written.
+func (d *Dumper) endQueue() error {
+	if d.queue == nil {
+		return nil
+	}
+
+	err := d.queue.close()
+	d.queue = nil
+	d.resetWriter()
+	return err
+}
+
+// flushQueue queues the chunk just read.
+func (d *Dumper) flushQueue() error {
+	if d.queue == nil {
+		return nil
+	}
+	return d.queue.flush()
+}
@@ -0,0 +1,165 @@
+package mysqldump
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	binary "github.com/MouseHatGames/go-mysqldump/internal/marshal"
+)
+
+// ErrReadBack is returned by a dump whose output doesn't read back as what was written, see WithReadBack.
+var ErrReadBack = errors.New("dump doesn't read back as written")
+
+// WithReadBack makes the dumper read every table back once it is written, decoding its records as
+// Restore would and counting its rows, and fail the dump with ErrReadBack if they aren't those written.
+// This catches encoding bugs before the dump is taken for a good backup. The records are kept in a
+// temporary file until they are read back, before any compression, encryption or conversion, so it
+// needs local disk of the size of the largest table. Tables read at once, see WithParallelTables,
+// are read back together once they are all written.
+func WithReadBack() Option {
+	return func(d *Dumper) {
+		d.readBack = &readBack{}
+	}
+}
+
+// readBack keeps the records of the tables being written to read them back, see WithReadBack.
+type readBack struct {
+	f *os.File
+	// Set while the records written are kept
+	capturing bool
+	// Rows written of every table since capturing started
+	rows map[string]int64
+	err  error
+}
+
+// encoderOutput returns where the encoder writes the records of the dump to, w along with the read
+// back file if tables are read back.
+func (d *Dumper) encoderOutput(w io.Writer) io.Writer {
+	if d.readBack == nil {
+		return w
+	}
+	return &readBackWriter{w, d.readBack}
+}
+
+type readBackWriter struct {
+	w  io.Writer
+	rb *readBack
+}
+
+func (w *readBackWriter) Write(p []byte) (int, error) {
+	if w.rb.capturing && w.rb.err == nil {
+		_, w.rb.err = w.rb.f.Write(p)
+	}
+	return w.w.Write(p)
+}
+
+// readingBack writes tables with write and reads them back, if WithReadBack was given.
+func (d *Dumper) readingBack(write func() error) error {
+	rb := d.readBack
+	if rb == nil {
+		return write()
+	}
+	if err := rb.start(d.interleave); err != nil {
+		return fmt.Errorf("read back: %w", err)
+	}
+	err := write()
+	rb.capturing = false
+	if err != nil {
+		return err
+	}
+	return rb.check()
+}
+
+// start keeps the records written from now on, after a file header the reader starts with.
+func (rb *readBack) start(interleaved bool) error {
+	if rb.f == nil {
+		f, err := ioutil.TempFile("", "mysqldump-readback-")
+		if err != nil {
+			return err
+		}
+		rb.f = f
+	}
+	if err := rb.f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := rb.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	rb.rows, rb.err = make(map[string]int64), nil
+	rb.capturing = true
+	return binary.NewWriter(rb.f).WriteFileHeader(&FileHeader{Interleaved: interleaved})
+}
+
+// section resets the rows counted of a table dumped again, see TableHeader.Restarted.
+func (rb *readBack) section(h *TableHeader) {
+	if _, ok := rb.rows[h.Name]; rb.capturing && (h.Restarted || !ok) {
+		rb.rows[h.Name] = 0
+	}
+}
+
+// row counts a row written of a table.
+func (rb *readBack) row(table string) {
+	if rb.capturing {
+		rb.rows[table]++
+	}
+}
+
+// check decodes the records kept and compares the rows of every table with those written.
+func (rb *readBack) check() error {
+	if rb.err != nil {
+		return fmt.Errorf("read back: %w", rb.err)
+	}
+	if _, err := rb.f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("read back: %w", err)
+	}
+
+	r := binary.NewReader(rb.f)
+	if _, err := r.ReadFileHeader(); err != nil {
+		return fmt.Errorf("%w: %s", ErrReadBack, err)
+	}
+	read := make(map[string]int64)
+	for {
+		t, err := r.ReadTableHeader()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("%w: read table header: %s", ErrReadBack, err)
+		}
+		if _, ok := rb.rows[t.Name]; !ok {
+			return fmt.Errorf("%w: table %s wasn't written", ErrReadBack, t.Name)
+		}
+		if t.Restarted {
+			read[t.Name] = 0
+		}
+
+		for {
+			if _, err = r.ReadRow(len(t.Columns)); errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return fmt.Errorf("%w: table %s after %d rows: %s", ErrReadBack, t.Name, read[t.Name], err)
+			}
+			read[t.Name]++
+		}
+	}
+
+	for name, n := range rb.rows {
+		if read[name] != n {
+			return fmt.Errorf("%w: %d rows of table %s were written, %d read back", ErrReadBack, n, name, read[name])
+		}
+	}
+	return nil
+}
+
+// close removes the file the records were kept in.
+func (rb *readBack) close() {
+	if rb == nil || rb.f == nil {
+		return
+	}
+	rb.f.Close()
+	os.Remove(rb.f.Name())
+	rb.f = nil
+}
@@ -0,0 +1,204 @@
+package mysqldump
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RetryPolicy is how a chunk query failing with a transient error is tried again, see WithRetryPolicy.
+type RetryPolicy struct {
+	// Times the query is tried again before the dump fails, 0 to fail it on the first error
+	Attempts int
+	// Wait before the first retry, doubled before every next one up to MaxDelay
+	Delay    time.Duration
+	MaxDelay time.Duration
+	// Reports whether an error is worth trying the query again after. Defaults to IsTransientError
+	Retryable func(error) bool
+}
+
+// DefaultRetryPolicy tries chunk queries 3 more times, 1, 2 and 4 seconds apart.
+var DefaultRetryPolicy = RetryPolicy{Attempts: 3, Delay: time.Second, MaxDelay: time.Minute}
+
+// WithRetryPolicy sets how chunk queries failing with transient errors are tried again, so a hiccup
+// doesn't abort a long dump. A dropped connection is re-established first, selecting the database and
+// running the session setup again. With retries, chunks are read into memory before their rows are
+// written, so a chunk whose rows fail part way is read again; tables without a chunk size can't be.
+// Defaults to DefaultRetryPolicy.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(d *Dumper) {
+		d.retry = p
+	}
+}
+
+// WithReconnect sets the attempts and first delay of the retry policy, see WithRetryPolicy. 0 attempts
+// fail the dump on the first dropped connection.
+func WithReconnect(attempts int, delay time.Duration) Option {
+	return func(d *Dumper) {
+		d.retry.Attempts = attempts
+		d.retry.Delay = delay
+	}
+}
+
+// delay returns the wait before the given retry.
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	delay := p.Delay
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+func (p RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return IsTransientError(err)
+}
+
+// Messages of the errors drivers return when the connection to the server was lost
+var connectionErrors = []string{
+	"invalid connection",
+	"bad connection",
+	"broken pipe",
+	"connection reset",
+	"connection refused",
+	"server has gone away",
+	"lost connection",
+}
+
+// Messages of the errors servers return for queries that may succeed when tried again: lock wait
+// timeouts, deadlocks and too many connections
+var transientErrors = []string{
+	"error 1205",
+	"error 1213",
+	"error 1040",
+	"lock wait timeout",
+	"deadlock",
+	"too many connections",
+}
+
+// IsTransientError reports whether a query failed with an error it may not fail with again, such as a
+// dropped connection, a lock wait timeout or a deadlock.
+func IsTransientError(err error) bool {
+	if isConnectionError(err) {
+		return true
+	}
+	if err == nil {
+		return false
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, e := range transientErrors {
+		if strings.Contains(msg, e) {
+			return true
+		}
+	}
+	return false
+}
+
+func isConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, e := range connectionErrors {
+		if strings.Contains(msg, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// queryChunk runs the query reading a chunk of table data. The connection is checked first, and if it
+// was dropped it is re-established and the chunk read again, rows from the chunks before it having
+// already been written. Queries failing with other transient errors are tried again as they are, see
+// WithRetryPolicy.
+func (d *Dumper) queryChunk(q string, args ...interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := d.retryChunk(func() *sql.Conn { return d.conn }, d.reconnect, func() error {
+		var err error
+		rows, err = d.query(q, args...)
+		return err
+	})
+	return rows, err
+}
+
+// retryChunk runs read, reading a chunk on the connection conn returns, the pool if nil, as
+// queryChunk does. reconnect re-establishes the connection once it was dropped.
+func (d *Dumper) retryChunk(conn func() *sql.Conn, reconnect func() error, read func() error) error {
+	if c := conn(); c != nil && d.retry.Attempts > 0 {
+		if err := c.PingContext(d.context()); err != nil {
+			logrus.Warnf("Connection check failed: %s", err)
+			if err = reconnect(); err != nil {
+				return err
+			}
+		}
+	}
+
+	err := read()
+	// A cancelled query can leave the connection looking dropped, it isn't retried
+	for attempt := 1; err != nil && d.retry.retryable(err) && d.context().Err() == nil && attempt <= d.retry.Attempts; attempt++ {
+		lost := isConnectionError(err) || isRollback(err)
+		if lost {
+			logrus.Warnf("Lost the session reading chunk, reconnecting (attempt %d of %d): %s", attempt, d.retry.Attempts, err)
+		} else {
+			logrus.Warnf("Reading chunk failed, trying again (attempt %d of %d): %s", attempt, d.retry.Attempts, err)
+		}
+		select {
+		case <-time.After(d.retry.delay(attempt)):
+		case <-d.context().Done():
+			return d.context().Err()
+		}
+
+		if lost {
+			if err = reconnect(); err != nil {
+				continue
+			}
+		}
+		err = read()
+	}
+	return err
+}
+
+// isRollback reports whether a query failed with an error rolling back the transaction it ran in, as
+// deadlocks do, which like a dropped connection ends the snapshot the session was reading from.
+func isRollback(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "error 1213") || strings.Contains(msg, "deadlock")
+}
+
+// reconnect re-establishes the session tables are read through, selecting the database and running
+// the setup of the pinned connection again.
+func (d *Dumper) reconnect() error {
+	if d.connSetup == nil {
+		if err := d.db.PingContext(d.context()); err != nil {
+			return err
+		}
+		return d.use(d.dbName)
+	}
+
+	if d.conn != nil {
+		d.conn.Close()
+		d.conn = nil
+	}
+	for _, q := range d.connSetup {
+		if strings.HasPrefix(q, "START TRANSACTION") || strings.HasPrefix(q, "BEGIN") {
+			d.warn(WarningSnapshot, "", "Reconnected in the middle of a snapshot, the rest of the dump is read from a new one")
+			break
+		}
+	}
+	return d.openConn()
+}
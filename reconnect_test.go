@@ -0,0 +1,172 @@
+package mysqldump
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParallelChunkRetried(t *testing.T) {
+	failures := 2
+	db, f := openFakeDB(t, func(q string, args []driver.Value) (*fakeRows, error) {
+		if failures > 0 {
+			failures--
+			return nil, errors.New("Error 1205: Lock wait timeout exceeded; try restarting transaction")
+		}
+		return stringRows([]string{"id"}, []string{"1"}, []string{"2"}), nil
+	})
+	d := NewDumper(db, ioutil.Discard, 0, WithRetryPolicy(RetryPolicy{Attempts: 2, Delay: time.Millisecond}))
+
+	tq := &tableQuery{filters: []string{""}, sel: "`id`", from: "`t`", columns: []string{"id"}}
+	pt := &parallelTable{name: "t", queries: []*tableQuery{tq}}
+	chunks := make(chan parallelChunk, 10)
+	var wg sync.WaitGroup
+	d.readParallelUnit(&parallelUnit{table: pt}, &wg, chunks, make(chan struct{}))
+	close(chunks)
+
+	var rows int
+	for c := range chunks {
+		if c.err != nil {
+			t.Fatalf("chunk failed: %s", c.err)
+		}
+		rows += len(c.rows)
+	}
+	if rows != 2 {
+		t.Errorf("read %d rows, want 2", rows)
+	}
+	if n := len(f.ran()); n != 3 {
+		t.Errorf("ran %d queries, want 3", n)
+	}
+}
+
+func TestParallelChunkNotRetried(t *testing.T) {
+	db, f := openFakeDB(t, func(q string, args []driver.Value) (*fakeRows, error) {
+		return nil, errors.New("Error 1146: Table 't' doesn't exist")
+	})
+	d := NewDumper(db, ioutil.Discard, 0, WithRetryPolicy(RetryPolicy{Attempts: 2, Delay: time.Millisecond}))
+
+	tq := &tableQuery{filters: []string{""}, sel: "`id`", from: "`t`"}
+	pt := &parallelTable{name: "t", queries: []*tableQuery{tq}}
+	chunks := make(chan parallelChunk, 10)
+	var wg sync.WaitGroup
+	d.readParallelUnit(&parallelUnit{table: pt}, &wg, chunks, make(chan struct{}))
+
+	if c := <-chunks; c.err == nil {
+		t.Error("chunk succeeded, want the error of the query")
+	}
+	if n := len(f.ran()); n != 1 {
+		t.Errorf("ran %d queries, want 1", n)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	p := RetryPolicy{Delay: time.Second, MaxDelay: 5 * time.Second}
+	for attempt, want := range []time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 5: 5 * time.Second} {
+		if attempt == 0 {
+			continue
+		}
+		if got := p.delay(attempt); got != want {
+			t.Errorf("delay(%d) = %s, want %s", attempt, got, want)
+		}
+	}
+}
+
+func TestChunkConnectionDropped(t *testing.T) {
+	db, _ := openFakeDB(t, func(q string, args []driver.Value) (*fakeRows, error) {
+		r := stringRows([]string{"id"}, []string{"1"})
+		r.err = driver.ErrBadConn
+		return r, nil
+	})
+	d := NewDumper(db, ioutil.Discard, 0, WithRetryPolicy(RetryPolicy{}))
+
+	tq := &tableQuery{filters: []string{""}, sel: "`id`", from: "`t`", columns: []string{"id"}}
+	q, args := tq.chunk("", 0)
+	rows := 0
+	_, err := d.readChunk("t", tq, q, args, func(row RowData) error {
+		rows++
+		return nil
+	})
+	if !errors.Is(err, driver.ErrBadConn) {
+		t.Errorf("read %d rows with %v, want the dropped connection", rows, err)
+	}
+}
+
+// droppingDB answers the first chunk with two rows, the first time failing with a dropped connection
+// after the first, and the next chunks with none.
+func droppingDB(t *testing.T) (*sql.DB, *fakeDB) {
+	dropped := false
+	return openFakeDB(t, func(q string, args []driver.Value) (*fakeRows, error) {
+		if len(args) == 2 && args[1] != int64(0) {
+			return &fakeRows{columns: []string{"id"}}, nil
+		}
+		if !dropped {
+			dropped = true
+			r := stringRows([]string{"id"}, []string{"-1"})
+			r.err = errors.New("invalid connection")
+			return r, nil
+		}
+		return stringRows([]string{"id"}, []string{"-1"}, []string{"2"}), nil
+	})
+}
+
+func TestChunkRowsRetried(t *testing.T) {
+	db, f := droppingDB(t)
+	rule, _ := ParseRowRule("id > 0")
+	d := NewDumper(db, ioutil.Discard, 0, WithRetryPolicy(RetryPolicy{Attempts: 1, Delay: time.Millisecond}),
+		WithRowRules(map[string][]RowRule{"t": {rule}}))
+
+	tq := &tableQuery{filters: []string{""}, sel: "`id`", from: "`t`", chunkSize: 10, order: "`id`", key: []string{"id"}, columns: []string{"id"}}
+	q, args := tq.chunk("", 0)
+	var got []interface{}
+	_, err := d.readChunk("t", tq, q, args, func(row RowData) error {
+		got = append(got, rowValues(row)...)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []interface{}{"-1", "2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("read %q, want %q", got, want)
+	}
+	if n := len(f.ran()); n != 2 {
+		t.Errorf("ran %d queries, want 2", n)
+	}
+	if !reflect.DeepEqual(tq.after, []interface{}{"2"}) {
+		t.Errorf("next chunk after %q, want the last row", tq.after)
+	}
+	if v := d.RuleViolations(); len(v) != 1 || v[0].Rows != 1 {
+		t.Errorf("violations %+v, want the row read again counted once", v)
+	}
+}
+
+func TestParallelChunkRowsRetried(t *testing.T) {
+	db, f := droppingDB(t)
+	d := NewDumper(db, ioutil.Discard, 0, WithRetryPolicy(RetryPolicy{Attempts: 1, Delay: time.Millisecond}))
+
+	tq := &tableQuery{filters: []string{""}, sel: "`id`", from: "`t`", chunkSize: 10, order: "`id`", columns: []string{"id"}}
+	pt := &parallelTable{name: "t", queries: []*tableQuery{tq}}
+	chunks := make(chan parallelChunk, 10)
+	var wg sync.WaitGroup
+	d.readParallelUnit(&parallelUnit{table: pt}, &wg, chunks, make(chan struct{}))
+	close(chunks)
+
+	var rows int
+	for c := range chunks {
+		if c.err != nil {
+			t.Fatalf("chunk failed: %s", c.err)
+		}
+		rows += len(c.rows)
+	}
+	if rows != 2 {
+		t.Errorf("read %d rows, want 2", rows)
+	}
+	// The last query reads the empty chunk after the rows
+	if n := len(f.ran()); n != 3 {
+		t.Errorf("ran %d queries, want the chunk read again", n)
+	}
+}
@@ -0,0 +1,287 @@
+package mysqldump
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ddlColumn is a column of a table as found in INFORMATION_SCHEMA.COLUMNS.
+type ddlColumn struct {
+	name       string
+	typ        string
+	nullable   bool
+	def        sql.NullString
+	extra      string
+	generation string
+	collation  sql.NullString
+	comment    string
+}
+
+// ddlIndexPart is a key part of an index as found in INFORMATION_SCHEMA.STATISTICS.
+type ddlIndexPart struct {
+	index     string
+	column    sql.NullString
+	nonUnique int
+	subPart   sql.NullInt64
+	indexType string
+}
+
+// reconstructTableSQL rebuilds the CREATE TABLE statement of a table from INFORMATION_SCHEMA, for accounts
+// that can read its rows but not run SHOW CREATE TABLE. The statement has the columns, indexes and foreign
+// keys of the table but may differ from the original in details like functional keys, check constraints,
+// partitioning or table options, see TableHeader.ReconstructedDDL.
+func (d *Dumper) reconstructTableSQL(name string, schema string, engine string) (string, error) {
+	if d.isPQ() || d.legacyMetadata() {
+		return "", fmt.Errorf("metadata tables not supported")
+	}
+
+	s, _ := d.server()
+	mariadb := s.flavor == flavorMariaDB
+	generated := s.atLeast(5, 7, 0) && (!mariadb || s.atLeast(10, 2, 0))
+
+	var defs []string
+	cols, err := d.ddlColumns(name, schema, generated)
+	if err != nil {
+		return "", fmt.Errorf("read columns: %w", err)
+	}
+	if len(cols) == 0 {
+		return "", fmt.Errorf("no columns of %s can be read", name)
+	}
+	for _, c := range cols {
+		defs = append(defs, c.definition(mariadb))
+	}
+
+	indexes, err := d.ddlIndexes(name, schema)
+	if err != nil {
+		return "", fmt.Errorf("read indexes: %w", err)
+	}
+	defs = append(defs, indexes...)
+
+	fks, err := d.ddlForeignKeys(name, schema)
+	if err != nil {
+		return "", fmt.Errorf("read foreign keys: %w", err)
+	}
+	defs = append(defs, fks...)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "CREATE TABLE %s (\n  %s\n)", quoteName(name), strings.Join(defs, ",\n  "))
+	if engine != "" {
+		fmt.Fprintf(&b, " ENGINE=%s", engine)
+	}
+	var collation sql.NullString
+	var comment string
+	if err = d.db.QueryRowContext(d.context(), stmtTableOptions, name, schema).Scan(&collation, &comment); err != nil {
+		return "", fmt.Errorf("read table options: %w", err)
+	}
+	if collation.Valid && collation.String != "" {
+		fmt.Fprintf(&b, " DEFAULT COLLATE=%s", collation.String)
+	}
+	if comment != "" {
+		b.WriteString(" COMMENT=")
+		writeSQLString(&b, comment)
+	}
+	return b.String(), nil
+}
+
+func (d *Dumper) ddlColumns(name string, schema string, generated bool) ([]ddlColumn, error) {
+	rows, err := d.db.QueryContext(d.context(), stmtDDLColumns(generated), name, schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cols []ddlColumn
+	for rows.Next() {
+		var c ddlColumn
+		var nullable string
+		if err = rows.Scan(&c.name, &c.typ, &nullable, &c.def, &c.extra, &c.generation, &c.collation, &c.comment); err != nil {
+			return nil, err
+		}
+		c.nullable = strings.EqualFold(nullable, "YES")
+		cols = append(cols, c)
+	}
+	return cols, rows.Err()
+}
+
+// definition returns the definition of a column in a CREATE TABLE statement. MariaDB lists defaults as
+// SQL expressions, MySQL as the values themselves, flagging expressions with DEFAULT_GENERATED.
+func (c ddlColumn) definition(mariadb bool) string {
+	var b strings.Builder
+	b.WriteString(quoteName(c.name))
+	b.WriteString(" ")
+	b.WriteString(c.typ)
+
+	extra := strings.TrimSpace(c.extra)
+	expression := false
+	if strings.HasPrefix(strings.ToUpper(extra), "DEFAULT_GENERATED") {
+		expression, extra = true, strings.TrimSpace(extra[len("DEFAULT_GENERATED"):])
+	}
+	if c.collation.Valid && c.collation.String != "" {
+		b.WriteString(" COLLATE ")
+		b.WriteString(c.collation.String)
+	}
+
+	// Generated columns have no default
+	if up := strings.ToUpper(extra); c.generation != "" && strings.Contains(up, " GENERATED") {
+		storage := "VIRTUAL"
+		if strings.Contains(up, "STORED GENERATED") || strings.Contains(up, "PERSISTENT GENERATED") {
+			storage = "STORED"
+		}
+		fmt.Fprintf(&b, " GENERATED ALWAYS AS (%s) %s", c.generation, storage)
+		if !c.nullable {
+			b.WriteString(" NOT NULL")
+		}
+		if strings.Contains(up, "INVISIBLE") {
+			b.WriteString(" INVISIBLE")
+		}
+		return b.String()
+	}
+
+	if !c.nullable {
+		b.WriteString(" NOT NULL")
+	}
+	switch {
+	case mariadb && c.def.Valid && strings.EqualFold(c.def.String, "NULL"):
+		if c.nullable {
+			b.WriteString(" DEFAULT NULL")
+		}
+	case mariadb && c.def.Valid:
+		b.WriteString(" DEFAULT ")
+		b.WriteString(c.def.String)
+	case !c.def.Valid:
+		if c.nullable {
+			b.WriteString(" DEFAULT NULL")
+		}
+	case expression && strings.HasPrefix(strings.ToUpper(c.def.String), "CURRENT_TIMESTAMP"):
+		b.WriteString(" DEFAULT ")
+		b.WriteString(c.def.String)
+	case expression:
+		fmt.Fprintf(&b, " DEFAULT (%s)", c.def.String)
+	case strings.HasPrefix(strings.ToLower(c.typ), "bit"):
+		b.WriteString(" DEFAULT ")
+		b.WriteString(c.def.String)
+	default:
+		b.WriteString(" DEFAULT ")
+		writeSQLString(&b, c.def.String)
+	}
+	if extra != "" {
+		b.WriteString(" ")
+		b.WriteString(extra)
+	}
+	if c.comment != "" {
+		b.WriteString(" COMMENT ")
+		writeSQLString(&b, c.comment)
+	}
+	return b.String()
+}
+
+// ddlIndexes returns the index definitions of a table, the primary key first and the others by name.
+// Indexes with functional key parts are left out.
+func (d *Dumper) ddlIndexes(name string, schema string) ([]string, error) {
+	rows, err := d.db.QueryContext(d.context(), stmtDDLIndexes, name, schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	parts := map[string][]ddlIndexPart{}
+	for rows.Next() {
+		var p ddlIndexPart
+		if err = rows.Scan(&p.index, &p.column, &p.nonUnique, &p.subPart, &p.indexType); err != nil {
+			return nil, err
+		}
+		if parts[p.index] == nil {
+			names = append(names, p.index)
+		}
+		parts[p.index] = append(parts[p.index], p)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(names, func(a, b int) bool { return names[a] == "PRIMARY" && names[b] != "PRIMARY" })
+
+	var defs []string
+next:
+	for _, n := range names {
+		idx := parts[n]
+		cols := make([]string, 0, len(idx))
+		for _, p := range idx {
+			if !p.column.Valid {
+				continue next
+			}
+			c := quoteName(p.column.String)
+			if p.subPart.Valid {
+				c += fmt.Sprintf("(%d)", p.subPart.Int64)
+			}
+			cols = append(cols, c)
+		}
+
+		kind := "KEY " + quoteName(n)
+		switch {
+		case n == "PRIMARY":
+			kind = "PRIMARY KEY"
+		case strings.EqualFold(idx[0].indexType, "FULLTEXT"), strings.EqualFold(idx[0].indexType, "SPATIAL"):
+			kind = strings.ToUpper(idx[0].indexType) + " " + kind
+		case idx[0].nonUnique == 0:
+			kind = "UNIQUE " + kind
+		}
+		defs = append(defs, kind+" ("+strings.Join(cols, ",")+")")
+	}
+	return defs, nil
+}
+
+// ddlForeignKeys returns the foreign key definitions of a table, by name.
+func (d *Dumper) ddlForeignKeys(name string, schema string) ([]string, error) {
+	rows, err := d.db.QueryContext(d.context(), stmtDDLForeignKeys, schema, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type fk struct {
+		name, refSchema, refTable, onUpdate, onDelete string
+		cols, refCols                                 []string
+	}
+	var fks []*fk
+	for rows.Next() {
+		var n, col, refSchema, refTable, refCol, onUpdate, onDelete string
+		if err = rows.Scan(&n, &col, &refSchema, &refTable, &refCol, &onUpdate, &onDelete); err != nil {
+			return nil, err
+		}
+		if len(fks) == 0 || fks[len(fks)-1].name != n {
+			fks = append(fks, &fk{name: n, refSchema: refSchema, refTable: refTable, onUpdate: onUpdate, onDelete: onDelete})
+		}
+		f := fks[len(fks)-1]
+		f.cols, f.refCols = append(f.cols, quoteName(col)), append(f.refCols, quoteName(refCol))
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	defs := make([]string, 0, len(fks))
+	for _, f := range fks {
+		ref := quoteName(f.refTable)
+		if f.refSchema != schema {
+			ref = quoteName(f.refSchema) + "." + ref
+		}
+		def := fmt.Sprintf("CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s)", quoteName(f.name), strings.Join(f.cols, ", "), ref, strings.Join(f.refCols, ", "))
+		if f.onDelete != "" && f.onDelete != "RESTRICT" {
+			def += " ON DELETE " + f.onDelete
+		}
+		if f.onUpdate != "" && f.onUpdate != "RESTRICT" {
+			def += " ON UPDATE " + f.onUpdate
+		}
+		defs = append(defs, def)
+	}
+	return defs, nil
+}
+
+// writeSQLString writes a quoted SQL string literal.
+func writeSQLString(b *strings.Builder, s string) {
+	b.WriteString("'")
+	writeEscapedString(b, s)
+	b.WriteString("'")
+}
@@ -0,0 +1,200 @@
+package mysqldump
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// JobState is how far a dump registered with a Registry got.
+type JobState int
+
+const (
+	// JobPending is a dump registered but not started yet.
+	JobPending JobState = iota
+	// JobRunning is a dump in progress.
+	JobRunning
+	// JobDone is a dump that completed.
+	JobDone
+	// JobInterrupted is a dump stopped by Interrupt, with a partial footer.
+	JobInterrupted
+	// JobFailed is a dump that failed.
+	JobFailed
+)
+
+func (s JobState) String() string {
+	switch s {
+	case JobRunning:
+		return "running"
+	case JobDone:
+		return "done"
+	case JobInterrupted:
+		return "interrupted"
+	case JobFailed:
+		return "failed"
+	}
+	return "pending"
+}
+
+// JobStatus describes a dump registered with a Registry.
+type JobStatus struct {
+	Name     string
+	Database string
+	State    JobState
+	// Start and end of the last dump of the job, zero until it starts and ends
+	Started time.Time
+	Ended   time.Time
+	// Last progress reported by the dump
+	Progress  ProgressEvent
+	Bandwidth Bandwidth
+	// Error the dump failed with
+	Err error
+}
+
+// Registry keeps the status of named dumps a process runs, for admin endpoints listing the dumps
+// in flight and those that ended recently. It can be used from any goroutine.
+type Registry struct {
+	mu     sync.Mutex
+	jobs   map[string]*JobStatus
+	recent int
+}
+
+// DefaultRegistry is the registry of the process, keeping the last 100 dumps that ended.
+var DefaultRegistry = NewRegistry(100)
+
+// NewRegistry returns an empty registry keeping the status of up to recent dumps that ended, along
+// with all the dumps in flight.
+func NewRegistry(recent int) *Registry {
+	return &Registry{jobs: make(map[string]*JobStatus), recent: recent}
+}
+
+// WithRegistry registers every dump of the Dumper with r under name, see Registry. A dump fails to
+// start while another one of the same name is running.
+func WithRegistry(r *Registry, name string) Option {
+	return func(d *Dumper) {
+		d.registry, d.jobName = r, name
+	}
+}
+
+// Register adds a pending job, replacing one of the same name that ended.
+func (r *Registry) Register(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if j := r.jobs[name]; j != nil && j.State == JobRunning {
+		return fmt.Errorf("dump %s is running already", name)
+	}
+	r.jobs[name] = &JobStatus{Name: name}
+	return nil
+}
+
+// Unregister removes a job, running or not.
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.jobs, name)
+}
+
+// Status returns the status of a job.
+func (r *Registry) Status(name string) (JobStatus, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	j := r.jobs[name]
+	if j == nil {
+		return JobStatus{}, false
+	}
+	return *j, true
+}
+
+// Jobs returns the status of every job, those pending or running first, then those that ended, most
+// recent first.
+func (r *Registry) Jobs() []JobStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	jobs := make([]JobStatus, 0, len(r.jobs))
+	for _, j := range r.jobs {
+		jobs = append(jobs, *j)
+	}
+	sort.Slice(jobs, func(a, b int) bool {
+		if ea, eb := jobs[a].State > JobRunning, jobs[b].State > JobRunning; ea != eb {
+			return eb
+		}
+		if !jobs[a].Ended.Equal(jobs[b].Ended) {
+			return jobs[a].Ended.After(jobs[b].Ended)
+		}
+		if !jobs[a].Started.Equal(jobs[b].Started) {
+			return jobs[a].Started.After(jobs[b].Started)
+		}
+		return jobs[a].Name < jobs[b].Name
+	})
+	return jobs
+}
+
+// start marks a job as running, registering it if needed.
+func (r *Registry) start(name string, db string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	j := r.jobs[name]
+	if j != nil && j.State == JobRunning {
+		return fmt.Errorf("dump %s is running already", name)
+	}
+	r.jobs[name] = &JobStatus{Name: name, Database: db, State: JobRunning, Started: time.Now()}
+	return nil
+}
+
+// update records the progress of a running job.
+func (r *Registry) update(name string, e ProgressEvent, b Bandwidth) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if j := r.jobs[name]; j != nil && j.State == JobRunning {
+		j.Progress, j.Bandwidth = e, b
+	}
+}
+
+// end records how a job ended, dropping the jobs that ended longest ago beyond the ones kept.
+func (r *Registry) end(name string, e ProgressEvent, b Bandwidth, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	j := r.jobs[name]
+	if j == nil {
+		return
+	}
+	j.Progress, j.Bandwidth, j.Ended = e, b, time.Now()
+	switch {
+	case err == nil:
+		j.State = JobDone
+	case errors.Is(err, ErrInterrupted):
+		j.State = JobInterrupted
+	default:
+		j.State, j.Err = JobFailed, err
+	}
+
+	var ended []*JobStatus
+	for _, j := range r.jobs {
+		if j.State > JobRunning {
+			ended = append(ended, j)
+		}
+	}
+	if len(ended) <= r.recent {
+		return
+	}
+	sort.Slice(ended, func(a, b int) bool { return ended[a].Ended.After(ended[b].Ended) })
+	for _, j := range ended[r.recent:] {
+		delete(r.jobs, j.Name)
+	}
+}
+
+// startJob marks the job of the Dumper as running, see WithRegistry, returning a function
+// recording how the dump ended.
+func (d *Dumper) startJob(db string) (func(err error), error) {
+	if d.registry == nil {
+		return func(error) {}, nil
+	}
+	if err := d.registry.start(d.jobName, db); err != nil {
+		return nil, err
+	}
+	return func(err error) {
+		d.registry.end(d.jobName, d.cur, d.Bandwidth(), err)
+	}, nil
+}
@@ -0,0 +1,100 @@
+package mysqldump
+
+import (
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRegistryStates(t *testing.T) {
+	r := NewRegistry(10)
+	if err := r.Register("nightly"); err != nil {
+		t.Fatal(err)
+	}
+	if s, ok := r.Status("nightly"); !ok || s.State != JobPending {
+		t.Errorf("registered as %+v, want pending", s)
+	}
+
+	if err := r.start("nightly", "app"); err != nil {
+		t.Fatal(err)
+	}
+	// Running jobs can't be started or registered again
+	if err := r.start("nightly", "app"); err == nil {
+		t.Error("started a running job again")
+	}
+	if err := r.Register("nightly"); err == nil {
+		t.Error("registered a running job again")
+	}
+	r.update("nightly", ProgressEvent{Table: "users", Rows: 10}, Bandwidth{})
+	if s, _ := r.Status("nightly"); s.State != JobRunning || s.Database != "app" || s.Started.IsZero() || s.Progress.Rows != 10 {
+		t.Errorf("running as %+v", s)
+	}
+
+	failed := errors.New("connection lost")
+	for _, tt := range []struct {
+		err   error
+		state JobState
+	}{
+		{nil, JobDone},
+		{ErrInterrupted, JobInterrupted},
+		{failed, JobFailed},
+	} {
+		r.start("nightly", "app")
+		r.end("nightly", ProgressEvent{Rows: 20}, Bandwidth{}, tt.err)
+		s, _ := r.Status("nightly")
+		if s.State != tt.state || s.Ended.IsZero() || s.Progress.Rows != 20 {
+			t.Errorf("ended with %v as %+v, want %s", tt.err, s, tt.state)
+		}
+		if tt.state == JobFailed && s.Err != failed {
+			t.Errorf("failed with %v, want %v", s.Err, failed)
+		}
+	}
+
+	// Ended jobs don't get progress anymore
+	r.update("nightly", ProgressEvent{Rows: 30}, Bandwidth{})
+	if s, _ := r.Status("nightly"); s.Progress.Rows != 20 {
+		t.Errorf("updated an ended job to %d rows", s.Progress.Rows)
+	}
+	r.Unregister("nightly")
+	if _, ok := r.Status("nightly"); ok {
+		t.Error("unregistered job is still there")
+	}
+}
+
+func TestRegistryRecent(t *testing.T) {
+	r := NewRegistry(2)
+	for _, name := range []string{"a", "b", "c"} {
+		r.start(name, "app")
+		r.end(name, ProgressEvent{}, Bandwidth{}, nil)
+		time.Sleep(time.Millisecond)
+	}
+	r.start("d", "app")
+	r.Register("e")
+
+	// Running and pending first, then the jobs that ended most recent first, a dropped
+	var names []string
+	for _, j := range r.Jobs() {
+		names = append(names, j.Name)
+	}
+	if want := []string{"d", "e", "c", "b"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("jobs %q, want %q", names, want)
+	}
+}
+
+func TestDumperJob(t *testing.T) {
+	r := NewRegistry(10)
+	d := NewDumper(nil, ioutil.Discard, 0, WithRegistry(r, "nightly"))
+	end, err := d.startJob("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewDumper(nil, ioutil.Discard, 0, WithRegistry(r, "nightly")).startJob("app"); err == nil {
+		t.Error("started a second dump of the same name")
+	}
+	end(nil)
+	if s, _ := r.Status("nightly"); s.State != JobDone || s.Database != "app" {
+		t.Errorf("ended as %+v, want done", s)
+	}
+}
@@ -0,0 +1,103 @@
+package mysqldump
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+const defaultTableRestarts = 2
+
+// errRestartedExisting fails restoring a table dumped again into a table that isn't created again, where
+// the rows restored of it before would stay along with those that replace them.
+var errRestartedExisting = errors.New("the table was dumped again after it was altered, its earlier rows can only be replaced by creating it again, not with SkipCreate or CreateIfNotExists")
+
+// WithTableRestarts sets how many times a table altered while its rows are read, as by online DDL, is
+// dumped again from the start under a fresh snapshot before the dump fails. The rows written of it
+// before are left in the output and replaced by those that follow, see TableHeader.Restarted. 0 fails
+// the dump on the first change. Defaults to 2. Tables read at once, see WithParallelTables, aren't
+// read from a snapshot and aren't dumped again.
+func WithTableRestarts(n int) Option {
+	return func(d *Dumper) {
+		d.tableRestarts = n
+	}
+}
+
+// Messages of the errors servers return when a table was altered after the snapshot it is read from started
+var schemaChangeErrors = []string{
+	"error 1412",
+	"table definition has changed",
+}
+
+// isSchemaChange reports whether reading a table failed because it was altered meanwhile.
+func isSchemaChange(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrColumnMismatch) {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, e := range schemaChangeErrors {
+		if strings.Contains(msg, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeTableRestarting writes a table, dumping it again from the start when it was altered while its
+// rows were read.
+func (d *Dumper) writeTableRestarting(name string, schema string, wg *sync.WaitGroup) error {
+	defer func() { d.restarting = "" }()
+
+	for attempt := 1; ; attempt++ {
+		err := d.writeTable(name, schema, wg)
+		if !isSchemaChange(err) || attempt > d.tableRestarts || d.isInterrupted() {
+			return err
+		}
+
+		d.warn(WarningSchemaChange, name, "Table %s changed while it was dumped, dumping it again (attempt %d of %d): %s",
+			name, attempt, d.tableRestarts, err)
+		wg.Wait()
+		d.forgetTable(name)
+		// The rows read of the table so far came from a snapshot the table no longer matches
+		if err = d.reconnect(); err != nil {
+			return err
+		}
+		d.restarting = name
+	}
+}
+
+// forgetTable drops what was counted of the rows of a table, before it is dumped again from the start.
+func (d *Dumper) forgetTable(name string) {
+	d.cur.TotalRows -= d.cur.Rows
+	d.cur.TotalBytes -= d.cur.Bytes
+	d.cur.Rows, d.cur.Bytes = 0, 0
+	delete(d.chunkKeys, name)
+	if d.outfile != nil {
+		// The table was altered, its columns have to be read again
+		for k := range d.outfile.columns {
+			if k.table == name {
+				delete(d.outfile.columns, k)
+			}
+		}
+	}
+
+	d.rulesMu.Lock()
+	defer d.rulesMu.Unlock()
+	delete(d.tableRules, name)
+	for k := range d.guardViolations {
+		if strings.HasPrefix(k, name+"\x00") {
+			delete(d.guardViolations, k)
+		}
+	}
+	kept := d.violations[:0]
+	for _, v := range d.violations {
+		if v.Table != name {
+			kept = append(kept, v)
+		}
+	}
+	d.violations = kept
+}
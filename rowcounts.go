@@ -0,0 +1,105 @@
+package mysqldump
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RowCountRange is the number of rows a table is expected to be dumped with, see WithRowCounts.
+type RowCountRange struct {
+	Min int64
+	// No upper bound if negative
+	Max int64
+}
+
+// ParseRowCountRange parses "non_empty", "n", "min..", "..max" or "min..max".
+func ParseRowCountRange(s string) (RowCountRange, error) {
+	s = strings.TrimSpace(s)
+	if strings.EqualFold(s, "non_empty") {
+		return RowCountRange{Min: 1, Max: -1}, nil
+	}
+
+	from, to := s, s
+	if i := strings.Index(s, ".."); i >= 0 {
+		from, to = s[:i], s[i+2:]
+	}
+	r := RowCountRange{Max: -1}
+	var err error
+	if from != "" {
+		if r.Min, err = strconv.ParseInt(from, 10, 64); err != nil || r.Min < 0 {
+			return RowCountRange{}, fmt.Errorf("invalid row count range: %s", s)
+		}
+	}
+	if to != "" {
+		if r.Max, err = strconv.ParseInt(to, 10, 64); err != nil || r.Max < r.Min {
+			return RowCountRange{}, fmt.Errorf("invalid row count range: %s", s)
+		}
+	}
+	if from == "" && to == "" {
+		return RowCountRange{}, fmt.Errorf("invalid row count range: %s", s)
+	}
+	return r, nil
+}
+
+func (r RowCountRange) String() string {
+	switch {
+	case r.Min == 1 && r.Max < 0:
+		return "non_empty"
+	case r.Max < 0:
+		return fmt.Sprintf("%d..", r.Min)
+	case r.Min == r.Max:
+		return strconv.FormatInt(r.Min, 10)
+	}
+	return fmt.Sprintf("%d..%d", r.Min, r.Max)
+}
+
+// contains reports whether n rows are in the range.
+func (r RowCountRange) contains(n int64) bool {
+	return n >= r.Min && (r.Max < 0 || n <= r.Max)
+}
+
+// WithRowCounts checks the number of rows every table with an expected range is dumped with once the
+// dump is done, so that a table accidentally emptied or truncated at the source doesn't silently end up
+// in the backup. Tables out of their range are warned about and returned by RowCountViolations, and the
+// dump is Suspect. Tables that weren't dumped, or whose rows were left out on purpose, aren't checked.
+func WithRowCounts(expected map[string]RowCountRange) Option {
+	return func(d *Dumper) {
+		d.rowCounts = expected
+	}
+}
+
+// RowCountViolation is a table dumped with a number of rows out of its expected range.
+type RowCountViolation struct {
+	Table    string
+	Expected RowCountRange
+	Rows     int64
+}
+
+// RowCountViolations returns the tables of the last dump out of their expected row count range, in
+// dump order, see WithRowCounts.
+func (d *Dumper) RowCountViolations() []RowCountViolation {
+	return append([]RowCountViolation(nil), d.rowCountViolations...)
+}
+
+// Suspect reports whether tables of the last dump were out of their expected row count range.
+func (d *Dumper) Suspect() bool {
+	return len(d.rowCountViolations) > 0
+}
+
+// checkRowCounts checks the tables of a dump that is done against their expected row counts.
+func (d *Dumper) checkRowCounts() {
+	d.rowCountViolations = nil
+	if len(d.rowCounts) == 0 || d.info == nil {
+		return
+	}
+
+	for _, t := range d.info.Tables {
+		r, ok := d.rowCounts[t.Header.Name]
+		if !ok || t.NoData != nil || r.contains(t.Rows) {
+			continue
+		}
+		d.rowCountViolations = append(d.rowCountViolations, RowCountViolation{Table: t.Header.Name, Expected: r, Rows: t.Rows})
+		d.warn(WarningRowCount, t.Header.Name, "Table %s was dumped with %d rows, expected %s", t.Header.Name, t.Rows, r)
+	}
+}
@@ -0,0 +1,208 @@
+package mysqldump
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RowRule is a data quality check on a column, run on every dumped row before it is transformed.
+type RowRule struct {
+	Column string
+	// Describes the rule in reports, such as "amount >= 0"
+	Name string
+	// Reports whether the value passes, nil for NULL
+	Check func(value *string) bool
+}
+
+// ParseRowRule parses a rule such as "amount >= 0", "email matches ^[^@]+@[^@]+$", "status in open|closed"
+// or "name not null". Comparisons take =, !=, <, <=, > and >= with a number, and like all rules but
+// "not null" they pass NULL values.
+func ParseRowRule(expr string) (RowRule, error) {
+	// The argument is the rest of the rule, as regular expressions can hold spaces
+	parts := strings.SplitN(strings.TrimSpace(expr), " ", 3)
+	if len(parts) < 2 {
+		return RowRule{}, fmt.Errorf("invalid rule: %s", expr)
+	}
+	rule := RowRule{Column: parts[0], Name: strings.TrimSpace(expr)}
+	op, arg := strings.ToLower(parts[1]), ""
+	if len(parts) == 3 {
+		arg = strings.TrimSpace(parts[2])
+	}
+
+	switch op {
+	case "not":
+		if strings.ToLower(arg) != "null" {
+			return RowRule{}, fmt.Errorf("invalid rule: %s", expr)
+		}
+		rule.Check = func(v *string) bool { return v != nil }
+	case "matches":
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			return RowRule{}, fmt.Errorf("invalid rule %s: %w", expr, err)
+		}
+		rule.Check = func(v *string) bool { return v == nil || re.MatchString(*v) }
+	case "in":
+		allowed := make(map[string]bool)
+		for _, s := range strings.Split(arg, "|") {
+			allowed[s] = true
+		}
+		rule.Check = func(v *string) bool { return v == nil || allowed[*v] }
+	case "=", "!=", "<", "<=", ">", ">=":
+		n, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return RowRule{}, fmt.Errorf("invalid rule %s: %s is not a number", expr, arg)
+		}
+		rule.Check = func(v *string) bool {
+			if v == nil {
+				return true
+			}
+			f, err := strconv.ParseFloat(*v, 64)
+			return err == nil && compare(f, op, n)
+		}
+	default:
+		return RowRule{}, fmt.Errorf("invalid rule: %s", expr)
+	}
+
+	return rule, nil
+}
+
+func compare(a float64, op string, b float64) bool {
+	switch op {
+	case "=":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	}
+	return a >= b
+}
+
+// WithRowRules checks the rows of each table against its rules while dumping them, counting the rows
+// breaking each rule. Rows are dumped either way, the counts are logged once each table is done
+// and returned by RuleViolations.
+func WithRowRules(rules map[string][]RowRule) Option {
+	return func(d *Dumper) {
+		d.rules = rules
+	}
+}
+
+// RuleViolation counts the dumped rows of a table breaking a rule.
+type RuleViolation struct {
+	Table string
+	Rule  string
+	Rows  int64
+}
+
+// RuleViolations returns the rules and value guards broken by the rows dumped so far, in the order they were first broken.
+func (d *Dumper) RuleViolations() []RuleViolation {
+	d.rulesMu.Lock()
+	defer d.rulesMu.Unlock()
+
+	var res []RuleViolation
+	for _, v := range d.violations {
+		if v.Rows > 0 {
+			res = append(res, *v)
+		}
+	}
+	return res
+}
+
+// tableRules is the rules of a table with the index of the column each one checks, by the columns of
+// the rows read. The column groups of a table each read other columns.
+type tableRules struct {
+	index      map[string][]int
+	found      []bool
+	violations []*RuleViolation
+}
+
+// checkRules counts the rules a row of a table breaks.
+func (d *Dumper) checkRules(table string, columns []string, row []*string) {
+	rules, ok := d.rules[table]
+	if !ok {
+		return
+	}
+	// Tables are checked at once with WithParallelTables
+	d.rulesMu.Lock()
+	defer d.rulesMu.Unlock()
+
+	tr := d.tableRules[table]
+	if tr == nil {
+		tr = d.prepareRules(table, rules)
+	}
+	set := strings.Join(columns, "\x00")
+	index, ok := tr.index[set]
+	if !ok {
+		index = tr.columnIndex(rules, columns)
+		tr.index[set] = index
+	}
+	for i, r := range rules {
+		if index[i] < 0 {
+			continue
+		}
+		if !r.Check(row[index[i]]) {
+			tr.violations[i].Rows++
+		}
+	}
+}
+
+func (d *Dumper) prepareRules(table string, rules []RowRule) *tableRules {
+	if d.tableRules == nil {
+		d.tableRules = make(map[string]*tableRules)
+	}
+
+	tr := &tableRules{index: make(map[string][]int), found: make([]bool, len(rules))}
+	for _, r := range rules {
+		v := &RuleViolation{Table: table, Rule: r.Name}
+		tr.violations = append(tr.violations, v)
+		d.violations = append(d.violations, v)
+	}
+
+	d.tableRules[table] = tr
+	return tr
+}
+
+// columnIndex returns the index of the column each rule checks in rows of columns, -1 if it isn't
+// there. The key columns every group of a table holds are only checked in the rows read first.
+func (tr *tableRules) columnIndex(rules []RowRule, columns []string) []int {
+	index := make([]int, len(rules))
+	for i, r := range rules {
+		index[i] = indexFold(columns, r.Column)
+		if index[i] >= 0 && tr.found[i] {
+			index[i] = -1
+		} else if index[i] >= 0 {
+			tr.found[i] = true
+		}
+	}
+	return index
+}
+
+// reportRules logs the rules and guards the rows of a table broke, and the masked columns it doesn't have.
+// The values are left out, as rules run before masking.
+func (d *Dumper) reportRules(table string) {
+	for _, check := range d.maskChecks {
+		check(table)
+	}
+
+	d.rulesMu.Lock()
+	defer d.rulesMu.Unlock()
+
+	if tr := d.tableRules[table]; tr != nil {
+		for i, r := range d.rules[table] {
+			if !tr.found[i] {
+				d.warn(WarningRule, table, "Rule %s on table %s checks a column that wasn't dumped, skipping it", r.Name, table)
+			}
+		}
+	}
+	for _, v := range d.violations {
+		if v.Table == table && v.Rows > 0 {
+			d.warn(WarningRule, table, "%d rows of %s break rule %s", v.Rows, table, v.Rule)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package mysqldump
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func mustRule(t *testing.T, expr string) RowRule {
+	r, err := ParseRowRule(expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestParseRowRule(t *testing.T) {
+	tests := []struct {
+		expr  string
+		value *string
+		pass  bool
+	}{
+		{"amount >= 0", strp("0"), true},
+		{"amount >= 0", strp("-1"), false},
+		{"amount >= 0", strp("abc"), false},
+		{"amount >= 0", nil, true},
+		{"email matches ^[^@ ]+@[^@ ]+$", strp("a@b"), true},
+		{"email matches ^[^@ ]+@[^@ ]+$", strp("a b"), false},
+		{"status in open|closed", strp("closed"), true},
+		{"status in open|closed", strp("lost"), false},
+		{"name not null", nil, false},
+		{"name not null", strp(""), true},
+	}
+	for _, tt := range tests {
+		if got := mustRule(t, tt.expr).Check(tt.value); got != tt.pass {
+			t.Errorf("%s on %v = %v, want %v", tt.expr, tt.value, got, tt.pass)
+		}
+	}
+	for _, expr := range []string{"amount", "amount >= x", "name not empty", "email matches (", "amount ~ 1"} {
+		if _, err := ParseRowRule(expr); err == nil {
+			t.Errorf("parsed %q", expr)
+		}
+	}
+}
+
+func strp(s string) *string {
+	return &s
+}
+
+func TestRulesColumnGroups(t *testing.T) {
+	d := NewDumper(nil, ioutil.Discard, 0, WithRowRules(map[string][]RowRule{
+		"t": {mustRule(t, "id > 0"), mustRule(t, "c >= 0"), mustRule(t, "d >= 0"), mustRule(t, "e not null")},
+	}))
+
+	// Two rows read by column group, the second group narrower than the first
+	for _, group := range []struct {
+		columns []string
+		rows    []RowData
+	}{
+		{[]string{"id", "a", "b", "c"}, []RowData{stringRow("-1", "x", "x", "-5"), stringRow("2", "x", "x", "5")}},
+		{[]string{"id", "d"}, []RowData{stringRow("-1", "-3"), stringRow("2", "-3")}},
+	} {
+		for _, row := range group.rows {
+			d.checkRules("t", group.columns, row)
+		}
+	}
+	d.reportRules("t")
+
+	want := map[string]int64{"id > 0": 1, "c >= 0": 1, "d >= 0": 2}
+	got := make(map[string]int64)
+	for _, v := range d.RuleViolations() {
+		got[v.Rule] = v.Rows
+	}
+	if len(got) != len(want) {
+		t.Errorf("violations %v, want %v", got, want)
+	}
+	for r, n := range want {
+		if got[r] != n {
+			t.Errorf("%s broken by %d rows, want %d", r, got[r], n)
+		}
+	}
+
+	var missing []string
+	for _, w := range d.Warnings() {
+		if w.Kind == WarningRule && w.Message == "Rule e not null on table t checks a column that wasn't dumped, skipping it" {
+			missing = append(missing, w.Message)
+		}
+	}
+	if len(missing) != 1 {
+		t.Errorf("warned %q, want the rule on e only", missing)
+	}
+}
+
+func TestRulesSameWidthGroups(t *testing.T) {
+	d := NewDumper(nil, ioutil.Discard, 0, WithRowRules(map[string][]RowRule{"t": {mustRule(t, "c >= 0")}}))
+	d.checkRules("t", []string{"id", "a", "b"}, stringRow("1", "-1", "-1"))
+	d.checkRules("t", []string{"id", "c", "d"}, stringRow("1", "-1", "1"))
+	d.reportRules("t")
+
+	v := d.RuleViolations()
+	if len(v) != 1 || v[0].Rows != 1 {
+		t.Errorf("violations %+v, want the row of the second group", v)
+	}
+	for _, w := range d.Warnings() {
+		if w.Message == "Rule c >= 0 on table t checks a column that wasn't dumped, skipping it" {
+			t.Error("warned about c, which the second group dumps")
+		}
+	}
+}
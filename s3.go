@@ -0,0 +1,252 @@
+package mysqldump
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Config is where an S3Sink uploads a dump to and with which credentials.
+type S3Config struct {
+	Bucket string
+	Key    string
+	Region string
+	// Endpoint of S3 compatible storage such as MinIO, like https://minio:9000, addressing buckets by path.
+	// Defaults to the virtual-hosted endpoint of the bucket on AWS
+	Endpoint string
+	// Address the bucket by path on the default endpoint too
+	PathStyle bool
+
+	AccessKeyID     string
+	SecretAccessKey string
+	// Set for temporary credentials
+	SessionToken string
+
+	// Size of the parts uploaded, at least 5MB. Defaults to 16MB, which limits dumps to about 160GB, as
+	// uploads have at most 10000 parts
+	PartSize int64
+	// http.DefaultClient if nil
+	Client *http.Client
+}
+
+const (
+	s3MinPartSize     = 5 << 20
+	s3DefaultPartSize = 16 << 20
+	s3MaxParts        = 10000
+)
+
+// S3Sink is a Sink uploading a dump to S3, or S3 compatible storage, with a multipart upload, a part at a
+// time. The object only appears once Close completes the upload.
+type S3Sink struct {
+	ctx context.Context
+	c   S3Config
+
+	uploadID string
+	buf      []byte
+	parts    []s3Part
+	err      error
+}
+
+type s3Part struct {
+	PartNumber int
+	ETag       string
+}
+
+// NewS3Sink starts a multipart upload to S3, sending its requests under ctx.
+func NewS3Sink(ctx context.Context, c S3Config) (*S3Sink, error) {
+	if c.Bucket == "" || c.Key == "" {
+		return nil, errors.New("an S3 upload needs a bucket and a key")
+	}
+	if c.Region == "" {
+		c.Region = "us-east-1"
+	}
+	if c.PartSize == 0 {
+		c.PartSize = s3DefaultPartSize
+	}
+	if c.PartSize < s3MinPartSize {
+		return nil, fmt.Errorf("parts of S3 uploads are at least %d bytes", s3MinPartSize)
+	}
+	if c.Endpoint != "" && !strings.Contains(c.Endpoint, "://") {
+		return nil, fmt.Errorf("invalid S3 endpoint %q, expected a URL such as https://minio:9000", c.Endpoint)
+	}
+
+	s := &S3Sink{ctx: ctx, c: c, buf: make([]byte, 0, c.PartSize)}
+	_, body, err := s.do(http.MethodPost, "uploads=", nil, http.StatusOK)
+	if err != nil {
+		return nil, fmt.Errorf("start S3 upload: %w", err)
+	}
+	var res struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err = xml.Unmarshal(body, &res); err != nil || res.UploadID == "" {
+		return nil, fmt.Errorf("start S3 upload: unexpected response %q", body)
+	}
+	s.uploadID = res.UploadID
+	return s, nil
+}
+
+func (s *S3Sink) Write(b []byte) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+
+	n := 0
+	for len(b) > 0 {
+		m := int(s.c.PartSize) - len(s.buf)
+		if m > len(b) {
+			m = len(b)
+		}
+		s.buf, b, n = append(s.buf, b[:m]...), b[m:], n+m
+		if int64(len(s.buf)) == s.c.PartSize {
+			if s.err = s.uploadPart(); s.err != nil {
+				return n, s.err
+			}
+		}
+	}
+	return n, nil
+}
+
+// uploadPart uploads the buffered part of the dump.
+func (s *S3Sink) uploadPart() error {
+	number := len(s.parts) + 1
+	if number > s3MaxParts {
+		return fmt.Errorf("S3 uploads have at most %d parts, raise the part size", s3MaxParts)
+	}
+
+	query := "partNumber=" + strconv.Itoa(number) + "&uploadId=" + awsEscape(s.uploadID, false)
+	res, _, err := s.do(http.MethodPut, query, s.buf, http.StatusOK)
+	if err != nil {
+		return fmt.Errorf("upload part %d to S3: %w", number, err)
+	}
+	s.parts = append(s.parts, s3Part{PartNumber: number, ETag: res.Header.Get("ETag")})
+	s.buf = s.buf[:0]
+	return nil
+}
+
+// Close uploads the last part and completes the upload.
+func (s *S3Sink) Close() error {
+	if s.err != nil {
+		return s.err
+	}
+	if len(s.buf) > 0 || len(s.parts) == 0 {
+		if err := s.uploadPart(); err != nil {
+			return err
+		}
+	}
+
+	body, _ := xml.Marshal(struct {
+		XMLName xml.Name `xml:"CompleteMultipartUpload"`
+		Parts   []s3Part `xml:"Part"`
+	}{Parts: s.parts})
+	// Errors completing an upload can come with a 200 status
+	_, res, err := s.do(http.MethodPost, "uploadId="+awsEscape(s.uploadID, false), body, http.StatusOK)
+	if err == nil && bytes.Contains(res, []byte("<Error>")) {
+		err = fmt.Errorf("%s", res)
+	}
+	if err != nil {
+		return fmt.Errorf("complete S3 upload: %w", err)
+	}
+	s.err = errors.New("S3 upload is complete")
+	return nil
+}
+
+// Abort discards the parts uploaded.
+func (s *S3Sink) Abort() error {
+	s.err = errors.New("S3 upload was aborted")
+	if _, _, err := s.do(http.MethodDelete, "uploadId="+awsEscape(s.uploadID, false), nil, http.StatusNoContent); err != nil {
+		return fmt.Errorf("abort S3 upload: %w", err)
+	}
+	return nil
+}
+
+// do sends a request signed with AWS Signature Version 4 about the object.
+func (s *S3Sink) do(method string, query string, body []byte, expect ...int) (*http.Response, []byte, error) {
+	host := s.c.Bucket + ".s3." + s.c.Region + ".amazonaws.com"
+	scheme, path := "https", "/"+awsEscape(s.c.Key, true)
+	if s.c.Endpoint != "" || s.c.PathStyle {
+		host = "s3." + s.c.Region + ".amazonaws.com"
+		if s.c.Endpoint != "" {
+			i := strings.Index(s.c.Endpoint, "://")
+			scheme, host = s.c.Endpoint[:i], strings.TrimSuffix(s.c.Endpoint[i+3:], "/")
+		}
+		path = "/" + awsEscape(s.c.Bucket, false) + path
+	}
+	sum := sha256.Sum256(body)
+	payload := hex.EncodeToString(sum[:])
+
+	return doSinkRequest(s.ctx, s.c.Client, func() (*http.Request, error) {
+		req, err := http.NewRequest(method, scheme+"://"+host+path+"?"+query, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		s.sign(req, host, path, query, payload, time.Now().UTC())
+		return req, nil
+	}, expect...)
+}
+
+// sign adds the Authorization header of AWS Signature Version 4 to a request.
+func (s *S3Sink) sign(req *http.Request, host, path, query, payload string, now time.Time) {
+	date := now.Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", date)
+	req.Header.Set("X-Amz-Content-Sha256", payload)
+	headers := map[string]string{"host": host, "x-amz-content-sha256": payload, "x-amz-date": date}
+	if s.c.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.c.SessionToken)
+		headers["x-amz-security-token"] = s.c.SessionToken
+	}
+
+	names := make([]string, 0, len(headers))
+	for n := range headers {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	var canonical strings.Builder
+	for _, n := range names {
+		canonical.WriteString(n + ":" + headers[n] + "\n")
+	}
+	signed := strings.Join(names, ";")
+
+	params := strings.Split(query, "&")
+	sort.Strings(params)
+	request := strings.Join([]string{req.Method, path, strings.Join(params, "&"), canonical.String(), signed, payload}, "\n")
+	requestSum := sha256.Sum256([]byte(request))
+
+	scope := now.Format("20060102") + "/" + s.c.Region + "/s3/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + date + "\n" + scope + "\n" + hex.EncodeToString(requestSum[:])
+	key := []byte("AWS4" + s.c.SecretAccessKey)
+	for _, part := range []string{now.Format("20060102"), s.c.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.c.AccessKeyID, scope, signed, hex.EncodeToString(hmacSHA256(key, toSign))))
+}
+
+func hmacSHA256(key []byte, s string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(s))
+	return h.Sum(nil)
+}
+
+// awsEscape percent-encodes everything but the unreserved characters of RFC 3986, and slashes in paths.
+func awsEscape(s string, path bool) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' || (path && c == '/') {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,308 @@
+package mysqldump
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// schemaObject is a table, view, routine, trigger or event of a database with the statement creating
+// it, and the table of a trigger.
+type schemaObject struct {
+	name  string
+	typ   string
+	ddl   string
+	table string
+}
+
+// DumpSchema writes the statements creating the tables, views, stored routines and triggers of a
+// database to w, without reading any rows. Tables come after the tables their foreign keys reference
+// and views after the views they select from, so the statements run one after the other. Foreign keys
+// of tables referencing each other are added once every table exists. The DDL transforms of the Dumper
+// apply to every statement.
+func (d *Dumper) DumpSchema(ctx context.Context, dbName string, w io.Writer) error {
+	defer d.withContext(ctx)()
+	if d.isPQ() {
+		return fmt.Errorf("dumping the schema is only supported on MySQL")
+	}
+
+	s, err := d.server()
+	if err != nil {
+		return fmt.Errorf("get server version: %w", err)
+	}
+
+	tables, views, err := d.schemaTables(dbName)
+	if err != nil {
+		return fmt.Errorf("list tables: %w", err)
+	}
+	var names []string
+	for _, t := range tables {
+		names = append(names, t.name)
+	}
+	fks, err := d.getForeignKeys(dbName, names)
+	if err != nil {
+		return fmt.Errorf("read foreign keys: %w", err)
+	}
+	cyclic := cyclicConstraints(fks)
+	refs := make(map[string][]string)
+	for _, fk := range fks {
+		if !cyclic[fk.table][fk.name] {
+			refs[fk.table] = append(refs[fk.table], fk.referenced)
+		}
+	}
+
+	var constraints []string
+	for i := range tables {
+		t := &tables[i]
+		if t.ddl, err = d.showCreate(fmt.Sprintf("SHOW CREATE %s %s", t.typ, qualifiedName(dbName, t.name)), 1); err != nil {
+			return fmt.Errorf("show create %s %s: %w", strings.ToLower(t.typ), t.name, err)
+		}
+		if t.typ == "TABLE" {
+			var alter []string
+			t.ddl, alter = splitConstraints(t.name, applyDDL(t.ddl, d.ddlTransforms), cyclic[t.name])
+			constraints = append(constraints, alter...)
+		}
+	}
+
+	if views, err = d.schemaViews(dbName, views); err != nil {
+		return err
+	}
+
+	routines, err := d.schemaRoutines(dbName)
+	if err != nil {
+		return fmt.Errorf("list routines: %w", err)
+	}
+	triggers, err := d.schemaTriggers(dbName)
+	if err != nil {
+		return fmt.Errorf("list triggers: %w", err)
+	}
+
+	fmt.Fprintf(w, "-- Schema of `%s`, server version %s\n\n", dbName, s.version)
+	fmt.Fprint(w, "/*!40101 SET NAMES utf8mb4 */;\n\n")
+	for _, t := range dependencyOrder(tables, refs) {
+		fmt.Fprintf(w, "%s;\n\n", t.ddl)
+	}
+	for _, q := range constraints {
+		fmt.Fprintf(w, "%s;\n\n", q)
+	}
+	// Views may call functions, and triggers call procedures, so routines come first
+	writeRoutines(w, routines)
+	for _, v := range views {
+		fmt.Fprintf(w, "%s;\n\n", v.ddl)
+	}
+	writeRoutines(w, triggers)
+	return nil
+}
+
+// schemaTables lists the tables and sequences of a database, and its views.
+func (d *Dumper) schemaTables(schema string) (tables []schemaObject, views []schemaObject, err error) {
+	rows, err := d.queryRows("SHOW FULL TABLES FROM " + quoteName(schema))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, r := range rows {
+		switch r[1] {
+		case "VIEW":
+			views = append(views, schemaObject{name: r[0], typ: "VIEW"})
+		case TableTypeSequence:
+			tables = append(tables, schemaObject{name: r[0], typ: "SEQUENCE"})
+		default:
+			tables = append(tables, schemaObject{name: r[0], typ: "TABLE"})
+		}
+	}
+	return tables, views, nil
+}
+
+// schemaViews reads the DDL of views of a database, returning them after the views they select from.
+func (d *Dumper) schemaViews(schema string, views []schemaObject) ([]schemaObject, error) {
+	for i := range views {
+		v := &views[i]
+		var err error
+		if v.ddl, err = d.showCreate("SHOW CREATE VIEW "+qualifiedName(schema, v.name), 1); err != nil {
+			return nil, fmt.Errorf("show create view %s: %w", v.name, err)
+		}
+		v.ddl = applyDDL(v.ddl, d.ddlTransforms)
+	}
+
+	refs := make(map[string][]string)
+	for _, v := range views {
+		for _, o := range views {
+			if o.name != v.name && strings.Contains(v.ddl, quoteName(o.name)) {
+				refs[v.name] = append(refs[v.name], o.name)
+			}
+		}
+	}
+	return dependencyOrder(views, refs), nil
+}
+
+// schemaRoutines returns the stored functions and procedures of a database, functions first since
+// procedures may call them.
+func (d *Dumper) schemaRoutines(schema string) ([]schemaObject, error) {
+	rows, err := d.db.QueryContext(d.context(), `SELECT ROUTINE_NAME, ROUTINE_TYPE FROM INFORMATION_SCHEMA.ROUTINES
+		WHERE ROUTINE_SCHEMA = ? AND ROUTINE_TYPE IN ('FUNCTION', 'PROCEDURE') ORDER BY ROUTINE_TYPE, ROUTINE_NAME`, schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var routines []schemaObject
+	for rows.Next() {
+		var r schemaObject
+		if err = rows.Scan(&r.name, &r.typ); err != nil {
+			return nil, err
+		}
+		routines = append(routines, r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range routines {
+		r := &routines[i]
+		if r.ddl, err = d.showCreate(fmt.Sprintf("SHOW CREATE %s %s", r.typ, qualifiedName(schema, r.name)), 2); err != nil {
+			return nil, fmt.Errorf("show create %s %s: %w", strings.ToLower(r.typ), r.name, err)
+		}
+		r.ddl = applyDDL(r.ddl, d.ddlTransforms)
+	}
+	return routines, nil
+}
+
+// schemaTriggers returns the triggers of a database, in the order they fire on each table.
+func (d *Dumper) schemaTriggers(schema string) ([]schemaObject, error) {
+	rows, err := d.queryRows("SHOW TRIGGERS FROM " + quoteName(schema))
+	if err != nil {
+		return nil, err
+	}
+
+	var triggers []schemaObject
+	for _, r := range rows {
+		t := schemaObject{name: r[0], typ: "TRIGGER", table: r[2]}
+		if t.ddl, err = d.showCreate("SHOW CREATE TRIGGER "+qualifiedName(schema, t.name), 2); err != nil {
+			return nil, fmt.Errorf("show create trigger %s: %w", t.name, err)
+		}
+		t.ddl = applyDDL(t.ddl, d.ddlTransforms)
+		triggers = append(triggers, t)
+	}
+	return triggers, nil
+}
+
+// schemaEvents returns the events of a database.
+func (d *Dumper) schemaEvents(schema string) ([]schemaObject, error) {
+	rows, err := d.queryRows("SHOW EVENTS FROM " + quoteName(schema))
+	if err != nil {
+		return nil, err
+	}
+
+	var events []schemaObject
+	for _, r := range rows {
+		e := schemaObject{name: r[1], typ: "EVENT"}
+		if e.ddl, err = d.showCreate("SHOW CREATE EVENT "+qualifiedName(schema, e.name), 3); err != nil {
+			return nil, fmt.Errorf("show create event %s: %w", e.name, err)
+		}
+		e.ddl = applyDDL(e.ddl, d.ddlTransforms)
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// writeRoutines writes statements whose bodies hold semicolons, between DELIMITER commands.
+func writeRoutines(w io.Writer, routines []schemaObject) {
+	if len(routines) == 0 {
+		return
+	}
+
+	fmt.Fprint(w, "DELIMITER ;;\n")
+	for _, r := range routines {
+		fmt.Fprintf(w, "%s ;;\n", r.ddl)
+	}
+	fmt.Fprint(w, "DELIMITER ;\n\n")
+}
+
+// dependencyOrder returns the objects with every one coming after the ones it references, keeping
+// their order otherwise. References back to an object being placed are ignored.
+func dependencyOrder(objects []schemaObject, refs map[string][]string) []schemaObject {
+	byName := make(map[string]schemaObject, len(objects))
+	for _, o := range objects {
+		byName[o.name] = o
+	}
+
+	var ordered []schemaObject
+	seen := make(map[string]bool, len(objects))
+	var visit func(name string)
+	visit = func(name string) {
+		o, ok := byName[name]
+		if !ok || seen[name] {
+			return
+		}
+		seen[name] = true
+		for _, r := range refs[name] {
+			visit(r)
+		}
+		ordered = append(ordered, o)
+	}
+	for _, o := range objects {
+		visit(o.name)
+	}
+	return ordered
+}
+
+// showCreate runs a SHOW CREATE statement and returns the column holding the statement.
+func (d *Dumper) showCreate(q string, column int) (string, error) {
+	rows, err := d.queryRows(q)
+	if err != nil {
+		return "", err
+	}
+	if len(rows) == 0 || column >= len(rows[0]) {
+		return "", sql.ErrNoRows
+	}
+	if rows[0][column] == "" {
+		// Routine bodies are hidden from users that don't own them
+		return "", fmt.Errorf("no statement returned, the user may lack privileges")
+	}
+	return rows[0][column], nil
+}
+
+// queryRows returns every column of every row a query returns, NULL as an empty string.
+func (d *Dumper) queryRows(q string) ([][]string, error) {
+	rows, err := d.db.QueryContext(d.context(), q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var res [][]string
+	for rows.Next() {
+		vals := make([]sql.NullString, len(cols))
+		ptrs := make([]interface{}, len(cols))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err = rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+
+		row := make([]string, len(cols))
+		for i, v := range vals {
+			row[i] = v.String
+		}
+		res = append(res, row)
+	}
+	return res, rows.Err()
+}
+
+// qualifiedName returns the quoted name of an object of a database.
+func qualifiedName(schema string, name string) string {
+	return quoteName(schema) + "." + quoteName(name)
+}
+
+func quoteName(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
@@ -0,0 +1,255 @@
+package mysqldump
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/MouseHatGames/go-mysqldump/internal/marshal"
+	"github.com/sirupsen/logrus"
+)
+
+// SchemaCheck controls how the Loader treats tables of the target whose columns differ from the dumped ones.
+type SchemaCheck int
+
+const (
+	// SchemaCheckOff inserts the dumped columns by name without looking at the target.
+	SchemaCheckOff SchemaCheck = iota
+	// SchemaCheckStrict fails the restore of a table whose columns differ from the dumped ones in name,
+	// type or order.
+	SchemaCheckStrict
+	// SchemaCheckMap inserts the dumped columns the target table has by name, leaving out the others,
+	// and warns about every difference.
+	SchemaCheckMap
+)
+
+// ParseSchemaCheck parses "off", "strict" or "map".
+func ParseSchemaCheck(s string) (SchemaCheck, error) {
+	switch strings.ToLower(s) {
+	case "off", "":
+		return SchemaCheckOff, nil
+	case "strict":
+		return SchemaCheckStrict, nil
+	case "map":
+		return SchemaCheckMap, nil
+	}
+
+	return 0, fmt.Errorf("invalid schema check: %s", s)
+}
+
+// targetColumn is a column of a table of the target, in table order.
+type targetColumn struct {
+	name string
+	typ  string
+}
+
+// columnNames returns the names the dumped columns of a table are inserted with, see ColumnMap.
+func (l *Loader) columnNames(t *marshal.TableHeader) []string {
+	renames := l.opt.ColumnMap[t.Name]
+	if len(renames) == 0 {
+		return t.Columns
+	}
+
+	names := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		names[i] = c
+		if n, ok := renames[c]; ok {
+			names[i] = n
+		}
+	}
+	return names
+}
+
+// insertColumns returns the position of the dumped columns inserted into a table, given their names,
+// nil for all of them.
+func (l *Loader) insertColumns(t *marshal.TableHeader, names []string) ([]int, error) {
+	keep, err := l.checkSchema(t, names)
+	if err != nil || keep != nil {
+		return keep, err
+	}
+
+	for i, n := range names {
+		if n != "" {
+			keep = append(keep, i)
+		}
+	}
+	if len(keep) == len(names) {
+		return nil, nil
+	}
+	if len(keep) == 0 {
+		return nil, fmt.Errorf("every dumped column is left out")
+	}
+	return keep, nil
+}
+
+// checkSchema compares the columns of a table on the target with the dumped ones, going by the names
+// they are inserted with, and returns the position of the dumped columns to insert, nil if they are
+// all as on the target.
+func (l *Loader) checkSchema(t *marshal.TableHeader, names []string) ([]int, error) {
+	if l.opt.SchemaCheck == SchemaCheckOff || l.db == nil || l.opt.DryRun != nil {
+		return nil, nil
+	}
+
+	rows, err := l.db.QueryContext(context.Background(), `SELECT COLUMN_NAME, COLUMN_TYPE FROM INFORMATION_SCHEMA.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION`, t.Name)
+	if err != nil {
+		return nil, fmt.Errorf("read target columns: %w", err)
+	}
+	defer rows.Close()
+
+	var target []targetColumn
+	for rows.Next() {
+		var c targetColumn
+		if err = rows.Scan(&c.name, &c.typ); err != nil {
+			return nil, fmt.Errorf("read target columns: %w", err)
+		}
+		target = append(target, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("read target columns: %w", err)
+	}
+	if len(target) == 0 {
+		return nil, fmt.Errorf("table doesn't exist on the target")
+	}
+	// Columns missing from the dump that are given a default aren't a difference
+	var defaulted []string
+	for name, cp := range l.opt.ColumnPolicies[t.Name] {
+		if cp.Default != nil {
+			defaulted = append(defaulted, name)
+		}
+	}
+	checked := target[:0]
+	for _, c := range target {
+		if indexFold(defaulted, c.name) < 0 || indexFold(names, c.name) >= 0 {
+			checked = append(checked, c)
+		}
+	}
+	target = checked
+	if t.ColumnGroups > 0 {
+		// A column group holds only some of the columns of the table
+		checked = target[:0]
+		for _, c := range target {
+			if indexFold(names, c.name) >= 0 {
+				checked = append(checked, c)
+			}
+		}
+		target = checked
+	}
+
+	keep, diffs := compareColumns(t, names, target)
+	if len(diffs) == 0 {
+		return nil, nil
+	}
+	if l.opt.SchemaCheck == SchemaCheckStrict {
+		return nil, fmt.Errorf("columns differ from the dump: %s", strings.Join(diffs, "; "))
+	}
+
+	for _, d := range diffs {
+		logrus.Warnf("Table %s: %s", t.Name, d)
+	}
+	if len(keep) == 0 {
+		return nil, fmt.Errorf("none of the dumped columns exist on the target")
+	}
+	return keep, nil
+}
+
+// compareColumns returns the position of the dumped columns the target has by the given names, and
+// how the columns differ. Columns without a name are left out. Types are only compared when the DDL of
+// the dump gives them.
+func compareColumns(t *marshal.TableHeader, names []string, target []targetColumn) (keep []int, diffs []string) {
+	dumped := make(map[string]string)
+	for _, def := range parseCreateTable(t.CreateSQL).columns {
+		dumped[strings.ToLower(def.name)] = columnType(def.def)
+	}
+
+	var order []string
+	for i, c := range names {
+		if c == "" {
+			continue
+		}
+		j := findColumn(target, c)
+		if j < 0 {
+			// Implicit period columns of a table's history aren't listed by the server
+			if t.SystemTime == "ALL" && (c == "ROW_START" || c == "ROW_END") {
+				keep = append(keep, i)
+				continue
+			}
+			diffs = append(diffs, fmt.Sprintf("column %s is missing on the target", c))
+			continue
+		}
+		keep = append(keep, i)
+		order = append(order, strings.ToLower(c))
+
+		a, b := normalizeType(dumped[strings.ToLower(t.Columns[i])]), normalizeType(target[j].typ)
+		if a != "" && a != b {
+			diffs = append(diffs, fmt.Sprintf("column %s is %s on the target, %s in the dump", c, b, a))
+		}
+	}
+
+	var targetOrder []string
+	for _, c := range target {
+		if indexFold(names, c.name) < 0 {
+			diffs = append(diffs, fmt.Sprintf("column %s of the target isn't in the dump", c.name))
+			continue
+		}
+		targetOrder = append(targetOrder, strings.ToLower(c.name))
+	}
+	if strings.Join(order, ",") != strings.Join(targetOrder, ",") {
+		diffs = append(diffs, fmt.Sprintf("columns are in the order %s on the target, %s in the dump", strings.Join(targetOrder, ", "), strings.Join(order, ", ")))
+	}
+
+	return keep, diffs
+}
+
+func findColumn(cols []targetColumn, name string) int {
+	for i, c := range cols {
+		if strings.EqualFold(c.name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// columnType returns the type of a column definition of SHOW CREATE TABLE, e.g. "int unsigned".
+func columnType(def string) string {
+	m := quotedNameRegex.FindStringIndex(def)
+	if m == nil || m[0] != 0 {
+		return ""
+	}
+	def = strings.TrimSpace(def[m[1]:])
+
+	// The type ends at the first space outside of parentheses and quotes, like enum('a b')
+	depth, quoted, end := 0, false, len(def)
+	for i, c := range def {
+		if quoted {
+			quoted = c != '\''
+			continue
+		}
+		if c == '\'' {
+			quoted = true
+		} else if c == '(' {
+			depth++
+		} else if c == ')' {
+			depth--
+		} else if c == ' ' && depth == 0 {
+			end = i
+			break
+		}
+	}
+	typ := def[:end]
+	for _, attr := range []string{" unsigned", " zerofill"} {
+		if strings.HasPrefix(def[end:], attr) {
+			typ += attr
+			end += len(attr)
+		}
+	}
+	return typ
+}
+
+// Integer display widths are dropped by MySQL 8.0.19 and later
+var intWidthRegex = regexp.MustCompile(`^(tinyint|smallint|mediumint|int|bigint)\(\d+\)`)
+
+func normalizeType(typ string) string {
+	return intWidthRegex.ReplaceAllString(strings.ToLower(typ), "$1")
+}
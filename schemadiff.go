@@ -0,0 +1,293 @@
+package mysqldump
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/MouseHatGames/go-mysqldump/internal/marshal"
+)
+
+type SchemaDiffOptions struct {
+	// Also generate the ALTER statements turning the schema of dump a into that of dump b
+	Alter bool
+}
+
+// DefinitionChange is a column, index or constraint present in both dumps with a different definition.
+type DefinitionChange struct {
+	Name string
+	A    string
+	B    string
+}
+
+// TableSchemaDiff describes how the DDL of a table differs between two dumps.
+type TableSchemaDiff struct {
+	Table string
+	// Set if the table is only in dump b or only in dump a
+	Added   bool
+	Removed bool
+
+	AddedColumns   []string
+	RemovedColumns []string
+	ChangedColumns []DefinitionChange
+	// Keys and constraints, named PRIMARY for the primary key
+	AddedIndexes   []string
+	RemovedIndexes []string
+	ChangedIndexes []DefinitionChange
+	// Table options such as the engine and charset, AUTO_INCREMENT left out
+	OptionsA string
+	OptionsB string
+
+	// Statements turning the table of dump a into that of dump b, if SchemaDiffOptions.Alter is set
+	Alter []string
+}
+
+// Changed reports whether the table's schema differs at all.
+func (t *TableSchemaDiff) Changed() bool {
+	return t.Added || t.Removed || len(t.AddedColumns) > 0 || len(t.RemovedColumns) > 0 || len(t.ChangedColumns) > 0 ||
+		len(t.AddedIndexes) > 0 || len(t.RemovedIndexes) > 0 || len(t.ChangedIndexes) > 0 || t.OptionsA != t.OptionsB
+}
+
+// SchemaDiff holds the schema differences between two dumps.
+type SchemaDiff struct {
+	// Tables whose schema changed, in the order of dump a followed by tables only in b
+	Tables []*TableSchemaDiff
+}
+
+// DiffSchema compares the DDL of the tables in two dumps, column by column and index by index,
+// without reading their rows. It is meant to track schema drift between backups.
+func DiffSchema(a, b io.Reader, opt SchemaDiffOptions) (*SchemaDiff, error) {
+	sa, err := readSchema(a)
+	if err != nil {
+		return nil, fmt.Errorf("read dump a: %w", err)
+	}
+	sb, err := readSchema(b)
+	if err != nil {
+		return nil, fmt.Errorf("read dump b: %w", err)
+	}
+
+	diff := &SchemaDiff{}
+	for _, ta := range sa {
+		td := &TableSchemaDiff{Table: ta.Name}
+		tb := findHeader(sb, ta.Name)
+		if tb != nil {
+			diffTableSchema(td, parseCreateTable(ta.CreateSQL), parseCreateTable(tb.CreateSQL))
+		} else {
+			td.Removed = true
+		}
+		if !td.Changed() {
+			continue
+		}
+
+		if opt.Alter {
+			td.Alter = alterStatements(td, ta, tb)
+		}
+		diff.Tables = append(diff.Tables, td)
+	}
+
+	for _, tb := range sb {
+		if findHeader(sa, tb.Name) != nil {
+			continue
+		}
+
+		td := &TableSchemaDiff{Table: tb.Name, Added: true}
+		if opt.Alter {
+			td.Alter = alterStatements(td, nil, tb)
+		}
+		diff.Tables = append(diff.Tables, td)
+	}
+
+	return diff, nil
+}
+
+// readSchema reads the table headers of a dump, skipping their rows. Tables dumped in several sections
+// and query results, which have no DDL, only appear once or not at all.
+func readSchema(in io.Reader) ([]*marshal.TableHeader, error) {
+	r := marshal.NewReader(in)
+	if _, err := r.ReadFileHeader(); err != nil {
+		return nil, fmt.Errorf("read file header: %w", err)
+	}
+
+	var tables []*marshal.TableHeader
+	for {
+		t, err := r.ReadTableHeader()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return tables, nil
+			}
+			return nil, fmt.Errorf("read table header: %w", err)
+		}
+
+		if t.Type != TableTypeQuery && findHeader(tables, t.Name) == nil {
+			tables = append(tables, t)
+		}
+		if err = r.SkipRows(len(t.Columns)); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("skip table %s: %w", t.Name, err)
+		}
+	}
+}
+
+func findHeader(tables []*marshal.TableHeader, name string) *marshal.TableHeader {
+	for _, t := range tables {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// definition is a named line of a CREATE TABLE statement.
+type definition struct {
+	name string
+	def  string
+}
+
+// tableSchema is a CREATE TABLE statement split into its parts.
+type tableSchema struct {
+	columns []definition
+	indexes []definition
+	options string
+}
+
+var (
+	autoIncrementRegex = regexp.MustCompile(`\s*AUTO_INCREMENT=\d+`)
+	quotedNameRegex    = regexp.MustCompile("`((?:[^`]|``)+)`")
+)
+
+// parseCreateTable splits the output of SHOW CREATE TABLE into columns, indexes and table options.
+func parseCreateTable(ddl string) *tableSchema {
+	s := &tableSchema{}
+
+	start, end := strings.Index(ddl, "(\n"), strings.LastIndex(ddl, "\n)")
+	if start < 0 || end < start {
+		s.options = ddl
+		return s
+	}
+	s.options = strings.TrimSpace(autoIncrementRegex.ReplaceAllString(ddl[end+2:], ""))
+
+	for _, line := range strings.Split(ddl[start+2:end], "\n") {
+		line = strings.TrimSuffix(strings.TrimSpace(line), ",")
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "`") {
+			name := quotedNameRegex.FindStringSubmatch(line)[1]
+			s.columns = append(s.columns, definition{name: name, def: line})
+			continue
+		}
+
+		name := "PRIMARY"
+		if !strings.HasPrefix(line, "PRIMARY KEY") {
+			if m := quotedNameRegex.FindStringSubmatch(line); m != nil {
+				name = m[1]
+			} else {
+				name = line
+			}
+		}
+		s.indexes = append(s.indexes, definition{name: name, def: line})
+	}
+
+	return s
+}
+
+func findDefinition(defs []definition, name string) *definition {
+	for i := range defs {
+		if defs[i].name == name {
+			return &defs[i]
+		}
+	}
+	return nil
+}
+
+func diffTableSchema(td *TableSchemaDiff, a, b *tableSchema) {
+	td.AddedColumns, td.RemovedColumns, td.ChangedColumns = diffDefinitions(a.columns, b.columns)
+	td.AddedIndexes, td.RemovedIndexes, td.ChangedIndexes = diffDefinitions(a.indexes, b.indexes)
+	if a.options != b.options {
+		td.OptionsA, td.OptionsB = a.options, b.options
+	}
+}
+
+func diffDefinitions(a, b []definition) (added, removed []string, changed []DefinitionChange) {
+	for _, da := range a {
+		db := findDefinition(b, da.name)
+		switch {
+		case db == nil:
+			removed = append(removed, da.name)
+		case db.def != da.def:
+			changed = append(changed, DefinitionChange{Name: da.name, A: da.def, B: db.def})
+		}
+	}
+	for _, db := range b {
+		if findDefinition(a, db.name) == nil {
+			added = append(added, db.name)
+		}
+	}
+	return
+}
+
+// alterStatements returns the statements turning table a into table b. Either is nil if the table
+// only exists in the other dump.
+func alterStatements(td *TableSchemaDiff, a, b *marshal.TableHeader) []string {
+	switch {
+	case b == nil:
+		return []string{"DROP TABLE " + quoteName(td.Table)}
+	case a == nil:
+		return []string{b.CreateSQL}
+	}
+
+	sa, sb := parseCreateTable(a.CreateSQL), parseCreateTable(b.CreateSQL)
+	var clauses []string
+
+	// Indexes go first so columns can be dropped, and foreign keys come back once the columns they use exist
+	for _, c := range td.ChangedIndexes {
+		clauses = append(clauses, dropIndex(findDefinition(sa.indexes, c.Name)))
+	}
+	for _, name := range td.RemovedIndexes {
+		clauses = append(clauses, dropIndex(findDefinition(sa.indexes, name)))
+	}
+	for _, name := range td.RemovedColumns {
+		clauses = append(clauses, "DROP COLUMN "+quoteName(name))
+	}
+	for _, c := range td.ChangedColumns {
+		clauses = append(clauses, "MODIFY COLUMN "+c.B)
+	}
+	for i, col := range sb.columns {
+		if findDefinition(sa.columns, col.name) != nil {
+			continue
+		}
+		pos := " FIRST"
+		if i > 0 {
+			pos = " AFTER " + quoteName(sb.columns[i-1].name)
+		}
+		clauses = append(clauses, "ADD COLUMN "+col.def+pos)
+	}
+	for _, c := range td.ChangedIndexes {
+		clauses = append(clauses, "ADD "+c.B)
+	}
+	for _, name := range td.AddedIndexes {
+		clauses = append(clauses, "ADD "+findDefinition(sb.indexes, name).def)
+	}
+	if td.OptionsA != td.OptionsB && sb.options != "" {
+		clauses = append(clauses, sb.options)
+	}
+
+	if len(clauses) == 0 {
+		return nil
+	}
+	return []string{"ALTER TABLE " + quoteName(td.Table) + "\n  " + strings.Join(clauses, ",\n  ")}
+}
+
+func dropIndex(d *definition) string {
+	switch {
+	case d.name == "PRIMARY":
+		return "DROP PRIMARY KEY"
+	case strings.Contains(d.def, "FOREIGN KEY"):
+		return "DROP FOREIGN KEY " + quoteName(d.name)
+	case strings.HasPrefix(d.def, "CONSTRAINT"):
+		return "DROP CONSTRAINT " + quoteName(d.name)
+	}
+	return "DROP INDEX " + quoteName(d.name)
+}
@@ -0,0 +1,320 @@
+package mysqldump
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type serverFlavor int
+
+const (
+	flavorMySQL serverFlavor = iota
+	flavorMariaDB
+	flavorPostgres
+	flavorTiDB
+	flavorVitess
+)
+
+func (f serverFlavor) String() string {
+	switch f {
+	case flavorMariaDB:
+		return "MariaDB"
+	case flavorPostgres:
+		return "PostgreSQL"
+	case flavorTiDB:
+		return "TiDB"
+	case flavorVitess:
+		return "Vitess"
+	}
+	return "MySQL"
+}
+
+// serverInfo is the parsed result of SELECT version().
+type serverInfo struct {
+	version string
+	flavor  serverFlavor
+	major   int
+	minor   int
+	patch   int
+	// "Aurora" or "RDS" for MySQL run by AWS, with the Aurora version if known
+	managed        string
+	managedVersion string
+	percona        bool
+	// The MyRocks storage engine is enabled
+	myrocks bool
+	// Table names are compared case-insensitively unless this is 0
+	lowerCaseTableNames int
+}
+
+var versionNumber = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?`)
+
+func parseServerVersion(v string) serverInfo {
+	info := serverInfo{version: v}
+
+	lower := strings.ToLower(v)
+	switch {
+	case strings.Contains(lower, "mariadb"):
+		info.flavor = flavorMariaDB
+		// Replication-compatible MariaDB servers report themselves as "5.5.5-10.6.12-MariaDB"
+		v = strings.TrimPrefix(v, "5.5.5-")
+	case strings.Contains(lower, "tidb"):
+		// TiDB reports the MySQL version it is compatible with, followed by its own: "5.7.25-TiDB-v6.5.0"
+		info.flavor = flavorTiDB
+		if i := strings.Index(lower, "tidb-v"); i >= 0 {
+			v = v[i+len("tidb-v"):]
+		}
+	case strings.Contains(lower, "vitess"):
+		// vtgate reports the MySQL version it emulates: "8.0.30-Vitess"
+		info.flavor = flavorVitess
+	case strings.HasPrefix(lower, "postgresql"):
+		info.flavor = flavorPostgres
+	case strings.Contains(lower, "mysql_aurora."):
+		// Aurora MySQL 2 reports "5.7.mysql_aurora.2.11.2", the patch version isn't there
+		info.managed = "Aurora"
+		info.managedVersion = v[strings.Index(lower, "mysql_aurora.")+len("mysql_aurora."):]
+		v = v[:strings.Index(lower, "mysql_aurora.")]
+	}
+
+	if m := versionNumber.FindStringSubmatch(v); m != nil {
+		info.major, _ = strconv.Atoi(m[1])
+		info.minor, _ = strconv.Atoi(m[2])
+		info.patch, _ = strconv.Atoi(m[3])
+	}
+
+	return info
+}
+
+// atLeast reports whether the server version is major.minor.patch or newer.
+func (s serverInfo) atLeast(major, minor, patch int) bool {
+	if s.major != major {
+		return s.major > major
+	}
+	if s.minor != minor {
+		return s.minor > minor
+	}
+	return s.patch >= patch
+}
+
+type feature int
+
+const (
+	featureUTF8MB4 feature = iota
+	featureGTID
+	featureRoles
+	featureHistograms
+	featureInvisibleColumns
+	featureSequences
+	featureSystemVersioning
+	featurePartitionSelection
+	featureInsertHistory
+	featureHistogramData
+	featureWindowFunctions
+	// SHOW BINARY LOG STATUS, replacing SHOW MASTER STATUS which MySQL 8.4 removed
+	featureBinaryLogStatus
+)
+
+// minVersions is the first version of every flavor supporting a feature, missing if it never does.
+var minVersions = map[feature]map[serverFlavor][3]int{
+	featureUTF8MB4: {
+		flavorMySQL:   {5, 5, 3},
+		flavorMariaDB: {5, 5, 0},
+		flavorTiDB:    {2, 1, 0},
+		flavorVitess:  {5, 7, 0},
+	},
+	featureGTID: {
+		flavorMySQL:  {5, 6, 5},
+		flavorVitess: {5, 7, 0},
+	},
+	featureRoles: {
+		flavorMySQL:   {8, 0, 0},
+		flavorMariaDB: {10, 0, 5},
+		flavorTiDB:    {3, 0, 0},
+	},
+	featureHistograms: {
+		flavorMySQL:   {8, 0, 0},
+		flavorMariaDB: {10, 0, 2},
+	},
+	featureInvisibleColumns: {
+		flavorMySQL:   {8, 0, 23},
+		flavorMariaDB: {10, 3, 3},
+		flavorVitess:  {8, 0, 23},
+	},
+	featureSequences: {
+		flavorMariaDB: {10, 3, 0},
+		flavorTiDB:    {4, 0, 0},
+	},
+	featureSystemVersioning: {
+		flavorMariaDB: {10, 3, 4},
+	},
+	featurePartitionSelection: {
+		flavorMySQL:   {5, 6, 2},
+		flavorMariaDB: {10, 0, 0},
+		flavorTiDB:    {3, 0, 0},
+	},
+	featureInsertHistory: {
+		flavorMariaDB: {10, 11, 0},
+	},
+	// ANALYZE TABLE ... UPDATE HISTOGRAM ... USING DATA
+	featureHistogramData: {
+		flavorMySQL: {8, 0, 31},
+	},
+	featureWindowFunctions: {
+		flavorMySQL:    {8, 0, 2},
+		flavorMariaDB:  {10, 2, 0},
+		flavorPostgres: {8, 4, 0},
+		flavorTiDB:     {3, 0, 0},
+	},
+	featureBinaryLogStatus: {
+		flavorMySQL: {8, 2, 0},
+	},
+}
+
+// supports reports whether the server version has a feature.
+func (s serverInfo) supports(f feature) bool {
+	v, ok := minVersions[f][s.flavor]
+	return ok && s.atLeast(v[0], v[1], v[2])
+}
+
+// server returns information about the server, querying it the first time.
+func (d *Dumper) server() (serverInfo, error) {
+	if d.serverInfo != nil {
+		return *d.serverInfo, nil
+	}
+
+	info, err := detectServer(d.context(), d.db)
+	if err != nil {
+		return serverInfo{}, err
+	}
+	d.serverInfo = &info
+	return info, nil
+}
+
+// detectServer queries the version of a server and the distribution it comes from.
+func detectServer(ctx context.Context, db *sql.DB) (serverInfo, error) {
+	v, err := getServerVersion(ctx, db)
+	if err != nil {
+		return serverInfo{}, err
+	}
+
+	info := parseServerVersion(v)
+	if info.flavor != flavorPostgres {
+		var lctn sql.NullInt64
+		if err = db.QueryRowContext(ctx, stmtLowerCaseTableNames).Scan(&lctn); err == nil {
+			info.lowerCaseTableNames = int(lctn.Int64)
+		}
+	}
+	if info.flavor == flavorMySQL || info.flavor == flavorMariaDB {
+		var support sql.NullString
+		if err = db.QueryRowContext(ctx, stmtMyRocks).Scan(&support); err == nil {
+			info.myrocks = support.String == "YES" || support.String == "DEFAULT"
+		}
+	}
+	if info.flavor == flavorMySQL {
+		if info.managed == "" {
+			info.managed, info.managedVersion = detectManaged(ctx, db)
+		}
+
+		var comment sql.NullString
+		if err = db.QueryRowContext(ctx, stmtVersionComment).Scan(&comment); err == nil {
+			info.percona = strings.Contains(strings.ToLower(comment.String), "percona")
+		}
+	}
+	return info, nil
+}
+
+// sameTableName reports whether two table names refer to the same table on the server.
+func (d *Dumper) sameTableName(a, b string) bool {
+	if a == b {
+		return true
+	}
+	s, err := d.server()
+	return err == nil && s.lowerCaseTableNames != 0 && strings.EqualFold(a, b)
+}
+
+func (d *Dumper) isMariaDB() bool {
+	s, err := d.server()
+	return err == nil && s.flavor == flavorMariaDB
+}
+
+// startConn pins the connection table data is read through, running setup on it first.
+// The setup is kept to run it again if the connection has to be re-established.
+func (d *Dumper) startConn(dbName string, setup ...string) error {
+	if dbName != "" {
+		setup = append([]string{stmtUseDatabase(dbName)}, setup...)
+	}
+	d.connSetup = setup
+	if err := d.openConn(); err != nil {
+		d.connSetup = nil
+		return err
+	}
+	return nil
+}
+
+func (d *Dumper) openConn() error {
+	conn, err := d.db.Conn(d.context())
+	if err != nil {
+		return fmt.Errorf("open connection: %w", err)
+	}
+	for _, q := range d.connSetup {
+		if _, err = conn.ExecContext(d.context(), q); err != nil {
+			conn.Close()
+			return err
+		}
+	}
+
+	d.conn = conn
+	return nil
+}
+
+func (d *Dumper) endConn() {
+	if d.conn != nil {
+		d.conn.Close()
+		d.conn = nil
+	}
+	d.connSetup = nil
+}
+
+// query runs a query reading table data, on the pinned connection if there is one.
+func (d *Dumper) query(q string, args ...interface{}) (*sql.Rows, error) {
+	if d.conn != nil {
+		return d.conn.QueryContext(d.context(), q, args...)
+	}
+	return d.db.QueryContext(d.context(), q, args...)
+}
+
+// queryRowMap runs a query returning a single row, keyed by column name, on the pinned connection if there is one.
+// It returns an empty map if there is no row.
+func (d *Dumper) queryRowMap(q string, args ...interface{}) (map[string]string, error) {
+	rows, err := d.query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]string, len(cols))
+	if !rows.Next() {
+		return res, rows.Err()
+	}
+
+	data := make([]sql.NullString, len(cols))
+	ptrs := make([]interface{}, len(cols))
+	for i := range data {
+		ptrs[i] = &data[i]
+	}
+	if err = rows.Scan(ptrs...); err != nil {
+		return nil, err
+	}
+	for i, c := range cols {
+		res[c] = data[i].String
+	}
+	return res, nil
+}
@@ -0,0 +1,51 @@
+package mysqldump
+
+// WithServerSnapshot records the global variables and status counters of the server as the dump starts
+// in the file header (FileHeader.ServerVariables and ServerStatus), giving whoever restores the backup
+// after an incident the configuration and load of the server it was taken from. PostgreSQL servers
+// only have their settings recorded.
+func WithServerSnapshot() Option {
+	return func(d *Dumper) {
+		d.serverSnapshot = true
+	}
+}
+
+// readServerSnapshot reads the global variables and status counters of the server, warning about
+// those that can't be read.
+func (d *Dumper) readServerSnapshot() (variables map[string]string, status map[string]string) {
+	if !d.serverSnapshot {
+		return nil, nil
+	}
+
+	q := "SHOW GLOBAL VARIABLES"
+	if d.isPQ() {
+		q = "SHOW ALL"
+	}
+	variables, err := d.queryPairs(q)
+	if err != nil {
+		d.warn(WarningMetadata, "", "Can't read the global variables of the server: %s", err)
+	}
+	if d.isPQ() {
+		return variables, nil
+	}
+	if status, err = d.queryPairs("SHOW GLOBAL STATUS"); err != nil {
+		d.warn(WarningMetadata, "", "Can't read the global status of the server: %s", err)
+	}
+	return variables, status
+}
+
+// queryPairs returns the first two columns of the rows a query returns, by the first one.
+func (d *Dumper) queryPairs(q string) (map[string]string, error) {
+	rows, err := d.queryRows(q)
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := make(map[string]string, len(rows))
+	for _, r := range rows {
+		if len(r) >= 2 {
+			pairs[r[0]] = r[1]
+		}
+	}
+	return pairs, nil
+}
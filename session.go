@@ -0,0 +1,74 @@
+package mysqldump
+
+import "sort"
+
+// DumpSessionPreset holds the session variables set for a dump, so the server doesn't drop the
+// connection while a slow destination keeps the dumper from reading, and so aggregating queries
+// given to DumpQuery aren't cut short.
+var DumpSessionPreset = map[string]string{
+	"net_read_timeout":     "3600",
+	"net_write_timeout":    "3600",
+	"wait_timeout":         "28800",
+	"group_concat_max_len": "16777216",
+}
+
+// WithSessionVariables sets session variables of the connection tables are read through, on top of
+// DumpSessionPreset. Values are SQL expressions, and an empty value leaves the variable out.
+func WithSessionVariables(vars map[string]string) Option {
+	return func(d *Dumper) {
+		d.sessionVars = vars
+	}
+}
+
+// sessionStatements returns the SET statements of the session variables, in a stable order.
+func (d *Dumper) sessionStatements() []string {
+	vars := make(map[string]string, len(DumpSessionPreset)+len(d.sessionVars))
+	for k, v := range DumpSessionPreset {
+		vars[k] = v
+	}
+	for k, v := range d.sessionVars {
+		vars[k] = v
+	}
+
+	names := make([]string, 0, len(vars))
+	for k, v := range vars {
+		if v != "" {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+
+	stmts := make([]string, len(names))
+	for i, k := range names {
+		stmts[i] = "SET SESSION " + k + " = " + vars[k]
+	}
+	return stmts
+}
+
+// startSession sets the session variables on the connection tables are read through, pinning one if
+// there is none yet, and reports whether it did so. Variables the server doesn't know or won't let the
+// user set are skipped with a warning.
+func (d *Dumper) startSession(dbName string) (bool, error) {
+	stmts := d.sessionStatements()
+	if d.isPQ() || len(stmts) == 0 {
+		return false, nil
+	}
+
+	pinned := false
+	if d.conn == nil {
+		if err := d.startConn(dbName); err != nil {
+			return false, err
+		}
+		pinned = true
+	}
+
+	for _, q := range stmts {
+		if _, err := d.conn.ExecContext(d.context(), q); err != nil {
+			d.warn(WarningSession, "", "Can't set session variable, skipping it: %s: %s", q, err)
+			continue
+		}
+		// Set again when reconnecting
+		d.connSetup = append(d.connSetup, q)
+	}
+	return pinned, nil
+}
@@ -0,0 +1,95 @@
+package mysqldump
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/MouseHatGames/go-mysqldump/internal/marshal"
+)
+
+// WithShards splits the rows of every table with a primary key into n sections by a hash of
+// the key, so they can be restored in parallel into n target shards (see LoaderOptions.Shard).
+// Tables without a primary key are dumped whole. Not supported on PostgreSQL.
+func WithShards(n int) Option {
+	return func(d *Dumper) {
+		d.shards = n
+	}
+}
+
+// tableUnit is a part of a table whose rows are read and written as a section of the dump.
+type tableUnit struct {
+	partition string
+	// Shard of the section starting at 1, 0 if the table isn't sharded
+	shard int
+	// Condition selecting the rows of the shard
+	where string
+	// Column group of the section starting at 1 and its columns, 0 and nil if the table isn't split
+	// by column, see WithColumnGroups
+	group   int
+	columns []string
+}
+
+// shardUnits splits every unit of a table by shard.
+func (d *Dumper) shardUnits(name string, schema string, units []tableUnit) ([]tableUnit, error) {
+	if d.shards <= 1 || d.isPQ() {
+		return units, nil
+	}
+
+	pk, err := d.primaryKey(name, schema)
+	if err != nil {
+		return nil, err
+	}
+	if len(pk) == 0 {
+		return units, nil
+	}
+
+	// CRC32 is stable across servers and versions, so the same rows land in the same shard every time
+	hash := "CRC32(CONCAT_WS(0x1F, " + quoteColumns(pk, false) + "))"
+	sharded := make([]tableUnit, 0, len(units)*d.shards)
+	for _, u := range units {
+		for i := 0; i < d.shards; i++ {
+			u.shard = i + 1
+			u.where = fmt.Sprintf("%s %% %d = %d", hash, d.shards, i)
+			sharded = append(sharded, u)
+		}
+	}
+	return sharded, nil
+}
+
+// primaryKey returns the columns of a table's primary key in key order.
+func (d *Dumper) primaryKey(name string, schema string) ([]string, error) {
+	rows, err := d.db.QueryContext(d.context(), stmtPrimaryKey, name, schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cols []string
+	for rows.Next() {
+		var c string
+		if err = rows.Scan(&c); err != nil {
+			return nil, err
+		}
+		cols = append(cols, c)
+	}
+	return cols, rows.Err()
+}
+
+// andWhere adds cond to a table filter.
+func andWhere(filter string, cond string) string {
+	if cond == "" {
+		return filter
+	}
+	if filter == "" {
+		return " WHERE " + cond
+	}
+	if strings.HasPrefix(strings.ToUpper(filter), " WHERE ") {
+		return " WHERE (" + filter[len(" WHERE "):] + ") AND " + cond
+	}
+	return filter + " AND " + cond
+}
+
+// shardSkipped reports whether a section belongs to a shard other than the one being restored.
+func shardSkipped(t *marshal.TableHeader, shard int) bool {
+	return shard > 0 && t.Shard > 0 && t.Shard != shard
+}
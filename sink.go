@@ -0,0 +1,96 @@
+package mysqldump
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Sink is an output a dump is streamed to, such as an upload to object storage, given to NewDumper as
+// its writer. Close completes the output once the dump is done, Abort discards it instead, as after a
+// failed dump. Sinks buffer a part of the dump at a time, so dumps don't need local disk of their size.
+type Sink interface {
+	io.WriteCloser
+	Abort() error
+}
+
+// FileSink is a Sink writing a dump to a local file, removed by Abort.
+type FileSink struct {
+	*os.File
+}
+
+// NewFileSink creates the file a dump is written to.
+func NewFileSink(path string) (*FileSink, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{f}, nil
+}
+
+func (s *FileSink) Abort() error {
+	s.File.Close()
+	return os.Remove(s.Name())
+}
+
+// Upload requests are tried this many times, sinkRetryDelay times the attempt number apart, before the
+// upload is given up on.
+const (
+	sinkAttempts   = 3
+	sinkRetryDelay = time.Second
+)
+
+// sinkRequest builds a request of an upload, called again for every attempt.
+type sinkRequest func() (*http.Request, error)
+
+// do sends a request of an upload, trying it again after network errors, throttling and server errors.
+// It returns the response with its body read, or an error for other responses than the expected ones.
+func doSinkRequest(ctx context.Context, client *http.Client, build sinkRequest, expect ...int) (*http.Response, []byte, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		var req *http.Request
+		if req, err = build(); err != nil {
+			return nil, nil, err
+		}
+
+		var res *http.Response
+		res, err = client.Do(req.WithContext(ctx))
+		if err == nil {
+			body, rerr := ioutil.ReadAll(res.Body)
+			res.Body.Close()
+			for _, code := range expect {
+				if res.StatusCode == code && rerr == nil {
+					return res, body, nil
+				}
+			}
+			err = rerr
+			if err == nil {
+				err = fmt.Errorf("%s %s: %s: %s", req.Method, req.URL.Path, res.Status, bytes.TrimSpace(body))
+			}
+			if res.StatusCode < 500 && res.StatusCode != http.StatusTooManyRequests && res.StatusCode != http.StatusRequestTimeout {
+				return res, body, err
+			}
+		}
+		if attempt == sinkAttempts || ctx.Err() != nil {
+			return nil, nil, err
+		}
+
+		logrus.Warnf("Upload request failed, trying again: %s", err)
+		select {
+		case <-time.After(time.Duration(attempt) * sinkRetryDelay):
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package mysqldump
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/MouseHatGames/go-mysqldump/internal/marshal"
+)
+
+// WithTableFiles writes every table to a dump of its own, opened through open and closed once the table
+// is done, so tables can be restored on their own and processed downstream at once. The output keeps
+// the file header, the schema objects and the footer. Per-table files are in the binary format and can't
+// be compressed, encrypted or journaled.
+func WithTableFiles(open func(table string) (io.WriteCloser, error)) Option {
+	return func(d *Dumper) {
+		d.tableOpen = open
+	}
+}
+
+// WithTableDir writes every table to its own file in dir, named after the table with a .dump suffix,
+// see WithTableFiles. The directory is created with the first table. Tables whose names aren't file
+// names, such as those with slashes, fail the dump.
+func WithTableDir(dir string) Option {
+	return WithTableFiles(func(table string) (io.WriteCloser, error) {
+		path := filepath.Join(dir, table+".dump")
+		if strings.ContainsAny(table, "/"+string(filepath.Separator)) || filepath.Dir(path) != filepath.Clean(dir) {
+			return nil, fmt.Errorf("table %s has no file name in the table directory", table)
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+		return os.Create(path)
+	})
+}
+
+// SplitTables splits a dump into one dump per table, opened through open and closed once the table is
+// done, writing the file header, the schema objects and the footer to w. Every table dump has the file
+// header of the dump, with the filters of its table only, and a footer of its own. Tables of interleaved
+// dumps are written one after the other, their files staying open until the end of the dump.
+func SplitTables(in io.Reader, w io.Writer, open func(table string) (io.WriteCloser, error)) error {
+	r := marshal.NewReader(in)
+	h, err := r.ReadFileHeader()
+	if err != nil {
+		return fmt.Errorf("read file header: %w", err)
+	}
+	interleaved := h.Interleaved
+	h.Interleaved = false
+
+	out := marshal.NewWriter(w)
+	if err = out.WriteFileHeader(h); err != nil {
+		return err
+	}
+
+	s := &tableSplitter{header: h, open: open, files: make(map[string]*tableFile), closed: make(map[string]bool)}
+	defer s.abort()
+	for {
+		t, err := r.ReadTableHeader()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("read table header: %w", err)
+		}
+
+		if !interleaved && s.cur != nil && s.cur.name != t.Name {
+			if err = s.close(s.cur, false); err != nil {
+				return err
+			}
+		}
+		tf, err := s.table(t.Name)
+		if err != nil {
+			return err
+		}
+		if err = tf.copy(t, r); err != nil {
+			return fmt.Errorf("split table %s: %w", t.Name, err)
+		}
+	}
+
+	for {
+		o, err := r.ReadObject()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return err
+		}
+		if err = out.WriteObject(o); err != nil {
+			return err
+		}
+	}
+	footer, err := r.ReadFileFooter()
+	if err != nil {
+		return fmt.Errorf("read footer: %w", err)
+	}
+
+	// The tables still open are cut short along with an interrupted dump
+	partial := footer != nil && footer.Partial
+	for _, tf := range s.order {
+		if s.files[tf.name] != nil {
+			if err = s.close(tf, partial); err != nil {
+				return err
+			}
+		}
+	}
+	if footer == nil {
+		return nil
+	}
+	// The rows the checksums are of are in the files of the tables
+	main := *footer
+	main.Checksums, main.Checksum = nil, ""
+	return out.WriteFileFooter(&main)
+}
+
+// tableSplitter keeps the files of the tables of a dump being split.
+type tableSplitter struct {
+	header *marshal.FileHeader
+	open   func(table string) (io.WriteCloser, error)
+	files  map[string]*tableFile
+	order  []*tableFile
+	cur    *tableFile
+	// Tables of a dump that isn't interleaved whose files were closed
+	closed map[string]bool
+}
+
+// tableFile is the dump a table is split into.
+type tableFile struct {
+	name string
+	f    io.WriteCloser
+	w    *marshal.Writer
+	rows int64
+	sum  *marshal.Checksum
+}
+
+// table returns the file of a table, opening it and writing its file header the first time.
+func (s *tableSplitter) table(name string) (*tableFile, error) {
+	if tf := s.files[name]; tf != nil {
+		s.cur = tf
+		return tf, nil
+	}
+	if s.closed[name] {
+		return nil, fmt.Errorf("rows of table %s follow those of other tables", name)
+	}
+
+	f, err := s.open(name)
+	if err != nil {
+		return nil, fmt.Errorf("open output of %s: %w", name, err)
+	}
+	tf := &tableFile{name: name, f: f, w: marshal.NewWriter(f), sum: marshal.NewChecksum()}
+	s.files[name], s.cur = tf, tf
+	s.order = append(s.order, tf)
+
+	h := *s.header
+	h.TableFilters = nil
+	if filters, ok := s.header.TableFilters[name]; ok {
+		h.TableFilters = map[string][]string{name: filters}
+	}
+	if err = tf.w.WriteFileHeader(&h); err != nil {
+		return nil, fmt.Errorf("write output of %s: %w", name, err)
+	}
+	return tf, nil
+}
+
+// copy writes a section of the table with its rows.
+func (tf *tableFile) copy(t *marshal.TableHeader, r *marshal.Reader) error {
+	noData := t.NoData
+	if t.Restarted {
+		tf.rows, tf.sum = 0, marshal.NewChecksum()
+	}
+	if err := tf.w.WriteTableHeader(t); err != nil {
+		return err
+	}
+	if noData != nil {
+		return tf.w.WriteNoData(noData)
+	}
+
+	for {
+		row, err := r.ReadRow(len(t.Columns))
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err = tf.w.WriteRowData(row); err != nil {
+			return err
+		}
+		tf.sum.Add(row)
+		tf.rows++
+	}
+}
+
+// close ends the file of a table with a footer.
+func (s *tableSplitter) close(tf *tableFile, partial bool) error {
+	delete(s.files, tf.name)
+	s.closed[tf.name] = true
+	if s.cur == tf {
+		s.cur = nil
+	}
+
+	sum := tf.sum.String()
+	footer := &marshal.FileFooter{Partial: partial, DumpEnd: time.Now().UTC(), Tables: 1, Rows: tf.rows,
+		Checksums: map[string]string{tf.name: sum}, Checksum: dumpChecksum([]string{sum})}
+	err := tf.w.WriteFileFooter(footer)
+	if cerr := tf.f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("write output of %s: %w", tf.name, err)
+	}
+	return nil
+}
+
+// abort closes the files of the tables left open by a failed split.
+func (s *tableSplitter) abort() {
+	for _, tf := range s.files {
+		tf.f.Close()
+	}
+}
@@ -0,0 +1,31 @@
+package mysqldump
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTableDirNames(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "tables")
+	d := NewDumper(nil, nil, 0, WithTableDir(dir))
+
+	for _, table := range []string{"../../x", "a/b", "/etc/passwd"} {
+		if f, err := d.tableOpen(table); err == nil {
+			f.Close()
+			t.Errorf("opened a file for table %s", table)
+		}
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("created the table directory for tables without file names: %v", err)
+	}
+
+	f, err := d.tableOpen("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if _, err := os.Stat(filepath.Join(dir, "users.dump")); err != nil {
+		t.Error(err)
+	}
+}
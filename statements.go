@@ -0,0 +1,148 @@
+package mysqldump
+
+import "strings"
+
+// The statements the Dumper reads the server and the tables of a dump with, before reading any rows.
+// They are kept here so that each is spelled out in one place, and only depend on their arguments, so
+// tests running a Dumper against a mocked driver like sqlmock can expect them verbatim with
+// sqlmock.QueryMatcherEqual. Rows are read with the statements of tableQuery.chunk. Names are quoted
+// with quoteIdent, and values are passed as arguments.
+const (
+	stmtServerVersion       = "SELECT version()"
+	stmtLowerCaseTableNames = "SELECT @@lower_case_table_names"
+	stmtVersionComment      = "SELECT @@version_comment"
+	stmtMyRocks             = "SELECT SUPPORT FROM INFORMATION_SCHEMA.ENGINES WHERE ENGINE = 'ROCKSDB'"
+	stmtAuroraVersion       = "SELECT AURORA_VERSION()"
+	stmtBaseDir             = "SELECT @@basedir"
+	stmtMasterStatus        = "SHOW MASTER STATUS"
+	stmtBinaryLogStatus     = "SHOW BINARY LOG STATUS"
+
+	stmtListTables   = "SHOW TABLES where Tables_in_mygpstracker not like 'gs_tracker_data%';"
+	stmtListTablesPQ = "SELECT table_name FROM information_schema.tables WHERE table_schema='public' AND table_type='BASE TABLE' ORDER BY table_name;"
+	stmtListViews    = "SELECT TABLE_NAME FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'VIEW' ORDER BY TABLE_NAME"
+
+	stmtTableColumns   = "SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_NAME = ? AND TABLE_SCHEMA = ? ORDER BY ORDINAL_POSITION"
+	stmtTableColumnsPQ = "SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_NAME = $1 AND TABLE_SCHEMA = 'public' ORDER BY ORDINAL_POSITION"
+	stmtSelectColumns  = `SELECT COLUMN_NAME, EXTRA, COALESCE(GENERATION_EXPRESSION, '') FROM INFORMATION_SCHEMA.COLUMNS
+		WHERE TABLE_NAME = ? AND TABLE_SCHEMA = ? ORDER BY ORDINAL_POSITION`
+
+	stmtDDLIndexes = `SELECT INDEX_NAME, COLUMN_NAME, NON_UNIQUE, SUB_PART, INDEX_TYPE FROM INFORMATION_SCHEMA.STATISTICS
+		WHERE TABLE_NAME = ? AND TABLE_SCHEMA = ? ORDER BY INDEX_NAME, SEQ_IN_INDEX`
+	stmtDDLForeignKeys = `SELECT k.CONSTRAINT_NAME, k.COLUMN_NAME, k.REFERENCED_TABLE_SCHEMA, k.REFERENCED_TABLE_NAME, k.REFERENCED_COLUMN_NAME, r.UPDATE_RULE, r.DELETE_RULE
+		FROM INFORMATION_SCHEMA.KEY_COLUMN_USAGE k JOIN INFORMATION_SCHEMA.REFERENTIAL_CONSTRAINTS r
+		ON r.CONSTRAINT_SCHEMA = k.CONSTRAINT_SCHEMA AND r.CONSTRAINT_NAME = k.CONSTRAINT_NAME AND r.TABLE_NAME = k.TABLE_NAME
+		WHERE k.TABLE_SCHEMA = ? AND k.TABLE_NAME = ? ORDER BY k.CONSTRAINT_NAME, k.ORDINAL_POSITION`
+	stmtTableOptions = "SELECT TABLE_COLLATION, TABLE_COMMENT FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_NAME = ? AND TABLE_SCHEMA = ?"
+
+	stmtIndexColumns = `SELECT INDEX_NAME, COLUMN_NAME, NON_UNIQUE, NULLABLE FROM INFORMATION_SCHEMA.STATISTICS
+		WHERE TABLE_NAME = ? AND TABLE_SCHEMA = ? ORDER BY INDEX_NAME, SEQ_IN_INDEX`
+	stmtIndexColumnsPQ = `SELECT i.relname, COALESCE(a.attname, ''), ix.indisprimary, COALESCE(a.attnotnull, false)
+		FROM pg_index ix
+		JOIN pg_class t ON t.oid = ix.indrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		JOIN pg_class i ON i.oid = ix.indexrelid
+		JOIN LATERAL unnest(ix.indkey::int2[]) WITH ORDINALITY AS k(attnum, ord) ON true
+		LEFT JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum AND k.attnum > 0
+		WHERE n.nspname = 'public' AND t.relname = $1 AND ix.indisunique AND ix.indpred IS NULL
+		ORDER BY i.relname, k.ord`
+	stmtPrimaryKey = `SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.KEY_COLUMN_USAGE
+		WHERE TABLE_NAME = ? AND TABLE_SCHEMA = ? AND CONSTRAINT_NAME = 'PRIMARY' ORDER BY ORDINAL_POSITION`
+	// Subpartitions are read through the partition they belong to
+	stmtPartitions = `SELECT PARTITION_NAME FROM INFORMATION_SCHEMA.PARTITIONS
+		WHERE TABLE_NAME = ? AND TABLE_SCHEMA = ? AND PARTITION_NAME IS NOT NULL
+		GROUP BY PARTITION_NAME ORDER BY MIN(PARTITION_ORDINAL_POSITION)`
+
+	stmtTableStats = `SELECT n_rows, clustered_index_size, sum_of_other_index_sizes FROM mysql.innodb_table_stats
+		WHERE database_name = ? AND table_name = ?`
+	stmtIndexStats = `SELECT index_name, stat_name, stat_value, sample_size, stat_description FROM mysql.innodb_index_stats
+		WHERE database_name = ? AND table_name = ? ORDER BY index_name, stat_name`
+	stmtHistograms = "SELECT COLUMN_NAME, HISTOGRAM FROM INFORMATION_SCHEMA.COLUMN_STATISTICS WHERE SCHEMA_NAME = ? AND TABLE_NAME = ?"
+
+	stmtBinlogGTIDPos = "SELECT BINLOG_GTID_POS(?, ?)"
+)
+
+func stmtUseDatabase(db string) string {
+	return "USE " + quoteName(db)
+}
+
+// stmtShowCreate returns the SHOW CREATE statement of a table or sequence.
+func stmtShowCreate(typ string, name string) string {
+	if typ == TableTypeSequence {
+		return "SHOW CREATE SEQUENCE " + quoteName(name)
+	}
+	return "SHOW CREATE TABLE " + quoteName(name)
+}
+
+// stmtDDLColumns returns the query reading the column definitions of a table, with the expressions of
+// generated columns if the server has them.
+func stmtDDLColumns(generated bool) string {
+	gen := "''"
+	if generated {
+		gen = "COALESCE(GENERATION_EXPRESSION, '')"
+	}
+	return `SELECT COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE, COLUMN_DEFAULT, EXTRA, ` + gen + `, COLLATION_NAME, COLUMN_COMMENT
+		FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_NAME = ? AND TABLE_SCHEMA = ? ORDER BY ORDINAL_POSITION`
+}
+
+// stmtColumnTypes returns a query reading the column types of a table without fetching any rows.
+func stmtColumnTypes(table string, pq bool) string {
+	return "SELECT * FROM " + quoteIdent(table, pq) + " LIMIT 0"
+}
+
+// stmtCheckFilter returns a query running a table filter without fetching any rows.
+func stmtCheckFilter(table string, filter string, pq bool) string {
+	return "SELECT * FROM " + quoteIdent(table, pq) + filter + " LIMIT 0"
+}
+
+// stmtShowColumns returns the SHOW COLUMNS statement of a table, of the current database if schema is empty.
+func stmtShowColumns(table string, schema string) string {
+	q := "SHOW COLUMNS FROM " + quoteName(table)
+	if schema != "" {
+		q += " FROM " + quoteName(schema)
+	}
+	return q
+}
+
+// stmtShowIndex returns the SHOW INDEX statement of a table, see stmtShowColumns.
+func stmtShowIndex(table string, schema string) string {
+	q := "SHOW INDEX FROM " + quoteName(table)
+	if schema != "" {
+		q += " FROM " + quoteName(schema)
+	}
+	return q
+}
+
+// stmtShowTableStatus returns the SHOW TABLE STATUS statement of the tables of a schema, see
+// stmtShowColumns, whose name is LIKE its argument, see likeName.
+func stmtShowTableStatus(schema string) string {
+	q := "SHOW TABLE STATUS"
+	if schema != "" {
+		q += " FROM " + quoteName(schema)
+	}
+	return q + " LIKE ?"
+}
+
+// likeName returns the LIKE pattern matching a name literally.
+func likeName(name string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(name)
+}
+
+// stmtNoRows returns a query of the columns sel of the rows from, without fetching any.
+func stmtNoRows(sel string, from string) string {
+	return "SELECT " + sel + " FROM " + from + " LIMIT 0"
+}
+
+// stmtKeyRange returns the query counting rows and reading the lowest and highest key among them.
+func stmtKeyRange(key string, rows string) string {
+	return "SELECT COUNT(*), MIN(" + key + "), MAX(" + key + ") FROM " + rows
+}
+
+// stmtChunkBoundaries returns the query reading the key of every chunkSize-th row in order, numbering
+// the rows in a single scan. The chunk size is its argument.
+func stmtChunkBoundaries(key string, order string, rows string, pq bool) string {
+	n := "?"
+	if pq {
+		n = "$1"
+	}
+	return "SELECT " + key + " FROM (SELECT " + key + ", ROW_NUMBER() OVER (ORDER BY " + order + ") AS n FROM " + rows + ") b WHERE MOD(n - 1, " + n + ") = 0 ORDER BY n"
+}
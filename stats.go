@@ -0,0 +1,210 @@
+package mysqldump
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	binary "github.com/MouseHatGames/go-mysqldump/internal/marshal"
+	"github.com/sirupsen/logrus"
+)
+
+// WithOptimizerStats saves the persistent statistics of every InnoDB table, read from
+// mysql.innodb_table_stats and mysql.innodb_index_stats, in its header along with the column
+// histograms of MySQL 8, so restoring with LoaderOptions.Stats gives the copy the same row estimates,
+// index cardinalities and query plans. Partitioned tables, whose statistics are kept per partition,
+// are left out. Not supported on PostgreSQL and TiDB.
+func WithOptimizerStats() Option {
+	return func(d *Dumper) {
+		d.optimizerStats = true
+	}
+}
+
+// getOptimizerStats returns the persistent statistics of a table, nil if it has none.
+func (d *Dumper) getOptimizerStats(name string, schema string, engine string) (*binary.TableStats, error) {
+	if !d.optimizerStats || d.isPQ() || d.isTiDB() || !strings.EqualFold(engine, "InnoDB") {
+		return nil, nil
+	}
+
+	st := &binary.TableStats{}
+	err := d.db.QueryRowContext(d.context(), stmtTableStats, schema, name).Scan(&st.Rows, &st.ClusteredIndexSize, &st.SumOfOtherIndexSizes)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := d.db.QueryContext(d.context(), stmtIndexStats, schema, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s binary.IndexStat
+		var sample sql.NullInt64
+		if err = rows.Scan(&s.Index, &s.Name, &s.Value, &sample, &s.Description); err != nil {
+			return nil, err
+		}
+		if sample.Valid {
+			s.SampleSize = &sample.Int64
+		}
+		st.Indexes = append(st.Indexes, s)
+	}
+	return st, rows.Err()
+}
+
+// getHistograms returns the column histograms of a table, nil if it has none.
+func (d *Dumper) getHistograms(name string, schema string) (map[string]string, error) {
+	if s, _ := d.server(); !d.optimizerStats || s.flavor != flavorMySQL || !s.supports(featureHistograms) {
+		return nil, nil
+	}
+
+	rows, err := d.db.QueryContext(d.context(), stmtHistograms, schema, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hs map[string]string
+	for rows.Next() {
+		var col, h string
+		if err = rows.Scan(&col, &h); err != nil {
+			return nil, err
+		}
+		if hs == nil {
+			hs = make(map[string]string)
+		}
+		hs[col] = h
+	}
+	return hs, rows.Err()
+}
+
+// readOptimizerStats sets the statistics in a table header, giving up on them for the rest of the dump if they can't be read.
+func (d *Dumper) readOptimizerStats(header *binary.TableHeader, schema string) {
+	st, err := d.getOptimizerStats(header.Name, schema, header.Engine)
+	if err == nil {
+		header.Histograms, err = d.getHistograms(header.Name, schema)
+	}
+	if err != nil {
+		d.warn(WarningMetadata, "", "Can't read optimizer statistics, dumping without them: %s", err)
+		d.optimizerStats = false
+		header.Stats, header.Histograms = nil, nil
+		return
+	}
+	header.Stats = st
+}
+
+// statsStatements returns the statements writing a table's persistent statistics into the current
+// database, followed by FLUSH TABLE so InnoDB reloads them.
+func statsStatements(table string, st *binary.TableStats) []string {
+	var b strings.Builder
+	b.WriteString("REPLACE INTO mysql.innodb_table_stats (database_name, table_name, last_update, n_rows, clustered_index_size, sum_of_other_index_sizes) VALUES (DATABASE(), '")
+	writeEscapedString(&b, table)
+	fmt.Fprintf(&b, "', NOW(), %d, %d, %d)", st.Rows, st.ClusteredIndexSize, st.SumOfOtherIndexSizes)
+	qs := []string{b.String()}
+
+	if len(st.Indexes) > 0 {
+		b.Reset()
+		b.WriteString("REPLACE INTO mysql.innodb_index_stats (database_name, table_name, index_name, last_update, stat_name, stat_value, sample_size, stat_description) VALUES ")
+		for i, s := range st.Indexes {
+			if i > 0 {
+				b.Write(comma)
+			}
+			sample := "NULL"
+			if s.SampleSize != nil {
+				sample = fmt.Sprint(*s.SampleSize)
+			}
+			b.WriteString("(DATABASE(), '")
+			writeEscapedString(&b, table)
+			b.WriteString("', '")
+			writeEscapedString(&b, s.Index)
+			b.WriteString("', NOW(), '")
+			writeEscapedString(&b, s.Name)
+			fmt.Fprintf(&b, "', %d, %s, '", s.Value, sample)
+			writeEscapedString(&b, s.Description)
+			b.WriteString("')")
+		}
+		qs = append(qs, b.String())
+	}
+
+	return append(qs, "FLUSH TABLE "+quoteName(table))
+}
+
+// histogramStatements returns the statements recreating a table's column histograms. Servers before
+// MySQL 8.0.31 can't load a histogram, so it is rebuilt from the restored rows with the same number of buckets.
+func histogramStatements(table string, hs map[string]string, loadData bool) []string {
+	cols := make([]string, 0, len(hs))
+	for c := range hs {
+		cols = append(cols, c)
+	}
+	sort.Strings(cols)
+
+	var qs []string
+	for _, c := range cols {
+		prefix := "ANALYZE TABLE " + quoteName(table) + " UPDATE HISTOGRAM ON " + quoteName(c) + " "
+		if loadData {
+			var b strings.Builder
+			b.WriteString(prefix + "USING DATA '")
+			writeEscapedString(&b, hs[c])
+			b.WriteString("'")
+			qs = append(qs, b.String())
+			continue
+		}
+
+		var h struct {
+			Buckets int `json:"number-of-buckets-specified"`
+		}
+		if err := json.Unmarshal([]byte(hs[c]), &h); err != nil || h.Buckets <= 0 {
+			h.Buckets = 100
+		}
+		qs = append(qs, fmt.Sprintf("%sWITH %d BUCKETS", prefix, h.Buckets))
+	}
+	return qs
+}
+
+// restoreStats restores the statistics of the tables loaded, or analyzes the ones the dump has none for.
+// ANALYZE TABLE reports its errors as rows, so histograms that can't be created are only missing afterwards.
+func (l *Loader) restoreStats(e *executor) error {
+	if err := e.wait(); err != nil {
+		return err
+	}
+
+	loadData := l.target != nil && l.target.supports(featureHistogramData)
+	for _, name := range l.report.Tables {
+		if hs := l.histograms[name]; len(hs) > 0 {
+			if err := execAll(e, histogramStatements(name, hs, loadData)); err != nil {
+				return fmt.Errorf("restore histograms of %s: %w", name, err)
+			}
+		}
+
+		if st := l.stats[name]; st != nil {
+			err := execAll(e, statsStatements(name, st))
+			if err == nil {
+				logrus.Infof("Restored optimizer statistics of %s", name)
+				continue
+			}
+			if !isPermissionError(err) {
+				return fmt.Errorf("restore statistics of %s: %w", name, err)
+			}
+			logrus.Warnf("Can't restore the statistics of %s, analyzing it instead: %s", name, err)
+		}
+
+		if err := e.execNow("ANALYZE TABLE " + quoteName(name)); err != nil {
+			return fmt.Errorf("analyze table %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func execAll(e *executor, qs []string) error {
+	for _, q := range qs {
+		if err := e.execNow(q); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package mysqldump
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// WithIncludeTables has DumpAllTables dump only the tables matching any of the patterns, e.g.
+// "orders_*". Patterns are those of path.Match, and compare names like the server does.
+func WithIncludeTables(patterns ...string) Option {
+	return func(d *Dumper) {
+		d.includeTables = patterns
+	}
+}
+
+// WithExcludeTables has DumpAllTables leave out the tables matching any of the patterns, e.g.
+// "rate_limit_*" or "sessions", such as log and cache tables. Exclusions apply after WithIncludeTables.
+func WithExcludeTables(patterns ...string) Option {
+	return func(d *Dumper) {
+		d.excludeTables = patterns
+	}
+}
+
+// matchTables returns the tables DumpAllTables dumps, by the include and exclude patterns.
+func (d *Dumper) matchTables(tables []string) ([]string, error) {
+	if len(d.includeTables) == 0 && len(d.excludeTables) == 0 {
+		return tables, nil
+	}
+
+	matched := make([]string, 0, len(tables))
+	for _, t := range tables {
+		include, err := d.matchesTable(d.includeTables, t)
+		if err != nil {
+			return nil, err
+		}
+		exclude, err := d.matchesTable(d.excludeTables, t)
+		if err != nil {
+			return nil, err
+		}
+		if (len(d.includeTables) > 0 && !include) || exclude {
+			logrus.Infof("Skipping table %s", t)
+			continue
+		}
+		matched = append(matched, t)
+	}
+	return matched, nil
+}
+
+// matchesTable reports whether a table name matches any of the patterns.
+func (d *Dumper) matchesTable(patterns []string, name string) (bool, error) {
+	fold := false
+	if len(patterns) > 0 {
+		s, err := d.server()
+		fold = err == nil && s.lowerCaseTableNames != 0
+	}
+
+	for _, p := range patterns {
+		if fold {
+			p, name = strings.ToLower(p), strings.ToLower(name)
+		}
+		ok, err := path.Match(p, name)
+		if err != nil {
+			return false, fmt.Errorf("table pattern %q: %w", p, err)
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,144 @@
+package mysqldump
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ThrottleWindow is a time of day range in which values are read from the server at another rate than
+// the default of a ThrottleSchedule.
+type ThrottleWindow struct {
+	// Minutes since midnight. The window wraps around midnight if End is before Start
+	Start int
+	End   int
+	// Bytes per second, no limit if 0
+	BytesPerSecond int64
+}
+
+// ParseTimeWindow parses a time of day range such as "02:00-05:00" into minutes since midnight.
+func ParseTimeWindow(s string) (start int, end int, err error) {
+	i := strings.Index(s, "-")
+	if i < 0 {
+		return 0, 0, fmt.Errorf("invalid time window %q, expected HH:MM-HH:MM", s)
+	}
+	if start, err = parseTimeOfDay(s[:i]); err == nil {
+		end, err = parseTimeOfDay(s[i+1:])
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid time window %q: %w", s, err)
+	}
+	return start, end, nil
+}
+
+func parseTimeOfDay(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	i := strings.Index(s, ":")
+	if i < 0 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	h, herr := strconv.Atoi(s[:i])
+	m, merr := strconv.Atoi(s[i+1:])
+	if herr != nil || merr != nil || h < 0 || h > 24 || m < 0 || m > 59 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return h*60 + m, nil
+}
+
+// contains reports whether a time of day, in minutes since midnight, is in the window.
+func (w ThrottleWindow) contains(minute int) bool {
+	if w.Start <= w.End {
+		return minute >= w.Start && minute < w.End
+	}
+	return minute >= w.Start || minute < w.End
+}
+
+// ThrottleSchedule is the rate values are read from the server at by time of day, see WithThrottle.
+type ThrottleSchedule struct {
+	// Bytes per second outside of the windows, no limit if 0
+	BytesPerSecond int64
+	// The first window a time is in sets the rate
+	Windows []ThrottleWindow
+	// Location of the times of the windows, time.Local if nil
+	Location *time.Location
+}
+
+// Rate returns the bytes per second values are read at, at t. No limit if 0.
+func (s ThrottleSchedule) Rate(t time.Time) int64 {
+	if s.Location != nil {
+		t = t.In(s.Location)
+	}
+	minute := t.Hour()*60 + t.Minute()
+	for _, w := range s.Windows {
+		if w.contains(minute) {
+			return w.BytesPerSecond
+		}
+	}
+	return s.BytesPerSecond
+}
+
+// WithThrottle limits the rate values are read from the server at, as counted by Bandwidth.ReadBytes,
+// by the time of day. The rate is evaluated as the dump runs, so a long dump started at night goes
+// unthrottled in its window and slows down to spare the server once business hours start.
+func WithThrottle(s ThrottleSchedule) Option {
+	return func(d *Dumper) {
+		d.throttle = &throttle{schedule: s, rate: -1}
+	}
+}
+
+// throttle paces the values read by a dump. Prefetched chunks are read concurrently.
+type throttle struct {
+	schedule ThrottleSchedule
+
+	mu sync.Mutex
+	// Time at which the bytes read so far are within the rate
+	next time.Time
+	// Rate of the last bytes read, to log changes
+	rate int64
+}
+
+// throttleRead blocks until n more bytes read are within the rate of the schedule, or the dump is cancelled.
+func (d *Dumper) throttleRead(n int64) {
+	t := d.throttle
+	if t == nil || n == 0 {
+		return
+	}
+
+	t.mu.Lock()
+	now := time.Now()
+	rate := t.schedule.Rate(now)
+	if rate != t.rate {
+		if rate <= 0 {
+			logrus.Infof("Reading unthrottled")
+		} else {
+			logrus.Infof("Throttling reads to %d bytes per second", rate)
+		}
+		t.rate = rate
+	}
+	if rate <= 0 {
+		t.next = now
+		t.mu.Unlock()
+		return
+	}
+	// Time not spent reading isn't saved up for a burst later
+	if t.next.Before(now) {
+		t.next = now
+	}
+	t.next = t.next.Add(time.Duration(float64(n) / float64(rate) * float64(time.Second)))
+	delay := t.next.Sub(now)
+	t.mu.Unlock()
+
+	if delay <= 0 {
+		return
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-d.context().Done():
+	}
+}
@@ -0,0 +1,95 @@
+package mysqldump
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestParseTimeWindow(t *testing.T) {
+	start, end, err := ParseTimeWindow("22:30-05:00")
+	if err != nil || start != 22*60+30 || end != 5*60 {
+		t.Errorf("parsed %d-%d with %v, want %d-%d", start, end, err, 22*60+30, 5*60)
+	}
+	if _, end, err = ParseTimeWindow("18:00-24:00"); err != nil || end != 24*60 {
+		t.Errorf("parsed the end of the day as %d with %v", end, err)
+	}
+	for _, s := range []string{"", "02:00", "2-5", "02:00-25:00", "24:30-01:00", "02:60-03:00", "a:00-03:00"} {
+		if _, _, err := ParseTimeWindow(s); err == nil {
+			t.Errorf("parsed %q", s)
+		}
+	}
+}
+
+func TestThrottleScheduleRate(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	s := ThrottleSchedule{
+		BytesPerSecond: 1000,
+		Windows: []ThrottleWindow{
+			{Start: 9 * 60, End: 17 * 60, BytesPerSecond: 100},
+			// Wraps around midnight
+			{Start: 22 * 60, End: 6 * 60},
+			// Shadowed by the first window
+			{Start: 12 * 60, End: 13 * 60, BytesPerSecond: 5},
+		},
+		Location: ny,
+	}
+	tests := []struct {
+		hour, minute int
+		want         int64
+	}{
+		{8, 59, 1000},
+		{9, 0, 100},
+		{12, 30, 100},
+		{16, 59, 100},
+		{17, 0, 1000},
+		{23, 0, 0},
+		{0, 0, 0},
+		{5, 59, 0},
+		{6, 0, 1000},
+	}
+	for _, tt := range tests {
+		// Times of the windows are in their location
+		at := time.Date(2025, time.March, 3, tt.hour, tt.minute, 0, 0, ny).UTC()
+		if got := s.Rate(at); got != tt.want {
+			t.Errorf("rate at %02d:%02d = %d, want %d", tt.hour, tt.minute, got, tt.want)
+		}
+	}
+}
+
+func TestThrottleRead(t *testing.T) {
+	d := NewDumper(nil, ioutil.Discard, 0, WithThrottle(ThrottleSchedule{BytesPerSecond: 1 << 20}))
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		d.throttleRead(64 << 10)
+	}
+	// A quarter of a megabyte at a megabyte per second
+	if elapsed := time.Since(start); elapsed < 240*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("read in %s, want 250ms", elapsed)
+	}
+
+	// Cancelled dumps don't wait
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	d.ctx = ctx
+	start = time.Now()
+	d.throttleRead(10 << 20)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("cancelled read waited %s", elapsed)
+	}
+
+	// Unthrottled windows don't wait either
+	d = NewDumper(nil, ioutil.Discard, 0, WithThrottle(ThrottleSchedule{
+		BytesPerSecond: 1,
+		Windows:        []ThrottleWindow{{Start: 0, End: 24 * 60}},
+	}))
+	start = time.Now()
+	d.throttleRead(10 << 20)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("unthrottled read waited %s", elapsed)
+	}
+}
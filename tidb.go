@@ -0,0 +1,61 @@
+package mysqldump
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// startSnapshot pins a connection reading at the current TiDB timestamp through tidb_snapshot,
+// so every table is read from the same point in time. It returns the TSO of the snapshot.
+func (d *Dumper) startSnapshot(dbName string) (string, error) {
+	// TiDB reports the current TSO as the binlog position
+	status, err := d.queryRowMap(stmtMasterStatus)
+	if err != nil {
+		return "", fmt.Errorf("get current TSO: %w", err)
+	}
+	tso := status["Position"]
+	if _, err = strconv.ParseUint(tso, 10, 64); err != nil {
+		return "", fmt.Errorf("invalid TSO %q", tso)
+	}
+
+	if err = d.startConn(dbName, "SET @@tidb_snapshot = "+tso); err != nil {
+		return "", fmt.Errorf("set up snapshot: %w", err)
+	}
+
+	logrus.Infof("Reading TiDB snapshot at TSO %s", tso)
+	return tso, nil
+}
+
+func (d *Dumper) isTiDB() bool {
+	s, err := d.server()
+	return err == nil && s.flavor == flavorTiDB
+}
+
+// tidbExplainRows returns the estimated row count of a TiDB plan, which is on its root operator.
+func tidbExplainRows(cols []string, rows *sql.Rows) (int64, error) {
+	idx := -1
+	for i, c := range cols {
+		if strings.EqualFold(c, "estRows") || strings.EqualFold(c, "count") {
+			idx = i
+		}
+	}
+	if idx < 0 || !rows.Next() {
+		return 0, rows.Err()
+	}
+
+	data := make([]sql.NullString, len(cols))
+	ptrs := make([]interface{}, len(cols))
+	for i := range data {
+		ptrs[i] = &data[i]
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		return 0, err
+	}
+
+	n, _ := strconv.ParseFloat(data[idx].String, 64)
+	return int64(n), nil
+}
@@ -0,0 +1,37 @@
+package mysqldump
+
+import "fmt"
+
+// WithSingleTransaction reads every table through a single connection within one REPEATABLE READ
+// transaction, started with START TRANSACTION WITH CONSISTENT SNAPSHOT, so the tables of the dump are
+// all as they were at the same point in time. Tables are then read one at a time, see WithParallelTables.
+// Managed servers, TiDB and Vitess already read from a single snapshot.
+func WithSingleTransaction() Option {
+	return func(d *Dumper) {
+		d.singleTransaction = true
+	}
+}
+
+// WithBinlogPosition records the binary log coordinates and GTID set the data was read at in the file
+// header, so the dump can seed a replica. Tables are then read from a single consistent snapshot, with
+// FLUSH TABLES WITH READ LOCK held while the snapshot starts so the position matches it exactly, see
+// BinlogPosition.Exact. Only MySQL and MariaDB have a binary log.
+func WithBinlogPosition() Option {
+	return func(d *Dumper) {
+		d.recordBinlog = true
+	}
+}
+
+// startSingleTransaction pins the connection tables are read through to a single transaction.
+func (d *Dumper) startSingleTransaction(dbName string) error {
+	var err error
+	if d.isPQ() {
+		err = d.startConn("", "BEGIN TRANSACTION ISOLATION LEVEL REPEATABLE READ READ ONLY")
+	} else {
+		err = d.startConn(dbName, "SET SESSION TRANSACTION ISOLATION LEVEL REPEATABLE READ", "START TRANSACTION WITH CONSISTENT SNAPSHOT")
+	}
+	if err != nil {
+		return fmt.Errorf("start transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package mysqldump
+
+// RowTransformer can modify rows before they are written, e.g. to mask personal data.
+// columns holds the names of the table's columns in the same order as row.
+type RowTransformer func(table string, columns []string, row []*string) []*string
+
+// WithRowTransformer makes the dumper pass every row through fn before writing it, after the row rules
+// checked it and before the value guards do. Transformers given more than once run in the order they were given, so
+// the masking of a job config and that of a caller both apply.
+func WithRowTransformer(fn RowTransformer) Option {
+	return func(d *Dumper) {
+		prev := d.transform
+		if prev == nil {
+			d.transform = fn
+			return
+		}
+		d.transform = func(table string, columns []string, row []*string) []*string {
+			return fn(table, columns, prev(table, columns, row))
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package mysqldump
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DDLTransform rewrites the CREATE statement of a table, e.g. to restore it on an older server.
+type DDLTransform func(ddl string) string
+
+// DDLTransforms are the named transforms that can be applied when dumping, restoring or converting.
+var DDLTransforms = map[string]DDLTransform{
+	// MySQL 8 collations, left out so columns and tables get the default collation of their charset
+	"strip_0900_collations": stripCollations,
+	// Every charset and collation changed to utf8mb4 and utf8mb4_general_ci, except binary ones
+	"utf8mb4_general_ci": toGeneralCI,
+	// CHECK constraints, which MySQL only enforces since 8.0.16
+	"strip_check_constraints": stripCheckConstraints,
+	// Percona Server column compression, see stripPercona
+	"strip_percona": stripPercona,
+	// DEFINER clauses of views, triggers, routines and events, which fail to restore if the user is missing
+	"strip_definer": stripDefiner,
+	// The same clauses pointed at the user restoring the dump
+	"definer_current_user": definerCurrentUser,
+}
+
+// ParseDDLTransforms returns the transforms with the given names.
+func ParseDDLTransforms(names []string) ([]DDLTransform, error) {
+	var ts []DDLTransform
+	for _, n := range names {
+		t, ok := DDLTransforms[strings.ToLower(n)]
+		if !ok {
+			return nil, fmt.Errorf("unknown DDL transform %s, expected one of %s", n, strings.Join(ddlTransformNames(), ", "))
+		}
+		ts = append(ts, t)
+	}
+	return ts, nil
+}
+
+func ddlTransformNames() []string {
+	names := make([]string, 0, len(DDLTransforms))
+	for n := range DDLTransforms {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WithDDLTransforms rewrites the CREATE statements of the dumped tables.
+func WithDDLTransforms(ts ...DDLTransform) Option {
+	return func(d *Dumper) {
+		d.ddlTransforms = ts
+	}
+}
+
+// applyDDL runs a statement through every transform in order.
+func applyDDL(ddl string, ts []DDLTransform) string {
+	for _, t := range ts {
+		ddl = t(ddl)
+	}
+	return ddl
+}
+
+var collation0900 = regexp.MustCompile(`(?i)\s*(?:DEFAULT\s+)?COLLATE\s*=?\s*utf8mb4_0900_\w+`)
+
+func stripCollations(ddl string) string {
+	return collation0900.ReplaceAllString(ddl, "")
+}
+
+var (
+	charsetClause   = regexp.MustCompile(`(?i)\b(CHARACTER\s+SET|CHARSET)(\s*=?\s*)(\w+)`)
+	collationClause = regexp.MustCompile(`(?i)\b(COLLATE)(\s*=?\s*)(\w+)`)
+)
+
+func toGeneralCI(ddl string) string {
+	replace := func(re *regexp.Regexp, to string) {
+		ddl = re.ReplaceAllStringFunc(ddl, func(m string) string {
+			sub := re.FindStringSubmatch(m)
+			if strings.EqualFold(sub[3], "binary") || strings.HasSuffix(strings.ToLower(sub[3]), "_bin") {
+				return m
+			}
+			return sub[1] + sub[2] + to
+		})
+	}
+	replace(charsetClause, "utf8mb4")
+	replace(collationClause, "utf8mb4_general_ci")
+	return ddl
+}
+
+var checkConstraint = regexp.MustCompile("(?i),\\s*(?:CONSTRAINT\\s+(?:`[^`]*`|\\w+)\\s+)?CHECK\\s*\\(")
+
+func stripCheckConstraints(ddl string) string {
+	for {
+		loc := checkConstraint.FindStringIndex(ddl)
+		if loc == nil {
+			return ddl
+		}
+
+		// Find the parenthesis closing the condition, skipping over quoted strings
+		end, depth := loc[1], 1
+		var quote byte
+		for ; end < len(ddl) && depth > 0; end++ {
+			c := ddl[end]
+			switch {
+			case quote != 0:
+				if c == '\\' {
+					end++
+				} else if c == quote {
+					quote = 0
+				}
+			case c == '\'' || c == '"' || c == '`':
+				quote = c
+			case c == '(':
+				depth++
+			case c == ')':
+				depth--
+			}
+		}
+
+		// MySQL adds whether it is enforced in a version comment
+		rest := strings.TrimLeft(ddl[end:], " ")
+		if strings.HasPrefix(rest, "/*!") {
+			if i := strings.Index(rest, "*/"); i >= 0 && strings.Contains(strings.ToUpper(rest[:i]), "ENFORCED") {
+				end = len(ddl) - len(rest) + i + 2
+			}
+		}
+
+		ddl = ddl[:loc[0]] + ddl[end:]
+	}
+}
+
+var definerClause = regexp.MustCompile("(?i)\\s*\\bDEFINER\\s*=\\s*(?:CURRENT_USER(?:\\s*\\(\\s*\\))?|(?:`[^`]*`|'[^']*'|[\\w.$-]+)\\s*@\\s*(?:`[^`]*`|'[^']*'|[\\w.%:-]+))")
+
+// replaceDefiner replaces the DEFINER clause of a statement, leaving clauses like SQL SECURITY DEFINER
+// alone. Only the first one is replaced, later ones being part of the body.
+func replaceDefiner(ddl string, to string) string {
+	loc := definerClause.FindStringIndex(ddl)
+	if loc == nil {
+		return ddl
+	}
+	return ddl[:loc[0]] + to + ddl[loc[1]:]
+}
+
+func stripDefiner(ddl string) string {
+	return replaceDefiner(ddl, "")
+}
+
+func definerCurrentUser(ddl string) string {
+	return replaceDefiner(ddl, " DEFINER=CURRENT_USER")
+}
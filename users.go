@@ -0,0 +1,221 @@
+package mysqldump
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// UsersOptions controls how DumpUsers writes accounts.
+type UsersOptions struct {
+	// If set, every user is created with this password, expired so it has to be changed at the first login
+	ResetPassword string
+	// Accounts to leave out as user@host, on top of the server's reserved accounts
+	Exclude []string
+}
+
+// Accounts the server creates itself, which already exist wherever the dump is restored
+var reservedAccounts = map[string]bool{
+	"mysql.sys@localhost":        true,
+	"mysql.session@localhost":    true,
+	"mysql.infoschema@localhost": true,
+	"rdsadmin@localhost":         true,
+	"mariadb.sys@localhost":      true,
+}
+
+type account struct {
+	user string
+	host string
+}
+
+func (a account) String() string {
+	return "'" + strings.ReplaceAll(a.user, "'", "''") + "'@'" + strings.ReplaceAll(a.host, "'", "''") + "'"
+}
+
+// DumpUsers writes the SQL statements recreating the server's users and roles, with their grants and
+// default roles. Authentication plugins and their password hashes are kept, written in hex where the
+// server supports it since caching_sha2_password hashes aren't printable.
+func (d *Dumper) DumpUsers(w io.Writer, opt UsersOptions) error {
+	return d.DumpUsersContext(context.Background(), w, opt)
+}
+
+// DumpUsersContext is DumpUsers, running every query under ctx.
+func (d *Dumper) DumpUsersContext(ctx context.Context, w io.Writer, opt UsersOptions) error {
+	defer d.withContext(ctx)()
+	if d.isPQ() {
+		return fmt.Errorf("dumping users is only supported on MySQL")
+	}
+
+	s, err := d.server()
+	if err != nil {
+		return fmt.Errorf("get server version: %w", err)
+	}
+
+	// The hex setting only applies to the session, so everything runs on the same connection
+	conn, err := d.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("open connection: %w", err)
+	}
+	defer conn.Close()
+
+	if s.flavor == flavorMySQL && s.atLeast(8, 0, 17) {
+		if _, err = conn.ExecContext(ctx, "SET SESSION print_identified_with_as_hex = ON"); err != nil {
+			return fmt.Errorf("set up session: %w", err)
+		}
+	}
+
+	exclude := make(map[string]bool, len(opt.Exclude))
+	for _, a := range opt.Exclude {
+		exclude[a] = true
+	}
+
+	accounts, err := queryAccounts(ctx, conn, "SELECT User, Host FROM mysql.user ORDER BY User, Host")
+	if err != nil {
+		return fmt.Errorf("list users: %w", err)
+	}
+	roles := make(map[account]bool)
+	if s.flavor == flavorMySQL && s.supports(featureRoles) {
+		rs, err := queryAccounts(ctx, conn, "SELECT DISTINCT FROM_USER, FROM_HOST FROM mysql.role_edges")
+		if err != nil {
+			return fmt.Errorf("list roles: %w", err)
+		}
+		for _, r := range rs {
+			roles[r] = true
+		}
+	}
+
+	var users []account
+	for _, a := range accounts {
+		name := a.user + "@" + a.host
+		if !reservedAccounts[name] && !exclude[name] {
+			users = append(users, a)
+		}
+	}
+
+	fmt.Fprintf(w, "-- Users and roles of %s\n\n", s.version)
+
+	// Roles have to exist before they are granted to users
+	for _, a := range users {
+		if roles[a] {
+			fmt.Fprintf(w, "CREATE ROLE IF NOT EXISTS %s;\n", a)
+		}
+	}
+	for _, a := range users {
+		if roles[a] {
+			continue
+		}
+		q, err := d.createUser(ctx, conn, a, opt)
+		if err != nil {
+			return fmt.Errorf("create user %s: %w", a, err)
+		}
+		fmt.Fprintf(w, "%s;\n", q)
+	}
+
+	// Grant the roles their privileges before they are handed out
+	for _, first := range []bool{true, false} {
+		for _, a := range users {
+			if roles[a] != first {
+				continue
+			}
+			grants, err := queryStrings(ctx, conn, "SHOW GRANTS FOR "+a.String())
+			if err != nil {
+				return fmt.Errorf("show grants for %s: %w", a, err)
+			}
+			for _, g := range grants {
+				fmt.Fprintf(w, "%s;\n", g)
+			}
+		}
+	}
+
+	if len(roles) > 0 {
+		rows, err := conn.QueryContext(ctx, "SELECT USER, HOST, DEFAULT_ROLE_USER, DEFAULT_ROLE_HOST FROM mysql.default_roles ORDER BY USER, HOST")
+		if err != nil {
+			return fmt.Errorf("list default roles: %w", err)
+		}
+		defer rows.Close()
+
+		defaults := make(map[account][]string)
+		var order []account
+		for rows.Next() {
+			var u, r account
+			if err = rows.Scan(&u.user, &u.host, &r.user, &r.host); err != nil {
+				return err
+			}
+			if reservedAccounts[u.user+"@"+u.host] || exclude[u.user+"@"+u.host] {
+				continue
+			}
+			if defaults[u] == nil {
+				order = append(order, u)
+			}
+			defaults[u] = append(defaults[u], r.String())
+		}
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		for _, u := range order {
+			fmt.Fprintf(w, "SET DEFAULT ROLE %s TO %s;\n", strings.Join(defaults[u], ", "), u)
+		}
+	}
+
+	return nil
+}
+
+// createUser returns the statement creating an account with its authentication and account settings.
+func (d *Dumper) createUser(ctx context.Context, conn *sql.Conn, a account, opt UsersOptions) (string, error) {
+	if opt.ResetPassword != "" {
+		var b strings.Builder
+		fmt.Fprintf(&b, "CREATE USER IF NOT EXISTS %s IDENTIFIED BY '", a)
+		writeEscapedString(&b, opt.ResetPassword)
+		b.WriteString("' PASSWORD EXPIRE")
+		return b.String(), nil
+	}
+
+	qs, err := queryStrings(ctx, conn, "SHOW CREATE USER "+a.String())
+	if err != nil {
+		return "", err
+	}
+	if len(qs) == 0 {
+		return "", fmt.Errorf("no CREATE USER statement returned")
+	}
+	return strings.Replace(qs[0], "CREATE USER ", "CREATE USER IF NOT EXISTS ", 1), nil
+}
+
+func queryAccounts(ctx context.Context, conn *sql.Conn, q string) ([]account, error) {
+	rows, err := conn.QueryContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []account
+	for rows.Next() {
+		var a account
+		if err = rows.Scan(&a.user, &a.host); err != nil {
+			return nil, err
+		}
+		res = append(res, a)
+	}
+	return res, rows.Err()
+}
+
+// queryStrings returns the first column of every row a query returns.
+func queryStrings(ctx context.Context, conn *sql.Conn, q string) ([]string, error) {
+	rows, err := conn.QueryContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []string
+	for rows.Next() {
+		var v sql.NullString
+		if err = rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		res = append(res, v.String)
+	}
+	return res, rows.Err()
+}
@@ -0,0 +1,205 @@
+package mysqldump
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/MouseHatGames/go-mysqldump/internal/marshal"
+)
+
+// ErrInvalidDump is returned (wrapped) when a dump is not well formed.
+var ErrInvalidDump = errors.New("invalid dump")
+
+// Validate checks that a dump is well formed, reading it entirely, and that its rows match the
+// checksums its footer records, so a dump can be checked for corruption without restoring it. Any
+// problem with the format or the checksums is reported as wrapping ErrInvalidDump.
+func Validate(in io.Reader) (*DumpInfo, error) {
+	info, err := Inspect(in)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidDump, err)
+	}
+
+	seen := make(map[string]bool, len(info.Tables))
+	for _, t := range info.Tables {
+		switch {
+		case t.Header.Name == "":
+			return nil, fmt.Errorf("%w: table without a name", ErrInvalidDump)
+		case seen[t.Header.Name]:
+			return nil, fmt.Errorf("%w: table %s appears more than once", ErrInvalidDump, t.Header.Name)
+		case len(t.Header.Columns) == 0:
+			return nil, fmt.Errorf("%w: table %s has no columns", ErrInvalidDump, t.Header.Name)
+		}
+		seen[t.Header.Name] = true
+	}
+	if err = checkChecksums(info); err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// checkChecksums compares the checksums of the tables of a dump with those its footer records.
+func checkChecksums(info *DumpInfo) error {
+	f := info.Footer
+	if f == nil {
+		return nil
+	}
+
+	if f.Checksums != nil {
+		for _, t := range info.Tables {
+			sum, ok := f.Checksums[t.Header.Name]
+			if !ok {
+				return fmt.Errorf("%w: footer records no checksum of table %s", ErrInvalidDump, t.Header.Name)
+			}
+			if sum != t.Checksum {
+				return fmt.Errorf("%w: rows of table %s have checksum %s, the footer records %s", ErrInvalidDump, t.Header.Name, t.Checksum, sum)
+			}
+		}
+		for name := range f.Checksums {
+			if info.Table(name) == nil {
+				return fmt.Errorf("%w: table %s recorded in the footer is missing", ErrInvalidDump, name)
+			}
+		}
+	}
+	if f.Checksum != "" && f.Checksum != info.Checksum {
+		return fmt.Errorf("%w: dump has checksum %s, the footer records %s", ErrInvalidDump, info.Checksum, f.Checksum)
+	}
+	return nil
+}
+
+// TableComparison holds the result of comparing a table in a dump with the live table.
+type TableComparison struct {
+	Table        string
+	DumpRows     int64
+	LiveRows     int64
+	DumpChecksum string
+	LiveChecksum string
+	// Set if the live table could not be read
+	Err error
+}
+
+// Match reports whether the dump and the live table contain the same rows.
+func (c *TableComparison) Match() bool {
+	return c.Err == nil && c.DumpRows == c.LiveRows && c.DumpChecksum == c.LiveChecksum
+}
+
+// Compare reads every table in info from the database, applying the filters the dump recorded, or
+// the Dumper's for dumps that recorded none, and compares its row count and checksum with the dumped ones.
+func (d *Dumper) Compare(dbName string, info *DumpInfo) ([]*TableComparison, error) {
+	return d.CompareContext(context.Background(), dbName, info)
+}
+
+// CompareContext is Compare, running every query under ctx.
+func (d *Dumper) CompareContext(ctx context.Context, dbName string, info *DumpInfo) ([]*TableComparison, error) {
+	defer d.withContext(ctx)()
+	if err := d.use(dbName); err != nil {
+		return nil, err
+	}
+	// The rows are read with the filters the dump was taken with, if it recorded them
+	if f := info.Header.TableFilters; f != nil {
+		prev := d.filters
+		d.filters = f
+		defer func() {
+			d.filters = prev
+		}()
+	}
+
+	var wg sync.WaitGroup
+	res := make([]*TableComparison, 0, len(info.Tables))
+
+	for _, t := range info.Tables {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		// Query results are snapshots of views that change all the time
+		if t.Header.Type == TableTypeQuery {
+			continue
+		}
+		// Neither are tables whose rows were left out
+		if t.Header.NoData != nil {
+			continue
+		}
+
+		c := &TableComparison{
+			Table:        t.Header.Name,
+			DumpRows:     t.Rows,
+			DumpChecksum: t.Checksum,
+		}
+
+		sum := marshal.NewChecksum()
+		c.Err = d.readTableValues(t.Header.Name, tableUnit{}, nil, dbName, &wg, func(row RowData) error {
+			c.LiveRows++
+			sum.Add(row)
+			return nil
+		})
+		c.LiveChecksum = sum.String()
+
+		res = append(res, c)
+	}
+
+	return res, nil
+}
+
+// VerifyReport is the result of verifying a dump against the database it was taken from.
+type VerifyReport struct {
+	Dump   *DumpInfo
+	Tables []*TableComparison
+}
+
+// Passed reports whether every table in the dump matches the live one.
+func (r *VerifyReport) Passed() bool {
+	for _, t := range r.Tables {
+		if !t.Match() {
+			return false
+		}
+	}
+	return true
+}
+
+// Verify checks that a dump is well formed and compares the row count and checksum of every
+// table in it with the database it was taken from, reading all tables from a single consistent
+// snapshot. opts should be the options the dump was taken with, so the same row transformers, and
+// filters for dumps that didn't record theirs, apply. Problems with the dump itself are reported as wrapping ErrInvalidDump.
+func Verify(ctx context.Context, db *sql.DB, in io.Reader, opts ...Option) (*VerifyReport, error) {
+	info, err := Validate(in)
+	if err != nil {
+		return nil, err
+	}
+
+	d := NewDumper(db, nil, 0, opts...)
+	dbName := info.Header.DatabaseName
+	if err = d.startVerifySnapshot(dbName); err != nil {
+		return nil, err
+	}
+	defer d.endConn()
+
+	tables, err := d.CompareContext(ctx, dbName, info)
+	if err != nil {
+		return nil, err
+	}
+	return &VerifyReport{Dump: info, Tables: tables}, nil
+}
+
+// startVerifySnapshot pins the connection tables are read through for Verify to a consistent snapshot.
+func (d *Dumper) startVerifySnapshot(dbName string) error {
+	switch {
+	case d.isPQ():
+		return nil
+	case d.isTiDB():
+		_, err := d.startSnapshot(dbName)
+		return err
+	case d.isVitess():
+		return d.startOLAP(dbName)
+	}
+
+	err := d.startConn(dbName, "SET SESSION TRANSACTION ISOLATION LEVEL REPEATABLE READ", "START TRANSACTION WITH CONSISTENT SNAPSHOT")
+	if err != nil {
+		return fmt.Errorf("start consistent snapshot: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package mysqldump
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/MouseHatGames/go-mysqldump/internal/marshal"
+)
+
+func stringRow(vals ...string) RowData {
+	row := make(RowData, len(vals))
+	for i := range vals {
+		if vals[i] != "NULL" {
+			row[i] = &vals[i]
+		}
+	}
+	return row
+}
+
+func rowsChecksum(rows ...RowData) string {
+	sum := marshal.NewChecksum()
+	for _, r := range rows {
+		sum.Add(r)
+	}
+	return sum.String()
+}
+
+func TestChecksum(t *testing.T) {
+	a, b := stringRow("1", "a"), stringRow("2", "b")
+	if rowsChecksum(a, b) != rowsChecksum(b, a) {
+		t.Error("checksum depends on the order of the rows")
+	}
+	for _, other := range []RowData{stringRow("1", "NULL"), stringRow("1", ""), stringRow("1a", ""), stringRow("1", "a", "")} {
+		if rowsChecksum(stringRow("1", "a")) == rowsChecksum(other) {
+			t.Errorf("rows %q and %q have the same checksum", rowValues(a), rowValues(other))
+		}
+	}
+}
+
+// testDump writes a dump of two tables, the second dumped by partition, whose footer is changed by footer.
+func testDump(t *testing.T, footer func(f *FileFooter)) []byte {
+	users := []RowData{stringRow("1", "ann"), stringRow("2", "NULL")}
+	orders := [][]RowData{{stringRow("1", "10")}, {stringRow("2", "20"), stringRow("3", "30")}}
+
+	var buf bytes.Buffer
+	w := marshal.NewWriter(&buf)
+	w.WriteFileHeader(&FileHeader{})
+	w.WriteTableHeader(&TableHeader{Name: "users", Columns: []string{"id", "name"}})
+	for _, r := range users {
+		w.WriteRowData(r)
+	}
+	for i, p := range []string{"p0", "p1"} {
+		w.WriteTableHeader(&TableHeader{Name: "orders", Columns: []string{"id", "total"}, Partition: p})
+		for _, r := range orders[i] {
+			w.WriteRowData(r)
+		}
+	}
+
+	sums := map[string]string{
+		"users":  rowsChecksum(users...),
+		"orders": rowsChecksum(append(orders[0], orders[1]...)...),
+	}
+	f := &FileFooter{Checksums: sums, Checksum: dumpChecksum([]string{sums["users"], sums["orders"]})}
+	footer(f)
+	if err := w.WriteFileFooter(f); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestValidateChecksums(t *testing.T) {
+	tests := map[string]struct {
+		footer func(f *FileFooter)
+		valid  bool
+	}{
+		"matching": {func(f *FileFooter) {}, true},
+		"without checksums": {func(f *FileFooter) {
+			f.Checksums, f.Checksum = nil, ""
+		}, true},
+		"other table checksum": {func(f *FileFooter) {
+			f.Checksums["orders"] = f.Checksums["users"]
+		}, false},
+		"table missing from the footer": {func(f *FileFooter) {
+			delete(f.Checksums, "orders")
+		}, false},
+		"table missing from the dump": {func(f *FileFooter) {
+			f.Checksums["items"] = f.Checksums["users"]
+		}, false},
+		"other dump checksum": {func(f *FileFooter) {
+			f.Checksum = dumpChecksum([]string{f.Checksums["orders"], f.Checksums["users"]})
+		}, false},
+	}
+	for name, tt := range tests {
+		_, err := Validate(bytes.NewReader(testDump(t, tt.footer)))
+		if tt.valid && err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidDump) {
+			t.Errorf("%s: validated with %v, want ErrInvalidDump", name, err)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package mysqldump
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// startOLAP pins a connection in the OLAP workload, which streams results instead of
+// enforcing vtgate's row limit on them.
+func (d *Dumper) startOLAP(dbName string) error {
+	if err := d.startConn(dbName, "SET workload = 'olap'"); err != nil {
+		return fmt.Errorf("set up olap workload: %w", err)
+	}
+
+	logrus.Info("Reading through Vitess in the OLAP workload")
+	return nil
+}
+
+func (d *Dumper) isVitess() bool {
+	s, err := d.server()
+	return err == nil && s.flavor == flavorVitess
+}
+
+// isPermissionError reports whether err is the server refusing a statement for lack of privileges,
+// like SUPER or REPLICATION CLIENT which managed servers such as PlanetScale don't grant.
+func isPermissionError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{"error 1227", "error 1142", "error 1044", "access denied", "not allowed"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package mysqldump
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Kinds of the issues reported as warnings, see Warning.
+const (
+	// A table without a primary key or unique NOT NULL index is read in a single query
+	WarningNoChunkKey = "no_chunk_key"
+	// The engine of a table has quirks, see EngineWarn
+	WarningEngine = "engine"
+	// A rule checks a column that wasn't dumped, or dumped rows break rules or value guards
+	WarningRule = "rule"
+	// A hook failed with OnError set to HookWarn
+	WarningHook = "hook"
+	// A session variable couldn't be set
+	WarningSession = "session"
+	// The data isn't read from a single consistent snapshot, or its binlog position is off
+	WarningSnapshot = "snapshot"
+	// Metadata like foreign keys, compression dictionaries or optimizer statistics couldn't be dumped
+	WarningMetadata = "metadata"
+	// An option couldn't be applied
+	WarningOption = "option"
+	// A table was dumped with a number of rows out of its expected range, see WithRowCounts
+	WarningRowCount = "row_count"
+	// The DDL of a table couldn't be read and was reconstructed from INFORMATION_SCHEMA
+	WarningReconstructedDDL = "reconstructed_ddl"
+	// A table went over its limit and the rest of its rows were left out, see WithTableLimits
+	WarningTableLimit = "table_limit"
+	// A table was altered while it was dumped and was dumped again, see WithTableRestarts
+	WarningSchemaChange = "schema_change"
+)
+
+// Warning is an issue that didn't stop the dump but may make it less than what was asked for.
+type Warning struct {
+	Kind string
+	// Empty for issues with the dump as a whole
+	Table   string
+	Message string
+}
+
+// Warnings returns the issues of the current or last dump, in the order they were met. They are also
+// logged as they are met, and reported with the next progress event, see ProgressEvent.Warning.
+func (d *Dumper) Warnings() []Warning {
+	d.warningsMu.Lock()
+	defer d.warningsMu.Unlock()
+	return append([]Warning(nil), d.warnings...)
+}
+
+// warn logs an issue and keeps it for Warnings, unless the same one was already met. It can be called from any goroutine.
+func (d *Dumper) warn(kind string, table string, format string, args ...interface{}) {
+	w := Warning{Kind: kind, Table: table, Message: fmt.Sprintf(format, args...)}
+	logrus.Warn(w.Message)
+
+	d.warningsMu.Lock()
+	defer d.warningsMu.Unlock()
+	for _, o := range d.warnings {
+		if o == w {
+			return
+		}
+	}
+	d.warnings = append(d.warnings, w)
+	d.pendingWarnings = append(d.pendingWarnings, w)
+}
+
+// takeWarnings returns the warnings not reported with a progress event yet.
+func (d *Dumper) takeWarnings() []Warning {
+	d.warningsMu.Lock()
+	defer d.warningsMu.Unlock()
+	p := d.pendingWarnings
+	d.pendingWarnings = nil
+	return p
+}
+
+func (d *Dumper) resetWarnings() {
+	d.warningsMu.Lock()
+	defer d.warningsMu.Unlock()
+	d.warnings, d.pendingWarnings = nil, nil
+}